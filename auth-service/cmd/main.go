@@ -2,18 +2,36 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"log"
 	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/mrityunjay-vashisth/auth-service/internal/auth"
+	"github.com/mrityunjay-vashisth/auth-service/internal/config"
+	"github.com/mrityunjay-vashisth/auth-service/internal/metrics"
 	"github.com/mrityunjay-vashisth/auth-service/internal/oauth"
+	"github.com/mrityunjay-vashisth/auth-service/internal/requestid"
 	"github.com/mrityunjay-vashisth/medusa-proto/authpb"
+	"github.com/mrityunjay-vashisth/medusa-shared/certs"
+	"github.com/mrityunjay-vashisth/medusa-shared/tracing"
+	"github.com/mrityunjay-vashisth/medusa-shared/userpb"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 var client *mongo.Client
@@ -43,23 +61,136 @@ func setupIndexes(client *mongo.Client) error {
 	return err
 }
 
+// watchMongoHealth keeps the gRPC health status in line with MongoDB reachability
+func watchMongoHealth(healthServer *health.Server, client *mongo.Client, interval time.Duration) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := client.Ping(ctx, readpref.Primary())
+		cancel()
+
+		if err != nil {
+			log.Printf("MongoDB health check failed: %v", err)
+			healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+		} else {
+			healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// chainMonitors fans MongoDB command events out to several monitors
+func chainMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
+
+// serveMetrics exposes Prometheus metrics over HTTP next to the gRPC server
+func serveMetrics(port string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	log.Println("Metrics server running on port " + port)
+	if err := http.ListenAndServe(":"+port, mux); err != nil {
+		log.Printf("metrics server stopped: %v", err)
+	}
+}
+
+// serverCredentials serves gRPC over TLS, requiring an allowed client certificate when a
+// client CA is configured. The certificate files are reloaded as they change.
+func serverCredentials(cfg *config.Config) (credentials.TransportCredentials, error) {
+	tlsConfig := cfg.GRPC.TLS
+	store, err := certs.NewStore(tlsConfig.CertFile, tlsConfig.KeyFile, tlsConfig.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	go store.Watch(context.Background(), cfg.TLS.ReloadInterval, func(err error) {
+		if err != nil {
+			log.Printf("Failed to reload TLS certificates from %s: %v", tlsConfig.CertFile, err)
+			return
+		}
+		log.Printf("Reloaded TLS certificates from %s", tlsConfig.CertFile)
+	})
+
+	var allowedClients []string
+	if tlsConfig.ClientCAFile != "" {
+		allowedClients = tlsConfig.AllowedClients
+	}
+	return credentials.NewTLS(certs.ServerConfig(store, allowedClients)), nil
+}
+
+// useDevCertificates issues the certificate of the service from the development CA and
+// uses it, with mutual TLS, for every TLS setting left unset
+func useDevCertificates(cfg *config.Config) error {
+	server := certs.DevLeaf{Name: "auth-service", DNSNames: []string{"auth-service", "localhost", "127.0.0.1", "::1"}, Server: true}
+	dir := cfg.TLS.DevDir
+	if err := certs.EnsureDev(dir, server); err != nil {
+		return err
+	}
+
+	cfg.GRPC.TLS.Enabled = true
+	if cfg.GRPC.TLS.CertFile == "" {
+		cfg.GRPC.TLS.CertFile, cfg.GRPC.TLS.KeyFile = server.CertFile(dir), server.KeyFile(dir)
+	}
+	if cfg.GRPC.TLS.ClientCAFile == "" {
+		cfg.GRPC.TLS.ClientCAFile = filepath.Join(dir, certs.DevCAFile)
+	}
+	return nil
+}
+
 func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if cfg.TLS.DevMode {
+		if err := useDevCertificates(cfg); err != nil {
+			log.Fatalf("Failed to prepare development certificates: %v", err)
+		}
+		log.Printf("Using development TLS certificates from %s", cfg.TLS.DevDir)
+	}
+	log.Printf("Effective configuration: %v", cfg.Redacted())
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	mongoURI := os.Getenv("MONGO_URI")
-	if mongoURI == "" {
-		mongoURI = "mongodb://192.168.1.14:27017"
-	}
-	var err error
-	client, err = mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	shutdownTracing, err := tracing.Init(ctx, tracing.Config{
+		ServiceName: "auth-service",
+		Exporter:    cfg.Tracing.Exporter,
+		FilePath:    cfg.Tracing.FilePath,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Failed to initialize tracing: %v", err)
 	}
+	defer shutdownTracing(context.Background())
 
-	jwtKeyString := os.Getenv("JWT_SECRET_KEY")
-	if jwtKeyString != "" {
-		auth.SetJWTKey([]byte(jwtKeyString))
+	monitor := chainMonitors(otelmongo.NewMonitor(), metrics.CommandMonitor())
+	client, err = mongo.Connect(ctx, options.Client().ApplyURI(cfg.Mongo.URI).SetMonitor(monitor))
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	if err := setupIndexes(client); err != nil {
@@ -68,26 +199,48 @@ func main() {
 
 	oauthManager := oauth.NewManager()
 	//oauthManager.RegisterProvider("google", oauth.NewGoogleProvider("YOUR_GOOGLE_CLIENT_ID", "YOUR_GOOGLE_CLIENT_SECRET"))
-	oauthManager.RegisterProvider("google", oauth.NewMockGoogleProvider())
+	oauthManager.RegisterProvider("google", oauth.NewMockGoogleProvider(cfg.Google.ClientID, string(cfg.Google.ClientSecret), cfg.Google.RedirectURL))
 
-	authService := auth.NewAuthService(client)
-	oauthService := auth.NewOAuthService(oauthManager, client)
+	jwtKey := []byte(cfg.JWT.Secret)
+	authService := auth.NewAuthService(client, jwtKey, cfg.JWT.TokenTTL())
+	oauthService := auth.NewOAuthService(oauthManager, client, jwtKey, cfg.JWT.TokenTTL())
 
-	grpcPort := os.Getenv("AUTH_GRPC_PORT")
-	if grpcPort == "" {
-		grpcPort = "50051"
-	}
+	grpcPort := strconv.Itoa(cfg.GRPC.Port)
 
 	listner, err := net.Listen("tcp", ":"+grpcPort)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	serverOptions := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(requestid.UnaryServerInterceptor(), metrics.UnaryServerInterceptor()),
+	}
+	if cfg.GRPC.TLS.Enabled {
+		creds, err := serverCredentials(cfg)
+		if err != nil {
+			log.Fatalf("Failed to load gRPC TLS certificates: %v", err)
+		}
+		serverOptions = append(serverOptions, grpc.Creds(creds))
+	}
+	grpcServer := grpc.NewServer(serverOptions...)
 	authpb.RegisterAuthServiceServer(grpcServer, authService)
 	authpb.RegisterOAuthServiceServer(grpcServer, oauthService)
+	// UserService has no authentication of its own, so it is only served to clients
+	// holding a certificate issued by the client CA
+	if cfg.GRPC.TLS.Enabled && cfg.GRPC.TLS.ClientCAFile != "" {
+		userpb.RegisterUserServiceServer(grpcServer, auth.NewUserService(client))
+	} else {
+		log.Println("UserService disabled: it requires gRPC TLS with a client CA file")
+	}
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	go watchMongoHealth(healthServer, client, 10*time.Second)
+
+	go serveMetrics(strconv.Itoa(cfg.Metrics.Port))
 
-	log.Println("gRPC server running on port 50051")
+	log.Println("gRPC server running on port " + grpcPort)
 	if err := grpcServer.Serve(listner); err != nil {
 		log.Fatalf("failed to serve %v", err)
 	}
@@ -2,22 +2,28 @@ package auth
 
 import (
 	"context"
-	"errors"
 	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/mrityunjay-vashisth/auth-service/internal/metrics"
+	"github.com/mrityunjay-vashisth/auth-service/internal/requestid"
 	"github.com/mrityunjay-vashisth/medusa-proto/authpb"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel"
 	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-var jwtKey = []byte("your-secure-jwt-secret-replace-in-production")
+var tracer = otel.Tracer("github.com/mrityunjay-vashisth/auth-service/internal/auth")
 
 type authService struct {
 	authpb.UnimplementedAuthServiceServer
-	client *mongo.Client
+	client   *mongo.Client
+	jwtKey   []byte        // Signs issued tokens, shared with the core service
+	tokenTTL time.Duration // Lifetime of issued tokens
 }
 
 type user struct {
@@ -35,18 +41,20 @@ type claims struct {
 	jwt.RegisteredClaims
 }
 
-func NewAuthService(client *mongo.Client) *authService {
-	return &authService{client: client}
+func NewAuthService(client *mongo.Client, jwtKey []byte, tokenTTL time.Duration) *authService {
+	return &authService{client: client, jwtKey: jwtKey, tokenTTL: tokenTTL}
 }
 
 func (s *authService) RegisterUser(ctx context.Context, req *authpb.RegisterUserRequest) (*authpb.RegisterUserResponse, error) {
 	// Validate input
 	if req.Username == "" || req.Password == "" || req.Email == "" || req.Role == "" {
-		return nil, errors.New("username, password email and role are required")
+		return nil, status.Error(codes.InvalidArgument, "username, password email and role are required")
 	}
+	_, hashSpan := tracer.Start(ctx, "bcrypt.GenerateFromPassword")
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashSpan.End()
 	if err != nil {
-		return nil, err
+		return nil, status.Error(codes.Internal, "failed to register user")
 	}
 
 	// Create user document
@@ -63,12 +71,15 @@ func (s *authService) RegisterUser(ctx context.Context, req *authpb.RegisterUser
 	// Insert the user directly, relying on unique indexes
 	_, err = collection.InsertOne(ctx, newUser)
 	if err != nil {
+		metrics.UserRegistrations.WithLabelValues("failure").Inc()
+		log.Printf("register failed request_id=%s: %v", requestid.FromContext(ctx), err)
 		if mongo.IsDuplicateKeyError(err) {
-			return nil, errors.New("email already registered, try logging in")
+			return nil, status.Error(codes.AlreadyExists, "email already registered, try logging in")
 		}
-		return nil, errors.New("failed to register user")
+		return nil, status.Error(codes.Internal, "failed to register user")
 	}
 
+	metrics.UserRegistrations.WithLabelValues("success").Inc()
 	return &authpb.RegisterUserResponse{Message: "User registered successfully"}, nil
 }
 
@@ -77,15 +88,19 @@ func (s *authService) Login(ctx context.Context, req *authpb.LoginRequest) (*aut
 	var u user
 	err := collection.FindOne(ctx, bson.M{"username": req.Username}).Decode(&u)
 	if err != nil {
-		return nil, errors.New("invalid username or password")
+		metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		return nil, status.Error(codes.Unauthenticated, "invalid username or password")
 	}
 
+	_, compareSpan := tracer.Start(ctx, "bcrypt.CompareHashAndPassword")
 	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(req.Password))
+	compareSpan.End()
 	if err != nil {
-		return nil, errors.New("invalid username or password")
+		metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		return nil, status.Error(codes.Unauthenticated, "invalid username or password")
 	}
 
-	expirationTime := time.Now().Add(24 * time.Hour)
+	expirationTime := time.Now().Add(s.tokenTTL)
 	claims := &claims{
 		Username: u.Username,
 		Role:     u.Role,
@@ -95,11 +110,12 @@ func (s *authService) Login(ctx context.Context, req *authpb.LoginRequest) (*aut
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, err := token.SignedString(s.jwtKey)
 	if err != nil {
-		return nil, err
+		return nil, status.Error(codes.Internal, "failed to issue token")
 	}
-	log.Printf("%s", u.Role)
+	log.Printf("login succeeded request_id=%s role=%s", requestid.FromContext(ctx), u.Role)
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
 	return &authpb.LoginResponse{
 		Token:   tokenString,
 		Message: "Successfully logged In",
@@ -110,19 +126,15 @@ func (s *authService) Login(ctx context.Context, req *authpb.LoginRequest) (*aut
 func (s *authService) CheckAccess(ctx context.Context, req *authpb.CheckAccessRequest) (*authpb.CheckAccessResponse, error) {
 	claims := &claims{}
 	token, err := jwt.ParseWithClaims(req.Token, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
+		return s.jwtKey, nil
 	})
 	if err != nil || !token.Valid {
-		return nil, errors.New("invalid token")
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
 	}
 
 	if claims.Role != "superuser" {
-		return nil, errors.New("access denied")
+		return nil, status.Error(codes.PermissionDenied, "access denied")
 	}
 	return &authpb.CheckAccessResponse{Message: "Access granted"}, nil
 
 }
-
-func SetJWTKey(key []byte) {
-	jwtKey = key
-}
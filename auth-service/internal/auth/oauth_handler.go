@@ -15,12 +15,14 @@ import (
 
 type oAuthService struct {
 	authpb.UnimplementedOAuthServiceServer
-	manager *oauth.Manager
-	client  *mongo.Client
+	manager  *oauth.Manager
+	client   *mongo.Client
+	jwtKey   []byte
+	tokenTTL time.Duration
 }
 
-func NewOAuthService(manager *oauth.Manager, client *mongo.Client) *oAuthService {
-	return &oAuthService{manager: manager, client: client}
+func NewOAuthService(manager *oauth.Manager, client *mongo.Client, jwtKey []byte, tokenTTL time.Duration) *oAuthService {
+	return &oAuthService{manager: manager, client: client, jwtKey: jwtKey, tokenTTL: tokenTTL}
 }
 
 func (s *oAuthService) OAuthLogin(ctx context.Context, req *authpb.OAuthLoginRequest) (*authpb.OAuthLoginResponse, error) {
@@ -46,7 +48,7 @@ func (s *oAuthService) OAuthCallback(ctx context.Context, req *authpb.OAuthCallb
 		}
 
 		// Generate JWT for mock user
-		tokenString := generateJWT(userInfo["name"].(string), "user")
+		tokenString := s.generateJWT(userInfo["name"].(string), "user")
 		return &authpb.OAuthCallbackResponse{
 			Token:   tokenString,
 			Message: "Mock Login Success",
@@ -92,7 +94,7 @@ func (s *oAuthService) OAuthCallback(ctx context.Context, req *authpb.OAuthCallb
 	}
 
 	// Generate JWT Token
-	tokenString := generateJWT(u.Username, u.Role)
+	tokenString := s.generateJWT(u.Username, u.Role)
 	return &authpb.OAuthCallbackResponse{
 		Token:   tokenString,
 		Message: "Successfully Logged In",
@@ -101,8 +103,8 @@ func (s *oAuthService) OAuthCallback(ctx context.Context, req *authpb.OAuthCallb
 }
 
 // Helper function to generate JWT token
-func generateJWT(username, role string) string {
-	expirationTime := time.Now().Add(24 * time.Hour)
+func (s *oAuthService) generateJWT(username, role string) string {
+	expirationTime := time.Now().Add(s.tokenTTL)
 	claims := &claims{
 		Username: username,
 		Role:     role,
@@ -112,7 +114,7 @@ func generateJWT(username, role string) string {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, err := token.SignedString(s.jwtKey)
 	if err != nil {
 		log.Println("Error generating JWT:", err)
 		return ""
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/mrityunjay-vashisth/auth-service/internal/requestid"
+	"github.com/mrityunjay-vashisth/medusa-shared/userpb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type userService struct {
+	userpb.UnimplementedUserServiceServer
+	client *mongo.Client
+}
+
+func NewUserService(client *mongo.Client) *userService {
+	return &userService{client: client}
+}
+
+func (s *userService) DeleteUser(ctx context.Context, req *userpb.DeleteUserRequest) (*userpb.DeleteUserResponse, error) {
+	if req.Username == "" || req.TenantId == "" {
+		return nil, status.Error(codes.InvalidArgument, "username and tenant_id are required")
+	}
+
+	collection := s.client.Database("authdb").Collection("users")
+	result, err := collection.DeleteOne(ctx, bson.M{"username": req.Username, "tenantid": req.TenantId})
+	if err != nil {
+		log.Printf("delete user failed request_id=%s: %v", requestid.FromContext(ctx), err)
+		return nil, status.Error(codes.Internal, "failed to delete user")
+	}
+	log.Printf("delete user request_id=%s tenant=%s deleted=%t", requestid.FromContext(ctx), req.TenantId, result.DeletedCount > 0)
+	return &userpb.DeleteUserResponse{Deleted: result.DeletedCount > 0}, nil
+}
+
+func (s *userService) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.User, error) {
+	filter, err := userFilter(req.Username, req.Email, req.TenantId)
+	if err != nil {
+		return nil, err
+	}
+
+	var u user
+	err = s.client.Database("authdb").Collection("users").FindOne(ctx, filter).Decode(&u)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	if err != nil {
+		log.Printf("get user failed request_id=%s: %v", requestid.FromContext(ctx), err)
+		return nil, status.Error(codes.Internal, "failed to get user")
+	}
+	return &userpb.User{Username: u.Username, Email: u.Email, Role: u.Role, TenantId: u.TenantId}, nil
+}
+
+func (s *userService) UserExists(ctx context.Context, req *userpb.UserExistsRequest) (*userpb.UserExistsResponse, error) {
+	filter, err := userFilter(req.Username, req.Email, req.TenantId)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.client.Database("authdb").Collection("users").CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		log.Printf("user exists failed request_id=%s: %v", requestid.FromContext(ctx), err)
+		return nil, status.Error(codes.Internal, "failed to look up user")
+	}
+	return &userpb.UserExistsResponse{Exists: count > 0}, nil
+}
+
+// userFilter matches the user of a tenant with every given username and email
+func userFilter(username, email, tenantID string) (bson.M, error) {
+	if tenantID == "" || (username == "" && email == "") {
+		return nil, status.Error(codes.InvalidArgument, "tenant_id and a username or email are required")
+	}
+	filter := bson.M{"tenantid": tenantID}
+	if username != "" {
+		filter["username"] = username
+	}
+	if email != "" {
+		filter["email"] = email
+	}
+	return filter, nil
+}
@@ -0,0 +1,417 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environments the service can run in
+const (
+	EnvironmentDevelopment = "development"
+	EnvironmentProduction  = "production"
+)
+
+// developmentJWTSecret signs tokens when no secret is configured outside production.
+// It matches the core service default so a local stack works without any setup.
+const developmentJWTSecret = "medusa-development-only-jwt-secret"
+
+// placeholderJWTSecret is the value shipped in the sample .env files
+const placeholderJWTSecret = "your-secure-jwt-secret-replace-in-production"
+
+// minProductionSecretLength is the shortest JWT secret accepted in production
+const minProductionSecretLength = 32
+
+// Config is the typed configuration of the auth service
+type Config struct {
+	Environment string        `yaml:"environment"`
+	Mongo       MongoConfig   `yaml:"mongo"`
+	JWT         JWTConfig     `yaml:"jwt"`
+	GRPC        GRPCConfig    `yaml:"grpc"`
+	Metrics     MetricsConfig `yaml:"metrics"`
+	Tracing     TracingConfig `yaml:"tracing"`
+	Google      OAuthConfig   `yaml:"google"`
+	TLS         TLSConfig     `yaml:"tls"`
+}
+
+// MongoConfig locates the database
+type MongoConfig struct {
+	URI string `yaml:"uri"`
+}
+
+// JWTConfig controls the tokens issued on login
+type JWTConfig struct {
+	Secret          Secret `yaml:"secret"`           // Shared with the core service
+	ExpirationHours int    `yaml:"expiration_hours"` // Lifetime of issued tokens
+}
+
+// GRPCConfig controls the gRPC listener
+type GRPCConfig struct {
+	Port int           `yaml:"port"`
+	TLS  GRPCTLSConfig `yaml:"tls"`
+}
+
+// GRPCTLSConfig serves gRPC over TLS. With a client CA, callers must present a certificate
+// issued by it that names one of the allowed clients.
+type GRPCTLSConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	CertFile       string   `yaml:"cert_file"`
+	KeyFile        string   `yaml:"key_file"`
+	ClientCAFile   string   `yaml:"client_ca_file"`
+	AllowedClients []string `yaml:"allowed_clients"` // Common or DNS names of accepted client certificates
+}
+
+// MetricsConfig controls the Prometheus listener
+type MetricsConfig struct {
+	Port int `yaml:"port"`
+}
+
+// TracingConfig controls how spans are sampled and exported
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`     // otlp, stdout, file or none
+	FilePath    string  `yaml:"file_path"`    // Destination of the file exporter
+	SampleRatio float64 `yaml:"sample_ratio"` // Fraction of new traces that are recorded
+}
+
+// OAuthConfig holds the client registration of an OAuth provider
+type OAuthConfig struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret Secret `yaml:"client_secret"`
+	RedirectURL  string `yaml:"redirect_url"`
+}
+
+// TLSConfig holds the settings shared by every TLS listener
+type TLSConfig struct {
+	DevMode        bool          `yaml:"dev_mode"`        // Generate a local CA and certificates for unset files
+	DevDir         string        `yaml:"dev_dir"`         // Where development certificates are kept
+	ReloadInterval time.Duration `yaml:"reload_interval"` // How often certificate files are checked for changes
+}
+
+// Secret is a configuration value that is never printed
+type Secret string
+
+// String hides the secret, showing only whether it is set
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// TokenTTL returns how long issued tokens stay valid
+func (c JWTConfig) TokenTTL() time.Duration {
+	return time.Duration(c.ExpirationHours) * time.Hour
+}
+
+// Default returns the configuration used when nothing else is set
+func Default() *Config {
+	return &Config{
+		Environment: EnvironmentDevelopment,
+		Mongo:       MongoConfig{URI: "mongodb://localhost:27017"},
+		JWT:         JWTConfig{ExpirationHours: 24},
+		GRPC:        GRPCConfig{Port: 50051, TLS: GRPCTLSConfig{AllowedClients: []string{"core-service"}}},
+		Metrics:     MetricsConfig{Port: 9091},
+		Tracing:     TracingConfig{Exporter: "none", FilePath: "auth-service-traces.json", SampleRatio: 1},
+		Google: OAuthConfig{
+			ClientID:     "mock-client-id",
+			ClientSecret: "mock-client-secret",
+			RedirectURL:  "http://localhost:8080/auth/google/callback",
+		},
+		TLS: TLSConfig{
+			DevDir:         filepath.Join(os.TempDir(), "medusa-dev-tls"),
+			ReloadInterval: 30 * time.Second,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, a YAML file, environment variables and
+// command line flags, each overriding the previous one, and validates the result. The
+// YAML file is named by the -config flag or the CONFIG_FILE variable.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet("auth-service", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML configuration file")
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		name := s.flag
+		fs.Func(name, fmt.Sprintf("%s (env %s)", s.usage, s.env), func(value string) error {
+			flagValues[name] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok && value != "" {
+			if err := s.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	for _, s := range settings {
+		if value, ok := flagValues[s.flag]; ok {
+			if err := s.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", s.flag, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if cfg.JWT.Secret == "" && cfg.Environment != EnvironmentProduction {
+		cfg.JWT.Secret = developmentJWTSecret
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile overrides the configuration with the values of a YAML file. Unknown keys are
+// rejected so that typos do not silently fall back to defaults.
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting. Production additionally requires a strong JWT
+// secret and mutual TLS on the gRPC listener.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Environment != EnvironmentDevelopment && c.Environment != EnvironmentProduction {
+		invalid("environment must be %q or %q", EnvironmentDevelopment, EnvironmentProduction)
+	}
+	if uri, err := url.Parse(c.Mongo.URI); err != nil || (uri.Scheme != "mongodb" && uri.Scheme != "mongodb+srv") {
+		invalid("mongo.uri must be a mongodb:// or mongodb+srv:// URI")
+	}
+	if c.JWT.Secret == "" {
+		invalid("jwt.secret is required")
+	}
+	if c.JWT.ExpirationHours < 1 {
+		invalid("jwt.expiration_hours must be positive")
+	}
+	for name, port := range map[string]int{"grpc.port": c.GRPC.Port, "metrics.port": c.Metrics.Port} {
+		if port < 1 || port > 65535 {
+			invalid("%s must be between 1 and 65535", name)
+		}
+	}
+	if c.GRPC.TLS.Enabled && !c.TLS.DevMode && (c.GRPC.TLS.CertFile == "" || c.GRPC.TLS.KeyFile == "") {
+		invalid("grpc.tls.cert_file and grpc.tls.key_file are required when TLS is enabled")
+	}
+	if c.GRPC.TLS.ClientCAFile != "" && len(c.GRPC.TLS.AllowedClients) == 0 {
+		invalid("grpc.tls.allowed_clients is required with a client CA")
+	}
+	if c.TLS.DevMode && c.TLS.DevDir == "" {
+		invalid("tls.dev_dir is required in development TLS mode")
+	}
+	if c.TLS.ReloadInterval <= 0 {
+		invalid("tls.reload_interval must be positive")
+	}
+	if c.GRPC.Port == c.Metrics.Port {
+		invalid("grpc.port and metrics.port must differ")
+	}
+	switch c.Tracing.Exporter {
+	case "otlp", "stdout", "file", "none":
+	default:
+		invalid("tracing.exporter must be otlp, stdout, file or none")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sample_ratio must be between 0 and 1")
+	}
+	if redirect, err := url.Parse(c.Google.RedirectURL); err != nil || redirect.Host == "" {
+		invalid("google.redirect_url must be an absolute URL")
+	}
+
+	if c.Environment == EnvironmentProduction {
+		secret := string(c.JWT.Secret)
+		if secret != "" && (len(secret) < minProductionSecretLength || secret == placeholderJWTSecret || secret == developmentJWTSecret) {
+			invalid("jwt.secret must be a unique secret of at least %d characters in production", minProductionSecretLength)
+		}
+		if c.TLS.DevMode {
+			invalid("tls.dev_mode must be disabled in production")
+		}
+		if !c.GRPC.TLS.Enabled || c.GRPC.TLS.ClientCAFile == "" {
+			invalid("grpc.tls must be enabled with a client CA in production")
+		}
+	}
+
+	// Sort so the report does not depend on map iteration
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// Redacted returns the effective configuration keyed by setting name, with secrets and
+// database credentials hidden, for logging at startup
+func (c *Config) Redacted() map[string]string {
+	values := make(map[string]string)
+	for _, s := range c.settings() {
+		values[s.key] = s.get()
+	}
+	return values
+}
+
+// setting binds one configuration field to its YAML key, environment variable and flag
+type setting struct {
+	key   string
+	env   string
+	flag  string
+	usage string
+	set   func(string) error
+	get   func() string
+}
+
+// settings lists every configurable field of c
+func (c *Config) settings() []setting {
+	return []setting{
+		stringSetting("environment", "APP_ENV", "Runtime environment: development or production", &c.Environment),
+		uriSetting("mongo.uri", "MONGO_URI", "MongoDB connection string", &c.Mongo.URI),
+		secretSetting("jwt.secret", "JWT_SECRET_KEY", "Secret used to sign tokens, shared with the core service", &c.JWT.Secret),
+		intSetting("jwt.expiration_hours", "JWT_EXPIRATION_HOURS", "Lifetime of issued tokens in hours", &c.JWT.ExpirationHours),
+		intSetting("grpc.port", "AUTH_GRPC_PORT", "gRPC port", &c.GRPC.Port),
+		boolSetting("grpc.tls.enabled", "AUTH_GRPC_TLS_ENABLED", "Serve gRPC over TLS", &c.GRPC.TLS.Enabled),
+		stringSetting("grpc.tls.cert_file", "AUTH_GRPC_TLS_CERT_FILE", "gRPC server certificate file", &c.GRPC.TLS.CertFile),
+		stringSetting("grpc.tls.key_file", "AUTH_GRPC_TLS_KEY_FILE", "gRPC server key file", &c.GRPC.TLS.KeyFile),
+		stringSetting("grpc.tls.client_ca_file", "AUTH_GRPC_TLS_CLIENT_CA_FILE", "CA bundle client certificates must be issued by", &c.GRPC.TLS.ClientCAFile),
+		listSetting("grpc.tls.allowed_clients", "AUTH_GRPC_TLS_ALLOWED_CLIENTS", "Comma separated identities allowed to call the gRPC API", &c.GRPC.TLS.AllowedClients),
+		intSetting("metrics.port", "AUTH_METRICS_PORT", "Prometheus metrics port", &c.Metrics.Port),
+		stringSetting("tracing.exporter", "OTEL_TRACES_EXPORTER", "Trace exporter: otlp, stdout, file or none", &c.Tracing.Exporter),
+		stringSetting("tracing.file_path", "OTEL_TRACES_FILE", "Destination of the file trace exporter", &c.Tracing.FilePath),
+		floatSetting("tracing.sample_ratio", "OTEL_TRACES_SAMPLER_ARG", "Fraction of new traces that are recorded", &c.Tracing.SampleRatio),
+		stringSetting("google.client_id", "GOOGLE_CLIENT_ID", "Google OAuth client ID", &c.Google.ClientID),
+		secretSetting("google.client_secret", "GOOGLE_CLIENT_SECRET", "Google OAuth client secret", &c.Google.ClientSecret),
+		stringSetting("google.redirect_url", "GOOGLE_REDIRECT_URL", "Google OAuth redirect URL", &c.Google.RedirectURL),
+		boolSetting("tls.dev_mode", "TLS_DEV_MODE", "Enable TLS with certificates from a generated local CA", &c.TLS.DevMode),
+		stringSetting("tls.dev_dir", "TLS_DEV_DIR", "Directory of the development CA, shared with the core service", &c.TLS.DevDir),
+		durationSetting("tls.reload_interval", "TLS_RELOAD_INTERVAL", "How often certificate files are checked for changes", &c.TLS.ReloadInterval),
+	}
+}
+
+func newSetting(key, env, usage string, set func(string) error, get func() string) setting {
+	return setting{key: key, env: env, flag: strings.NewReplacer(".", "-", "_", "-").Replace(key), usage: usage, set: set, get: get}
+}
+
+func stringSetting(key, env, usage string, target *string) setting {
+	return newSetting(key, env, usage,
+		func(value string) error { *target = value; return nil },
+		func() string { return *target })
+}
+
+func secretSetting(key, env, usage string, target *Secret) setting {
+	return newSetting(key, env, usage,
+		func(value string) error { *target = Secret(value); return nil },
+		func() string { return target.String() })
+}
+
+// uriSetting hides the password of a connection string when printed
+func uriSetting(key, env, usage string, target *string) setting {
+	return newSetting(key, env, usage,
+		func(value string) error { *target = value; return nil },
+		func() string {
+			if parsed, err := url.Parse(*target); err == nil {
+				return parsed.Redacted()
+			}
+			return "[redacted]"
+		})
+}
+
+func intSetting(key, env, usage string, target *int) setting {
+	return newSetting(key, env, usage,
+		func(value string) error {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid integer %q", value)
+			}
+			*target = parsed
+			return nil
+		},
+		func() string { return strconv.Itoa(*target) })
+}
+
+func floatSetting(key, env, usage string, target *float64) setting {
+	return newSetting(key, env, usage,
+		func(value string) error {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid number %q", value)
+			}
+			*target = parsed
+			return nil
+		},
+		func() string { return strconv.FormatFloat(*target, 'g', -1, 64) })
+}
+
+func boolSetting(key, env, usage string, target *bool) setting {
+	return newSetting(key, env, usage,
+		func(value string) error {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid boolean %q", value)
+			}
+			*target = parsed
+			return nil
+		},
+		func() string { return strconv.FormatBool(*target) })
+}
+
+func durationSetting(key, env, usage string, target *time.Duration) setting {
+	return newSetting(key, env, usage,
+		func(value string) error {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid duration %q", value)
+			}
+			*target = parsed
+			return nil
+		},
+		func() string { return target.String() })
+}
+
+func listSetting(key, env, usage string, target *[]string) setting {
+	return newSetting(key, env, usage,
+		func(value string) error {
+			var items []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			*target = items
+			return nil
+		},
+		func() string { return strings.Join(*target, ",") })
+}
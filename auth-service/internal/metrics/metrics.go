@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/event"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// gRPC server metrics
+var (
+	GRPCServerRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_requests_total",
+		Help: "Total gRPC calls handled by method and status code",
+	}, []string{"method", "code"})
+	GRPCServerRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_request_duration_seconds",
+		Help:    "gRPC call latency in seconds by method",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+	GRPCServerRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "grpc_server_requests_in_flight",
+		Help: "gRPC calls currently being handled",
+	})
+)
+
+// Database metrics
+var (
+	DBCommandsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_commands_total",
+		Help: "Total MongoDB commands by command name and result",
+	}, []string{"command", "result"})
+	DBCommandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_command_duration_seconds",
+		Help:    "MongoDB command latency in seconds by command name",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"command"})
+)
+
+// Business metrics
+var (
+	LoginAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "login_attempts_total",
+		Help: "Login attempts by result",
+	}, []string{"result"})
+	UserRegistrations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_registrations_total",
+		Help: "User registrations by result",
+	}, []string{"result"})
+)
+
+// Handler serves all registered metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// UnaryServerInterceptor records count, latency and in-flight unary gRPC calls
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		GRPCServerRequestsInFlight.Inc()
+		defer GRPCServerRequestsInFlight.Dec()
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		GRPCServerRequestsTotal.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		GRPCServerRequestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// CommandMonitor records the latency and outcome of every MongoDB command
+func CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			DBCommandsTotal.WithLabelValues(e.CommandName, "success").Inc()
+			DBCommandDuration.WithLabelValues(e.CommandName).Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			DBCommandsTotal.WithLabelValues(e.CommandName, "error").Inc()
+			DBCommandDuration.WithLabelValues(e.CommandName).Observe(e.Duration.Seconds())
+		},
+	}
+}
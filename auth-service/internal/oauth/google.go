@@ -48,26 +48,37 @@ package oauth
 
 import (
 	"errors"
+	"net/url"
 
 	"golang.org/x/oauth2"
 )
 
-type MockGoogleProvider struct{}
+type MockGoogleProvider struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+}
 
-func NewMockGoogleProvider() *MockGoogleProvider {
-	return &MockGoogleProvider{}
+// NewMockGoogleProvider creates a provider whose mock endpoints are served by the host
+// of the redirect URL
+func NewMockGoogleProvider(clientID, clientSecret, redirectURL string) *MockGoogleProvider {
+	return &MockGoogleProvider{clientID: clientID, clientSecret: clientSecret, redirectURL: redirectURL}
 }
 
 // Return a fake OAuth config (not calling real Google OAuth)
 func (g *MockGoogleProvider) GetConfig() *oauth2.Config {
+	base := g.redirectURL
+	if redirect, err := url.Parse(g.redirectURL); err == nil {
+		base = redirect.Scheme + "://" + redirect.Host
+	}
 	return &oauth2.Config{
-		ClientID:     "mock-client-id",
-		ClientSecret: "mock-client-secret",
-		RedirectURL:  "http://192.168.1.14:8080/auth/google/callback",
+		ClientID:     g.clientID,
+		ClientSecret: g.clientSecret,
+		RedirectURL:  g.redirectURL,
 		Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
 		Endpoint: oauth2.Endpoint{
-			AuthURL:  "http://192.168.1.14:8080/mock/oauth/google/auth",
-			TokenURL: "http://192.168.1.14:8080/mock/oauth/google/token",
+			AuthURL:  base + "/mock/oauth/google/auth",
+			TokenURL: base + "/mock/oauth/google/token",
 		},
 	}
 }
@@ -0,0 +1,41 @@
+package requestid
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataKey carries the request ID of the originating HTTP request
+const MetadataKey = "x-request-id"
+
+type contextKey struct{}
+
+// FromContext returns the request ID of the call, or an empty string
+func FromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(contextKey{}).(string)
+	return requestID
+}
+
+// UnaryServerInterceptor stores the caller's request ID in the context and logs every call with it
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestID := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(MetadataKey); len(values) > 0 {
+				requestID = values[0]
+			}
+		}
+		ctx = context.WithValue(ctx, contextKey{}, requestID)
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		log.Printf("grpc %s request_id=%s code=%s duration=%s",
+			info.FullMethod, requestID, status.Code(err), time.Since(start))
+		return resp, err
+	}
+}
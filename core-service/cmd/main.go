@@ -2,30 +2,74 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/mrityunjay-vashisth/core-service/internal/apiserver"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
 	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/grpcapi"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
 	"github.com/mrityunjay-vashisth/core-service/internal/services"
+	"github.com/mrityunjay-vashisth/medusa-shared/certs"
+	"github.com/mrityunjay-vashisth/medusa-shared/tracing"
 	"github.com/rs/cors"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	logLevel, _ := zapcore.ParseLevel(cfg.LogLevel)
+	loggerConfig := zap.NewProductionConfig()
+	loggerConfig.Level = zap.NewAtomicLevelAt(logLevel)
+	logger, err := loggerConfig.Build()
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer logger.Sync()
+	if cfg.TLS.DevMode {
+		if err := useDevCertificates(cfg); err != nil {
+			logger.Fatal("Failed to prepare development certificates", zap.Error(err))
+		}
+		logger.Warn("Using development TLS certificates", zap.String("dir", cfg.TLS.DevDir))
+	}
+	logger.Info("Effective configuration", zap.Any("config", cfg.Redacted()))
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	mongoURI := os.Getenv("MONGO_URI")
-	if mongoURI == "" {
-		mongoURI = "mongodb://192.168.1.14:27017"
+	shutdownTracing, err := tracing.Init(ctx, tracing.Config{
+		ServiceName: "core-service",
+		Exporter:    cfg.Tracing.Exporter,
+		FilePath:    cfg.Tracing.FilePath,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
 	}
+	defer shutdownTracing(context.Background())
 
 	dbConfig := db.DBConfig{
 		Type:           db.MongoDB,
-		URI:            mongoURI,
+		URI:            cfg.Mongo.URI,
 		DatabaseName:   "coredb",
 		CollectionName: "entities",
 	}
@@ -35,34 +79,103 @@ func main() {
 		log.Fatal(err)
 	}
 
-	logger, err := zap.NewProduction()
+	ctx = context.WithValue(ctx, "logger", logger)
+	serviceMg := services.NewServiceManager(ctx, dbClient, cfg)
+	apiServer, err := apiserver.NewAPIServer(ctx, dbClient, serviceMg.GetRegistry(), cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize logger: %v", err)
+		logger.Fatal("Failed to set up the API server", zap.Error(err))
 	}
-	defer logger.Sync()
-	logger.Info("ajhdjashjhwdjhj")
 
-	ctx = context.WithValue(ctx, "logger", logger)
-	serviceMg := services.NewServiceManager(ctx, dbClient)
-	apiServer, err := apiserver.NewAPIServer(ctx, dbClient, serviceMg.GetRegistry())
-	if err != nil {
-		log.Println(err)
+	if cfg.GRPC.Enabled {
+		protection := grpcapi.Protection{
+			RateLimits:     apiServer.Buckets,
+			Security:       apiServer.Security,
+			InspectionMode: cfg.Inspection.Mode,
+		}
+		go serveGRPC(cfg, serviceMg.GetRegistry(), protection, logger)
 	}
 
 	corsMiddleware := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"}, // Your React app's URL
+		AllowedOrigins:   cfg.HTTP.CORS.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
 		AllowCredentials: true,
-		// Debug mode for troubleshooting
-		Debug: true,
+		Debug:            cfg.HTTP.CORS.Debug,
+		// Let browser clients read the request ID and the caching and rate limit headers
+		ExposedHeaders: []string{
+			"X-Request-ID", "ETag", "Last-Modified", "Retry-After", "Idempotent-Replayed",
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy",
+		},
 	})
 
-	corsHandler := corsMiddleware.Handler(apiServer.Router)
-	apiPort := os.Getenv("API_PORT")
-	if apiPort == "" {
-		apiPort = "8080"
+	corsHandler := corsMiddleware.Handler(apiServer.Handler())
+	apiPort := strconv.Itoa(cfg.HTTP.Port)
+	server := &http.Server{Addr: ":" + apiPort, Handler: corsHandler}
+	if !cfg.HTTP.TLS.Enabled {
+		log.Println("Core API Server running on port " + apiPort + "...")
+		log.Fatal(server.ListenAndServe())
+	}
+
+	store, err := certs.NewStore(cfg.HTTP.TLS.CertFile, cfg.HTTP.TLS.KeyFile, "")
+	if err != nil {
+		logger.Fatal("Failed to load HTTPS certificate", zap.Error(err))
+	}
+	go store.Watch(context.Background(), cfg.TLS.ReloadInterval, logging.CertificateReloads(logger, store.CertFile()))
+	server.TLSConfig = certs.ServerConfig(store, nil)
+	log.Println("Core API Server running on port " + apiPort + " (HTTPS)...")
+	log.Fatal(server.ListenAndServeTLS("", ""))
+}
+
+// serveGRPC serves the gRPC API on its own port until the process exits. Calls share the
+// rate limits and inspection rules of the REST API.
+func serveGRPC(cfg *config.Config, serviceRegistry registry.ServiceRegistry, protection grpcapi.Protection, logger *zap.Logger) {
+	var opts []grpc.ServerOption
+	if cfg.GRPC.TLS.Enabled {
+		store, err := certs.NewStore(cfg.GRPC.TLS.CertFile, cfg.GRPC.TLS.KeyFile, "")
+		if err != nil {
+			logger.Fatal("Failed to load gRPC certificate", zap.Error(err))
+		}
+		go store.Watch(context.Background(), cfg.TLS.ReloadInterval, logging.CertificateReloads(logger, store.CertFile()))
+		opts = append(opts, grpc.Creds(credentials.NewTLS(certs.ServerConfig(store, nil))))
+	}
+
+	grpcPort := strconv.Itoa(cfg.GRPC.Port)
+	listener, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		logger.Fatal("Failed to listen for gRPC", zap.Error(err))
+	}
+	server := grpcapi.NewServer(serviceRegistry, []byte(cfg.Auth.JWTSecret), protection, logger, opts...)
+	logger.Info("Core gRPC Server running", zap.String("port", grpcPort), zap.Bool("tls", cfg.GRPC.TLS.Enabled))
+	if err := server.Serve(listener); err != nil {
+		logger.Fatal("gRPC server stopped", zap.Error(err))
+	}
+}
+
+// useDevCertificates issues the certificates of the service from the development CA and
+// uses them for every TLS setting left unset
+func useDevCertificates(cfg *config.Config) error {
+	hosts := []string{"core-service", "localhost", "127.0.0.1", "::1"}
+	server := certs.DevLeaf{Name: "core-service", DNSNames: hosts, Server: true}
+	client := certs.DevLeaf{Name: "core-service-client", DNSNames: []string{"core-service"}, Client: true}
+	dir := cfg.TLS.DevDir
+	if err := certs.EnsureDev(dir, server, client); err != nil {
+		return err
+	}
+
+	cfg.HTTP.TLS.Enabled = true
+	if cfg.HTTP.TLS.CertFile == "" {
+		cfg.HTTP.TLS.CertFile, cfg.HTTP.TLS.KeyFile = server.CertFile(dir), server.KeyFile(dir)
+	}
+	cfg.GRPC.TLS.Enabled = true
+	if cfg.GRPC.TLS.CertFile == "" {
+		cfg.GRPC.TLS.CertFile, cfg.GRPC.TLS.KeyFile = server.CertFile(dir), server.KeyFile(dir)
+	}
+	cfg.Auth.TLS.Enabled = true
+	if cfg.Auth.TLS.CAFile == "" {
+		cfg.Auth.TLS.CAFile = filepath.Join(dir, certs.DevCAFile)
+	}
+	if cfg.Auth.TLS.CertFile == "" {
+		cfg.Auth.TLS.CertFile, cfg.Auth.TLS.KeyFile = client.CertFile(dir), client.KeyFile(dir)
 	}
-	log.Println("Core API Server running on port " + apiPort + "...")
-	log.Fatal(http.ListenAndServe(":"+apiPort, corsHandler))
+	return nil
 }
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+)
+
+// requestTimeout bounds a single API call
+const requestTimeout = 30 * time.Second
+
+// apiClient calls the core-service REST API with the token of a context
+type apiClient struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+// apiError is a problem reported by the API
+type apiError struct {
+	utility.Problem
+}
+
+func (e *apiError) Error() string {
+	message := e.Title
+	if e.Detail != "" {
+		message += ": " + e.Detail
+	}
+	for _, field := range e.Errors {
+		message += fmt.Sprintf("\n  %s: %s", field.Field, field.Message)
+	}
+	if e.Status == http.StatusUnauthorized {
+		message += "\nSign in again with: medusactl login"
+	}
+	return message
+}
+
+// client returns an API client of the current context
+func (e *env) client() (*apiClient, error) {
+	_, ctx, err := e.current()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if ctx.CAFile != "" {
+		pem, err := os.ReadFile(ctx.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", ctx.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &apiClient{
+		server: ctx.Server,
+		token:  ctx.Token,
+		http:   &http.Client{Transport: transport, Timeout: requestTimeout},
+	}, nil
+}
+
+// do sends a request to path under /apis/core/v1 and decodes the JSON response into out,
+// unless out is nil
+func (c *apiClient) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	target := c.server + "/apis/core/v1" + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		problem := &apiError{}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err := json.Unmarshal(data, &problem.Problem); err != nil || problem.Title == "" {
+			// Not every error is a problem document, for example from a proxy
+			problem.Title = resp.Status
+			var message struct {
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(data, &message) == nil {
+				problem.Detail = message.Message
+			}
+		}
+		problem.Status = resp.StatusCode
+		return problem
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func login(env *env, args []string) error {
+	flags := commandFlags(env, "login", "")
+	var (
+		username      = flags.String("username", "", "username (defaults to the context's last one)")
+		tenantID      = flags.String("tenant", "", "tenant ID (defaults to the context's last one)")
+		passwordStdin = flags.Bool("password-stdin", false, "read the password from standard input instead of $MEDUSACTL_PASSWORD or a prompt")
+	)
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	name, ctx, err := env.current()
+	if err != nil {
+		return err
+	}
+	if *username == "" {
+		*username = ctx.Username
+	}
+	if *tenantID == "" {
+		*tenantID = ctx.TenantID
+	}
+	if *username == "" {
+		return errors.New("--username is required")
+	}
+
+	password, err := readPassword(env, *passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	client.token = ""
+	var resp struct {
+		Token string `json:"token"`
+	}
+	loginCtx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	err = client.do(loginCtx, http.MethodPost, "/auth/login", nil, map[string]string{
+		"username": *username,
+		"password": password,
+		"tenantid": *tenantID,
+	}, &resp)
+	if err != nil {
+		return err
+	}
+
+	ctx.Username = *username
+	ctx.TenantID = *tenantID
+	ctx.Token = resp.Token
+	if err := env.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stdout, "Signed in to %q as %s\n", name, *username)
+	return nil
+}
+
+// readPassword reads a password from standard input when asked to, else from
+// $MEDUSACTL_PASSWORD, else by prompting for it
+func readPassword(env *env, fromStdin bool) (string, error) {
+	if !fromStdin {
+		if password := os.Getenv("MEDUSACTL_PASSWORD"); password != "" {
+			return password, nil
+		}
+		fmt.Fprint(env.stdout, "Password: ")
+	}
+	line, err := bufio.NewReader(env.stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("no password given")
+	}
+	return password, nil
+}
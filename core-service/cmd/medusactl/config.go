@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the medusactl configuration file. It holds tokens, so it is only readable by
+// its owner.
+type Config struct {
+	CurrentContext string              `yaml:"current_context"`
+	Contexts       map[string]*Context `yaml:"contexts"`
+}
+
+// Context is one environment medusactl talks to
+type Context struct {
+	Server   string                 `yaml:"server" json:"server"`                       // Base URL of core-service, like https://medusa.example.com
+	CAFile   string                 `yaml:"ca_file,omitempty" json:"ca_file,omitempty"` // CA bundle the server is verified against; system roots when empty
+	Username string                 `yaml:"username,omitempty" json:"username,omitempty"`
+	TenantID string                 `yaml:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	Token    string                 `yaml:"token,omitempty" json:"-"`                       // From the last login
+	AuthAddr string                 `yaml:"auth_addr,omitempty" json:"auth_addr,omitempty"` // auth-service gRPC address, for managing users
+	AuthTLS  config.ClientTLSConfig `yaml:"auth_tls,omitempty" json:"auth_tls"`
+}
+
+// env is what a command runs with
+type env struct {
+	config      *Config
+	configPath  string
+	contextName string // Set with -context, overriding the current context
+	output      string
+	stdin       io.Reader
+	stdout      io.Writer
+}
+
+// defaultConfigPath is $MEDUSACTL_CONFIG, or medusactl/config.yaml in the user's
+// configuration directory
+func defaultConfigPath() string {
+	if path := os.Getenv("MEDUSACTL_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "medusactl.yaml"
+	}
+	return filepath.Join(dir, "medusactl", "config.yaml")
+}
+
+// loadConfig reads the configuration file, which does not have to exist yet
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{Contexts: map[string]*Context{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if cfg.Contexts == nil {
+		cfg.Contexts = map[string]*Context{}
+	}
+	return cfg, nil
+}
+
+// save writes the configuration file
+func (e *env) save() error {
+	data, err := yaml.Marshal(e.config)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(e.configPath), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(e.configPath, data, 0o600)
+}
+
+// current returns the name and settings of the context to use
+func (e *env) current() (string, *Context, error) {
+	name := e.contextName
+	if name == "" {
+		name = e.config.CurrentContext
+	}
+	if name == "" {
+		return "", nil, errors.New("no context selected, create one with: medusactl context set NAME --server URL")
+	}
+	ctx, ok := e.config.Contexts[name]
+	if !ok {
+		return "", nil, fmt.Errorf("context %q does not exist", name)
+	}
+	return name, ctx, nil
+}
+
+func contextList(env *env, args []string) error {
+	if _, err := parseArgs(commandFlags(env, "context list", ""), args); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(env.config.Contexts))
+	for name := range env.config.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if env.output == outputJSON {
+		// Tokens are not printed
+		return env.printJSON(map[string]interface{}{"current_context": env.config.CurrentContext, "contexts": env.config.Contexts})
+	}
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		ctx := env.config.Contexts[name]
+		current := ""
+		if name == env.config.CurrentContext {
+			current = "*"
+		}
+		rows = append(rows, []string{current, name, ctx.Server, ctx.Username, ctx.TenantID, ctx.AuthAddr})
+	}
+	return env.printTable([]string{"CURRENT", "NAME", "SERVER", "USERNAME", "TENANT", "AUTH"}, rows)
+}
+
+func contextSet(env *env, args []string) error {
+	flags := commandFlags(env, "context set", "NAME")
+	var (
+		server         = flags.String("server", "", "base URL of core-service, like https://medusa.example.com")
+		caFile         = flags.String("ca-file", "", "CA bundle the server is verified against")
+		authAddr       = flags.String("auth-addr", "", "auth-service gRPC address, for managing users")
+		authCA         = flags.String("auth-ca-file", "", "CA bundle the auth service is verified against")
+		authCert       = flags.String("auth-cert-file", "", "client certificate for the auth service")
+		authKey        = flags.String("auth-key-file", "", "client key for the auth service")
+		authServerName = flags.String("auth-server-name", "", "name expected in the auth service certificate")
+		authInsecure   = flags.Bool("auth-insecure", false, "connect to the auth service without TLS")
+	)
+	values, err := parseArgs(flags, args, "NAME")
+	if err != nil {
+		return err
+	}
+	name := values[0]
+
+	ctx, ok := env.config.Contexts[name]
+	if !ok {
+		ctx = &Context{AuthTLS: config.ClientTLSConfig{Enabled: true}}
+	}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "server":
+			ctx.Server = strings.TrimRight(*server, "/")
+		case "ca-file":
+			ctx.CAFile = *caFile
+		case "auth-addr":
+			ctx.AuthAddr = *authAddr
+		case "auth-ca-file":
+			ctx.AuthTLS.CAFile = *authCA
+		case "auth-cert-file":
+			ctx.AuthTLS.CertFile = *authCert
+		case "auth-key-file":
+			ctx.AuthTLS.KeyFile = *authKey
+		case "auth-server-name":
+			ctx.AuthTLS.ServerName = *authServerName
+		case "auth-insecure":
+			ctx.AuthTLS.Enabled = !*authInsecure
+		}
+	})
+	if ctx.Server == "" {
+		return errors.New("--server is required for a new context")
+	}
+
+	env.config.Contexts[name] = ctx
+	if env.config.CurrentContext == "" {
+		env.config.CurrentContext = name
+	}
+	if err := env.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stdout, "Context %q saved\n", name)
+	return nil
+}
+
+func contextUse(env *env, args []string) error {
+	values, err := parseArgs(commandFlags(env, "context use", "NAME"), args, "NAME")
+	if err != nil {
+		return err
+	}
+	if _, ok := env.config.Contexts[values[0]]; !ok {
+		return fmt.Errorf("context %q does not exist", values[0])
+	}
+	env.config.CurrentContext = values[0]
+	if err := env.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stdout, "Switched to context %q\n", values[0])
+	return nil
+}
+
+func contextDelete(env *env, args []string) error {
+	values, err := parseArgs(commandFlags(env, "context delete", "NAME"), args, "NAME")
+	if err != nil {
+		return err
+	}
+	if _, ok := env.config.Contexts[values[0]]; !ok {
+		return fmt.Errorf("context %q does not exist", values[0])
+	}
+	delete(env.config.Contexts, values[0])
+	if env.config.CurrentContext == values[0] {
+		env.config.CurrentContext = ""
+	}
+	if err := env.save(); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.stdout, "Context %q deleted\n", values[0])
+	return nil
+}
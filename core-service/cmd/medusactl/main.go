@@ -0,0 +1,166 @@
+// Command medusactl is the operator's command-line client of Medusa. It signs in like any
+// other client and keeps a context per environment with the server and token to use.
+//
+//	medusactl context set prod --server https://medusa.example.com
+//	medusactl login --username ops --tenant medusa
+//	medusactl requests list --status failed
+//	medusactl requests approve 8fKq2xLm0aPz --wait
+//	medusactl requests reject 3hVn9wQe1bRt --reason duplicate
+//	medusactl recovery run
+//	medusactl audit tail --follow
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// command is a medusactl subcommand, such as "requests list"
+type command struct {
+	path    []string
+	args    string // Positional arguments, for usage
+	summary string
+	run     func(env *env, args []string) error
+}
+
+var commands = []command{
+	{[]string{"context", "list"}, "", "List contexts, marking the current one", contextList},
+	{[]string{"context", "set"}, "NAME", "Create or change a context", contextSet},
+	{[]string{"context", "use"}, "NAME", "Switch to a context", contextUse},
+	{[]string{"context", "delete"}, "NAME", "Remove a context", contextDelete},
+	{[]string{"login"}, "", "Sign in and keep the token in the current context", login},
+	{[]string{"requests", "list"}, "", "List onboarding requests in a status", requestsList},
+	{[]string{"requests", "get"}, "REQUEST_ID", "Show an onboarding request", requestsGet},
+	{[]string{"requests", "approve"}, "REQUEST_ID", "Start approving a pending onboarding request", requestsApprove},
+	{[]string{"requests", "approval"}, "APPROVAL_ID", "Show the progress of an approval", requestsApproval},
+	{[]string{"requests", "reject"}, "REQUEST_ID", "Reject a pending or failed onboarding request", requestsReject},
+	{[]string{"requests", "dead-letters"}, "", "List requests whose approval failed too often to be retried", requestsDeadLetters},
+	{[]string{"requests", "failures"}, "REQUEST_ID", "Show the failed approvals of a dead-lettered request", requestsFailures},
+	{[]string{"requests", "requeue"}, "REQUEST_ID", "Return a dead-lettered request to the automatic retries", requestsRequeue},
+	{[]string{"recovery", "status"}, "", "Show the stuck request recovery, its leader and its latest run", recoveryStatus},
+	{[]string{"recovery", "run"}, "", "Have the recovery leader recover stuck onboarding requests now", recoveryRun},
+	{[]string{"recovery", "runs"}, "", "List the latest recovery runs", recoveryRuns},
+	{[]string{"recovery", "get"}, "RUN_ID", "Show a recovery run and what it did to each request", recoveryGet},
+	{[]string{"users", "create"}, "", "Create a user through the auth service", usersCreate},
+	{[]string{"roles"}, "", "List the roles users can have", rolesList},
+	{[]string{"audit", "tail"}, "", "Show recent audit entries, optionally following new ones", auditTail},
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "medusactl:", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the global flags and runs the subcommand named by the remaining arguments
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	global := flag.NewFlagSet("medusactl", flag.ContinueOnError)
+	global.SetOutput(stdout)
+	var (
+		configPath  = global.String("config", defaultConfigPath(), "configuration file (defaults to $MEDUSACTL_CONFIG)")
+		contextName = global.String("context", "", "context to use instead of the current one")
+		output      = global.String("o", outputTable, "output format: table or json")
+	)
+	global.Usage = func() { usage(global) }
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+	if *output != outputTable && *output != outputJSON {
+		return fmt.Errorf("unknown output format %q, use table or json", *output)
+	}
+
+	cmd, rest := findCommand(global.Args())
+	if cmd == nil {
+		usage(global)
+		if global.NArg() == 0 {
+			return errors.New("no command given")
+		}
+		return fmt.Errorf("unknown command %q", strings.Join(global.Args(), " "))
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	return cmd.run(&env{
+		config:      cfg,
+		configPath:  *configPath,
+		contextName: *contextName,
+		output:      *output,
+		stdin:       stdin,
+		stdout:      stdout,
+	}, rest)
+}
+
+// findCommand returns the command named by the first arguments and the arguments after
+// its name
+func findCommand(args []string) (*command, []string) {
+	for i := range commands {
+		cmd := &commands[i]
+		if len(args) >= len(cmd.path) && strings.Join(args[:len(cmd.path)], " ") == strings.Join(cmd.path, " ") {
+			return cmd, args[len(cmd.path):]
+		}
+	}
+	return nil, nil
+}
+
+func usage(global *flag.FlagSet) {
+	out := global.Output()
+	fmt.Fprintln(out, "Usage: medusactl [flags] COMMAND [command flags]")
+	fmt.Fprintln(out, "\nCommands:")
+	for _, cmd := range commands {
+		name := strings.Join(cmd.path, " ")
+		if cmd.args != "" {
+			name += " " + cmd.args
+		}
+		fmt.Fprintf(out, "  %-28s %s\n", name, cmd.summary)
+	}
+	fmt.Fprintln(out, "\nFlags:")
+	global.PrintDefaults()
+	fmt.Fprintln(out, "\nRun a command with -h for its flags.")
+}
+
+// commandFlags returns the flag set of a command, printing its usage with -h
+func commandFlags(env *env, name, positional string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(env.stdout)
+	flags.Usage = func() {
+		fmt.Fprintf(env.stdout, "Usage: medusactl %s [flags] %s\n", name, positional)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseArgs parses the flags of a command and checks it got exactly the positional
+// arguments it takes, which may come before or after the flags
+func parseArgs(flags *flag.FlagSet, args []string, positional ...string) ([]string, error) {
+	var values []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		values = append(values, args[0])
+		args = args[1:]
+	}
+	if len(values) != len(positional) {
+		flags.Usage()
+		if len(positional) == 0 {
+			return nil, fmt.Errorf("unexpected argument %q", values[0])
+		}
+		return nil, fmt.Errorf("expected %s", strings.Join(positional, " "))
+	}
+	return values, nil
+}
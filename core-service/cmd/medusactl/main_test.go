@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCoreService answers login, listing and approval like core-service, accepting only
+// the token it issued
+func fakeCoreService(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /apis/core/v1/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		assert.Equal(t, "ops", req["username"])
+		assert.Equal(t, "secret", req["password"])
+		json.NewEncoder(w).Encode(map[string]string{"token": "token-1"})
+	})
+	mux.HandleFunc("GET /apis/core/v1/tenants/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"title":"Unauthorized","status":401,"code":"invalid_token"}`))
+			return
+		}
+		assert.Equal(t, "failed", r.URL.Query().Get("state"))
+		w.Write([]byte(`[{"request_id":"request-1","organization_name":"Clinic","status":"failed","retry_count":2}]`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestLoginAndListRequests(t *testing.T) {
+	server := fakeCoreService(t)
+	defer server.Close()
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+
+	medusactl := func(stdin string, args ...string) (string, error) {
+		var out bytes.Buffer
+		err := run(append([]string{"-config", configPath}, args...), strings.NewReader(stdin), &out)
+		return out.String(), err
+	}
+
+	_, err := medusactl("", "context", "set", "dev", "--server", server.URL+"/")
+	assert.NoError(t, err)
+
+	// Without a token the API answers with a problem, and the user is told to sign in
+	_, err = medusactl("", "requests", "list", "--status", "failed")
+	assert.ErrorContains(t, err, "medusactl login")
+
+	_, err = medusactl("secret\n", "login", "--username", "ops", "--tenant", "medusa", "--password-stdin")
+	assert.NoError(t, err)
+
+	out, err := medusactl("", "requests", "list", "--status", "failed")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "REQUEST ID")
+	assert.Contains(t, out, "request-1")
+
+	out, err = medusactl("", "-o", "json", "requests", "list", "--status", "failed")
+	assert.NoError(t, err)
+	var requests []map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(out), &requests))
+	assert.Len(t, requests, 1)
+
+	// The token is kept in the file, but never printed
+	cfg, err := loadConfig(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", cfg.Contexts["dev"].Token)
+	assert.Equal(t, server.URL, cfg.Contexts["dev"].Server)
+	out, err = medusactl("", "-o", "json", "context", "list")
+	assert.NoError(t, err)
+	assert.NotContains(t, out, "token-1")
+}
+
+func TestContexts(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	medusactl := func(args ...string) error {
+		return run(append([]string{"-config", configPath}, args...), strings.NewReader(""), &bytes.Buffer{})
+	}
+
+	assert.ErrorContains(t, medusactl("requests", "list"), "no context selected")
+	assert.NoError(t, medusactl("context", "set", "staging", "--server", "https://staging.example.com"))
+	assert.NoError(t, medusactl("context", "set", "prod", "--server", "https://medusa.example.com", "--auth-addr", "auth:50051"))
+	assert.Error(t, medusactl("context", "use", "missing"))
+	assert.NoError(t, medusactl("context", "use", "prod"))
+
+	cfg, _ := loadConfig(configPath)
+	assert.Equal(t, "prod", cfg.CurrentContext)
+	assert.True(t, cfg.Contexts["prod"].AuthTLS.Enabled, "The auth service is reached over TLS unless told otherwise")
+
+	assert.NoError(t, medusactl("context", "delete", "prod"))
+	cfg, _ = loadConfig(configPath)
+	assert.Empty(t, cfg.CurrentContext)
+	assert.Len(t, cfg.Contexts, 1)
+}
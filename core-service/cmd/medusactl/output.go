@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printJSON writes value as indented JSON
+func (e *env) printJSON(value interface{}) error {
+	encoder := json.NewEncoder(e.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// printTable writes rows under a header, in aligned columns
+func (e *env) printTable(header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// print writes value as JSON, or as the table returned by toTable
+func (e *env) print(value interface{}, toTable func() ([]string, [][]string)) error {
+	if e.output == outputJSON {
+		return e.printJSON(value)
+	}
+	header, rows := toTable()
+	return e.printTable(header, rows)
+}
+
+// printFields writes the fields of a JSON object, one per line, in the order given and
+// then the rest
+func (e *env) printFields(object map[string]interface{}, order ...string) error {
+	if e.output == outputJSON {
+		return e.printJSON(object)
+	}
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	printed := map[string]bool{"_id": true}
+	for _, key := range order {
+		if value, ok := object[key]; ok {
+			fmt.Fprintf(w, "%s:\t%s\n", key, text(value))
+			printed[key] = true
+		}
+	}
+	for _, key := range sortedKeys(object) {
+		if !printed[key] {
+			fmt.Fprintf(w, "%s:\t%s\n", key, text(object[key]))
+		}
+	}
+	return w.Flush()
+}
+
+// text formats a decoded JSON value for a table cell
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return fmt.Sprint(v)
+	case bool:
+		return fmt.Sprint(v)
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// timeText formats a time for a table cell, empty when unset
+func timeText(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+// optionalTimeText formats a time that may be unset for a table cell
+func optionalTimeText(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return timeText(*t)
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
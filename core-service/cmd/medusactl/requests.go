@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/saga"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/onboardingsvc"
+)
+
+func requestsList(env *env, args []string) error {
+	flags := commandFlags(env, "requests list", "")
+	status := flags.String("status", string(models.OnboardingStatusPending),
+		"unverified, pending, approval_in_progress, user_created, failed, dead_letter, rejected or active")
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	var requests []map[string]interface{}
+	if err := client.do(context.Background(), http.MethodGet, "/tenants/status", url.Values{"state": {*status}}, nil, &requests); err != nil {
+		return err
+	}
+
+	return env.print(requests, func() ([]string, [][]string) {
+		rows := make([][]string, 0, len(requests))
+		for _, request := range requests {
+			rows = append(rows, []string{
+				text(request["request_id"]),
+				text(request["tenant_id"]),
+				text(request["organization_name"]),
+				text(request["email"]),
+				text(request["status"]),
+				text(request["retry_count"]),
+				text(request["failure_reason"]),
+			})
+		}
+		return []string{"REQUEST ID", "TENANT ID", "ORGANIZATION", "EMAIL", "STATUS", "RETRIES", "FAILURE"}, rows
+	})
+}
+
+func requestsGet(env *env, args []string) error {
+	values, err := parseArgs(commandFlags(env, "requests get", "REQUEST_ID"), args, "REQUEST_ID")
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	var request map[string]interface{}
+	if err := client.do(context.Background(), http.MethodGet, "/tenants/status/"+url.PathEscape(values[0]), nil, nil, &request); err != nil {
+		return err
+	}
+	return env.printFields(request, "request_id", "tenant_id", "organization_name", "email", "username", "status")
+}
+
+func requestsApprove(env *env, args []string) error {
+	flags := commandFlags(env, "requests approve", "REQUEST_ID")
+	var (
+		wait    = flags.Bool("wait", false, "wait for the approval to finish and show its steps")
+		timeout = flags.Duration("timeout", 5*time.Minute, "how long --wait waits")
+	)
+	values, err := parseArgs(flags, args, "REQUEST_ID")
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Message    string `json:"message"`
+		ApprovalID string `json:"approval_id"`
+		StatusURL  string `json:"status_url"`
+	}
+	err = client.do(context.Background(), http.MethodPost, "/tenants/approve", nil, map[string]string{"request_id": values[0]}, &resp)
+	if err != nil {
+		return err
+	}
+	if !*wait {
+		if env.output == outputJSON {
+			return env.printJSON(resp)
+		}
+		fmt.Fprintf(env.stdout, "%s: %s\nFollow it with: medusactl requests approval %s\n", resp.Message, resp.ApprovalID, resp.ApprovalID)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	for {
+		approval, err := getApproval(ctx, client, resp.ApprovalID)
+		if err != nil {
+			return err
+		}
+		if approval.Finished() {
+			if err := printApproval(env, approval); err != nil {
+				return err
+			}
+			if approval.Status != saga.StatusCompleted {
+				return fmt.Errorf("approval %s", approval.Status)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("approval %s still %s after %s", approval.ID, approval.Status, *timeout)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func requestsApproval(env *env, args []string) error {
+	values, err := parseArgs(commandFlags(env, "requests approval", "APPROVAL_ID"), args, "APPROVAL_ID")
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	approval, err := getApproval(context.Background(), client, values[0])
+	if err != nil {
+		return err
+	}
+	return printApproval(env, approval)
+}
+
+// getApproval fetches the progress of an approval
+func getApproval(ctx context.Context, client *apiClient, id string) (saga.Instance, error) {
+	var approval saga.Instance
+	err := client.do(ctx, http.MethodGet, "/tenants/approvals/"+url.PathEscape(id), nil, nil, &approval)
+	return approval, err
+}
+
+// printApproval shows an approval and the state of each of its steps
+func printApproval(env *env, approval saga.Instance) error {
+	return env.print(approval, func() ([]string, [][]string) {
+		rows := [][]string{{"approval", approval.Status, "", approval.Error}}
+		for _, step := range approval.Steps {
+			attempts := fmt.Sprint(step.Attempts)
+			if step.CompensationAttempts > 0 {
+				attempts += fmt.Sprintf(" (+%d undoing)", step.CompensationAttempts)
+			}
+			rows = append(rows, []string{step.Name, step.Status, attempts, step.LastError})
+		}
+		return []string{"STEP", "STATUS", "ATTEMPTS", "ERROR"}, rows
+	})
+}
+
+func requestsReject(env *env, args []string) error {
+	flags := commandFlags(env, "requests reject", "REQUEST_ID")
+	var (
+		reason  = flags.String("reason", "", "incomplete_information, unverifiable_organization, duplicate, not_eligible or other")
+		comment = flags.String("comment", "", "explanation emailed to the applicant")
+	)
+	values, err := parseArgs(flags, args, "REQUEST_ID")
+	if err != nil {
+		return err
+	}
+	if *reason == "" {
+		return errors.New("--reason is required")
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Message      string    `json:"message"`
+		ReapplyAfter time.Time `json:"reapply_after"`
+	}
+	err = client.do(context.Background(), http.MethodPost, "/tenants/reject", nil, models.OnboardingRejection{
+		RequestID:  values[0],
+		ReasonCode: models.RejectionReason(*reason),
+		Comment:    *comment,
+	}, &resp)
+	if err != nil {
+		return err
+	}
+	if env.output == outputJSON {
+		return env.printJSON(resp)
+	}
+	fmt.Fprintf(env.stdout, "%s. The email can apply again from %s.\n", resp.Message, timeText(resp.ReapplyAfter))
+	return nil
+}
+
+func requestsDeadLetters(env *env, args []string) error {
+	if _, err := parseArgs(commandFlags(env, "requests dead-letters", ""), args); err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	var requests []map[string]interface{}
+	if err := client.do(context.Background(), http.MethodGet, "/admin/onboarding/dead-letters", nil, nil, &requests); err != nil {
+		return err
+	}
+
+	return env.print(requests, func() ([]string, [][]string) {
+		rows := make([][]string, 0, len(requests))
+		for _, request := range requests {
+			rows = append(rows, []string{
+				text(request["request_id"]),
+				text(request["organization_name"]),
+				text(request["retry_count"]),
+				text(request["dead_lettered_at"]),
+				text(request["failure_reason"]),
+			})
+		}
+		return []string{"REQUEST ID", "ORGANIZATION", "FAILURES", "DEAD-LETTERED", "LAST FAILURE"}, rows
+	})
+}
+
+func requestsFailures(env *env, args []string) error {
+	values, err := parseArgs(commandFlags(env, "requests failures", "REQUEST_ID"), args, "REQUEST_ID")
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	var request struct {
+		FailureHistory []models.ApprovalFailure `json:"failure_history"`
+	}
+	if err := client.do(context.Background(), http.MethodGet, "/admin/onboarding/dead-letters/"+url.PathEscape(values[0]), nil, nil, &request); err != nil {
+		return err
+	}
+
+	return env.print(request, func() ([]string, [][]string) {
+		rows := make([][]string, 0, len(request.FailureHistory))
+		for _, failure := range request.FailureHistory {
+			rows = append(rows, []string{fmt.Sprint(failure.Attempt), timeText(failure.FailedAt), failure.ApprovalID, failure.Reason})
+		}
+		return []string{"ATTEMPT", "FAILED", "APPROVAL ID", "ERROR"}, rows
+	})
+}
+
+func requestsRequeue(env *env, args []string) error {
+	values, err := parseArgs(commandFlags(env, "requests requeue", "REQUEST_ID"), args, "REQUEST_ID")
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	var request map[string]interface{}
+	path := "/admin/onboarding/dead-letters/" + url.PathEscape(values[0]) + "/requeue"
+	if err := client.do(context.Background(), http.MethodPost, path, nil, nil, &request); err != nil {
+		return err
+	}
+	return env.printFields(request, "request_id", "tenant_id", "organization_name", "status", "next_retry_at")
+}
+
+func recoveryStatus(env *env, args []string) error {
+	if _, err := parseArgs(commandFlags(env, "recovery status", ""), args); err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	var status onboardingsvc.RecoveryStatus
+	if err := client.do(context.Background(), http.MethodGet, "/admin/recovery", nil, nil, &status); err != nil {
+		return err
+	}
+
+	return env.print(status, func() ([]string, [][]string) {
+		rows := [][]string{
+			{"interval", status.Interval},
+			{"in progress max age", status.InProgressMaxAge},
+			{"user created max age", status.UserCreatedMaxAge},
+			{"lease ttl", status.LeaseTTL},
+			{"leader", "none"},
+		}
+		if status.Leader != nil {
+			rows[len(rows)-1][1] = status.Leader.Holder
+			rows = append(rows, []string{"leading since", timeText(status.Leader.AcquiredAt)})
+		}
+		if status.LastSuccessfulRun != nil {
+			rows = append(rows, []string{"last successful run", timeText(*status.LastSuccessfulRun)})
+		}
+		if status.LastRun != nil {
+			rows = append(rows, runRows(*status.LastRun, "last run ")...)
+		}
+		return []string{"FIELD", "VALUE"}, rows
+	})
+}
+
+func recoveryRun(env *env, args []string) error {
+	if _, err := parseArgs(commandFlags(env, "recovery run", ""), args); err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	var run onboardingsvc.RecoveryRun
+	if err := client.do(context.Background(), http.MethodPost, "/admin/recovery/runs", nil, nil, &run); err != nil {
+		return err
+	}
+	if err := printRun(env, run); err != nil {
+		return err
+	}
+	if !run.Finished() && env.output != outputJSON {
+		fmt.Fprintf(env.stdout, "The run is still %s. Follow it with: medusactl recovery get %s\n", run.Status, run.ID)
+	}
+	return nil
+}
+
+func recoveryRuns(env *env, args []string) error {
+	flags := commandFlags(env, "recovery runs", "")
+	limit := flags.Int("limit", 20, "how many of the latest runs to show, at most 100")
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	var runs []onboardingsvc.RecoveryRun
+	query := url.Values{"limit": {fmt.Sprint(*limit)}}
+	if err := client.do(context.Background(), http.MethodGet, "/admin/recovery/runs", query, nil, &runs); err != nil {
+		return err
+	}
+
+	return env.print(runs, func() ([]string, [][]string) {
+		rows := make([][]string, 0, len(runs))
+		for _, run := range runs {
+			rows = append(rows, []string{
+				run.ID,
+				run.Trigger,
+				run.Status,
+				run.Replica,
+				optionalTimeText(run.StartedAt),
+				fmt.Sprint(len(run.Requests)),
+				fmt.Sprint(run.Skipped),
+				run.Error,
+			})
+		}
+		return []string{"RUN ID", "TRIGGER", "STATUS", "REPLICA", "STARTED", "REQUESTS", "SKIPPED", "ERROR"}, rows
+	})
+}
+
+func recoveryGet(env *env, args []string) error {
+	values, err := parseArgs(commandFlags(env, "recovery get", "RUN_ID"), args, "RUN_ID")
+	if err != nil {
+		return err
+	}
+
+	client, err := env.client()
+	if err != nil {
+		return err
+	}
+	var run onboardingsvc.RecoveryRun
+	if err := client.do(context.Background(), http.MethodGet, "/admin/recovery/runs/"+url.PathEscape(values[0]), nil, nil, &run); err != nil {
+		return err
+	}
+	return printRun(env, run)
+}
+
+// printRun shows a recovery run followed by what it did to each stuck request
+func printRun(env *env, run onboardingsvc.RecoveryRun) error {
+	return env.print(run, func() ([]string, [][]string) {
+		rows := runRows(run, "")
+		for _, request := range run.Requests {
+			action := request.Action + " (was " + request.Status + ")"
+			if request.Error != "" {
+				action += ": " + request.Error
+			}
+			rows = append(rows, []string{"request " + request.RequestID, action})
+		}
+		return []string{"FIELD", "VALUE"}, rows
+	})
+}
+
+// runRows describes a recovery run as table rows, labels starting with prefix
+func runRows(run onboardingsvc.RecoveryRun, prefix string) [][]string {
+	rows := [][]string{
+		{prefix + "id", run.ID},
+		{prefix + "trigger", run.Trigger},
+	}
+	if run.RequestedBy != "" {
+		rows = append(rows, []string{prefix + "requested by", run.RequestedBy})
+	}
+	rows = append(rows, [][]string{
+		{prefix + "status", run.Status},
+		{prefix + "replica", run.Replica},
+		{prefix + "started", optionalTimeText(run.StartedAt)},
+		{prefix + "finished", optionalTimeText(run.FinishedAt)},
+		{prefix + "promoted to user_created", fmt.Sprint(run.PromotedToUserCreated)},
+		{prefix + "reverted to pending", fmt.Sprint(run.RevertedToPending)},
+		{prefix + "approvals completed", fmt.Sprint(run.ApprovalsCompleted)},
+		{prefix + "skipped", fmt.Sprint(run.Skipped)},
+	}...)
+	if run.Error != "" {
+		rows = append(rows, []string{prefix + "error", run.Error})
+	}
+	return rows
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/services/authsvc"
+	"github.com/mrityunjay-vashisth/medusa-proto/authpb"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// role is a role users can be given, as granted in the OpenAPI specs
+type role struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+var roles = []role{
+	{"superuser", "Operates Medusa: approves tenants, runs recovery, reads every tenant's audit trail"},
+	{"admin", "Administers a tenant: departments, webhooks, the tenant's audit trail and appointments"},
+	{"receptionist", "Books and changes appointments of a tenant"},
+	{"doctor", "Reads appointments and availability of a tenant"},
+}
+
+func usersCreate(env *env, args []string) error {
+	flags := commandFlags(env, "users create", "")
+	var (
+		username      = flags.String("username", "", "username to sign in with")
+		email         = flags.String("email", "", "email address, unique across users")
+		roleName      = flags.String("role", "", "role of the user, see medusactl roles")
+		tenantID      = flags.String("tenant", "", "tenant the user belongs to, empty for superusers")
+		passwordStdin = flags.Bool("password-stdin", false, "read the password from standard input instead of $MEDUSACTL_PASSWORD or a prompt")
+	)
+	if _, err := parseArgs(flags, args); err != nil {
+		return err
+	}
+	if *username == "" || *email == "" || *roleName == "" {
+		return errors.New("--username, --email and --role are required")
+	}
+	if !slices.ContainsFunc(roles, func(r role) bool { return r.Name == *roleName }) {
+		return fmt.Errorf("unknown role %q, see medusactl roles", *roleName)
+	}
+	if *roleName != "superuser" && *tenantID == "" {
+		return errors.New("--tenant is required for users other than superusers")
+	}
+
+	password, err := readPassword(env, *passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	conn, err := env.authConn()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	resp, err := authpb.NewAuthServiceClient(conn).RegisterUser(ctx, &authpb.RegisterUserRequest{
+		Username: *username,
+		Password: password,
+		Email:    *email,
+		Role:     *roleName,
+		TenantId: *tenantID,
+	})
+	if err != nil {
+		return err
+	}
+	if env.output == outputJSON {
+		return env.printJSON(map[string]string{"message": resp.Message, "username": *username, "role": *roleName})
+	}
+	fmt.Fprintln(env.stdout, resp.Message)
+	return nil
+}
+
+func rolesList(env *env, args []string) error {
+	if _, err := parseArgs(commandFlags(env, "roles", ""), args); err != nil {
+		return err
+	}
+	return env.print(roles, func() ([]string, [][]string) {
+		rows := make([][]string, 0, len(roles))
+		for _, r := range roles {
+			rows = append(rows, []string{r.Name, r.Description})
+		}
+		return []string{"ROLE", "DESCRIPTION"}, rows
+	})
+}
+
+// authConn connects to the auth service of the current context, with the client
+// certificate the context names for mutual TLS
+func (e *env) authConn() (*grpc.ClientConn, error) {
+	_, ctx, err := e.current()
+	if err != nil {
+		return nil, err
+	}
+	if ctx.AuthAddr == "" {
+		return nil, errors.New("the context has no auth service, set one with: medusactl context set NAME --auth-addr HOST:PORT")
+	}
+	credentials, err := authsvc.TransportCredentials(ctx.AuthTLS, time.Hour, zap.NewNop())
+	if err != nil {
+		return nil, err
+	}
+	return grpc.NewClient(ctx.AuthAddr, grpc.WithTransportCredentials(credentials))
+}
@@ -0,0 +1,5 @@
+// Package corepb holds the protobuf messages and gRPC services of the core service API.
+// Other services import it to call core-service over gRPC.
+package corepb
+
+//go:generate protoc -I .. --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative ../corepb/reception.proto ../corepb/onboarding.proto
@@ -0,0 +1,249 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: corepb/onboarding.proto
+
+package corepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	OnboardingService_OnboardTenant_FullMethodName = "/medusa.core.v1.OnboardingService/OnboardTenant"
+	OnboardingService_ListTenants_FullMethodName   = "/medusa.core.v1.OnboardingService/ListTenants"
+	OnboardingService_GetTenant_FullMethodName     = "/medusa.core.v1.OnboardingService/GetTenant"
+	OnboardingService_CheckTenant_FullMethodName   = "/medusa.core.v1.OnboardingService/CheckTenant"
+)
+
+// OnboardingServiceClient is the client API for OnboardingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// OnboardingService submits and looks up tenant onboarding requests. Approval is only
+// available through the REST API.
+type OnboardingServiceClient interface {
+	// OnboardTenant submits a request to onboard an organization. No token is required.
+	OnboardTenant(ctx context.Context, in *OnboardTenantRequest, opts ...grpc.CallOption) (*OnboardTenantResponse, error)
+	// ListTenants returns pending requests or active tenants. Roles: superuser.
+	ListTenants(ctx context.Context, in *ListTenantsRequest, opts ...grpc.CallOption) (*ListTenantsResponse, error)
+	// GetTenant returns an onboarding request. Roles: superuser.
+	GetTenant(ctx context.Context, in *GetTenantRequest, opts ...grpc.CallOption) (*Tenant, error)
+	// CheckTenant reports whether a tenant is active. No token is required.
+	CheckTenant(ctx context.Context, in *CheckTenantRequest, opts ...grpc.CallOption) (*CheckTenantResponse, error)
+}
+
+type onboardingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOnboardingServiceClient(cc grpc.ClientConnInterface) OnboardingServiceClient {
+	return &onboardingServiceClient{cc}
+}
+
+func (c *onboardingServiceClient) OnboardTenant(ctx context.Context, in *OnboardTenantRequest, opts ...grpc.CallOption) (*OnboardTenantResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OnboardTenantResponse)
+	err := c.cc.Invoke(ctx, OnboardingService_OnboardTenant_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *onboardingServiceClient) ListTenants(ctx context.Context, in *ListTenantsRequest, opts ...grpc.CallOption) (*ListTenantsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTenantsResponse)
+	err := c.cc.Invoke(ctx, OnboardingService_ListTenants_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *onboardingServiceClient) GetTenant(ctx context.Context, in *GetTenantRequest, opts ...grpc.CallOption) (*Tenant, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Tenant)
+	err := c.cc.Invoke(ctx, OnboardingService_GetTenant_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *onboardingServiceClient) CheckTenant(ctx context.Context, in *CheckTenantRequest, opts ...grpc.CallOption) (*CheckTenantResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckTenantResponse)
+	err := c.cc.Invoke(ctx, OnboardingService_CheckTenant_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OnboardingServiceServer is the server API for OnboardingService service.
+// All implementations must embed UnimplementedOnboardingServiceServer
+// for forward compatibility.
+//
+// OnboardingService submits and looks up tenant onboarding requests. Approval is only
+// available through the REST API.
+type OnboardingServiceServer interface {
+	// OnboardTenant submits a request to onboard an organization. No token is required.
+	OnboardTenant(context.Context, *OnboardTenantRequest) (*OnboardTenantResponse, error)
+	// ListTenants returns pending requests or active tenants. Roles: superuser.
+	ListTenants(context.Context, *ListTenantsRequest) (*ListTenantsResponse, error)
+	// GetTenant returns an onboarding request. Roles: superuser.
+	GetTenant(context.Context, *GetTenantRequest) (*Tenant, error)
+	// CheckTenant reports whether a tenant is active. No token is required.
+	CheckTenant(context.Context, *CheckTenantRequest) (*CheckTenantResponse, error)
+	mustEmbedUnimplementedOnboardingServiceServer()
+}
+
+// UnimplementedOnboardingServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOnboardingServiceServer struct{}
+
+func (UnimplementedOnboardingServiceServer) OnboardTenant(context.Context, *OnboardTenantRequest) (*OnboardTenantResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method OnboardTenant not implemented")
+}
+func (UnimplementedOnboardingServiceServer) ListTenants(context.Context, *ListTenantsRequest) (*ListTenantsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTenants not implemented")
+}
+func (UnimplementedOnboardingServiceServer) GetTenant(context.Context, *GetTenantRequest) (*Tenant, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTenant not implemented")
+}
+func (UnimplementedOnboardingServiceServer) CheckTenant(context.Context, *CheckTenantRequest) (*CheckTenantResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckTenant not implemented")
+}
+func (UnimplementedOnboardingServiceServer) mustEmbedUnimplementedOnboardingServiceServer() {}
+func (UnimplementedOnboardingServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafeOnboardingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OnboardingServiceServer will
+// result in compilation errors.
+type UnsafeOnboardingServiceServer interface {
+	mustEmbedUnimplementedOnboardingServiceServer()
+}
+
+func RegisterOnboardingServiceServer(s grpc.ServiceRegistrar, srv OnboardingServiceServer) {
+	// If the following call pancis, it indicates UnimplementedOnboardingServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&OnboardingService_ServiceDesc, srv)
+}
+
+func _OnboardingService_OnboardTenant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OnboardTenantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OnboardingServiceServer).OnboardTenant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OnboardingService_OnboardTenant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OnboardingServiceServer).OnboardTenant(ctx, req.(*OnboardTenantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OnboardingService_ListTenants_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTenantsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OnboardingServiceServer).ListTenants(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OnboardingService_ListTenants_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OnboardingServiceServer).ListTenants(ctx, req.(*ListTenantsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OnboardingService_GetTenant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTenantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OnboardingServiceServer).GetTenant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OnboardingService_GetTenant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OnboardingServiceServer).GetTenant(ctx, req.(*GetTenantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OnboardingService_CheckTenant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckTenantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OnboardingServiceServer).CheckTenant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OnboardingService_CheckTenant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OnboardingServiceServer).CheckTenant(ctx, req.(*CheckTenantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OnboardingService_ServiceDesc is the grpc.ServiceDesc for OnboardingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OnboardingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "medusa.core.v1.OnboardingService",
+	HandlerType: (*OnboardingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "OnboardTenant",
+			Handler:    _OnboardingService_OnboardTenant_Handler,
+		},
+		{
+			MethodName: "ListTenants",
+			Handler:    _OnboardingService_ListTenants_Handler,
+		},
+		{
+			MethodName: "GetTenant",
+			Handler:    _OnboardingService_GetTenant_Handler,
+		},
+		{
+			MethodName: "CheckTenant",
+			Handler:    _OnboardingService_CheckTenant_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "corepb/onboarding.proto",
+}
@@ -0,0 +1,381 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: corepb/reception.proto
+
+package corepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ReceptionService_ListAppointments_FullMethodName      = "/medusa.core.v1.ReceptionService/ListAppointments"
+	ReceptionService_GetAppointment_FullMethodName        = "/medusa.core.v1.ReceptionService/GetAppointment"
+	ReceptionService_CreateAppointment_FullMethodName     = "/medusa.core.v1.ReceptionService/CreateAppointment"
+	ReceptionService_UpdateAppointment_FullMethodName     = "/medusa.core.v1.ReceptionService/UpdateAppointment"
+	ReceptionService_CancelAppointment_FullMethodName     = "/medusa.core.v1.ReceptionService/CancelAppointment"
+	ReceptionService_GetDoctorAvailability_FullMethodName = "/medusa.core.v1.ReceptionService/GetDoctorAvailability"
+	ReceptionService_WatchAppointments_FullMethodName     = "/medusa.core.v1.ReceptionService/WatchAppointments"
+)
+
+// ReceptionServiceClient is the client API for ReceptionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ReceptionService manages the appointments of the caller's tenant. Every RPC requires a
+// bearer token in the "authorization" metadata; the tenant is taken from the token.
+type ReceptionServiceClient interface {
+	// ListAppointments returns the appointments matching every filter that is set.
+	// Roles: admin, receptionist, doctor.
+	ListAppointments(ctx context.Context, in *ListAppointmentsRequest, opts ...grpc.CallOption) (*ListAppointmentsResponse, error)
+	// GetAppointment returns a single appointment. Roles: admin, receptionist, doctor.
+	GetAppointment(ctx context.Context, in *GetAppointmentRequest, opts ...grpc.CallOption) (*Appointment, error)
+	// CreateAppointment books an appointment if the doctor is free. Roles: admin, receptionist.
+	CreateAppointment(ctx context.Context, in *CreateAppointmentRequest, opts ...grpc.CallOption) (*Appointment, error)
+	// UpdateAppointment changes the fields that are set. Roles: admin, receptionist.
+	UpdateAppointment(ctx context.Context, in *UpdateAppointmentRequest, opts ...grpc.CallOption) (*Appointment, error)
+	// CancelAppointment cancels an appointment. Roles: admin, receptionist.
+	CancelAppointment(ctx context.Context, in *CancelAppointmentRequest, opts ...grpc.CallOption) (*Appointment, error)
+	// GetDoctorAvailability lists the 30 minute slots of a working day.
+	// Roles: admin, receptionist, doctor.
+	GetDoctorAvailability(ctx context.Context, in *GetDoctorAvailabilityRequest, opts ...grpc.CallOption) (*GetDoctorAvailabilityResponse, error)
+	// WatchAppointments streams appointment changes as they happen until the client cancels.
+	// Clients that fall behind are disconnected with RESOURCE_EXHAUSTED and should list
+	// appointments again before watching. Roles: admin, receptionist, doctor.
+	WatchAppointments(ctx context.Context, in *WatchAppointmentsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AppointmentEvent], error)
+}
+
+type receptionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReceptionServiceClient(cc grpc.ClientConnInterface) ReceptionServiceClient {
+	return &receptionServiceClient{cc}
+}
+
+func (c *receptionServiceClient) ListAppointments(ctx context.Context, in *ListAppointmentsRequest, opts ...grpc.CallOption) (*ListAppointmentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAppointmentsResponse)
+	err := c.cc.Invoke(ctx, ReceptionService_ListAppointments_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *receptionServiceClient) GetAppointment(ctx context.Context, in *GetAppointmentRequest, opts ...grpc.CallOption) (*Appointment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Appointment)
+	err := c.cc.Invoke(ctx, ReceptionService_GetAppointment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *receptionServiceClient) CreateAppointment(ctx context.Context, in *CreateAppointmentRequest, opts ...grpc.CallOption) (*Appointment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Appointment)
+	err := c.cc.Invoke(ctx, ReceptionService_CreateAppointment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *receptionServiceClient) UpdateAppointment(ctx context.Context, in *UpdateAppointmentRequest, opts ...grpc.CallOption) (*Appointment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Appointment)
+	err := c.cc.Invoke(ctx, ReceptionService_UpdateAppointment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *receptionServiceClient) CancelAppointment(ctx context.Context, in *CancelAppointmentRequest, opts ...grpc.CallOption) (*Appointment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Appointment)
+	err := c.cc.Invoke(ctx, ReceptionService_CancelAppointment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *receptionServiceClient) GetDoctorAvailability(ctx context.Context, in *GetDoctorAvailabilityRequest, opts ...grpc.CallOption) (*GetDoctorAvailabilityResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDoctorAvailabilityResponse)
+	err := c.cc.Invoke(ctx, ReceptionService_GetDoctorAvailability_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *receptionServiceClient) WatchAppointments(ctx context.Context, in *WatchAppointmentsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AppointmentEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ReceptionService_ServiceDesc.Streams[0], ReceptionService_WatchAppointments_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchAppointmentsRequest, AppointmentEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ReceptionService_WatchAppointmentsClient = grpc.ServerStreamingClient[AppointmentEvent]
+
+// ReceptionServiceServer is the server API for ReceptionService service.
+// All implementations must embed UnimplementedReceptionServiceServer
+// for forward compatibility.
+//
+// ReceptionService manages the appointments of the caller's tenant. Every RPC requires a
+// bearer token in the "authorization" metadata; the tenant is taken from the token.
+type ReceptionServiceServer interface {
+	// ListAppointments returns the appointments matching every filter that is set.
+	// Roles: admin, receptionist, doctor.
+	ListAppointments(context.Context, *ListAppointmentsRequest) (*ListAppointmentsResponse, error)
+	// GetAppointment returns a single appointment. Roles: admin, receptionist, doctor.
+	GetAppointment(context.Context, *GetAppointmentRequest) (*Appointment, error)
+	// CreateAppointment books an appointment if the doctor is free. Roles: admin, receptionist.
+	CreateAppointment(context.Context, *CreateAppointmentRequest) (*Appointment, error)
+	// UpdateAppointment changes the fields that are set. Roles: admin, receptionist.
+	UpdateAppointment(context.Context, *UpdateAppointmentRequest) (*Appointment, error)
+	// CancelAppointment cancels an appointment. Roles: admin, receptionist.
+	CancelAppointment(context.Context, *CancelAppointmentRequest) (*Appointment, error)
+	// GetDoctorAvailability lists the 30 minute slots of a working day.
+	// Roles: admin, receptionist, doctor.
+	GetDoctorAvailability(context.Context, *GetDoctorAvailabilityRequest) (*GetDoctorAvailabilityResponse, error)
+	// WatchAppointments streams appointment changes as they happen until the client cancels.
+	// Clients that fall behind are disconnected with RESOURCE_EXHAUSTED and should list
+	// appointments again before watching. Roles: admin, receptionist, doctor.
+	WatchAppointments(*WatchAppointmentsRequest, grpc.ServerStreamingServer[AppointmentEvent]) error
+	mustEmbedUnimplementedReceptionServiceServer()
+}
+
+// UnimplementedReceptionServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedReceptionServiceServer struct{}
+
+func (UnimplementedReceptionServiceServer) ListAppointments(context.Context, *ListAppointmentsRequest) (*ListAppointmentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAppointments not implemented")
+}
+func (UnimplementedReceptionServiceServer) GetAppointment(context.Context, *GetAppointmentRequest) (*Appointment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAppointment not implemented")
+}
+func (UnimplementedReceptionServiceServer) CreateAppointment(context.Context, *CreateAppointmentRequest) (*Appointment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAppointment not implemented")
+}
+func (UnimplementedReceptionServiceServer) UpdateAppointment(context.Context, *UpdateAppointmentRequest) (*Appointment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateAppointment not implemented")
+}
+func (UnimplementedReceptionServiceServer) CancelAppointment(context.Context, *CancelAppointmentRequest) (*Appointment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelAppointment not implemented")
+}
+func (UnimplementedReceptionServiceServer) GetDoctorAvailability(context.Context, *GetDoctorAvailabilityRequest) (*GetDoctorAvailabilityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDoctorAvailability not implemented")
+}
+func (UnimplementedReceptionServiceServer) WatchAppointments(*WatchAppointmentsRequest, grpc.ServerStreamingServer[AppointmentEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchAppointments not implemented")
+}
+func (UnimplementedReceptionServiceServer) mustEmbedUnimplementedReceptionServiceServer() {}
+func (UnimplementedReceptionServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafeReceptionServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReceptionServiceServer will
+// result in compilation errors.
+type UnsafeReceptionServiceServer interface {
+	mustEmbedUnimplementedReceptionServiceServer()
+}
+
+func RegisterReceptionServiceServer(s grpc.ServiceRegistrar, srv ReceptionServiceServer) {
+	// If the following call pancis, it indicates UnimplementedReceptionServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ReceptionService_ServiceDesc, srv)
+}
+
+func _ReceptionService_ListAppointments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAppointmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceptionServiceServer).ListAppointments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceptionService_ListAppointments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceptionServiceServer).ListAppointments(ctx, req.(*ListAppointmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReceptionService_GetAppointment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAppointmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceptionServiceServer).GetAppointment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceptionService_GetAppointment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceptionServiceServer).GetAppointment(ctx, req.(*GetAppointmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReceptionService_CreateAppointment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAppointmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceptionServiceServer).CreateAppointment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceptionService_CreateAppointment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceptionServiceServer).CreateAppointment(ctx, req.(*CreateAppointmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReceptionService_UpdateAppointment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateAppointmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceptionServiceServer).UpdateAppointment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceptionService_UpdateAppointment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceptionServiceServer).UpdateAppointment(ctx, req.(*UpdateAppointmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReceptionService_CancelAppointment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelAppointmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceptionServiceServer).CancelAppointment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceptionService_CancelAppointment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceptionServiceServer).CancelAppointment(ctx, req.(*CancelAppointmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReceptionService_GetDoctorAvailability_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDoctorAvailabilityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReceptionServiceServer).GetDoctorAvailability(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReceptionService_GetDoctorAvailability_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReceptionServiceServer).GetDoctorAvailability(ctx, req.(*GetDoctorAvailabilityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReceptionService_WatchAppointments_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchAppointmentsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReceptionServiceServer).WatchAppointments(m, &grpc.GenericServerStream[WatchAppointmentsRequest, AppointmentEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ReceptionService_WatchAppointmentsServer = grpc.ServerStreamingServer[AppointmentEvent]
+
+// ReceptionService_ServiceDesc is the grpc.ServiceDesc for ReceptionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ReceptionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "medusa.core.v1.ReceptionService",
+	HandlerType: (*ReceptionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListAppointments",
+			Handler:    _ReceptionService_ListAppointments_Handler,
+		},
+		{
+			MethodName: "GetAppointment",
+			Handler:    _ReceptionService_GetAppointment_Handler,
+		},
+		{
+			MethodName: "CreateAppointment",
+			Handler:    _ReceptionService_CreateAppointment_Handler,
+		},
+		{
+			MethodName: "UpdateAppointment",
+			Handler:    _ReceptionService_UpdateAppointment_Handler,
+		},
+		{
+			MethodName: "CancelAppointment",
+			Handler:    _ReceptionService_CancelAppointment_Handler,
+		},
+		{
+			MethodName: "GetDoctorAvailability",
+			Handler:    _ReceptionService_GetDoctorAvailability_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchAppointments",
+			Handler:       _ReceptionService_WatchAppointments_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "corepb/reception.proto",
+}
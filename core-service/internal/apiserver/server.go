@@ -4,16 +4,25 @@ import (
 	"context"
 	"net/http"
 	"path/filepath"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
 	"github.com/mrityunjay-vashisth/core-service/internal/db"
 	"github.com/mrityunjay-vashisth/core-service/internal/handlers/adminhdlr"
 	"github.com/mrityunjay-vashisth/core-service/internal/handlers/authhdlr"
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/healthhdlr"
 	"github.com/mrityunjay-vashisth/core-service/internal/handlers/onboardinghdlr"
 	"github.com/mrityunjay-vashisth/core-service/internal/handlers/receptionhdlr"
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/webhookhdlr"
+	"github.com/mrityunjay-vashisth/core-service/internal/idempotency"
+	"github.com/mrityunjay-vashisth/core-service/internal/inspection"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
 	"github.com/mrityunjay-vashisth/core-service/internal/middleware"
+	"github.com/mrityunjay-vashisth/core-service/internal/ratelimit"
 	"github.com/mrityunjay-vashisth/core-service/internal/registry"
 	"github.com/mrityunjay-vashisth/go-apigen/pkg/generator"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
 )
 
@@ -24,6 +33,7 @@ const (
 	adminSpecFile     = "admin.yaml"
 	tenantSpecFile    = "onboarding.yaml"
 	receptionSpecFile = "reception.yaml"
+	webhooksSpecFile  = "webhooks.yaml"
 )
 
 // APIServer holds the router and related components
@@ -31,10 +41,22 @@ type APIServer struct {
 	Router   *mux.Router
 	Logger   *zap.Logger
 	Registry registry.ServiceRegistry
+	Security config.SecurityPolicies
+	Limiter  *middleware.RateLimiter
+	Buckets  ratelimit.Store // Rate limit buckets, shared with the gRPC API
+
+	Idempotency idempotency.Store
+	TrustProxy  bool
+	JWTSecret   []byte
+
+	InspectionMode string // Default mode of operations that do not declare one
+
+	Compression    config.CompressionConfig
+	EventHeartbeat time.Duration // Interval of heartbeats on idle event streams
 }
 
 // NewAPIServer initializes the API server with all routers
-func NewAPIServer(ctx context.Context, db db.DBClientInterface, serviceRegistry registry.ServiceRegistry) (*APIServer, error) {
+func NewAPIServer(ctx context.Context, db db.DBClientInterface, serviceRegistry registry.ServiceRegistry, cfg *config.Config) (*APIServer, error) {
 	logger, ok := ctx.Value("logger").(*zap.Logger)
 	if !ok {
 		logger = zap.NewNop()
@@ -44,13 +66,42 @@ func NewAPIServer(ctx context.Context, db db.DBClientInterface, serviceRegistry
 		Router:   mux.NewRouter(),
 		Logger:   logger,
 		Registry: serviceRegistry,
+		Security: make(config.SecurityPolicies),
+
+		TrustProxy: cfg.RateLimit.TrustProxy,
+		JWTSecret:  []byte(cfg.Auth.JWTSecret),
+
+		InspectionMode: cfg.Inspection.Mode,
+
+		Compression:    cfg.HTTP.Compression,
+		EventHeartbeat: cfg.HTTP.Events.HeartbeatInterval,
+	}
+
+	store, err := newRateLimitStore(ctx, db, cfg.RateLimit.Store)
+	if err != nil {
+		logger.Error("Failed to set up rate limit store", zap.Error(err))
+		return nil, err
+	}
+	server.Buckets = store
+	server.Limiter = middleware.NewRateLimiter(store, server.TrustProxy, logger)
+
+	server.Idempotency, err = idempotency.NewMongoStore(ctx, db)
+	if err != nil {
+		logger.Error("Failed to set up idempotency store", zap.Error(err))
+		return nil, err
 	}
 
 	// Create main API router
 	apiRouter := server.Router.PathPrefix("/apis/core/v1").Subrouter()
 
-	// Set up global health check endpoint
-	server.Router.HandleFunc("/health", server.healthCheckHandler).Methods("GET")
+	// Set up global health check endpoints
+	healthHandler := healthhdlr.NewHealthHandler(serviceRegistry, logger)
+	server.Router.Handle("/livez", operation("livez", http.HandlerFunc(healthHandler.Liveness))).Methods("GET")
+	server.Router.Handle("/readyz", operation("readyz", http.HandlerFunc(healthHandler.Readiness))).Methods("GET")
+	server.Router.Handle("/health", operation("health", http.HandlerFunc(healthHandler.Readiness))).Methods("GET")
+
+	// Expose Prometheus metrics
+	server.Router.Handle("/metrics", operation("metrics", metrics.Handler())).Methods("GET")
 
 	// Set up routes for different domains
 	if err := server.setupAuthRoutes(apiRouter, ctx); err != nil {
@@ -69,10 +120,160 @@ func NewAPIServer(ctx context.Context, db db.DBClientInterface, serviceRegistry
 		return nil, err
 	}
 
-	logger.Info("API Server initialized with OpenAPI specs")
+	if err := server.setupWebhookRoutes(apiRouter, ctx); err != nil {
+		return nil, err
+	}
+
+	logger.Info("API Server initialized with OpenAPI specs",
+		zap.Strings("public_routes", server.PublicRoutes()))
 	return server, nil
 }
 
+// Handler returns the router wrapped with the middleware applied to every request
+func (s *APIServer) Handler() http.Handler {
+	middlewares := []mux.MiddlewareFunc{
+		middleware.RequestIDMiddleware(),
+		middleware.ClientIPMiddleware(s.TrustProxy),
+		middleware.MetricsMiddleware(),
+		middleware.LoggingMiddleware(s.Logger),
+		middleware.RecoveryMiddleware(s.Logger),
+	}
+	// Compression wraps the route middleware so ETags and replayed responses are
+	// computed on the uncompressed body
+	if s.Compression.Enabled {
+		middlewares = append(middlewares, middleware.CompressionMiddleware(s.Compression.MinSize))
+	}
+	handler := chain(middlewares...)(s.Router)
+
+	// Start a server span per request, renamed to the operation ID once routed
+	return otelhttp.NewHandler(handler, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "HTTP " + r.Method
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !untracedPaths[r.URL.Path]
+		}),
+	)
+}
+
+// untracedPaths are polled by infrastructure and would only add noise to traces
+var untracedPaths = map[string]bool{
+	"/livez":   true,
+	"/readyz":  true,
+	"/health":  true,
+	"/metrics": true,
+}
+
+// PublicRoutes returns the routes the specs declare as not requiring authentication
+func (s *APIServer) PublicRoutes() []string {
+	return s.Security.PublicRoutes()
+}
+
+// loadSecurity derives per-operation security from an OpenAPI spec file
+func (s *APIServer) loadSecurity(specFile string) (config.SecurityPolicies, error) {
+	policies, err := config.LoadSecurityPolicies(filepath.Join(openapiDir, specFile))
+	if err != nil {
+		s.Logger.Error("Failed to load security requirements", zap.String("spec", specFile), zap.Error(err))
+		return nil, err
+	}
+
+	for operationID, policy := range policies {
+		s.Security[operationID] = policy
+	}
+	return policies, nil
+}
+
+// newRateLimitStore selects where rate limit buckets are kept. The mongo store shares
+// limits across replicas; the default memory store limits each replica on its own.
+func newRateLimitStore(ctx context.Context, database db.DBClientInterface, kind string) (ratelimit.Store, error) {
+	if kind == "mongo" {
+		return ratelimit.NewMongoStore(ctx, database)
+	}
+	return ratelimit.NewMemoryStore(), nil
+}
+
+// routeMiddlewares builds the middleware of an operation route: it tags the request with
+// the operation ID, enforces the security declared for it, applies its rate limit, inspects
+// the request, honours Idempotency-Key and then handles ETags. The chain is composed into a single middleware so
+// the order does not depend on how the router applies them.
+func (s *APIServer) routeMiddlewares(policies config.SecurityPolicies, operationID string) []mux.MiddlewareFunc {
+	middlewares := []mux.MiddlewareFunc{middleware.OperationMiddleware(operationID)}
+	middlewares = append(middlewares, s.securedBy(policies, operationID)...)
+	middlewares = append(middlewares, s.Limiter.Middleware(operationID, s.rateLimitsOf(policies, operationID)))
+	middlewares = append(middlewares, s.inspectionOf(policies, operationID))
+	middlewares = append(middlewares, middleware.IdempotencyMiddleware(s.Idempotency, operationID, s.Logger))
+	middlewares = append(middlewares, middleware.ETagMiddleware())
+	return []mux.MiddlewareFunc{chain(middlewares...)}
+}
+
+// eventStreamMiddlewares builds the middleware of an event stream route, which also accepts
+// the token in the query since browsers cannot send headers on these requests
+func (s *APIServer) eventStreamMiddlewares(policies config.SecurityPolicies, operationID string) []mux.MiddlewareFunc {
+	return []mux.MiddlewareFunc{chain(append([]mux.MiddlewareFunc{middleware.QueryTokenMiddleware()}, s.routeMiddlewares(policies, operationID)...)...)}
+}
+
+// rateLimitsOf returns the rate limits of an operation, using the authenticated default
+// for operations missing from the spec
+func (s *APIServer) rateLimitsOf(policies config.SecurityPolicies, operationID string) []config.RateLimitPolicy {
+	if policy, ok := policies[operationID]; ok {
+		return policy.RateLimits
+	}
+	return []config.RateLimitPolicy{config.DefaultAuthenticatedRateLimit}
+}
+
+// inspectionOf builds the request inspection of an operation. Operations missing from the
+// spec still get operator detection on their query and body.
+func (s *APIServer) inspectionOf(policies config.SecurityPolicies, operationID string) mux.MiddlewareFunc {
+	policy := config.InspectionPolicy{InspectBody: true}
+	if declared, ok := policies[operationID]; ok {
+		policy = declared.Inspection
+	}
+	mode := policy.Mode
+	if mode == "" {
+		mode = s.InspectionMode
+	}
+	return middleware.InspectionMiddleware(inspection.NewRuleSet(policy), mode, operationID, s.Logger)
+}
+
+// securedBy builds the middleware chain enforcing the declared security of an operation.
+// Operations missing from the spec fail closed and require authentication.
+func (s *APIServer) securedBy(policies config.SecurityPolicies, operationID string) []mux.MiddlewareFunc {
+	policy, ok := policies[operationID]
+	if !ok {
+		s.Logger.Warn("No security declared for operation, requiring authentication",
+			zap.String("operationId", operationID))
+		return []mux.MiddlewareFunc{middleware.AuthRequiredMiddleware(s.Registry, s.JWTSecret)}
+	}
+
+	if policy.Public {
+		return nil
+	}
+
+	authMiddleware := middleware.AuthRequiredMiddleware(s.Registry, s.JWTSecret)
+	if len(policy.Roles) == 0 {
+		return []mux.MiddlewareFunc{authMiddleware}
+	}
+
+	// Compose authentication and role checks so claims are always populated first
+	roleMiddleware := middleware.RoleRequiredMiddleware(policy.Roles, s.Logger)
+	return []mux.MiddlewareFunc{chain(authMiddleware, roleMiddleware)}
+}
+
+// chain composes middlewares so the first one is the outermost
+func chain(middlewares ...mux.MiddlewareFunc) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+		return next
+	}
+}
+
+// operation tags a route that is not generated from a spec with an operation ID
+func operation(operationID string, handler http.Handler) http.Handler {
+	return middleware.OperationMiddleware(operationID)(handler)
+}
+
 // setupAuthRoutes creates the auth subrouter using go-apigen
 func (s *APIServer) setupAuthRoutes(parent *mux.Router, ctx context.Context) error {
 	// Parse OpenAPI spec for auth endpoints
@@ -82,16 +283,23 @@ func (s *APIServer) setupAuthRoutes(parent *mux.Router, ctx context.Context) err
 		return err
 	}
 
+	authSecurity, err := s.loadSecurity(authSpecFile)
+	if err != nil {
+		return err
+	}
+
 	// Get auth handler
 	authHandler := authhdlr.NewAuthHandler(s.Registry, s.Logger)
 
 	// Define operations map for auth endpoints
 	authOps := generator.OperationMap{
 		"loginUser": generator.RouteDefinition{
-			Handler: authHandler.Login,
+			Handler:     authHandler.Login,
+			Middlewares: s.routeMiddlewares(authSecurity, "loginUser"),
 		},
 		"registerUser": generator.RouteDefinition{
-			Handler: authHandler.Register,
+			Handler:     authHandler.Register,
+			Middlewares: s.routeMiddlewares(authSecurity, "registerUser"),
 		},
 	}
 
@@ -120,28 +328,51 @@ func (s *APIServer) setupTenantRoutes(parent *mux.Router, ctx context.Context) e
 		return err
 	}
 
+	tenantSecurity, err := s.loadSecurity(tenantSpecFile)
+	if err != nil {
+		return err
+	}
+
 	// Get onboarding handler
 	onboardingHandler := onboardinghdlr.NewOnboardingHandler(s.Registry, s.Logger)
 
 	// Define operations map for tenant endpoints
 	tenantOps := generator.OperationMap{
 		"onboardTenant": generator.RouteDefinition{
-			Handler: onboardingHandler.OnboardTenant,
+			Handler:     onboardingHandler.OnboardTenant,
+			Middlewares: s.routeMiddlewares(tenantSecurity, "onboardTenant"),
+		},
+		"verifyTenantEmail": generator.RouteDefinition{
+			Handler:     onboardingHandler.VerifyEmail,
+			Middlewares: s.routeMiddlewares(tenantSecurity, "verifyTenantEmail"),
+		},
+		"resendTenantVerification": generator.RouteDefinition{
+			Handler:     onboardingHandler.ResendVerification,
+			Middlewares: s.routeMiddlewares(tenantSecurity, "resendTenantVerification"),
 		},
 		"getTenants": generator.RouteDefinition{
 			Handler:     onboardingHandler.GetTenants,
-			Middlewares: []mux.MiddlewareFunc{middleware.AuthRequiredMiddleware(s.Registry)},
+			Middlewares: s.routeMiddlewares(tenantSecurity, "getTenants"),
 		},
 		"getTenantById": generator.RouteDefinition{
 			Handler:     onboardingHandler.GetTenantByRequestID,
-			Middlewares: []mux.MiddlewareFunc{middleware.AuthRequiredMiddleware(s.Registry)},
+			Middlewares: s.routeMiddlewares(tenantSecurity, "getTenantById"),
 		},
 		"approveTenant": generator.RouteDefinition{
 			Handler:     onboardingHandler.ApproveOnboarding,
-			Middlewares: []mux.MiddlewareFunc{middleware.AuthRequiredMiddleware(s.Registry)},
+			Middlewares: s.routeMiddlewares(tenantSecurity, "approveTenant"),
+		},
+		"getApproval": generator.RouteDefinition{
+			Handler:     onboardingHandler.GetApproval,
+			Middlewares: s.routeMiddlewares(tenantSecurity, "getApproval"),
+		},
+		"rejectTenant": generator.RouteDefinition{
+			Handler:     onboardingHandler.RejectOnboarding,
+			Middlewares: s.routeMiddlewares(tenantSecurity, "rejectTenant"),
 		},
 		"checkTenantById": generator.RouteDefinition{
-			Handler: onboardingHandler.GetTenantExistsByRequestID,
+			Handler:     onboardingHandler.GetTenantExistsByRequestID,
+			Middlewares: s.routeMiddlewares(tenantSecurity, "checkTenantById"),
 		},
 	}
 
@@ -170,33 +401,71 @@ func (s *APIServer) setupAdminRoutes(parent *mux.Router, ctx context.Context) er
 		return err
 	}
 
+	adminSecurity, err := s.loadSecurity(adminSpecFile)
+	if err != nil {
+		return err
+	}
+
 	// Get admin handler
 	adminHandler := adminhdlr.NewAdminHandler(s.Registry, s.Logger)
 
-	// Common admin middleware - ensure auth is required for all admin routes
-	adminMiddleware := middleware.AuthRequiredMiddleware(s.Registry)
-
 	// Define operations map for admin endpoints
 	adminOps := generator.OperationMap{
 		"listDepartments": generator.RouteDefinition{
 			Handler:     adminHandler.ServeHTTP,
-			Middlewares: []mux.MiddlewareFunc{adminMiddleware},
+			Middlewares: s.routeMiddlewares(adminSecurity, "listDepartments"),
 		},
 		"createDepartment": generator.RouteDefinition{
 			Handler:     adminHandler.ServeHTTP,
-			Middlewares: []mux.MiddlewareFunc{adminMiddleware},
+			Middlewares: s.routeMiddlewares(adminSecurity, "createDepartment"),
 		},
 		"getDepartmentById": generator.RouteDefinition{
 			Handler:     adminHandler.ServeHTTP,
-			Middlewares: []mux.MiddlewareFunc{adminMiddleware},
+			Middlewares: s.routeMiddlewares(adminSecurity, "getDepartmentById"),
 		},
 		"updateDepartment": generator.RouteDefinition{
 			Handler:     adminHandler.ServeHTTP,
-			Middlewares: []mux.MiddlewareFunc{adminMiddleware},
+			Middlewares: s.routeMiddlewares(adminSecurity, "updateDepartment"),
 		},
 		"deleteDepartment": generator.RouteDefinition{
 			Handler:     adminHandler.ServeHTTP,
-			Middlewares: []mux.MiddlewareFunc{adminMiddleware},
+			Middlewares: s.routeMiddlewares(adminSecurity, "deleteDepartment"),
+		},
+		"listAuditEntries": generator.RouteDefinition{
+			Handler:     adminHandler.ListAuditEntries,
+			Middlewares: s.routeMiddlewares(adminSecurity, "listAuditEntries"),
+		},
+		"verifyAuditChain": generator.RouteDefinition{
+			Handler:     adminHandler.VerifyAuditChain,
+			Middlewares: s.routeMiddlewares(adminSecurity, "verifyAuditChain"),
+		},
+		"getRecoveryStatus": generator.RouteDefinition{
+			Handler:     adminHandler.GetRecoveryStatus,
+			Middlewares: s.routeMiddlewares(adminSecurity, "getRecoveryStatus"),
+		},
+		"runRecovery": generator.RouteDefinition{
+			Handler:     adminHandler.RunRecovery,
+			Middlewares: s.routeMiddlewares(adminSecurity, "runRecovery"),
+		},
+		"listRecoveryRuns": generator.RouteDefinition{
+			Handler:     adminHandler.ListRecoveryRuns,
+			Middlewares: s.routeMiddlewares(adminSecurity, "listRecoveryRuns"),
+		},
+		"getRecoveryRun": generator.RouteDefinition{
+			Handler:     adminHandler.GetRecoveryRun,
+			Middlewares: s.routeMiddlewares(adminSecurity, "getRecoveryRun"),
+		},
+		"listDeadLetters": generator.RouteDefinition{
+			Handler:     adminHandler.ListDeadLetters,
+			Middlewares: s.routeMiddlewares(adminSecurity, "listDeadLetters"),
+		},
+		"getDeadLetter": generator.RouteDefinition{
+			Handler:     adminHandler.GetDeadLetter,
+			Middlewares: s.routeMiddlewares(adminSecurity, "getDeadLetter"),
+		},
+		"requeueDeadLetter": generator.RouteDefinition{
+			Handler:     adminHandler.RequeueDeadLetter,
+			Middlewares: s.routeMiddlewares(adminSecurity, "requeueDeadLetter"),
 		},
 	}
 
@@ -225,37 +494,48 @@ func (s *APIServer) setupReceptionRoutes(parent *mux.Router, ctx context.Context
 		return err
 	}
 
-	// Get reception handler
-	receptionHandler := receptionhdlr.NewReceptionHandler(s.Registry, s.Logger)
+	receptionSecurity, err := s.loadSecurity(receptionSpecFile)
+	if err != nil {
+		return err
+	}
 
-	// Common middleware for all reception routes
-	receptionMiddleware := middleware.AuthRequiredMiddleware(s.Registry)
+	// Get reception handlers
+	receptionHandler := receptionhdlr.NewReceptionHandler(s.Registry, s.Logger)
+	eventsHandler := receptionhdlr.NewAppointmentEventsHandler(s.Registry, s.EventHeartbeat, s.Logger)
 
 	// Define operations map for reception endpoints
 	receptionOps := generator.OperationMap{
 		"listAppointments": generator.RouteDefinition{
 			Handler:     receptionHandler.ListAppointments,
-			Middlewares: []mux.MiddlewareFunc{receptionMiddleware},
+			Middlewares: s.routeMiddlewares(receptionSecurity, "listAppointments"),
 		},
 		"createAppointment": generator.RouteDefinition{
 			Handler:     receptionHandler.CreateAppointment,
-			Middlewares: []mux.MiddlewareFunc{receptionMiddleware},
+			Middlewares: s.routeMiddlewares(receptionSecurity, "createAppointment"),
 		},
 		"getAppointmentById": generator.RouteDefinition{
 			Handler:     receptionHandler.GetAppointmentByID,
-			Middlewares: []mux.MiddlewareFunc{receptionMiddleware},
+			Middlewares: s.routeMiddlewares(receptionSecurity, "getAppointmentById"),
 		},
 		"updateAppointment": generator.RouteDefinition{
 			Handler:     receptionHandler.UpdateAppointment,
-			Middlewares: []mux.MiddlewareFunc{receptionMiddleware},
+			Middlewares: s.routeMiddlewares(receptionSecurity, "updateAppointment"),
 		},
 		"cancelAppointment": generator.RouteDefinition{
 			Handler:     receptionHandler.CancelAppointment,
-			Middlewares: []mux.MiddlewareFunc{receptionMiddleware},
+			Middlewares: s.routeMiddlewares(receptionSecurity, "cancelAppointment"),
 		},
 		"getAvailability": generator.RouteDefinition{
 			Handler:     receptionHandler.GetDoctorAvailability,
-			Middlewares: []mux.MiddlewareFunc{receptionMiddleware},
+			Middlewares: s.routeMiddlewares(receptionSecurity, "getAvailability"),
+		},
+		"streamAppointmentEvents": generator.RouteDefinition{
+			Handler:     eventsHandler.StreamEvents,
+			Middlewares: s.eventStreamMiddlewares(receptionSecurity, "streamAppointmentEvents"),
+		},
+		"streamAppointmentEventsWebSocket": generator.RouteDefinition{
+			Handler:     eventsHandler.StreamEventsWebSocket,
+			Middlewares: s.eventStreamMiddlewares(receptionSecurity, "streamAppointmentEventsWebSocket"),
 		},
 	}
 
@@ -275,9 +555,67 @@ func (s *APIServer) setupReceptionRoutes(parent *mux.Router, ctx context.Context
 	return nil
 }
 
-// healthCheckHandler provides a simple health check endpoint
-func (s *APIServer) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status":"healthy"}`))
+// setupWebhookRoutes creates the webhooks subrouter using go-apigen
+func (s *APIServer) setupWebhookRoutes(parent *mux.Router, ctx context.Context) error {
+	// Parse OpenAPI spec for webhook endpoints
+	webhooksSpec, err := generator.ParseOpenAPIFile(filepath.Join(openapiDir, webhooksSpecFile))
+	if err != nil {
+		s.Logger.Error("Failed to parse webhooks OpenAPI spec", zap.Error(err))
+		return err
+	}
+
+	webhooksSecurity, err := s.loadSecurity(webhooksSpecFile)
+	if err != nil {
+		return err
+	}
+
+	// Get webhook handlers
+	webhookHandler := webhookhdlr.NewWebhookHandler(s.Registry, s.Logger)
+
+	// Define operations map for webhook endpoints
+	webhookOps := generator.OperationMap{
+		"listWebhookEndpoints": generator.RouteDefinition{
+			Handler:     webhookHandler.ListEndpoints,
+			Middlewares: s.routeMiddlewares(webhooksSecurity, "listWebhookEndpoints"),
+		},
+		"createWebhookEndpoint": generator.RouteDefinition{
+			Handler:     webhookHandler.CreateEndpoint,
+			Middlewares: s.routeMiddlewares(webhooksSecurity, "createWebhookEndpoint"),
+		},
+		"getWebhookEndpoint": generator.RouteDefinition{
+			Handler:     webhookHandler.GetEndpoint,
+			Middlewares: s.routeMiddlewares(webhooksSecurity, "getWebhookEndpoint"),
+		},
+		"updateWebhookEndpoint": generator.RouteDefinition{
+			Handler:     webhookHandler.UpdateEndpoint,
+			Middlewares: s.routeMiddlewares(webhooksSecurity, "updateWebhookEndpoint"),
+		},
+		"deleteWebhookEndpoint": generator.RouteDefinition{
+			Handler:     webhookHandler.DeleteEndpoint,
+			Middlewares: s.routeMiddlewares(webhooksSecurity, "deleteWebhookEndpoint"),
+		},
+		"listWebhookDeliveries": generator.RouteDefinition{
+			Handler:     webhookHandler.ListDeliveries,
+			Middlewares: s.routeMiddlewares(webhooksSecurity, "listWebhookDeliveries"),
+		},
+		"redeliverWebhook": generator.RouteDefinition{
+			Handler:     webhookHandler.Redeliver,
+			Middlewares: s.routeMiddlewares(webhooksSecurity, "redeliverWebhook"),
+		},
+	}
+
+	// Generate router using go-apigen
+	webhooksRouter, err := generator.GenerateMuxRouter(webhooksSpec, webhookOps)
+	if err != nil {
+		s.Logger.Error("Failed to generate webhooks router", zap.Error(err))
+		return err
+	}
+
+	// Mount webhooks router under /webhooks path prefix
+	parent.PathPrefix("/webhooks").Handler(
+		http.StripPrefix("/apis/core/v1/webhooks", webhooksRouter),
+	)
+
+	s.Logger.Info("Webhook routes configured")
+	return nil
 }
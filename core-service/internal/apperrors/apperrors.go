@@ -0,0 +1,108 @@
+package apperrors
+
+import "errors"
+
+// Kind classifies a domain error independently of the transport that reports it
+type Kind string
+
+const (
+	KindValidation   Kind = "validation"   // The request is malformed or incomplete
+	KindUnauthorized Kind = "unauthorized" // The caller could not be authenticated
+	KindForbidden    Kind = "forbidden"    // The caller may not perform the action
+	KindNotFound     Kind = "not_found"    // The resource does not exist
+	KindConflict     Kind = "conflict"     // The request conflicts with the current state
+	KindPrecondition Kind = "precondition" // A precondition sent by the caller no longer holds
+	KindUnavailable  Kind = "unavailable"  // A dependency is down, the request can be retried
+	KindInternal     Kind = "internal"     // Anything else
+)
+
+// FieldError describes a problem with a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error. Message and Fields are safe to show to clients,
+// the wrapped error is only logged.
+type Error struct {
+	Kind    Kind
+	Code    string // Machine-readable, e.g. appointment_not_found
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+// Error returns the message followed by the wrapped error, if any
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the wrapped error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches domain errors by code so sentinel errors work with errors.Is
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code && t.Kind == e.Kind
+}
+
+// Wrap returns a copy of the error carrying the underlying cause
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+// As returns the domain error in err's chain, if any
+func As(err error) (*Error, bool) {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr, true
+	}
+	return nil, false
+}
+
+// Validation reports a malformed or incomplete request
+func Validation(code, message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+// Unauthorized reports a caller that could not be authenticated
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+// Forbidden reports a caller that may not perform the action
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+// NotFound reports a missing resource
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+// Conflict reports a request that conflicts with the current state
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+// PreconditionFailed reports a caller precondition that no longer holds
+func PreconditionFailed(code, message string) *Error {
+	return &Error{Kind: KindPrecondition, Code: code, Message: message}
+}
+
+// Unavailable reports a failing dependency
+func Unavailable(code, message string, err error) *Error {
+	return &Error{Kind: KindUnavailable, Code: code, Message: message, Err: err}
+}
+
+// Internal reports an unexpected failure
+func Internal(code, message string, err error) *Error {
+	return &Error{Kind: KindInternal, Code: code, Message: message, Err: err}
+}
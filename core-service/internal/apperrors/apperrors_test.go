@@ -0,0 +1,34 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorMatching(t *testing.T) {
+	errNotFound := NotFound("appointment_not_found", "appointment not found")
+	cause := errors.New("connection refused")
+	errUnavailable := Unavailable("database_unavailable", "failed to read appointment", nil)
+
+	// Sentinels match through wrapping and by code
+	wrapped := fmt.Errorf("loading: %w", errNotFound)
+	assert.True(t, errors.Is(wrapped, errNotFound))
+	assert.False(t, errors.Is(wrapped, NotFound("tenant_not_found", "tenant not found")))
+
+	// Wrapping keeps the kind and code and exposes the cause
+	withCause := errUnavailable.Wrap(cause)
+	assert.True(t, errors.Is(withCause, errUnavailable))
+	assert.True(t, errors.Is(withCause, cause))
+	assert.Nil(t, errUnavailable.Err, "Wrap should not modify the sentinel")
+	assert.Equal(t, "failed to read appointment: connection refused", withCause.Error())
+
+	domainErr, ok := As(wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, KindNotFound, domainErr.Kind)
+
+	_, ok = As(cause)
+	assert.False(t, ok)
+}
@@ -0,0 +1,253 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Change is a field whose value differs between the state before and after a mutation.
+// Values are kept in their rendered form so the entry hashes the same after a round trip
+// through the database.
+type Change struct {
+	Field  string `json:"field"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Entry is a single record of the audit trail. Each entry carries the hash of the one
+// before it, so editing or deleting an entry breaks the chain from that point on.
+type Entry struct {
+	Sequence     int64     `json:"sequence"`
+	Timestamp    time.Time `json:"timestamp"`
+	Actor        string    `json:"actor"`
+	Role         string    `json:"role,omitempty"`
+	TenantID     string    `json:"tenant_id,omitempty"`
+	Operation    string    `json:"operation"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+	Changes      []Change  `json:"changes,omitempty"`
+	IP           string    `json:"ip,omitempty"`
+	RequestID    string    `json:"request_id,omitempty"`
+	EventID      string    `json:"event_id,omitempty"` // Outbox event that carried the entry
+	PrevHash     string    `json:"prev_hash"`
+	Hash         string    `json:"hash"`
+}
+
+// Resource types recorded in the audit trail
+const (
+	ResourceAppointment       = "appointment"
+	ResourceOnboardingRequest = "onboarding_request"
+	ResourceTenant            = "tenant"
+	ResourceUser              = "user"
+	ResourceWebhookEndpoint   = "webhook_endpoint"
+)
+
+// Event describes a mutation to record. Before and After are the resource as it was and
+// as it is now; Before is nil for creations.
+type Event struct {
+	Operation    string
+	ResourceType string
+	ResourceID   string
+	TenantID     string
+	Before       interface{}
+	After        interface{}
+}
+
+// Filter selects entries of the audit trail. Zero values match everything.
+type Filter struct {
+	Actor         string
+	TenantID      string
+	ResourceType  string
+	ResourceID    string
+	EventID       string
+	From          time.Time
+	To            time.Time
+	AfterSequence int64 // Only entries with a greater sequence, for paging
+	Limit         int64
+}
+
+// Store keeps the audit trail. Implementations only ever append.
+type Store interface {
+	// Append links the entry to the end of the chain and saves it, returning the saved entry
+	Append(ctx context.Context, entry Entry) (Entry, error)
+	// Query returns matching entries in sequence order
+	Query(ctx context.Context, filter Filter) ([]Entry, error)
+}
+
+// ComputeHash returns the hash of an entry over every field except the hash itself
+func ComputeHash(entry Entry) string {
+	entry.Hash = ""
+	entry.Timestamp = entry.Timestamp.UTC()
+	payload, _ := json.Marshal(entry)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Link chains an entry after the previous one, setting its sequence, previous hash and hash.
+// A nil previous entry starts the chain.
+func Link(entry Entry, previous *Entry) Entry {
+	entry.Sequence = 1
+	entry.PrevHash = ""
+	if previous != nil {
+		entry.Sequence = previous.Sequence + 1
+		entry.PrevHash = previous.Hash
+	}
+	// Mongo keeps milliseconds, so hash what will be read back
+	entry.Timestamp = entry.Timestamp.UTC().Truncate(time.Millisecond)
+	entry.Hash = ComputeHash(entry)
+	return entry
+}
+
+// ChainError reports the first entry where the chain does not hold
+type ChainError struct {
+	Sequence int64
+	Reason   string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("audit chain broken at sequence %d: %s", e.Sequence, e.Reason)
+}
+
+// VerifyChain checks that entries, in sequence order, follow on from the previous entry
+// and have not been altered. A nil previous entry means entries start the chain.
+func VerifyChain(entries []Entry, previous *Entry) error {
+	for i := range entries {
+		entry := entries[i]
+		expectedSequence, expectedPrev := int64(1), ""
+		if previous != nil {
+			expectedSequence, expectedPrev = previous.Sequence+1, previous.Hash
+		}
+
+		switch {
+		case entry.Sequence != expectedSequence:
+			return &ChainError{Sequence: expectedSequence, Reason: fmt.Sprintf("entry missing, found sequence %d", entry.Sequence)}
+		case entry.PrevHash != expectedPrev:
+			return &ChainError{Sequence: entry.Sequence, Reason: "previous hash does not match"}
+		case entry.Hash != ComputeHash(entry):
+			return &ChainError{Sequence: entry.Sequence, Reason: "entry was modified"}
+		}
+		previous = &entry
+	}
+	return nil
+}
+
+// Verification is the outcome of checking a whole audit trail
+type Verification struct {
+	Valid        bool   `json:"valid"`
+	Entries      int64  `json:"entries"`
+	LastSequence int64  `json:"last_sequence"`
+	BrokenAt     int64  `json:"broken_at,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// verifyPageSize is how many entries Verify reads at a time
+const verifyPageSize = 1000
+
+// Verify walks the whole trail in store, page by page, and reports where the chain breaks
+func Verify(ctx context.Context, store Store) (*Verification, error) {
+	result := &Verification{Valid: true}
+	var previous *Entry
+	for {
+		filter := Filter{Limit: verifyPageSize}
+		if previous != nil {
+			filter.AfterSequence = previous.Sequence
+		}
+		entries, err := store.Query(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := VerifyChain(entries, previous); err != nil {
+			var chainErr *ChainError
+			if !errors.As(err, &chainErr) {
+				return nil, err
+			}
+			result.Valid = false
+			result.BrokenAt = chainErr.Sequence
+			result.Reason = chainErr.Reason
+			return result, nil
+		}
+		if len(entries) == 0 {
+			return result, nil
+		}
+
+		result.Entries += int64(len(entries))
+		previous = &entries[len(entries)-1]
+		result.LastSequence = previous.Sequence
+		if len(entries) < verifyPageSize {
+			return result, nil
+		}
+	}
+}
+
+// Snapshot renders a resource as a flat map of its JSON fields, for diffing
+func Snapshot(resource interface{}) map[string]string {
+	if resource == nil {
+		return nil
+	}
+
+	encoded, err := json.Marshal(resource)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil
+	}
+
+	snapshot := make(map[string]string, len(fields))
+	for field, value := range fields {
+		if value == nil {
+			continue
+		}
+		if text, ok := value.(string); ok {
+			snapshot[field] = text
+			continue
+		}
+		rendered, _ := json.Marshal(value)
+		snapshot[field] = string(rendered)
+	}
+	return snapshot
+}
+
+// Diff lists the fields that differ between two snapshots, sorted by field name
+func Diff(before, after map[string]string) []Change {
+	fields := make(map[string]bool, len(before)+len(after))
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	changes := []Change{}
+	for field := range fields {
+		if before[field] != after[field] {
+			changes = append(changes, Change{Field: field, Before: before[field], After: after[field]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// contextKey is a private type so request values cannot collide with other packages
+type contextKey string
+
+const clientIPKey contextKey = "client_ip"
+
+// WithClientIP returns a copy of ctx carrying the IP address of the caller
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIPFromContext returns the IP address of the caller, if known
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
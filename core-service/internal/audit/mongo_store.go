@@ -0,0 +1,217 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// appendAttempts bounds how often Append retries when other writers take the next sequence
+const appendAttempts = 5
+
+// MongoStore keeps the audit trail in coredb. The sequence is the document _id, so two
+// writers can never link an entry to the same predecessor.
+type MongoStore struct {
+	db db.DBClientInterface
+}
+
+// NewMongoStore creates a store backed by the audit_log collection
+func NewMongoStore(database db.DBClientInterface) *MongoStore {
+	return &MongoStore{db: database}
+}
+
+// EnsureEventIndex makes entries unique by the outbox event that carried them, so an event
+// delivered twice cannot be appended twice
+func (s *MongoStore) EnsureEventIndex(ctx context.Context) error {
+	return s.db.EnsureUniqueIndex(ctx, "event_id", s.options()...)
+}
+
+func (s *MongoStore) options(extra ...db.DBOption) []db.DBOption {
+	return append([]db.DBOption{
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.AuditLog),
+	}, extra...)
+}
+
+// Append links the entry after the last one and inserts it, retrying when another
+// writer appended first
+func (s *MongoStore) Append(ctx context.Context, entry Entry) (Entry, error) {
+	for attempt := 0; attempt < appendAttempts; attempt++ {
+		previous, err := s.last(ctx)
+		if err != nil {
+			return Entry{}, err
+		}
+
+		linked := Link(entry, previous)
+		_, err = s.db.Create(ctx, encodeEntry(linked), s.options()...)
+		if err == nil {
+			return linked, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return Entry{}, err
+		}
+	}
+	return Entry{}, errors.New("audit entry could not be appended")
+}
+
+// Query returns matching entries in sequence order
+func (s *MongoStore) Query(ctx context.Context, filter Filter) ([]Entry, error) {
+	query := bson.M{}
+	if filter.Actor != "" {
+		query["actor"] = filter.Actor
+	}
+	if filter.TenantID != "" {
+		query["tenant_id"] = filter.TenantID
+	}
+	if filter.ResourceType != "" {
+		query["resource_type"] = filter.ResourceType
+	}
+	if filter.ResourceID != "" {
+		query["resource_id"] = filter.ResourceID
+	}
+	if filter.EventID != "" {
+		query["event_id"] = filter.EventID
+	}
+	if filter.AfterSequence > 0 {
+		query["_id"] = bson.M{"$gt": filter.AfterSequence}
+	}
+	if !filter.From.IsZero() || !filter.To.IsZero() {
+		timestamp := bson.M{}
+		if !filter.From.IsZero() {
+			timestamp["$gte"] = filter.From
+		}
+		if !filter.To.IsZero() {
+			timestamp["$lt"] = filter.To
+		}
+		query["timestamp"] = timestamp
+	}
+
+	results, err := s.db.ReadAll(ctx, query, s.options(db.WithSort("_id", 1), db.WithLimit(filter.Limit))...)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, _ := results.([]map[string]interface{})
+	entries := make([]Entry, 0, len(docs))
+	for _, doc := range docs {
+		entries = append(entries, decodeEntry(doc))
+	}
+	return entries, nil
+}
+
+// last returns the entry at the end of the chain, or nil when the trail is empty
+func (s *MongoStore) last(ctx context.Context) (*Entry, error) {
+	result, err := s.db.Read(ctx, bson.M{}, s.options(db.WithSort("_id", -1))...)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := result.(map[string]interface{})
+	if !ok || len(doc) == 0 {
+		return nil, nil
+	}
+	entry := decodeEntry(doc)
+	return &entry, nil
+}
+
+// encodeEntry converts an entry to the document saved in the database
+func encodeEntry(entry Entry) map[string]interface{} {
+	changes := make([]map[string]interface{}, 0, len(entry.Changes))
+	for _, change := range entry.Changes {
+		changes = append(changes, map[string]interface{}{
+			"field":  change.Field,
+			"before": change.Before,
+			"after":  change.After,
+		})
+	}
+
+	doc := map[string]interface{}{
+		"_id":           entry.Sequence,
+		"timestamp":     entry.Timestamp,
+		"actor":         entry.Actor,
+		"role":          entry.Role,
+		"tenant_id":     entry.TenantID,
+		"operation":     entry.Operation,
+		"resource_type": entry.ResourceType,
+		"resource_id":   entry.ResourceID,
+		"changes":       changes,
+		"ip":            entry.IP,
+		"request_id":    entry.RequestID,
+		"prev_hash":     entry.PrevHash,
+		"hash":          entry.Hash,
+	}
+	if entry.EventID != "" {
+		doc["event_id"] = entry.EventID
+	}
+	return doc
+}
+
+// decodeEntry reads an entry document returned by the database
+func decodeEntry(doc map[string]interface{}) Entry {
+	entry := Entry{}
+	switch sequence := doc["_id"].(type) {
+	case int64:
+		entry.Sequence = sequence
+	case int32:
+		entry.Sequence = int64(sequence)
+	case int:
+		entry.Sequence = int64(sequence)
+	}
+	switch timestamp := doc["timestamp"].(type) {
+	case time.Time:
+		entry.Timestamp = timestamp.UTC()
+	case primitive.DateTime:
+		entry.Timestamp = timestamp.Time().UTC()
+	}
+	entry.Actor, _ = doc["actor"].(string)
+	entry.Role, _ = doc["role"].(string)
+	entry.TenantID, _ = doc["tenant_id"].(string)
+	entry.Operation, _ = doc["operation"].(string)
+	entry.ResourceType, _ = doc["resource_type"].(string)
+	entry.ResourceID, _ = doc["resource_id"].(string)
+	entry.IP, _ = doc["ip"].(string)
+	entry.RequestID, _ = doc["request_id"].(string)
+	entry.EventID, _ = doc["event_id"].(string)
+	entry.PrevHash, _ = doc["prev_hash"].(string)
+	entry.Hash, _ = doc["hash"].(string)
+
+	var changes []interface{}
+	switch list := doc["changes"].(type) {
+	case primitive.A:
+		changes = list
+	case []interface{}:
+		changes = list
+	}
+	for _, item := range changes {
+		fields := documentFields(item)
+		change := Change{}
+		change.Field, _ = fields["field"].(string)
+		change.Before, _ = fields["before"].(string)
+		change.After, _ = fields["after"].(string)
+		entry.Changes = append(entry.Changes, change)
+	}
+	return entry
+}
+
+// documentFields reads an embedded document, which the driver may decode as a map or as
+// an ordered document
+func documentFields(item interface{}) map[string]interface{} {
+	switch doc := item.(type) {
+	case map[string]interface{}:
+		return doc
+	case primitive.M:
+		return doc
+	case primitive.D:
+		fields := make(map[string]interface{}, len(doc))
+		for _, element := range doc {
+			fields[element.Key] = element.Value
+		}
+		return fields
+	}
+	return nil
+}
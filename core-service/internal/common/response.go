@@ -23,13 +23,29 @@ type SuccessResponse struct {
 	RequestID string      `json:"request_id,omitempty"`
 }
 
+// contextKey is a private type so request values cannot collide with other packages
+type contextKey string
+
+const requestIDKey contextKey = "request_id"
+
+// RequestIDHeader carries the request ID on HTTP requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDMetadataKey carries the request ID on outgoing gRPC calls
+const RequestIDMetadataKey = "x-request-id"
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
 // GetRequestIDFromContext extracts request ID from context
 func GetRequestIDFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return ""
 	}
 
-	if requestID, ok := ctx.Value("request_id").(string); ok {
+	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
 		return requestID
 	}
 	return ""
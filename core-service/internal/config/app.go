@@ -0,0 +1,754 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// Environments the service can run in
+const (
+	EnvironmentDevelopment = "development"
+	EnvironmentProduction  = "production"
+)
+
+// developmentJWTSecret signs tokens when no secret is configured outside production.
+// It matches the auth service default so a local stack works without any setup.
+const developmentJWTSecret = "medusa-development-only-jwt-secret"
+
+// placeholderJWTSecret is the value shipped in the sample .env files
+const placeholderJWTSecret = "your-secure-jwt-secret-replace-in-production"
+
+// minProductionSecretLength is the shortest JWT secret accepted in production
+const minProductionSecretLength = 32
+
+// Config is the typed configuration of the core service
+type Config struct {
+	Environment string           `yaml:"environment"`
+	LogLevel    string           `yaml:"log_level"`
+	HTTP        HTTPConfig       `yaml:"http"`
+	GRPC        GRPCConfig       `yaml:"grpc"`
+	Mongo       MongoConfig      `yaml:"mongo"`
+	Auth        AuthConfig       `yaml:"auth"`
+	RateLimit   RateLimitConfig  `yaml:"rate_limit"`
+	Inspection  InspectionConfig `yaml:"inspection"`
+	Recovery    RecoveryConfig   `yaml:"recovery"`
+	Onboarding  OnboardingConfig `yaml:"onboarding"`
+	Mail        MailConfig       `yaml:"mail"`
+	Outbox      OutboxConfig     `yaml:"outbox"`
+	Sagas       SagasConfig      `yaml:"sagas"`
+	Webhooks    WebhooksConfig   `yaml:"webhooks"`
+	Tracing     TracingConfig    `yaml:"tracing"`
+	TLS         TLSConfig        `yaml:"tls"`
+}
+
+// HTTPConfig controls the REST API listener
+type HTTPConfig struct {
+	Port        int               `yaml:"port"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Compression CompressionConfig `yaml:"compression"`
+	Events      EventsConfig      `yaml:"events"`
+	TLS         ServerTLSConfig   `yaml:"tls"`
+}
+
+// CORSConfig controls which browser origins may call the API
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	Debug          bool     `yaml:"debug"`
+}
+
+// CompressionConfig controls gzip and zstd response compression
+type CompressionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	MinSize int  `yaml:"min_size"` // Bodies smaller than this many bytes are sent as is
+}
+
+// EventsConfig controls the appointment event streams
+type EventsConfig struct {
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"` // Idle streams get a heartbeat this often
+	PollInterval      time.Duration `yaml:"poll_interval"`      // How often each replica reads new changes from the outbox
+}
+
+// GRPCConfig controls the gRPC API listener
+type GRPCConfig struct {
+	Enabled bool            `yaml:"enabled"`
+	Port    int             `yaml:"port"`
+	TLS     ServerTLSConfig `yaml:"tls"`
+}
+
+// ServerTLSConfig serves a listener over TLS
+type ServerTLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// MongoConfig locates the database
+type MongoConfig struct {
+	URI string `yaml:"uri"`
+}
+
+// AuthConfig locates the auth service and holds the secret shared with it
+type AuthConfig struct {
+	ServiceAddr string          `yaml:"service_addr"`
+	JWTSecret   Secret          `yaml:"jwt_secret"`
+	TLS         ClientTLSConfig `yaml:"tls"`
+}
+
+// ClientTLSConfig secures a connection to another service. With a certificate and key
+// the connection uses mutual TLS.
+type ClientTLSConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	CAFile     string `yaml:"ca_file"`     // CA bundle the server is verified against; system roots when empty
+	CertFile   string `yaml:"cert_file"`   // Client certificate
+	KeyFile    string `yaml:"key_file"`    // Client key
+	ServerName string `yaml:"server_name"` // Name expected in the server certificate; the host of the address when empty
+}
+
+// RateLimitConfig selects where rate limit buckets are kept
+type RateLimitConfig struct {
+	Store      string `yaml:"store"`       // memory or mongo
+	TrustProxy bool   `yaml:"trust_proxy"` // Use X-Forwarded-For as the client address
+}
+
+// InspectionConfig sets how request inspection findings are handled
+type InspectionConfig struct {
+	Mode string `yaml:"mode"` // block, log or monitor; operations may override it
+}
+
+// RecoveryConfig tunes the stuck onboarding request recovery
+type RecoveryConfig struct {
+	Interval          time.Duration `yaml:"interval"`             // How often the recovery runs
+	InProgressMaxAge  time.Duration `yaml:"in_progress_max_age"`  // How long a request can be "in progress"
+	UserCreatedMaxAge time.Duration `yaml:"user_created_max_age"` // How long a request can be in "user created"
+	LeaseTTL          time.Duration `yaml:"lease_ttl"`            // How long the leading replica holds the lease between renewals
+	RunRetention      time.Duration `yaml:"run_retention"`        // How long run reports are kept
+}
+
+// OnboardingConfig tunes the review of onboarding requests and the retries of failed
+// approvals
+type OnboardingConfig struct {
+	ReapplyCooldown  time.Duration `yaml:"reapply_cooldown"`   // How long the email of a rejected request must wait to apply again
+	RetryInterval    time.Duration `yaml:"retry_interval"`     // How often failed approvals due for a retry are retried
+	RetryMaxAttempts int           `yaml:"retry_max_attempts"` // Failed approvals before a request is dead-lettered
+	RetryBaseDelay   time.Duration `yaml:"retry_base_delay"`   // Wait before the first retry, doubling with every further one
+	RetryMaxDelay    time.Duration `yaml:"retry_max_delay"`    // Longest wait between retries
+
+	// Applicants confirm their email before a request is reviewed
+	VerificationSecret         Secret        `yaml:"verification_secret"`          // Signs verification tokens, derived from auth.jwt_secret when empty
+	VerificationURL            string        `yaml:"verification_url"`             // Page the emailed link opens, with the token in its token parameter
+	VerificationTTL            time.Duration `yaml:"verification_ttl"`             // How long a request may stay unverified before it expires
+	VerificationResendInterval time.Duration `yaml:"verification_resend_interval"` // Shortest wait between two verification emails of a request
+	VerificationMaxSends       int           `yaml:"verification_max_sends"`       // Verification emails a request may be sent, the first included
+	VerificationSweepInterval  time.Duration `yaml:"verification_sweep_interval"`  // How often expired unverified requests are removed
+}
+
+// MailConfig selects how email is sent
+type MailConfig struct {
+	Driver string     `yaml:"driver"` // smtp, or file to write each message to Dir instead
+	From   string     `yaml:"from"`   // Sender address, optionally with a display name
+	Dir    string     `yaml:"dir"`    // Where the file driver writes messages
+	SMTP   SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig locates the mail relay. The connection is upgraded with STARTTLS when the
+// relay offers it, and credentials are only sent over TLS.
+type SMTPConfig struct {
+	Addr     string `yaml:"addr"` // host:port
+	Username string `yaml:"username"`
+	Password Secret `yaml:"password"`
+}
+
+// OutboxConfig tunes the delivery of domain events to their subscribers
+type OutboxConfig struct {
+	PollInterval   time.Duration `yaml:"poll_interval"`    // How often pending events are dispatched
+	MaxAttempts    int           `yaml:"max_attempts"`     // Attempts before an event is given up on
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay"` // Wait before the first retry, doubling with every further one
+	Retention      time.Duration `yaml:"retention"`        // How long dispatched events are kept
+}
+
+// SagasConfig tunes how multi-step processes such as tenant approval run their steps
+type SagasConfig struct {
+	PollInterval   time.Duration `yaml:"poll_interval"`    // How often due steps are run
+	MaxAttempts    int           `yaml:"max_attempts"`     // Attempts of a step before it fails for good
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay"` // Wait before the first retry of a step, doubling with every further one
+	Retention      time.Duration `yaml:"retention"`        // How long finished sagas are kept
+}
+
+// WebhooksConfig tunes the delivery of tenant webhooks
+type WebhooksConfig struct {
+	PollInterval   time.Duration `yaml:"poll_interval"`    // How often due deliveries are sent
+	Timeout        time.Duration `yaml:"timeout"`          // Of a single delivery request
+	MaxAttempts    int           `yaml:"max_attempts"`     // Attempts before a delivery fails
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay"` // Wait before the first retry, doubling with every further one
+	DisableAfter   time.Duration `yaml:"disable_after"`    // How long an endpoint may fail without a success before it is disabled
+	Retention      time.Duration `yaml:"retention"`        // How long deliveries are kept
+	AllowInsecure  bool          `yaml:"allow_insecure"`   // Accept http:// URLs and private addresses, for local receivers
+}
+
+// TracingConfig controls how spans are sampled and exported
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`     // otlp, stdout, file or none
+	FilePath    string  `yaml:"file_path"`    // Destination of the file exporter
+	SampleRatio float64 `yaml:"sample_ratio"` // Fraction of new traces that are recorded
+}
+
+// TLSConfig holds the settings shared by every TLS connection
+type TLSConfig struct {
+	DevMode        bool          `yaml:"dev_mode"`        // Generate a local CA and certificates for unset files
+	DevDir         string        `yaml:"dev_dir"`         // Where development certificates are kept
+	ReloadInterval time.Duration `yaml:"reload_interval"` // How often certificate files are checked for changes
+}
+
+// Secret is a configuration value that is never printed
+type Secret string
+
+// String hides the secret, showing only whether it is set
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// Default returns the configuration used when nothing else is set
+func Default() *Config {
+	return &Config{
+		Environment: EnvironmentDevelopment,
+		LogLevel:    "info",
+		HTTP: HTTPConfig{
+			Port:        8080,
+			CORS:        CORSConfig{AllowedOrigins: []string{"http://localhost:3000"}},
+			Compression: CompressionConfig{Enabled: true, MinSize: 1024},
+			Events:      EventsConfig{HeartbeatInterval: 15 * time.Second, PollInterval: time.Second},
+		},
+		GRPC:       GRPCConfig{Enabled: true, Port: 9090},
+		Mongo:      MongoConfig{URI: "mongodb://localhost:27017"},
+		Auth:       AuthConfig{ServiceAddr: "localhost:50051"},
+		RateLimit:  RateLimitConfig{Store: "memory"},
+		Inspection: InspectionConfig{Mode: InspectionBlock},
+		Recovery: RecoveryConfig{
+			Interval:          time.Minute,
+			InProgressMaxAge:  3 * time.Minute,
+			UserCreatedMaxAge: 3 * time.Minute,
+			LeaseTTL:          15 * time.Second,
+			RunRetention:      30 * 24 * time.Hour,
+		},
+		Onboarding: OnboardingConfig{
+			ReapplyCooldown:  30 * 24 * time.Hour,
+			RetryInterval:    30 * time.Second,
+			RetryMaxAttempts: 5,
+			RetryBaseDelay:   time.Minute,
+			RetryMaxDelay:    time.Hour,
+
+			VerificationURL:            "http://localhost:3000/onboarding/verify",
+			VerificationTTL:            24 * time.Hour,
+			VerificationResendInterval: 2 * time.Minute,
+			VerificationMaxSends:       5,
+			VerificationSweepInterval:  5 * time.Minute,
+		},
+		Mail: MailConfig{
+			Driver: "file",
+			From:   "Medusa <no-reply@medusa.localhost>",
+			Dir:    filepath.Join(os.TempDir(), "medusa-mail"),
+		},
+		Outbox: OutboxConfig{
+			PollInterval:   time.Second,
+			MaxAttempts:    10,
+			RetryBaseDelay: 5 * time.Second,
+			Retention:      7 * 24 * time.Hour,
+		},
+		Sagas: SagasConfig{
+			PollInterval:   time.Second,
+			MaxAttempts:    8,
+			RetryBaseDelay: 2 * time.Second,
+			Retention:      30 * 24 * time.Hour,
+		},
+		Webhooks: WebhooksConfig{
+			PollInterval:   5 * time.Second,
+			Timeout:        10 * time.Second,
+			MaxAttempts:    8,
+			RetryBaseDelay: 30 * time.Second,
+			DisableAfter:   24 * time.Hour,
+			Retention:      30 * 24 * time.Hour,
+		},
+		Tracing: TracingConfig{Exporter: "none", FilePath: "core-service-traces.json", SampleRatio: 1},
+		TLS: TLSConfig{
+			DevDir:         filepath.Join(os.TempDir(), "medusa-dev-tls"),
+			ReloadInterval: 30 * time.Second,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, a YAML file, environment variables and
+// command line flags, each overriding the previous one, and validates the result. The
+// YAML file is named by the -config flag or the CONFIG_FILE variable.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet("core-service", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML configuration file")
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		name := s.flag
+		fs.Func(name, fmt.Sprintf("%s (env %s)", s.usage, s.env), func(value string) error {
+			flagValues[name] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok && value != "" {
+			if err := s.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	for _, s := range settings {
+		if value, ok := flagValues[s.flag]; ok {
+			if err := s.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", s.flag, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if cfg.Auth.JWTSecret == "" && cfg.Environment != EnvironmentProduction {
+		cfg.Auth.JWTSecret = developmentJWTSecret
+	}
+	if cfg.Onboarding.VerificationSecret == "" && cfg.Auth.JWTSecret != "" {
+		// A key of its own, so verification tokens can never pass for JWTs or the reverse
+		mac := hmac.New(sha256.New, []byte(cfg.Auth.JWTSecret))
+		mac.Write([]byte("medusa onboarding email verification"))
+		cfg.Onboarding.VerificationSecret = Secret(hex.EncodeToString(mac.Sum(nil)))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile overrides the configuration with the values of a YAML file. Unknown keys are
+// rejected so that typos do not silently fall back to defaults.
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting. Production additionally requires a strong JWT
+// secret, a CORS policy limited to known origins and TLS on every connection.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Environment != EnvironmentDevelopment && c.Environment != EnvironmentProduction {
+		invalid("environment must be %q or %q", EnvironmentDevelopment, EnvironmentProduction)
+	}
+	if _, err := zapcore.ParseLevel(c.LogLevel); err != nil {
+		invalid("log_level: %v", err)
+	}
+	if c.HTTP.Port < 1 || c.HTTP.Port > 65535 {
+		invalid("http.port must be between 1 and 65535")
+	}
+	if c.GRPC.Enabled {
+		if c.GRPC.Port < 1 || c.GRPC.Port > 65535 {
+			invalid("grpc.port must be between 1 and 65535")
+		} else if c.GRPC.Port == c.HTTP.Port {
+			invalid("grpc.port must differ from http.port")
+		}
+	}
+	if uri, err := url.Parse(c.Mongo.URI); err != nil || (uri.Scheme != "mongodb" && uri.Scheme != "mongodb+srv") {
+		invalid("mongo.uri must be a mongodb:// or mongodb+srv:// URI")
+	}
+	if _, _, err := net.SplitHostPort(c.Auth.ServiceAddr); err != nil {
+		invalid("auth.service_addr must be host:port")
+	}
+	if c.Auth.JWTSecret == "" {
+		invalid("auth.jwt_secret is required")
+	}
+	if c.HTTP.TLS.Enabled && !c.TLS.DevMode && (c.HTTP.TLS.CertFile == "" || c.HTTP.TLS.KeyFile == "") {
+		invalid("http.tls.cert_file and http.tls.key_file are required when TLS is enabled")
+	}
+	if c.GRPC.TLS.Enabled && !c.TLS.DevMode && (c.GRPC.TLS.CertFile == "" || c.GRPC.TLS.KeyFile == "") {
+		invalid("grpc.tls.cert_file and grpc.tls.key_file are required when TLS is enabled")
+	}
+	if (c.Auth.TLS.CertFile == "") != (c.Auth.TLS.KeyFile == "") {
+		invalid("auth.tls.cert_file and auth.tls.key_file must be set together")
+	}
+	if c.TLS.DevMode && c.TLS.DevDir == "" {
+		invalid("tls.dev_dir is required in development TLS mode")
+	}
+	if c.TLS.ReloadInterval <= 0 {
+		invalid("tls.reload_interval must be positive")
+	}
+	if c.HTTP.Events.HeartbeatInterval <= 0 {
+		invalid("http.events.heartbeat_interval must be positive")
+	}
+	if c.HTTP.Events.PollInterval <= 0 {
+		invalid("http.events.poll_interval must be positive")
+	}
+	if c.HTTP.Compression.MinSize < 0 {
+		invalid("http.compression.min_size must not be negative")
+	}
+	if c.RateLimit.Store != "memory" && c.RateLimit.Store != "mongo" {
+		invalid("rate_limit.store must be memory or mongo")
+	}
+	switch c.Inspection.Mode {
+	case InspectionBlock, InspectionLog, InspectionMonitor:
+	default:
+		invalid("inspection.mode must be block, log or monitor")
+	}
+	for name, value := range map[string]time.Duration{
+		"recovery.interval":                      c.Recovery.Interval,
+		"recovery.in_progress_max_age":           c.Recovery.InProgressMaxAge,
+		"recovery.user_created_max_age":          c.Recovery.UserCreatedMaxAge,
+		"recovery.lease_ttl":                     c.Recovery.LeaseTTL,
+		"recovery.run_retention":                 c.Recovery.RunRetention,
+		"onboarding.retry_interval":              c.Onboarding.RetryInterval,
+		"onboarding.retry_base_delay":            c.Onboarding.RetryBaseDelay,
+		"onboarding.retry_max_delay":             c.Onboarding.RetryMaxDelay,
+		"onboarding.verification_ttl":            c.Onboarding.VerificationTTL,
+		"onboarding.verification_sweep_interval": c.Onboarding.VerificationSweepInterval,
+		"outbox.poll_interval":                   c.Outbox.PollInterval,
+		"outbox.retry_base_delay":                c.Outbox.RetryBaseDelay,
+		"outbox.retention":                       c.Outbox.Retention,
+		"sagas.poll_interval":                    c.Sagas.PollInterval,
+		"sagas.retry_base_delay":                 c.Sagas.RetryBaseDelay,
+		"sagas.retention":                        c.Sagas.Retention,
+		"webhooks.poll_interval":                 c.Webhooks.PollInterval,
+		"webhooks.timeout":                       c.Webhooks.Timeout,
+		"webhooks.retry_base_delay":              c.Webhooks.RetryBaseDelay,
+		"webhooks.disable_after":                 c.Webhooks.DisableAfter,
+		"webhooks.retention":                     c.Webhooks.Retention,
+	} {
+		if value <= 0 {
+			invalid("%s must be positive", name)
+		}
+	}
+	if c.Recovery.LeaseTTL > 0 && c.Recovery.LeaseTTL < 3*time.Second {
+		invalid("recovery.lease_ttl must be at least 3s")
+	}
+	if c.Onboarding.ReapplyCooldown < 0 {
+		invalid("onboarding.reapply_cooldown must not be negative")
+	}
+	switch c.Mail.Driver {
+	case "smtp":
+		if c.Mail.SMTP.Addr == "" {
+			invalid("mail.smtp.addr is required by the smtp mail driver")
+		}
+	case "file":
+		if c.Mail.Dir == "" {
+			invalid("mail.dir is required by the file mail driver")
+		}
+	default:
+		invalid("mail.driver must be smtp or file")
+	}
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		invalid("mail.from must be an email address")
+	}
+	if c.Onboarding.RetryMaxAttempts < 1 {
+		invalid("onboarding.retry_max_attempts must be at least 1")
+	}
+	if c.Onboarding.RetryMaxDelay < c.Onboarding.RetryBaseDelay {
+		invalid("onboarding.retry_max_delay must not be shorter than onboarding.retry_base_delay")
+	}
+	if c.Onboarding.VerificationResendInterval < 0 {
+		invalid("onboarding.verification_resend_interval must not be negative")
+	}
+	if c.Onboarding.VerificationMaxSends < 1 {
+		invalid("onboarding.verification_max_sends must be at least 1")
+	}
+	if verifyURL, err := url.Parse(c.Onboarding.VerificationURL); err != nil || (verifyURL.Scheme != "http" && verifyURL.Scheme != "https") || verifyURL.Host == "" {
+		invalid("onboarding.verification_url must be an http or https URL")
+	}
+	if c.Sagas.MaxAttempts < 1 {
+		invalid("sagas.max_attempts must be at least 1")
+	}
+	if c.Outbox.MaxAttempts < 1 {
+		invalid("outbox.max_attempts must be at least 1")
+	}
+	if c.Webhooks.MaxAttempts < 1 {
+		invalid("webhooks.max_attempts must be at least 1")
+	}
+	switch c.Tracing.Exporter {
+	case "otlp", "stdout", "file", "none":
+	default:
+		invalid("tracing.exporter must be otlp, stdout, file or none")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sample_ratio must be between 0 and 1")
+	}
+
+	if c.Environment == EnvironmentProduction {
+		secret := string(c.Auth.JWTSecret)
+		if secret != "" && (len(secret) < minProductionSecretLength || secret == placeholderJWTSecret || secret == developmentJWTSecret) {
+			invalid("auth.jwt_secret must be a unique secret of at least %d characters in production", minProductionSecretLength)
+		}
+		if secret := c.Onboarding.VerificationSecret; secret != "" && len(secret) < minProductionSecretLength {
+			invalid("onboarding.verification_secret must be at least %d characters in production", minProductionSecretLength)
+		}
+		for _, origin := range c.HTTP.CORS.AllowedOrigins {
+			if origin == "*" {
+				invalid("http.cors.allowed_origins must list explicit origins in production")
+				break
+			}
+		}
+		if c.HTTP.CORS.Debug {
+			invalid("http.cors.debug must be disabled in production")
+		}
+		if c.TLS.DevMode {
+			invalid("tls.dev_mode must be disabled in production")
+		}
+		if !c.HTTP.TLS.Enabled {
+			invalid("http.tls.enabled is required in production")
+		}
+		if c.GRPC.Enabled && !c.GRPC.TLS.Enabled {
+			invalid("grpc.tls.enabled is required in production")
+		}
+		if c.Webhooks.AllowInsecure {
+			invalid("webhooks.allow_insecure must be disabled in production")
+		}
+		if c.Mail.Driver != "smtp" {
+			invalid("mail.driver must be smtp in production")
+		}
+		if !c.Auth.TLS.Enabled || c.Auth.TLS.CertFile == "" {
+			invalid("auth.tls must be enabled with a client certificate in production")
+		}
+	}
+
+	// Sort so the report does not depend on map iteration
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errors.Join(errs...)
+}
+
+// Redacted returns the effective configuration keyed by setting name, with secrets and
+// database credentials hidden, for logging at startup
+func (c *Config) Redacted() map[string]string {
+	values := make(map[string]string)
+	for _, s := range c.settings() {
+		values[s.key] = s.get()
+	}
+	return values
+}
+
+// setting binds one configuration field to its YAML key, environment variable and flag
+type setting struct {
+	key   string
+	env   string
+	flag  string
+	usage string
+	set   func(string) error
+	get   func() string
+}
+
+// settings lists every configurable field of c
+func (c *Config) settings() []setting {
+	return []setting{
+		stringSetting("environment", "APP_ENV", "Runtime environment: development or production", &c.Environment),
+		stringSetting("log_level", "LOG_LEVEL", "Minimum log level", &c.LogLevel),
+		intSetting("http.port", "API_PORT", "REST API port", &c.HTTP.Port),
+		listSetting("http.cors.allowed_origins", "CORS_ALLOWED_ORIGINS", "Comma separated origins allowed by CORS", &c.HTTP.CORS.AllowedOrigins),
+		boolSetting("http.cors.debug", "CORS_DEBUG", "Log CORS decisions", &c.HTTP.CORS.Debug),
+		boolSetting("http.compression.enabled", "COMPRESSION_ENABLED", "Compress responses with gzip or zstd", &c.HTTP.Compression.Enabled),
+		intSetting("http.compression.min_size", "COMPRESSION_MIN_SIZE", "Smallest response body in bytes that is compressed", &c.HTTP.Compression.MinSize),
+		durationSetting("http.events.heartbeat_interval", "EVENTS_HEARTBEAT_INTERVAL", "How often idle appointment event streams get a heartbeat", &c.HTTP.Events.HeartbeatInterval),
+		durationSetting("http.events.poll_interval", "EVENTS_POLL_INTERVAL", "How often each replica reads new appointment changes for its event streams", &c.HTTP.Events.PollInterval),
+		boolSetting("http.tls.enabled", "HTTP_TLS_ENABLED", "Serve the REST API over HTTPS", &c.HTTP.TLS.Enabled),
+		stringSetting("http.tls.cert_file", "HTTP_TLS_CERT_FILE", "HTTPS certificate file", &c.HTTP.TLS.CertFile),
+		stringSetting("http.tls.key_file", "HTTP_TLS_KEY_FILE", "HTTPS key file", &c.HTTP.TLS.KeyFile),
+		boolSetting("grpc.enabled", "GRPC_ENABLED", "Serve the gRPC API", &c.GRPC.Enabled),
+		intSetting("grpc.port", "GRPC_PORT", "gRPC API port", &c.GRPC.Port),
+		boolSetting("grpc.tls.enabled", "GRPC_TLS_ENABLED", "Serve the gRPC API over TLS", &c.GRPC.TLS.Enabled),
+		stringSetting("grpc.tls.cert_file", "GRPC_TLS_CERT_FILE", "gRPC API certificate file", &c.GRPC.TLS.CertFile),
+		stringSetting("grpc.tls.key_file", "GRPC_TLS_KEY_FILE", "gRPC API key file", &c.GRPC.TLS.KeyFile),
+		uriSetting("mongo.uri", "MONGO_URI", "MongoDB connection string", &c.Mongo.URI),
+		stringSetting("auth.service_addr", "AUTH_SERVICE_ADDR", "Auth service gRPC address", &c.Auth.ServiceAddr),
+		secretSetting("auth.jwt_secret", "JWT_SECRET_KEY", "Secret shared with the auth service to verify tokens", &c.Auth.JWTSecret),
+		boolSetting("auth.tls.enabled", "AUTH_TLS_ENABLED", "Connect to the auth service over TLS", &c.Auth.TLS.Enabled),
+		stringSetting("auth.tls.ca_file", "AUTH_TLS_CA_FILE", "CA bundle the auth service certificate is verified against", &c.Auth.TLS.CAFile),
+		stringSetting("auth.tls.cert_file", "AUTH_TLS_CERT_FILE", "Client certificate presented to the auth service", &c.Auth.TLS.CertFile),
+		stringSetting("auth.tls.key_file", "AUTH_TLS_KEY_FILE", "Client key presented to the auth service", &c.Auth.TLS.KeyFile),
+		stringSetting("auth.tls.server_name", "AUTH_TLS_SERVER_NAME", "Name expected in the auth service certificate", &c.Auth.TLS.ServerName),
+		stringSetting("rate_limit.store", "RATE_LIMIT_STORE", "Rate limit store: memory or mongo", &c.RateLimit.Store),
+		boolSetting("rate_limit.trust_proxy", "RATE_LIMIT_TRUST_PROXY", "Take the client address from X-Forwarded-For", &c.RateLimit.TrustProxy),
+		stringSetting("inspection.mode", "INSPECTION_MODE", "Request inspection mode: block, log or monitor", &c.Inspection.Mode),
+		durationSetting("recovery.interval", "RECOVERY_INTERVAL", "How often stuck onboarding requests are recovered", &c.Recovery.Interval),
+		durationSetting("recovery.in_progress_max_age", "RECOVERY_IN_PROGRESS_MAX_AGE", "Age after which an in progress request is stuck", &c.Recovery.InProgressMaxAge),
+		durationSetting("recovery.user_created_max_age", "RECOVERY_USER_CREATED_MAX_AGE", "Age after which a user created request is stuck", &c.Recovery.UserCreatedMaxAge),
+		durationSetting("recovery.lease_ttl", "RECOVERY_LEASE_TTL", "How long the replica leading recovery holds its lease between renewals", &c.Recovery.LeaseTTL),
+		durationSetting("recovery.run_retention", "RECOVERY_RUN_RETENTION", "How long recovery run reports are kept", &c.Recovery.RunRetention),
+		durationSetting("onboarding.reapply_cooldown", "ONBOARDING_REAPPLY_COOLDOWN", "How long the email of a rejected onboarding request must wait to apply again", &c.Onboarding.ReapplyCooldown),
+		durationSetting("onboarding.retry_interval", "ONBOARDING_RETRY_INTERVAL", "How often failed onboarding approvals due for a retry are retried", &c.Onboarding.RetryInterval),
+		intSetting("onboarding.retry_max_attempts", "ONBOARDING_RETRY_MAX_ATTEMPTS", "Failed approvals before an onboarding request is dead-lettered", &c.Onboarding.RetryMaxAttempts),
+		durationSetting("onboarding.retry_base_delay", "ONBOARDING_RETRY_BASE_DELAY", "Wait before the first retry of a failed approval, doubling with every further one", &c.Onboarding.RetryBaseDelay),
+		durationSetting("onboarding.retry_max_delay", "ONBOARDING_RETRY_MAX_DELAY", "Longest wait between retries of a failed approval", &c.Onboarding.RetryMaxDelay),
+		secretSetting("onboarding.verification_secret", "ONBOARDING_VERIFICATION_SECRET", "Secret signing email verification tokens, derived from the JWT secret when empty", &c.Onboarding.VerificationSecret),
+		stringSetting("onboarding.verification_url", "ONBOARDING_VERIFICATION_URL", "Page the email verification link opens, with the token in its token parameter", &c.Onboarding.VerificationURL),
+		durationSetting("onboarding.verification_ttl", "ONBOARDING_VERIFICATION_TTL", "How long an onboarding request may stay unverified before it expires", &c.Onboarding.VerificationTTL),
+		durationSetting("onboarding.verification_resend_interval", "ONBOARDING_VERIFICATION_RESEND_INTERVAL", "Shortest wait between two verification emails of an onboarding request", &c.Onboarding.VerificationResendInterval),
+		intSetting("onboarding.verification_max_sends", "ONBOARDING_VERIFICATION_MAX_SENDS", "Verification emails an onboarding request may be sent", &c.Onboarding.VerificationMaxSends),
+		durationSetting("onboarding.verification_sweep_interval", "ONBOARDING_VERIFICATION_SWEEP_INTERVAL", "How often expired unverified onboarding requests are removed", &c.Onboarding.VerificationSweepInterval),
+		stringSetting("mail.driver", "MAIL_DRIVER", "Mail driver: smtp, or file to write messages to a directory", &c.Mail.Driver),
+		stringSetting("mail.from", "MAIL_FROM", "Sender address of email", &c.Mail.From),
+		stringSetting("mail.dir", "MAIL_DIR", "Directory the file mail driver writes messages to", &c.Mail.Dir),
+		stringSetting("mail.smtp.addr", "MAIL_SMTP_ADDR", "Mail relay address, host:port", &c.Mail.SMTP.Addr),
+		stringSetting("mail.smtp.username", "MAIL_SMTP_USERNAME", "Mail relay username", &c.Mail.SMTP.Username),
+		secretSetting("mail.smtp.password", "MAIL_SMTP_PASSWORD", "Mail relay password", &c.Mail.SMTP.Password),
+		durationSetting("outbox.poll_interval", "OUTBOX_POLL_INTERVAL", "How often pending domain events are dispatched", &c.Outbox.PollInterval),
+		intSetting("outbox.max_attempts", "OUTBOX_MAX_ATTEMPTS", "Attempts before a domain event is given up on", &c.Outbox.MaxAttempts),
+		durationSetting("outbox.retry_base_delay", "OUTBOX_RETRY_BASE_DELAY", "Wait before the first retry of a domain event, doubling with every further one", &c.Outbox.RetryBaseDelay),
+		durationSetting("outbox.retention", "OUTBOX_RETENTION", "How long dispatched domain events are kept", &c.Outbox.Retention),
+		durationSetting("sagas.poll_interval", "SAGAS_POLL_INTERVAL", "How often due saga steps are run", &c.Sagas.PollInterval),
+		intSetting("sagas.max_attempts", "SAGAS_MAX_ATTEMPTS", "Attempts of a saga step before it fails for good", &c.Sagas.MaxAttempts),
+		durationSetting("sagas.retry_base_delay", "SAGAS_RETRY_BASE_DELAY", "Wait before the first retry of a saga step, doubling with every further one", &c.Sagas.RetryBaseDelay),
+		durationSetting("sagas.retention", "SAGAS_RETENTION", "How long finished sagas are kept", &c.Sagas.Retention),
+		durationSetting("webhooks.poll_interval", "WEBHOOKS_POLL_INTERVAL", "How often due webhook deliveries are sent", &c.Webhooks.PollInterval),
+		durationSetting("webhooks.timeout", "WEBHOOKS_TIMEOUT", "Timeout of a webhook delivery request", &c.Webhooks.Timeout),
+		intSetting("webhooks.max_attempts", "WEBHOOKS_MAX_ATTEMPTS", "Attempts before a webhook delivery fails", &c.Webhooks.MaxAttempts),
+		durationSetting("webhooks.retry_base_delay", "WEBHOOKS_RETRY_BASE_DELAY", "Wait before the first webhook retry, doubling with every further one", &c.Webhooks.RetryBaseDelay),
+		durationSetting("webhooks.disable_after", "WEBHOOKS_DISABLE_AFTER", "How long a webhook endpoint may fail before it is disabled", &c.Webhooks.DisableAfter),
+		durationSetting("webhooks.retention", "WEBHOOKS_RETENTION", "How long webhook deliveries are kept", &c.Webhooks.Retention),
+		boolSetting("webhooks.allow_insecure", "WEBHOOKS_ALLOW_INSECURE", "Accept http:// webhook URLs and private addresses, for local receivers", &c.Webhooks.AllowInsecure),
+		stringSetting("tracing.exporter", "OTEL_TRACES_EXPORTER", "Trace exporter: otlp, stdout, file or none", &c.Tracing.Exporter),
+		stringSetting("tracing.file_path", "OTEL_TRACES_FILE", "Destination of the file trace exporter", &c.Tracing.FilePath),
+		floatSetting("tracing.sample_ratio", "OTEL_TRACES_SAMPLER_ARG", "Fraction of new traces that are recorded", &c.Tracing.SampleRatio),
+		boolSetting("tls.dev_mode", "TLS_DEV_MODE", "Enable TLS with certificates from a generated local CA", &c.TLS.DevMode),
+		stringSetting("tls.dev_dir", "TLS_DEV_DIR", "Directory of the development CA, shared with the auth service", &c.TLS.DevDir),
+		durationSetting("tls.reload_interval", "TLS_RELOAD_INTERVAL", "How often certificate files are checked for changes", &c.TLS.ReloadInterval),
+	}
+}
+
+func newSetting(key, env, usage string, set func(string) error, get func() string) setting {
+	return setting{key: key, env: env, flag: strings.NewReplacer(".", "-", "_", "-").Replace(key), usage: usage, set: set, get: get}
+}
+
+func stringSetting(key, env, usage string, target *string) setting {
+	return newSetting(key, env, usage,
+		func(value string) error { *target = value; return nil },
+		func() string { return *target })
+}
+
+func secretSetting(key, env, usage string, target *Secret) setting {
+	return newSetting(key, env, usage,
+		func(value string) error { *target = Secret(value); return nil },
+		func() string { return target.String() })
+}
+
+// uriSetting hides the password of a connection string when printed
+func uriSetting(key, env, usage string, target *string) setting {
+	return newSetting(key, env, usage,
+		func(value string) error { *target = value; return nil },
+		func() string {
+			if parsed, err := url.Parse(*target); err == nil {
+				return parsed.Redacted()
+			}
+			return "[redacted]"
+		})
+}
+
+func intSetting(key, env, usage string, target *int) setting {
+	return newSetting(key, env, usage,
+		func(value string) error {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid integer %q", value)
+			}
+			*target = parsed
+			return nil
+		},
+		func() string { return strconv.Itoa(*target) })
+}
+
+func floatSetting(key, env, usage string, target *float64) setting {
+	return newSetting(key, env, usage,
+		func(value string) error {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid number %q", value)
+			}
+			*target = parsed
+			return nil
+		},
+		func() string { return strconv.FormatFloat(*target, 'g', -1, 64) })
+}
+
+func boolSetting(key, env, usage string, target *bool) setting {
+	return newSetting(key, env, usage,
+		func(value string) error {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid boolean %q", value)
+			}
+			*target = parsed
+			return nil
+		},
+		func() string { return strconv.FormatBool(*target) })
+}
+
+func durationSetting(key, env, usage string, target *time.Duration) setting {
+	return newSetting(key, env, usage,
+		func(value string) error {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid duration %q", value)
+			}
+			*target = parsed
+			return nil
+		},
+		func() string { return target.String() })
+}
+
+func listSetting(key, env, usage string, target *[]string) setting {
+	return newSetting(key, env, usage,
+		func(value string) error {
+			var items []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			*target = items
+			return nil
+		},
+		func() string { return strings.Join(*target, ",") })
+}
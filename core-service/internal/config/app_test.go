@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "core.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(`
+http:
+  port: 9000
+mongo:
+  uri: mongodb://file:27017
+auth:
+  service_addr: file:50051
+recovery:
+  interval: 30s
+`), 0o600))
+
+	t.Setenv("MONGO_URI", "mongodb://env:27017")
+	t.Setenv("AUTH_SERVICE_ADDR", "env:50051")
+
+	cfg, err := Load([]string{"-config", file, "-auth-service-addr", "flag:50051"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 9000, cfg.HTTP.Port, "file overrides defaults")
+	assert.Equal(t, 30*time.Second, cfg.Recovery.Interval, "file overrides defaults")
+	assert.Equal(t, 3*time.Minute, cfg.Recovery.InProgressMaxAge, "defaults apply when unset")
+	assert.Equal(t, "mongodb://env:27017", cfg.Mongo.URI, "env overrides file")
+	assert.Equal(t, "flag:50051", cfg.Auth.ServiceAddr, "flags override env")
+	assert.Equal(t, Secret(developmentJWTSecret), cfg.Auth.JWTSecret, "development falls back to the development secret")
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "core.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("http:\n  prot: 9000\n"), 0o600))
+
+	_, err := Load([]string{"-config", file})
+	assert.Error(t, err)
+}
+
+func TestValidateProduction(t *testing.T) {
+	cfg := Default()
+	cfg.Environment = EnvironmentProduction
+	cfg.HTTP.CORS.AllowedOrigins = []string{"*"}
+	cfg.HTTP.CORS.Debug = true
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "auth.jwt_secret is required")
+	assert.ErrorContains(t, err, "http.cors.allowed_origins")
+	assert.ErrorContains(t, err, "http.cors.debug")
+	assert.ErrorContains(t, err, "http.tls.enabled is required")
+	assert.ErrorContains(t, err, "grpc.tls.enabled is required")
+	assert.ErrorContains(t, err, "auth.tls must be enabled")
+	assert.ErrorContains(t, err, "mail.driver must be smtp")
+
+	cfg.Auth.JWTSecret = placeholderJWTSecret
+	assert.ErrorContains(t, cfg.Validate(), "unique secret")
+
+	cfg.Auth.JWTSecret = "a-production-secret-that-is-long-enough"
+	cfg.HTTP.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	cfg.HTTP.CORS.Debug = false
+	cfg.HTTP.TLS = ServerTLSConfig{Enabled: true, CertFile: "server.pem", KeyFile: "server-key.pem"}
+	cfg.GRPC.TLS = ServerTLSConfig{Enabled: true, CertFile: "server.pem", KeyFile: "server-key.pem"}
+	cfg.Auth.TLS = ClientTLSConfig{Enabled: true, CAFile: "ca.pem", CertFile: "client.pem", KeyFile: "client-key.pem"}
+	cfg.Mail = MailConfig{Driver: "smtp", From: "no-reply@example.com", SMTP: SMTPConfig{Addr: "smtp.example.com:587"}}
+	assert.NoError(t, cfg.Validate())
+
+	// Generated certificates are for development only
+	cfg.TLS.DevMode = true
+	assert.ErrorContains(t, cfg.Validate(), "tls.dev_mode")
+
+	// So are webhooks to local receivers
+	cfg.TLS.DevMode = false
+	cfg.Webhooks.AllowInsecure = true
+	assert.ErrorContains(t, cfg.Validate(), "webhooks.allow_insecure")
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Mongo.URI = "mongodb://admin:hunter2@db:27017"
+	cfg.Auth.JWTSecret = "a-production-secret-that-is-long-enough"
+
+	values := cfg.Redacted()
+	assert.Equal(t, "[redacted]", values["auth.jwt_secret"])
+	assert.NotContains(t, values["mongo.uri"], "hunter2")
+	assert.Equal(t, "8080", values["http.port"])
+}
@@ -15,11 +15,29 @@ var (
 		UserData           string
 		Sessions           string
 		Appointments       string
+		RateLimits         string
+		IdempotencyKeys    string
+		AuditLog           string
+		WebhookEndpoints   string
+		WebhookDeliveries  string
+		EventOutbox        string
+		Sagas              string
+		Leases             string
+		RecoveryRuns       string
 	}{
 		OnboardingRequests: "onboarding_requests",
 		OnboardedTenants:   "onboarded_tenants",
 		UserData:           "user_data",
 		Sessions:           "session_store",
 		Appointments:       "appointments",
+		RateLimits:         "rate_limits",
+		IdempotencyKeys:    "idempotency_keys",
+		AuditLog:           "audit_log",
+		WebhookEndpoints:   "webhook_endpoints",
+		WebhookDeliveries:  "webhook_deliveries",
+		EventOutbox:        "event_outbox",
+		Sagas:              "sagas",
+		Leases:             "leases",
+		RecoveryRuns:       "recovery_runs",
 	}
 )
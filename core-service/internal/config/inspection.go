@@ -0,0 +1,171 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// OpenAPI extensions that declare request inspection
+const (
+	inspectionExtension = "x-inspection" // Operation level: mode and body inspection
+	paramTypeExtension  = "x-param-type" // Parameter level: overrides the type derived from the schema
+)
+
+// Inspection modes
+const (
+	InspectionBlock   = "block"   // Reject requests with findings
+	InspectionLog     = "log"     // Log findings and let the request through
+	InspectionMonitor = "monitor" // Only count findings in metrics
+)
+
+// Parameter types, each accepting an allowlist of values
+const (
+	ParamString   = "string"    // Any printable text
+	ParamName     = "name"      // Letters, digits, spaces and common name punctuation
+	ParamID       = "id"        // Letters, digits, hyphens and underscores
+	ParamUUID     = "uuid"      // RFC 4122 UUID
+	ParamInteger  = "integer"   // Whole number
+	ParamNumber   = "number"    // Decimal number
+	ParamBoolean  = "boolean"   // true or false
+	ParamDate     = "date"      // 2006-01-02
+	ParamDateTime = "date-time" // RFC 3339 timestamp
+	ParamEmail    = "email"     // Email address
+	ParamEnum     = "enum"      // One of the declared values
+)
+
+// ParamRule restricts the values of a single path or query parameter
+type ParamRule struct {
+	Name      string
+	In        string // path or query
+	Type      string
+	Enum      []string
+	Pattern   *regexp.Regexp // Declared schema pattern, checked in addition to the type
+	MaxLength int            // Zero uses the default of the type
+}
+
+// InspectionPolicy declares how requests to an operation are inspected
+type InspectionPolicy struct {
+	Mode        string // Empty uses the configured default
+	Params      []ParamRule
+	InspectBody bool // Inspect the JSON body for database operators
+}
+
+// inspectionSpec mirrors the x-inspection extension, e.g.
+//
+//	x-inspection:
+//	  mode: log
+//	  body: false
+type inspectionSpec struct {
+	Mode string `json:"mode"`
+	Body *bool  `json:"body"`
+}
+
+// parseInspection derives the inspection policy of an operation from its parameters,
+// request body and x-inspection extension. Parameter types follow the schema type and
+// format unless x-param-type names one explicitly. Header and cookie parameters are
+// left to the middleware that reads them.
+func parseInspection(item *openapi3.PathItem, op *openapi3.Operation) (InspectionPolicy, error) {
+	policy := InspectionPolicy{InspectBody: op.RequestBody != nil}
+
+	if raw, ok := op.Extensions[inspectionExtension]; ok {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return policy, err
+		}
+		var spec inspectionSpec
+		if err := json.Unmarshal(data, &spec); err != nil {
+			return policy, fmt.Errorf("invalid %s: %w", inspectionExtension, err)
+		}
+		switch spec.Mode {
+		case "", InspectionBlock, InspectionLog, InspectionMonitor:
+			policy.Mode = spec.Mode
+		default:
+			return policy, fmt.Errorf("unsupported inspection mode %q", spec.Mode)
+		}
+		if spec.Body != nil {
+			policy.InspectBody = *spec.Body
+		}
+	}
+
+	// Operation parameters override path item parameters of the same name and location
+	params := make(map[string]*openapi3.Parameter)
+	for _, list := range []openapi3.Parameters{item.Parameters, op.Parameters} {
+		for _, ref := range list {
+			if ref == nil || ref.Value == nil {
+				continue
+			}
+			if ref.Value.In == openapi3.ParameterInPath || ref.Value.In == openapi3.ParameterInQuery {
+				params[ref.Value.In+":"+ref.Value.Name] = ref.Value
+			}
+		}
+	}
+
+	for _, param := range params {
+		rule, err := paramRule(param)
+		if err != nil {
+			return policy, fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+		policy.Params = append(policy.Params, rule)
+	}
+	sort.Slice(policy.Params, func(i, j int) bool {
+		if policy.Params[i].In != policy.Params[j].In {
+			return policy.Params[i].In < policy.Params[j].In
+		}
+		return policy.Params[i].Name < policy.Params[j].Name
+	})
+	return policy, nil
+}
+
+// paramRule maps a parameter schema to the allowlist its values must match
+func paramRule(param *openapi3.Parameter) (ParamRule, error) {
+	rule := ParamRule{Name: param.Name, In: param.In, Type: ParamString}
+
+	if param.Schema != nil && param.Schema.Value != nil {
+		schema := param.Schema.Value
+		switch {
+		case len(schema.Enum) > 0:
+			rule.Type = ParamEnum
+			for _, value := range schema.Enum {
+				rule.Enum = append(rule.Enum, fmt.Sprint(value))
+			}
+		case schema.Type.Is(openapi3.TypeInteger):
+			rule.Type = ParamInteger
+		case schema.Type.Is(openapi3.TypeNumber):
+			rule.Type = ParamNumber
+		case schema.Type.Is(openapi3.TypeBoolean):
+			rule.Type = ParamBoolean
+		case schema.Format == ParamDate, schema.Format == ParamDateTime,
+			schema.Format == ParamUUID, schema.Format == ParamEmail:
+			rule.Type = schema.Format
+		}
+		if schema.Pattern != "" {
+			pattern, err := regexp.Compile(schema.Pattern)
+			if err != nil {
+				return rule, fmt.Errorf("invalid pattern: %w", err)
+			}
+			rule.Pattern = pattern
+		}
+		if schema.MaxLength != nil {
+			rule.MaxLength = int(*schema.MaxLength)
+		}
+	}
+
+	if raw, ok := param.Extensions[paramTypeExtension]; ok {
+		var paramType string
+		if data, err := json.Marshal(raw); err == nil {
+			_ = json.Unmarshal(data, &paramType)
+		}
+		switch paramType {
+		case ParamString, ParamName, ParamID, ParamUUID, ParamInteger, ParamNumber,
+			ParamBoolean, ParamDate, ParamDateTime, ParamEmail:
+			rule.Type = paramType
+		default:
+			return rule, fmt.Errorf("unsupported %s %v", paramTypeExtension, raw)
+		}
+	}
+	return rule, nil
+}
@@ -37,15 +37,6 @@ type APIRegistry map[string]map[string]map[string]APIResource
 
 var Registry APIRegistry
 
-var (
-	PublicRoutes = []string{
-		"/apis/core/v1/auth/login",
-		"/apis/core/v1/auth/register",
-		"/apis/core/v1/tenants/onboard",
-		"/openapi/v2",
-	}
-)
-
 // func init() {
 // 	// Load the API registry from the embedded file
 // 	cwd, _ := os.Getwd()
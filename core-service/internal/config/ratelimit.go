@@ -0,0 +1,132 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// rateLimitExtension is the OpenAPI extension that declares the rate limit of an operation
+const rateLimitExtension = "x-rate-limit"
+
+// Rate limit keys
+const (
+	RateLimitKeyIP       = "ip"       // Client address
+	RateLimitKeyUser     = "user"     // Username from the token claims
+	RateLimitKeyTenant   = "tenant"   // Tenant ID from the token claims
+	RateLimitKeyAPIKey   = "api_key"  // API key validated by authentication
+	RateLimitKeyUsername = "username" // Username field of the JSON request body, for logins
+)
+
+// RateLimitPolicy is a token bucket holding Burst requests and refilled
+// with Requests tokens every Period, counted separately for every key
+type RateLimitPolicy struct {
+	Key      string
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// Default rate limits for operations that do not declare their own
+var (
+	DefaultPublicRateLimit        = RateLimitPolicy{Key: RateLimitKeyIP, Requests: 60, Period: time.Minute, Burst: 20}
+	DefaultAuthenticatedRateLimit = RateLimitPolicy{Key: RateLimitKeyUser, Requests: 600, Period: time.Minute, Burst: 100}
+)
+
+// Rate returns the number of tokens added to the bucket per second
+func (p RateLimitPolicy) Rate() float64 {
+	return float64(p.Requests) / p.Period.Seconds()
+}
+
+// rateLimitSpec mirrors the x-rate-limit extension, e.g.
+//
+//	x-rate-limit:
+//	  key: ip
+//	  requests: 10
+//	  period: 1m
+//	  burst: 5
+//
+// The extension may also list several limits, each of which must allow the request.
+type rateLimitSpec struct {
+	Key      string `json:"key"`
+	Requests int    `json:"requests"`
+	Period   string `json:"period"`
+	Burst    int    `json:"burst"`
+}
+
+// parseRateLimits reads the x-rate-limit extension of an operation, falling back to the
+// default for public or authenticated operations when the extension is absent
+func parseRateLimits(extensions map[string]any, public bool) ([]RateLimitPolicy, error) {
+	raw, ok := extensions[rateLimitExtension]
+	if !ok {
+		return []RateLimitPolicy{defaultRateLimit(public)}, nil
+	}
+
+	list, ok := raw.([]any)
+	if !ok {
+		list = []any{raw}
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("empty %s", rateLimitExtension)
+	}
+
+	policies := make([]RateLimitPolicy, 0, len(list))
+	for _, item := range list {
+		policy, err := parseRateLimit(item, public)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// defaultRateLimit returns the limit of operations that do not declare their own
+func defaultRateLimit(public bool) RateLimitPolicy {
+	if public {
+		return DefaultPublicRateLimit
+	}
+	return DefaultAuthenticatedRateLimit
+}
+
+// parseRateLimit reads a single limit of the x-rate-limit extension, taking unset fields
+// from the default
+func parseRateLimit(raw any, public bool) (RateLimitPolicy, error) {
+	policy := defaultRateLimit(public)
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return policy, err
+	}
+	var spec rateLimitSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return policy, fmt.Errorf("invalid %s: %w", rateLimitExtension, err)
+	}
+
+	if spec.Key != "" {
+		policy.Key = spec.Key
+	}
+	if spec.Requests > 0 {
+		policy.Requests = spec.Requests
+	}
+	if spec.Period != "" {
+		period, err := time.ParseDuration(spec.Period)
+		if err != nil || period <= 0 {
+			return policy, fmt.Errorf("invalid %s period %q", rateLimitExtension, spec.Period)
+		}
+		policy.Period = period
+	}
+	if spec.Burst > 0 {
+		policy.Burst = spec.Burst
+	} else if spec.Requests > 0 {
+		policy.Burst = spec.Requests
+	}
+
+	switch policy.Key {
+	case RateLimitKeyIP, RateLimitKeyUser, RateLimitKeyTenant, RateLimitKeyAPIKey, RateLimitKeyUsername:
+	default:
+		return policy, errors.New("unsupported rate limit key: " + policy.Key)
+	}
+	return policy, nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// OperationSecurity describes the access requirements of a single API operation
+// as declared by the security requirements and rate limit of its OpenAPI spec
+type OperationSecurity struct {
+	OperationID string
+	Method      string
+	Path        string            // Full request path including the server base path
+	Public      bool              // No authentication required
+	Roles       []string          // Any of these roles grants access; empty means any authenticated user
+	RateLimits  []RateLimitPolicy // Every limit must allow the request
+	Inspection  InspectionPolicy
+}
+
+// SecurityPolicies maps operation IDs to their declared security
+type SecurityPolicies map[string]OperationSecurity
+
+// LoadSecurityPolicies reads an OpenAPI spec and derives the security of every operation.
+// Operation-level security overrides the document default. An empty requirement list
+// marks the operation as public, and the scopes listed for a scheme are treated as the
+// roles allowed to call it. Rate limits come from the x-rate-limit extension and request
+// inspection from the parameter schemas and the x-inspection extension.
+func LoadSecurityPolicies(specPath string) (SecurityPolicies, error) {
+	spec, err := openapi3.NewLoader().LoadFromFile(specPath)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Paths == nil {
+		return nil, errors.New("openapi spec has no paths")
+	}
+
+	basePath := ""
+	if len(spec.Servers) > 0 {
+		if u, err := url.Parse(spec.Servers[0].URL); err == nil {
+			basePath = strings.TrimSuffix(u.Path, "/")
+		}
+	}
+
+	policies := make(SecurityPolicies)
+	for path, item := range spec.Paths.Map() {
+		for method, op := range item.Operations() {
+			if op.OperationID == "" {
+				continue
+			}
+
+			requirements := spec.Security
+			if op.Security != nil {
+				requirements = *op.Security
+			}
+
+			public := len(requirements) == 0
+			rateLimits, err := parseRateLimits(op.Extensions, public)
+			if err != nil {
+				return nil, fmt.Errorf("operation %s: %w", op.OperationID, err)
+			}
+
+			inspection, err := parseInspection(item, op)
+			if err != nil {
+				return nil, fmt.Errorf("operation %s: %w", op.OperationID, err)
+			}
+
+			policies[op.OperationID] = OperationSecurity{
+				OperationID: op.OperationID,
+				Method:      method,
+				Path:        basePath + path,
+				Public:      public,
+				Roles:       requiredRoles(requirements),
+				RateLimits:  rateLimits,
+				Inspection:  inspection,
+			}
+		}
+	}
+
+	return policies, nil
+}
+
+// requiredRoles collects the roles accepted by a set of alternative security requirements.
+// If any alternative only asks for authentication, no role restriction applies.
+func requiredRoles(requirements openapi3.SecurityRequirements) []string {
+	seen := make(map[string]bool)
+	roles := []string{}
+	for _, requirement := range requirements {
+		for _, scopes := range requirement {
+			if len(scopes) == 0 {
+				return nil
+			}
+			for _, scope := range scopes {
+				if !seen[scope] {
+					seen[scope] = true
+					roles = append(roles, scope)
+				}
+			}
+		}
+	}
+	if len(roles) == 0 {
+		return nil
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// PublicRoutes lists the paths of every operation that does not require authentication
+func (p SecurityPolicies) PublicRoutes() []string {
+	routes := []string{}
+	for _, policy := range p {
+		if policy.Public {
+			routes = append(routes, policy.Path)
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
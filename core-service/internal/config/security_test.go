@@ -0,0 +1,78 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSecurityPolicies(t *testing.T) {
+	policies, err := LoadSecurityPolicies("openapi/onboarding.yaml")
+	assert.NoError(t, err, "Loading the onboarding spec should not return an error")
+
+	// Operations opting out with an empty security list are public
+	onboard := policies["onboardTenant"]
+	assert.True(t, onboard.Public, "onboardTenant should be public")
+	assert.Equal(t, "/apis/core/v1/tenants/onboard", onboard.Path)
+
+	// Scopes on the bearer scheme are treated as required roles
+	approve := policies["approveTenant"]
+	assert.False(t, approve.Public, "approveTenant should require authentication")
+	assert.Equal(t, []string{"superuser"}, approve.Roles)
+
+	assert.Equal(t, []string{
+		"/apis/core/v1/tenants/onboard",
+		"/apis/core/v1/tenants/tenant/{id}",
+		"/apis/core/v1/tenants/verify",
+		"/apis/core/v1/tenants/verify/resend",
+	}, policies.PublicRoutes())
+}
+
+func TestLoadSecurityPoliciesDefaultsToAuthenticated(t *testing.T) {
+	policies, err := LoadSecurityPolicies("openapi/reception.yaml")
+	assert.NoError(t, err, "Loading the reception spec should not return an error")
+
+	for operationID, policy := range policies {
+		assert.False(t, policy.Public, "%s should require authentication", operationID)
+		assert.NotEmpty(t, policy.Roles, "%s should declare its roles", operationID)
+	}
+	assert.Empty(t, policies.PublicRoutes())
+
+	// Parameter allowlists follow the schema unless x-param-type narrows them
+	list := policies["listAppointments"].Inspection
+	assert.Equal(t, "", list.Mode, "operations without x-inspection use the configured mode")
+	types := map[string]string{}
+	for _, param := range list.Params {
+		types[param.Name] = param.Type
+	}
+	assert.Equal(t, ParamID, types["doctor_id"])
+	assert.Equal(t, ParamDate, types["date_from"])
+	assert.Equal(t, ParamEnum, types["status"])
+	assert.True(t, policies["createAppointment"].Inspection.InspectBody, "operations with a body inspect it")
+}
+
+func TestLoadSecurityPoliciesRateLimits(t *testing.T) {
+	policies, err := LoadSecurityPolicies("openapi/onboarding.yaml")
+	assert.NoError(t, err, "Loading the onboarding spec should not return an error")
+
+	// Declared limits override the public default
+	assert.Len(t, policies["onboardTenant"].RateLimits, 1)
+	onboard := policies["onboardTenant"].RateLimits[0]
+	assert.Equal(t, RateLimitKeyIP, onboard.Key)
+	assert.Equal(t, 5, onboard.Requests)
+	assert.Equal(t, time.Hour, onboard.Period)
+	assert.Equal(t, 3, onboard.Burst)
+
+	// Operations without x-rate-limit use the authenticated default
+	assert.Equal(t, []RateLimitPolicy{DefaultAuthenticatedRateLimit}, policies["approveTenant"].RateLimits)
+
+	// Logins are limited per client address and per username
+	login, err := LoadSecurityPolicies("openapi/auth.yaml")
+	assert.NoError(t, err, "Loading the auth spec should not return an error")
+	keys := []string{}
+	for _, policy := range login["loginUser"].RateLimits {
+		keys = append(keys, policy.Key)
+	}
+	assert.Equal(t, []string{RateLimitKeyIP, RateLimitKeyUsername}, keys)
+}
@@ -3,10 +3,13 @@ package db
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 type DBType string
@@ -18,6 +21,9 @@ const (
 type dbOptions struct {
 	databaseName   string
 	collectionName string
+	sortField      string
+	sortOrder      int
+	limit          int64
 }
 
 type DBOption func(*dbOptions)
@@ -31,15 +37,25 @@ type DBConfig struct {
 
 type DBClientInterface interface {
 	Connect(ctx context.Context) error
+	Ping(ctx context.Context) error
 	Create(ctx context.Context, data map[string]interface{}, opts ...DBOption) (interface{}, error)
 	Read(ctx context.Context, data map[string]interface{}, opts ...DBOption) (interface{}, error)
 	ReadAll(ctx context.Context, data map[string]interface{}, opts ...DBOption) (interface{}, error)
+	Stream(ctx context.Context, data map[string]interface{}, fn func(map[string]interface{}) error, opts ...DBOption) error
 	Delete(ctx context.Context, data map[string]interface{}, opts ...DBOption) (interface{}, error)
 	UpdateOne(ctx context.Context, filter map[string]interface{}, update map[string]interface{}, opts ...DBOption) (int64, error)
+	EnsureTTLIndex(ctx context.Context, field string, expireAfter time.Duration, opts ...DBOption) error
+	EnsureUniqueIndex(ctx context.Context, field string, opts ...DBOption) error
+	// WithTransaction runs fn so that the operations it makes with the context it is given
+	// are committed together or not at all. fn may run again after a transient error.
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type mongoClient struct {
 	client *mongo.Client
+
+	topologyMutex sync.Mutex
+	transactional *bool // Whether the deployment supports transactions, once known
 }
 
 type DBClient struct {
@@ -68,13 +84,28 @@ func WithCollectionName(collectionName string) DBOption {
 	}
 }
 
+// WithSort orders the documents returned by Read and ReadAll by a field, 1 for ascending and -1 for descending
+func WithSort(field string, order int) DBOption {
+	return func(o *dbOptions) {
+		o.sortField = field
+		o.sortOrder = order
+	}
+}
+
+// WithLimit caps the number of documents returned by ReadAll
+func WithLimit(limit int64) DBOption {
+	return func(o *dbOptions) {
+		o.limit = limit
+	}
+}
+
 func (d *DBClient) Connect(ctx context.Context) error {
 	switch d.config.Type {
 	case MongoDB:
 		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 		defer cancel()
 
-		client, err := mongo.Connect(ctx, options.Client().ApplyURI(d.config.URI))
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(d.config.URI).SetMonitor(otelmongo.NewMonitor()))
 		if err != nil {
 			return err
 		}
@@ -85,10 +116,22 @@ func (d *DBClient) Connect(ctx context.Context) error {
 	return nil
 }
 
+// Ping verifies the database is reachable
+func (d *DBClient) Ping(ctx context.Context) error {
+	switch d.config.Type {
+	case MongoDB:
+		return d.mongoClient.ping(ctx)
+	default:
+		return errors.New("unsupported database type")
+	}
+}
+
 func (d *DBClient) Create(ctx context.Context, data map[string]interface{}, opts ...DBOption) (interface{}, error) {
 	switch d.config.Type {
 	case MongoDB:
+		start := time.Now()
 		result, err := d.mongoClient.create(ctx, data, opts...)
+		d.observe("create", start, err, opts)
 		return result, err
 	default:
 		return nil, errors.New("unsupported database type")
@@ -98,7 +141,9 @@ func (d *DBClient) Create(ctx context.Context, data map[string]interface{}, opts
 func (d *DBClient) Read(ctx context.Context, data map[string]interface{}, opts ...DBOption) (interface{}, error) {
 	switch d.config.Type {
 	case MongoDB:
+		start := time.Now()
 		result, err := d.mongoClient.read(ctx, data, opts...)
+		d.observe("read", start, err, opts)
 		return result, err
 	default:
 		return nil, errors.New("unsupported database type")
@@ -108,17 +153,35 @@ func (d *DBClient) Read(ctx context.Context, data map[string]interface{}, opts .
 func (d *DBClient) ReadAll(ctx context.Context, data map[string]interface{}, opts ...DBOption) (interface{}, error) {
 	switch d.config.Type {
 	case MongoDB:
+		start := time.Now()
 		result, err := d.mongoClient.readall(ctx, data, opts...)
+		d.observe("readall", start, err, opts)
 		return result, err
 	default:
 		return nil, errors.New("unsupported database type")
 	}
 }
 
+// Stream calls fn with every matching document as it is read from the cursor, so large
+// result sets are never held in memory. An error returned by fn stops the iteration.
+func (d *DBClient) Stream(ctx context.Context, data map[string]interface{}, fn func(map[string]interface{}) error, opts ...DBOption) error {
+	switch d.config.Type {
+	case MongoDB:
+		start := time.Now()
+		err := d.mongoClient.stream(ctx, data, fn, opts...)
+		d.observe("stream", start, err, opts)
+		return err
+	default:
+		return errors.New("unsupported database type")
+	}
+}
+
 func (d *DBClient) Delete(ctx context.Context, data map[string]interface{}, opts ...DBOption) (interface{}, error) {
 	switch d.config.Type {
 	case MongoDB:
+		start := time.Now()
 		result, err := d.mongoClient.delete(ctx, data, opts...)
+		d.observe("delete", start, err, opts)
 		return result, err
 	default:
 		return nil, errors.New("unsupported database type")
@@ -129,9 +192,59 @@ func (d *DBClient) Delete(ctx context.Context, data map[string]interface{}, opts
 func (d *DBClient) UpdateOne(ctx context.Context, filter map[string]interface{}, update map[string]interface{}, opts ...DBOption) (int64, error) {
 	switch d.config.Type {
 	case MongoDB:
+		start := time.Now()
 		result, err := d.mongoClient.updateOne(ctx, filter, update, opts...)
+		d.observe("update_one", start, err, opts)
 		return result, err
 	default:
 		return 0, errors.New("unsupported database type")
 	}
 }
+
+// EnsureUniqueIndex makes the database reject a document repeating the value of field.
+// Documents without the field are not indexed.
+func (d *DBClient) EnsureUniqueIndex(ctx context.Context, field string, opts ...DBOption) error {
+	switch d.config.Type {
+	case MongoDB:
+		return d.mongoClient.ensureUniqueIndex(ctx, field, opts...)
+	default:
+		return errors.New("unsupported database type")
+	}
+}
+
+// EnsureTTLIndex makes the database expire documents once the given time field is older than expireAfter
+func (d *DBClient) EnsureTTLIndex(ctx context.Context, field string, expireAfter time.Duration, opts ...DBOption) error {
+	switch d.config.Type {
+	case MongoDB:
+		return d.mongoClient.ensureTTLIndex(ctx, field, expireAfter, opts...)
+	default:
+		return errors.New("unsupported database type")
+	}
+}
+
+// WithTransaction runs fn in a transaction. Standalone MongoDB servers have no transactions,
+// so there fn runs without one and its operations are not atomic.
+func (d *DBClient) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	switch d.config.Type {
+	case MongoDB:
+		return d.mongoClient.withTransaction(ctx, fn)
+	default:
+		return errors.New("unsupported database type")
+	}
+}
+
+// SupportsTransactions reports whether the deployment is a replica set or sharded cluster
+func (d *DBClient) SupportsTransactions(ctx context.Context) (bool, error) {
+	switch d.config.Type {
+	case MongoDB:
+		return d.mongoClient.supportsTransactions(ctx)
+	default:
+		return false, errors.New("unsupported database type")
+	}
+}
+
+// observe records the latency and outcome of a database operation
+func (d *DBClient) observe(operation string, start time.Time, err error, opts []DBOption) {
+	_, collection := d.mongoClient.getDatabaseAndCollection(opts...)
+	metrics.ObserveDBOperation(operation, collection, time.Since(start), err)
+}
@@ -2,12 +2,24 @@ package db
 
 import (
 	"context"
+	"errors"
 	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
+// ping checks that the primary is reachable.
+func (m *mongoClient) ping(ctx context.Context) error {
+	if m.client == nil {
+		return errors.New("mongodb client is not connected")
+	}
+	return m.client.Ping(ctx, readpref.Primary())
+}
+
 // Create inserts a document into the specified collection.
 func (m *mongoClient) create(ctx context.Context, data map[string]interface{}, opts ...DBOption) (interface{}, error) {
 	dbName, collName := m.getDatabaseAndCollection(opts...)
@@ -26,7 +38,7 @@ func (m *mongoClient) read(ctx context.Context, filter bson.M, opts ...DBOption)
 
 	collection := m.client.Database(dbName).Collection(collName)
 	var result map[string]interface{}
-	err := collection.FindOne(ctx, filter).Decode(&result)
+	err := collection.FindOne(ctx, filter, m.findOneOptions(opts...)).Decode(&result)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil // No match found
 	} else if err != nil {
@@ -40,7 +52,7 @@ func (m *mongoClient) readall(ctx context.Context, filter bson.M, opts ...DBOpti
 	dbName, collName := m.getDatabaseAndCollection(opts...)
 
 	collection := m.client.Database(dbName).Collection(collName)
-	cursor, err := collection.Find(ctx, filter)
+	cursor, err := collection.Find(ctx, filter, m.findOptions(opts...))
 	if err != nil {
 		return nil, err
 	}
@@ -53,6 +65,29 @@ func (m *mongoClient) readall(ctx context.Context, filter bson.M, opts ...DBOpti
 	return results, nil
 }
 
+// stream iterates the documents that match the filter one at a time.
+func (m *mongoClient) stream(ctx context.Context, filter bson.M, fn func(map[string]interface{}) error, opts ...DBOption) error {
+	dbName, collName := m.getDatabaseAndCollection(opts...)
+
+	collection := m.client.Database(dbName).Collection(collName)
+	cursor, err := collection.Find(ctx, filter, m.findOptions(opts...))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var document map[string]interface{}
+		if err := cursor.Decode(&document); err != nil {
+			return err
+		}
+		if err := fn(document); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
 // Delete removes one or more documents that match the filter.
 func (m *mongoClient) delete(ctx context.Context, filter bson.M, opts ...DBOption) (int64, error) {
 	dbName, collName := m.getDatabaseAndCollection(opts...)
@@ -85,6 +120,106 @@ func (m *mongoClient) updateOne(ctx context.Context, filter bson.M, update bson.
 	return result.ModifiedCount, nil
 }
 
+// ensureTTLIndex creates a TTL index on a date field; creating an identical index again is a no-op.
+func (m *mongoClient) ensureTTLIndex(ctx context.Context, field string, expireAfter time.Duration, opts ...DBOption) error {
+	dbName, collName := m.getDatabaseAndCollection(opts...)
+
+	collection := m.client.Database(dbName).Collection(collName)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(expireAfter.Seconds())),
+	})
+	return err
+}
+
+// ensureUniqueIndex creates a sparse unique index on a field; creating an identical index again is a no-op.
+func (m *mongoClient) ensureUniqueIndex(ctx context.Context, field string, opts ...DBOption) error {
+	dbName, collName := m.getDatabaseAndCollection(opts...)
+
+	collection := m.client.Database(dbName).Collection(collName)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: 1}},
+		Options: options.Index().SetUnique(true).SetSparse(true),
+	})
+	return err
+}
+
+// withTransaction runs fn in a session transaction, or directly on a standalone server.
+func (m *mongoClient) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	transactional, err := m.supportsTransactions(ctx)
+	if err != nil {
+		return err
+	}
+	if !transactional {
+		return fn(ctx)
+	}
+
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	// Operations join the transaction through the session context
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+	return err
+}
+
+// supportsTransactions asks the server whether it is part of a replica set or a sharded
+// cluster, and remembers the answer.
+func (m *mongoClient) supportsTransactions(ctx context.Context) (bool, error) {
+	m.topologyMutex.Lock()
+	defer m.topologyMutex.Unlock()
+	if m.transactional != nil {
+		return *m.transactional, nil
+	}
+	if m.client == nil {
+		return false, errors.New("mongodb client is not connected")
+	}
+
+	var hello bson.M
+	if err := m.client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, err
+	}
+	_, replicaSet := hello["setName"]
+	transactional := replicaSet || hello["msg"] == "isdbgrid"
+	m.transactional = &transactional
+	return transactional, nil
+}
+
+// findOptions applies the sort and limit overrides to a Find.
+func (m *mongoClient) findOptions(opts ...DBOption) *options.FindOptions {
+	userOpts := &dbOptions{}
+	for _, opt := range opts {
+		opt(userOpts)
+	}
+
+	findOpts := options.Find()
+	if userOpts.sortField != "" {
+		findOpts.SetSort(bson.D{{Key: userOpts.sortField, Value: userOpts.sortOrder}})
+	}
+	if userOpts.limit > 0 {
+		findOpts.SetLimit(userOpts.limit)
+	}
+	return findOpts
+}
+
+// findOneOptions applies the sort override to a FindOne.
+func (m *mongoClient) findOneOptions(opts ...DBOption) *options.FindOneOptions {
+	userOpts := &dbOptions{}
+	for _, opt := range opts {
+		opt(userOpts)
+	}
+
+	findOpts := options.FindOne()
+	if userOpts.sortField != "" {
+		findOpts.SetSort(bson.D{{Key: userOpts.sortField, Value: userOpts.sortOrder}})
+	}
+	return findOpts
+}
+
 // hasUpdateOperators checks if the update document already contains MongoDB update operators
 func hasUpdateOperators(update bson.M) bool {
 	for key := range update {
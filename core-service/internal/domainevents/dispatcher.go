@@ -0,0 +1,127 @@
+package domainevents
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+	"github.com/mrityunjay-vashisth/core-service/internal/worker"
+	"go.uber.org/zap"
+)
+
+// dispatchBatch is how many due events a dispatcher claims at a time
+const dispatchBatch = 20
+
+// handlerTimeout bounds the time the subscribers of one event may take
+const handlerTimeout = 10 * time.Second
+
+// claimLease keeps a claimed batch from other dispatchers while its events are handled
+// one after another
+const claimLease = 5 * time.Minute
+
+// maxBackoff caps the wait between two attempts of an event
+const maxBackoff = time.Hour
+
+// Dispatcher periodically hands pending outbox events to their subscribers, one event at a
+// time, the longest due first. Several dispatchers may share an outbox; each batch of
+// events is leased to one of them.
+type Dispatcher struct {
+	// Runs DispatchDue. Events being handled when it stops are leased and dispatched again
+	// by a later run.
+	*worker.Loop
+
+	bus         *Bus
+	logger      *zap.Logger
+	maxAttempts int
+	retryDelay  time.Duration // Before the first retry, doubling with every further one
+	now         func() time.Time
+}
+
+// NewDispatcher creates a dispatcher of the events published on bus
+func NewDispatcher(bus *Bus, settings config.OutboxConfig, logger *zap.Logger) *Dispatcher {
+	d := &Dispatcher{
+		bus:         bus,
+		logger:      logger,
+		maxAttempts: settings.MaxAttempts,
+		retryDelay:  settings.RetryBaseDelay,
+		now:         time.Now,
+	}
+	d.Loop = worker.NewLoop("Domain event dispatcher", settings.PollInterval, d.DispatchDue, logger)
+	return d
+}
+
+// DispatchDue hands every due event to the subscribers that have not handled it yet
+func (d *Dispatcher) DispatchDue(ctx context.Context) error {
+	for {
+		due, err := d.bus.store.ClaimDue(ctx, d.now(), claimLease, dispatchBatch)
+		if err != nil {
+			return fmt.Errorf("failed to claim due domain events: %w", err)
+		}
+		for _, record := range due {
+			d.dispatch(ctx, record)
+		}
+		if len(due) < dispatchBatch {
+			break
+		}
+	}
+	return nil
+}
+
+// dispatch delivers an event to its remaining subscribers and records the outcome
+func (d *Dispatcher) dispatch(ctx context.Context, record Record) {
+	logger := d.logger.With(zap.String("event_id", record.ID), zap.String("event_type", record.Type))
+	handlerCtx, cancel := context.WithTimeout(ctx, handlerTimeout)
+	defer cancel()
+
+	msg := record.Message
+	msg.Attempt = record.Attempts + 1
+	record.LastError = ""
+	for _, sub := range d.bus.subscriptions(record.Type) {
+		if slices.Contains(record.DeliveredTo, sub.name) {
+			continue
+		}
+		if err := handle(handlerCtx, sub.handler, msg); err != nil {
+			logger.Warn("Domain event subscriber failed", zap.String("subscriber", sub.name), zap.Error(err))
+			record.LastError = sub.name + ": " + err.Error()
+			continue
+		}
+		record.DeliveredTo = append(record.DeliveredTo, sub.name)
+	}
+
+	now := d.now().UTC()
+	outcome := StatusDelivered
+	switch {
+	case record.LastError == "":
+		record.Status = StatusDelivered
+		record.CompletedAt = &now
+	case record.Attempts+1 >= d.maxAttempts:
+		outcome = StatusFailed
+		record.Attempts++
+		record.Status = StatusFailed
+		record.CompletedAt = &now
+		logger.Error("Giving up on domain event", zap.Int("attempts", record.Attempts), zap.String("error", record.LastError))
+	default:
+		outcome = "retrying"
+		record.Attempts++
+		record.NextAttemptAt = now.Add(worker.Backoff(d.retryDelay, maxBackoff, record.Attempts))
+	}
+
+	// Saving failed means the event is dispatched again once its lease ends
+	if err := d.bus.store.Save(ctx, record); err != nil {
+		logger.Error("Failed to save domain event progress", zap.Error(err))
+	}
+	metrics.DomainEventsDispatched.WithLabelValues(record.Type, outcome).Inc()
+}
+
+// handle calls a subscriber, turning a panic into an error so it cannot stop the dispatcher
+func handle(ctx context.Context, handler Handler, msg Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("subscriber panicked: %v", r)
+		}
+	}()
+	return handler(ctx, msg)
+}
@@ -0,0 +1,249 @@
+// Package domainevents lets one part of the system react to changes made by another.
+// Services publish typed events through the Bus with the context of the transaction that
+// makes the change, so an event is written to the outbox if and only if its change is
+// committed. A Dispatcher then hands every event to the subscribers of its type, at least
+// once: a subscriber may see an event again, and deduplicates it by its ID.
+package domainevents
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/go-idforge/pkg/idforge"
+)
+
+// Event types
+const (
+	TypeAppointmentCreated   = "appointment.created"
+	TypeAppointmentUpdated   = "appointment.updated"
+	TypeAppointmentCancelled = "appointment.cancelled"
+	TypeTenantApproved       = "tenant.approved"
+	TypeOnboardingFailed     = "onboarding.failed"
+	TypeOnboardingRejected   = "onboarding.rejected"
+
+	TypeOnboardingVerificationRequested = "onboarding.verification_requested"
+	TypeAuditRecorded                   = "audit.recorded"
+)
+
+// Outbox record statuses
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered" // Every subscriber handled the event
+	StatusFailed    = "failed"    // Given up on after the last attempt
+)
+
+// Event is a change that other parts of the system may react to
+type Event interface {
+	EventType() string
+	EventTenant() string // Tenant the change belongs to
+}
+
+// AppointmentCreated is published when an appointment is booked
+type AppointmentCreated struct {
+	TenantID    string                     `json:"tenant_id"`
+	Appointment models.AppointmentResponse `json:"appointment"`
+}
+
+func (e AppointmentCreated) EventType() string   { return TypeAppointmentCreated }
+func (e AppointmentCreated) EventTenant() string { return e.TenantID }
+
+// AppointmentUpdated is published when an appointment changes, other than being cancelled
+type AppointmentUpdated struct {
+	TenantID    string                     `json:"tenant_id"`
+	Appointment models.AppointmentResponse `json:"appointment"`
+}
+
+func (e AppointmentUpdated) EventType() string   { return TypeAppointmentUpdated }
+func (e AppointmentUpdated) EventTenant() string { return e.TenantID }
+
+// AppointmentCancelled is published when an appointment is cancelled
+type AppointmentCancelled struct {
+	TenantID    string                     `json:"tenant_id"`
+	Appointment models.AppointmentResponse `json:"appointment"`
+}
+
+func (e AppointmentCancelled) EventType() string   { return TypeAppointmentCancelled }
+func (e AppointmentCancelled) EventTenant() string { return e.TenantID }
+
+// TenantApproved is published when an onboarding request becomes an active tenant
+type TenantApproved struct {
+	TenantID         string    `json:"tenant_id"`
+	RequestID        string    `json:"request_id"`
+	OrganizationName string    `json:"organization_name"`
+	Email            string    `json:"email"`
+	Username         string    `json:"username"`
+	ApprovedAt       time.Time `json:"approved_at"`
+}
+
+func (e TenantApproved) EventType() string   { return TypeTenantApproved }
+func (e TenantApproved) EventTenant() string { return e.TenantID }
+
+// OnboardingFailed is published when an approval step of an onboarding request fails
+type OnboardingFailed struct {
+	TenantID     string     `json:"tenant_id"`
+	RequestID    string     `json:"request_id"`
+	Email        string     `json:"email"`
+	Reason       string     `json:"reason"`
+	RetryCount   int        `json:"retry_count"`             // Failures of the request so far, this one included
+	DeadLettered bool       `json:"dead_lettered"`           // Failed too often to be retried automatically
+	NextRetryAt  *time.Time `json:"next_retry_at,omitempty"` // When the approval is retried, unless dead-lettered
+	FailedAt     time.Time  `json:"failed_at"`
+}
+
+func (e OnboardingFailed) EventType() string   { return TypeOnboardingFailed }
+func (e OnboardingFailed) EventTenant() string { return e.TenantID }
+
+// OnboardingRejected is published when a reviewer rejects an onboarding request
+type OnboardingRejected struct {
+	TenantID         string    `json:"tenant_id"`
+	RequestID        string    `json:"request_id"`
+	OrganizationName string    `json:"organization_name"`
+	Email            string    `json:"email"`
+	ReasonCode       string    `json:"reason_code"`
+	Comment          string    `json:"comment,omitempty"`
+	RejectedAt       time.Time `json:"rejected_at"`
+	ReapplyAfter     time.Time `json:"reapply_after"`
+}
+
+func (e OnboardingRejected) EventType() string   { return TypeOnboardingRejected }
+func (e OnboardingRejected) EventTenant() string { return e.TenantID }
+
+// OnboardingVerificationRequested is published when the applicant of an onboarding request
+// is to be sent a link to verify their email. The payload names which verification email of
+// the request this is and the subscriber signs the link, so no token is kept in the outbox.
+// The event is only for the applicant email and never forwarded to webhooks.
+type OnboardingVerificationRequested struct {
+	TenantID         string    `json:"tenant_id"`
+	RequestID        string    `json:"request_id"`
+	OrganizationName string    `json:"organization_name"`
+	Email            string    `json:"email"`
+	Send             int       `json:"send"` // 1 for the first verification email of the request
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+func (e OnboardingVerificationRequested) EventType() string {
+	return TypeOnboardingVerificationRequested
+}
+func (e OnboardingVerificationRequested) EventTenant() string { return e.TenantID }
+
+// AuditRecorded carries a mutation to the audit trail, which links it into the chain when
+// the event is delivered. It is never forwarded to webhooks.
+type AuditRecorded struct {
+	Entry audit.Entry `json:"entry"` // Not linked yet: no sequence or hashes
+}
+
+func (e AuditRecorded) EventType() string   { return TypeAuditRecorded }
+func (e AuditRecorded) EventTenant() string { return e.Entry.TenantID }
+
+// Message is an event as a subscriber receives it
+type Message struct {
+	ID         string          `json:"id"` // The same on every delivery of the event
+	Type       string          `json:"type"`
+	TenantID   string          `json:"tenant_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempt    int             `json:"attempt"` // 1 on the first delivery
+}
+
+// Decode reads the payload into the typed event
+func (m Message) Decode(event interface{}) error {
+	return json.Unmarshal(m.Payload, event)
+}
+
+// Handler reacts to an event. A returned error makes the dispatcher deliver the event to
+// the subscriber again later.
+type Handler func(ctx context.Context, msg Message) error
+
+// Record is an event in the outbox
+type Record struct {
+	Message
+	Status        string
+	DeliveredTo   []string // Subscribers that handled the event
+	Attempts      int      // Dispatches that left a subscriber failing
+	LastError     string
+	NextAttemptAt time.Time
+	CompletedAt   *time.Time
+}
+
+// Store is the outbox
+type Store interface {
+	// Append writes events with the context it is given, so they join its transaction
+	Append(ctx context.Context, records ...Record) error
+	// ClaimDue leases up to limit pending events whose next attempt is due, oldest first,
+	// so no other dispatcher delivers them until the lease ends
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int64) ([]Record, error)
+	// Save stores the progress of a claimed event: its status, the subscribers that handled
+	// it, its attempts and when it is next due
+	Save(ctx context.Context, record Record) error
+}
+
+// subscription is a handler registered for some event types
+type subscription struct {
+	name    string
+	handler Handler
+}
+
+// Bus publishes events to the outbox and keeps track of their subscribers
+type Bus struct {
+	store Store
+	now   func() time.Time
+
+	mu          sync.RWMutex
+	subscribers map[string][]subscription // By event type
+}
+
+// NewBus creates a bus writing events to store
+func NewBus(store Store) *Bus {
+	return &Bus{
+		store:       store,
+		now:         time.Now,
+		subscribers: make(map[string][]subscription),
+	}
+}
+
+// Subscribe registers a handler for the given event types. The name records which
+// subscribers have handled an event, so it must stay the same across releases.
+func (b *Bus) Subscribe(name string, handler Handler, eventTypes ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, eventType := range eventTypes {
+		b.subscribers[eventType] = append(b.subscribers[eventType], subscription{name: name, handler: handler})
+	}
+}
+
+// Publish writes events to the outbox. Pass the context of the transaction that makes the
+// change, so the events are stored together with it.
+func (b *Bus) Publish(ctx context.Context, events ...Event) error {
+	now := b.now().UTC()
+	records := make([]Record, 0, len(events))
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		records = append(records, Record{
+			Message: Message{
+				ID:         idforge.GenerateWithSize(20),
+				Type:       event.EventType(),
+				TenantID:   event.EventTenant(),
+				OccurredAt: now,
+				Payload:    payload,
+			},
+			Status:        StatusPending,
+			DeliveredTo:   []string{},
+			NextAttemptAt: now,
+		})
+	}
+	return b.store.Append(ctx, records...)
+}
+
+// subscriptions returns the subscribers of an event type
+func (b *Bus) subscriptions(eventType string) []subscription {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.subscribers[eventType]
+}
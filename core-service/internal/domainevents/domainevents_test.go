@@ -0,0 +1,221 @@
+package domainevents
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryStore keeps the outbox in memory for tests
+type memoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: map[string]Record{}}
+}
+
+func (s *memoryStore) Append(_ context.Context, records ...Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, record := range records {
+		s.records[record.ID] = record
+	}
+	return nil
+}
+
+func (s *memoryStore) ClaimDue(_ context.Context, now time.Time, lease time.Duration, limit int64) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []Record
+	for id, record := range s.records {
+		if int64(len(due)) == limit {
+			break
+		}
+		if record.Status == StatusPending && !record.NextAttemptAt.After(now) {
+			due = append(due, record)
+			record.NextAttemptAt = now.Add(lease)
+			s.records[id] = record
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) Save(_ context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.ID] = record
+	return nil
+}
+
+func (s *memoryStore) Since(_ context.Context, from time.Time, types []string, limit int64) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []Record
+	for _, record := range s.records {
+		if slices.Contains(types, record.Type) && !record.OccurredAt.Before(from) {
+			records = append(records, record)
+		}
+	}
+	slices.SortFunc(records, func(a, b Record) int { return a.OccurredAt.Compare(b.OccurredAt) })
+	if int64(len(records)) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// only returns the single record in the store
+func (s *memoryStore) only() Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, record := range s.records {
+		return record
+	}
+	return Record{}
+}
+
+// newTestDispatcher returns a dispatcher of bus whose clock is controlled by the test
+func newTestDispatcher(bus *Bus, clock *time.Time) *Dispatcher {
+	settings := config.OutboxConfig{
+		PollInterval:   time.Second,
+		MaxAttempts:    3,
+		RetryBaseDelay: time.Minute,
+	}
+	dispatcher := NewDispatcher(bus, settings, zap.NewNop())
+	dispatcher.now = func() time.Time { return *clock }
+	bus.now = dispatcher.now
+	return dispatcher
+}
+
+func TestPublishAndDispatch(t *testing.T) {
+	clock := time.Now()
+	store := newMemoryStore()
+	bus := NewBus(store)
+	dispatcher := newTestDispatcher(bus, &clock)
+
+	var received []Message
+	bus.Subscribe("test", func(_ context.Context, msg Message) error {
+		received = append(received, msg)
+		return nil
+	}, TypeTenantApproved)
+
+	assert.NoError(t, bus.Publish(context.Background(), TenantApproved{TenantID: "tenant-1", RequestID: "request-1"}))
+	assert.NoError(t, dispatcher.DispatchDue(context.Background()))
+
+	if assert.Len(t, received, 1) {
+		var event TenantApproved
+		assert.NoError(t, received[0].Decode(&event))
+		assert.Equal(t, "request-1", event.RequestID)
+		assert.Equal(t, "tenant-1", received[0].TenantID)
+		assert.Equal(t, 1, received[0].Attempt)
+	}
+	assert.Equal(t, StatusDelivered, store.only().Status)
+
+	// A delivered event is not dispatched again
+	assert.NoError(t, dispatcher.DispatchDue(context.Background()))
+	assert.Len(t, received, 1)
+}
+
+func TestFailingSubscriberRetried(t *testing.T) {
+	clock := time.Now()
+	store := newMemoryStore()
+	bus := NewBus(store)
+	dispatcher := newTestDispatcher(bus, &clock)
+
+	var healthyCalls, flakyCalls int
+	var ids []string
+	bus.Subscribe("healthy", func(_ context.Context, msg Message) error {
+		healthyCalls++
+		return nil
+	}, TypeAppointmentCreated)
+	bus.Subscribe("flaky", func(_ context.Context, msg Message) error {
+		flakyCalls++
+		ids = append(ids, msg.ID)
+		if flakyCalls == 1 {
+			return errors.New("unavailable")
+		}
+		return nil
+	}, TypeAppointmentCreated)
+
+	assert.NoError(t, bus.Publish(context.Background(), AppointmentCreated{TenantID: "tenant-1"}))
+	assert.NoError(t, dispatcher.DispatchDue(context.Background()))
+	record := store.only()
+	assert.Equal(t, StatusPending, record.Status)
+	assert.Equal(t, []string{"healthy"}, record.DeliveredTo)
+	assert.Equal(t, "flaky: unavailable", record.LastError)
+
+	// The retry waits for the backoff, and only the failed subscriber sees the event again
+	assert.NoError(t, dispatcher.DispatchDue(context.Background()))
+	assert.Equal(t, 1, flakyCalls, "The retry is not due yet")
+	clock = clock.Add(time.Minute)
+	assert.NoError(t, dispatcher.DispatchDue(context.Background()))
+	assert.Equal(t, 1, healthyCalls)
+	assert.Equal(t, 2, flakyCalls)
+	assert.Equal(t, ids[0], ids[1], "Retries carry the same event ID")
+	assert.Equal(t, StatusDelivered, store.only().Status)
+}
+
+func TestGiveUpAfterMaxAttempts(t *testing.T) {
+	clock := time.Now()
+	store := newMemoryStore()
+	bus := NewBus(store)
+	dispatcher := newTestDispatcher(bus, &clock)
+
+	bus.Subscribe("broken", func(_ context.Context, _ Message) error {
+		panic("broken subscriber")
+	}, TypeOnboardingFailed)
+
+	assert.NoError(t, bus.Publish(context.Background(), OnboardingFailed{TenantID: "tenant-1"}))
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, dispatcher.DispatchDue(context.Background()), "A panic does not stop the dispatcher")
+		clock = clock.Add(time.Hour)
+	}
+
+	record := store.only()
+	assert.Equal(t, StatusFailed, record.Status)
+	assert.Equal(t, 3, record.Attempts)
+	assert.Contains(t, record.LastError, "broken subscriber")
+	assert.NotNil(t, record.CompletedAt)
+}
+
+func TestFeedReachesEveryReplica(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	bus := NewBus(store)
+	bus.Subscribe("webhooks", func(context.Context, Message) error { return nil }, TypeAppointmentCreated)
+
+	seen := map[string][]string{}
+	newReplica := func(name string) *Feed {
+		return NewFeed(store, time.Second, func(_ context.Context, msg Message) error {
+			seen[name] = append(seen[name], msg.ID)
+			return nil
+		}, zap.NewNop(), TypeAppointmentCreated)
+	}
+	first, second := newReplica("first"), newReplica("second")
+
+	assert.NoError(t, bus.Publish(ctx, AppointmentCreated{TenantID: "t1"}))
+	published := store.only()
+	// Dispatching the event does not hide it from the feeds
+	clock := time.Now()
+	assert.NoError(t, newTestDispatcher(bus, &clock).DispatchDue(ctx))
+
+	for _, feed := range []*Feed{first, second, first} {
+		assert.NoError(t, feed.ReadNew(ctx))
+	}
+	assert.Equal(t, []string{published.ID}, seen["first"], "handled once")
+	assert.Equal(t, []string{published.ID}, seen["second"])
+
+	// An event committed after a newer one is still handled
+	late := Record{Message: Message{ID: "late", Type: TypeAppointmentCreated, OccurredAt: published.OccurredAt.Add(-time.Second)}}
+	assert.NoError(t, store.Append(ctx, late))
+	assert.NoError(t, first.ReadNew(ctx))
+	assert.Equal(t, []string{published.ID, "late"}, seen["first"])
+}
@@ -0,0 +1,92 @@
+package domainevents
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/worker"
+	"go.uber.org/zap"
+)
+
+// feedBatch is how many events a feed reads at a time
+const feedBatch = 100
+
+// feedLookback is how far back a feed reads again for events committed after newer ones.
+// An event's time is taken before its transaction commits, so it can show up late.
+const feedLookback = 30 * time.Second
+
+// History reads back published events, whatever their delivery status
+type History interface {
+	// Since returns up to limit events of the given types that occurred at or after a
+	// time, oldest first
+	Since(ctx context.Context, from time.Time, types []string, limit int64) ([]Record, error)
+}
+
+// Feed hands every new event of some types to a handler on each replica. Unlike the
+// Dispatcher, which delivers an event to a subscriber once across all replicas, it suits
+// per-replica state such as the subscribers of live streams. Events published before the
+// feed was created are skipped, and a failing handler does not get the event again.
+type Feed struct {
+	// Runs ReadNew
+	*worker.Loop
+
+	history History
+	types   []string
+	handler Handler
+	logger  *zap.Logger
+	cursor  time.Time            // Time of the newest event handled
+	seen    map[string]time.Time // Events handled within the lookback, by ID
+}
+
+// NewFeed creates a feed of the events of the given types, read every interval
+func NewFeed(history History, interval time.Duration, handler Handler, logger *zap.Logger, types ...string) *Feed {
+	f := &Feed{
+		history: history,
+		types:   types,
+		handler: handler,
+		logger:  logger,
+		cursor:  time.Now().UTC(),
+		seen:    make(map[string]time.Time),
+	}
+	f.Loop = worker.NewLoop("Domain event feed", interval, f.ReadNew, logger)
+	return f
+}
+
+// ReadNew hands the events published since the last run to the handler
+func (f *Feed) ReadNew(ctx context.Context) error {
+	from := f.cursor.Add(-feedLookback)
+	for {
+		records, err := f.history.Since(ctx, from, f.types, feedBatch)
+		if err != nil {
+			return fmt.Errorf("failed to read new domain events: %w", err)
+		}
+		for _, record := range records {
+			if _, ok := f.seen[record.ID]; ok {
+				continue
+			}
+			f.seen[record.ID] = record.OccurredAt
+			if record.OccurredAt.After(f.cursor) {
+				f.cursor = record.OccurredAt
+			}
+			handlerCtx, cancel := context.WithTimeout(ctx, handlerTimeout)
+			if err := handle(handlerCtx, f.handler, record.Message); err != nil {
+				f.logger.Warn("Domain event feed handler failed",
+					zap.String("event_id", record.ID), zap.String("event_type", record.Type), zap.Error(err))
+			}
+			cancel()
+		}
+		// A full batch of events sharing one time would be read again forever
+		if len(records) < feedBatch || !records[len(records)-1].OccurredAt.After(from) {
+			break
+		}
+		from = records[len(records)-1].OccurredAt
+	}
+
+	for id, occurredAt := range f.seen {
+		if occurredAt.Before(f.cursor.Add(-feedLookback)) {
+			delete(f.seen, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package domainevents
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MongoStore keeps the outbox in coredb, keyed by event ID
+type MongoStore struct {
+	db db.DBClientInterface
+}
+
+// NewMongoStore creates an outbox backed by the event_outbox collection
+func NewMongoStore(database db.DBClientInterface) *MongoStore {
+	return &MongoStore{db: database}
+}
+
+// EnsureRetention makes the database expire events once they were completed longer than
+// retention ago. Pending events have no completion time and are kept.
+func (s *MongoStore) EnsureRetention(ctx context.Context, retention time.Duration) error {
+	return s.db.EnsureTTLIndex(ctx, "completed_at", retention, s.outbox()...)
+}
+
+func (s *MongoStore) outbox(extra ...db.DBOption) []db.DBOption {
+	return append([]db.DBOption{
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.EventOutbox),
+	}, extra...)
+}
+
+func (s *MongoStore) Append(ctx context.Context, records ...Record) error {
+	for _, record := range records {
+		if _, err := s.db.Create(ctx, encodeRecord(record), s.outbox()...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MongoStore) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int64) ([]Record, error) {
+	results, err := s.db.ReadAll(ctx,
+		bson.M{"status": StatusPending, "next_attempt_at": bson.M{"$lte": now}},
+		s.outbox(db.WithSort("next_attempt_at", 1), db.WithLimit(limit))...)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, _ := results.([]map[string]interface{})
+	claimed := make([]Record, 0, len(docs))
+	for _, doc := range docs {
+		// Moving the next attempt past the lease claims the event; a dispatcher that read
+		// the same document first leaves nothing to modify
+		leased, err := s.db.UpdateOne(ctx,
+			bson.M{"_id": doc["_id"], "status": StatusPending, "next_attempt_at": doc["next_attempt_at"]},
+			bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}},
+			s.outbox()...)
+		if err != nil {
+			return claimed, err
+		}
+		if leased > 0 {
+			claimed = append(claimed, decodeRecord(doc))
+		}
+	}
+	return claimed, nil
+}
+
+func (s *MongoStore) Save(ctx context.Context, record Record) error {
+	_, err := s.db.UpdateOne(ctx,
+		bson.M{"_id": record.ID},
+		bson.M{"$set": bson.M{
+			"status":          record.Status,
+			"delivered_to":    record.DeliveredTo,
+			"attempts":        record.Attempts,
+			"last_error":      record.LastError,
+			"next_attempt_at": record.NextAttemptAt,
+			"completed_at":    record.CompletedAt,
+		}},
+		s.outbox()...)
+	return err
+}
+
+func (s *MongoStore) Since(ctx context.Context, from time.Time, types []string, limit int64) ([]Record, error) {
+	results, err := s.db.ReadAll(ctx,
+		bson.M{"type": bson.M{"$in": types}, "occurred_at": bson.M{"$gte": from}},
+		s.outbox(db.WithSort("occurred_at", 1), db.WithLimit(limit))...)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, _ := results.([]map[string]interface{})
+	records := make([]Record, 0, len(docs))
+	for _, doc := range docs {
+		records = append(records, decodeRecord(doc))
+	}
+	return records, nil
+}
+
+func encodeRecord(record Record) map[string]interface{} {
+	return map[string]interface{}{
+		"_id":             record.ID,
+		"type":            record.Type,
+		"tenant_id":       record.TenantID,
+		"occurred_at":     record.OccurredAt,
+		"payload":         string(record.Payload),
+		"status":          record.Status,
+		"delivered_to":    record.DeliveredTo,
+		"attempts":        record.Attempts,
+		"last_error":      record.LastError,
+		"next_attempt_at": record.NextAttemptAt,
+		"completed_at":    record.CompletedAt,
+	}
+}
+
+// decodeRecord reads an outbox document returned by the database
+func decodeRecord(doc map[string]interface{}) Record {
+	record := Record{
+		NextAttemptAt: timeField(doc["next_attempt_at"]),
+		DeliveredTo:   []string{},
+	}
+	record.ID, _ = doc["_id"].(string)
+	record.Type, _ = doc["type"].(string)
+	record.TenantID, _ = doc["tenant_id"].(string)
+	record.OccurredAt = timeField(doc["occurred_at"])
+	if payload, _ := doc["payload"].(string); payload != "" {
+		record.Payload = json.RawMessage(payload)
+	}
+	record.Status, _ = doc["status"].(string)
+	if delivered, ok := doc["delivered_to"].(primitive.A); ok {
+		for _, name := range delivered {
+			if s, ok := name.(string); ok {
+				record.DeliveredTo = append(record.DeliveredTo, s)
+			}
+		}
+	}
+	switch n := doc["attempts"].(type) {
+	case int32:
+		record.Attempts = int(n)
+	case int64:
+		record.Attempts = int(n)
+	}
+	record.LastError, _ = doc["last_error"].(string)
+	if completedAt := timeField(doc["completed_at"]); !completedAt.IsZero() {
+		record.CompletedAt = &completedAt
+	}
+	return record
+}
+
+func timeField(value interface{}) time.Time {
+	switch t := value.(type) {
+	case time.Time:
+		return t.UTC()
+	case primitive.DateTime:
+		return t.Time().UTC()
+	}
+	return time.Time{}
+}
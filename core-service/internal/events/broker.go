@@ -0,0 +1,198 @@
+// Package events fans appointment changes out to in-process subscribers such as the
+// streaming APIs. Every replica feeds its broker from the domain event outbox.
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/domainevents"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+)
+
+// Types of appointment events
+const (
+	AppointmentCreated   = "appointment.created"
+	AppointmentUpdated   = "appointment.updated"
+	AppointmentCancelled = "appointment.cancelled"
+)
+
+// subscriptionBuffer is how many events a subscriber may fall behind before it is dropped
+const subscriptionBuffer = 64
+
+// historySize is how many recent events of each tenant are kept for resuming subscribers
+const historySize = 256
+
+// AppointmentEvent describes a change to an appointment
+type AppointmentEvent struct {
+	ID          uint64                     `json:"id"` // Increases with every event
+	Type        string                     `json:"type"`
+	TenantID    string                     `json:"-"`
+	Appointment models.AppointmentResponse `json:"appointment"`
+	OccurredAt  time.Time                  `json:"occurred_at"`
+}
+
+// Broker delivers appointment events to the subscribers of the event's tenant. Publishing
+// never blocks: a subscriber whose buffer is full is dropped and its channel closed. The
+// last events of each tenant are kept so a subscriber can resume after reconnecting.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*Subscription]struct{} // By tenant ID
+	history     map[string]*tenantHistory
+	lastID      uint64
+}
+
+// tenantHistory holds the recent events of a tenant, oldest first
+type tenantHistory struct {
+	events  []AppointmentEvent
+	evicted uint64 // ID of the newest event no longer kept
+}
+
+// Subscription receives the events of one tenant
+type Subscription struct {
+	events  chan AppointmentEvent
+	broker  *Broker
+	tenant  string
+	startID uint64 // ID of the last event published before the subscription
+	dropped bool   // Closed because the subscriber fell behind
+	closed  bool
+}
+
+// NewBroker creates a broker with no subscribers. Event IDs start from the current time in
+// microseconds, so IDs handed out before a restart are lower than the ones after it.
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[string]map[*Subscription]struct{}),
+		history:     make(map[string]*tenantHistory),
+		lastID:      uint64(time.Now().UnixMicro()),
+	}
+}
+
+// Subscribe starts delivering the events of a tenant. The subscription must be closed when
+// the subscriber is done.
+func (b *Broker) Subscribe(tenantID string) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.subscribe(tenantID)
+}
+
+// SubscribeSince starts delivering the events of a tenant and returns the events published
+// after lastEventID. It reports false when some of them are no longer kept, or the ID was not
+// issued by this broker, in which case the subscriber should reload its state instead.
+func (b *Broker) SubscribeSince(tenantID string, lastEventID uint64) (*Subscription, []AppointmentEvent, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := b.subscribe(tenantID)
+	history := b.history[tenantID]
+	if lastEventID > b.lastID || (history != nil && lastEventID < history.evicted) {
+		return sub, nil, false
+	}
+
+	var missed []AppointmentEvent
+	if history != nil {
+		for _, event := range history.events {
+			if event.ID > lastEventID {
+				missed = append(missed, event)
+			}
+		}
+	}
+	return sub, missed, true
+}
+
+// subscribe registers a subscription. The caller holds the lock.
+func (b *Broker) subscribe(tenantID string) *Subscription {
+	sub := &Subscription{events: make(chan AppointmentEvent, subscriptionBuffer), broker: b, tenant: tenantID, startID: b.lastID}
+	if b.subscribers[tenantID] == nil {
+		b.subscribers[tenantID] = make(map[*Subscription]struct{})
+	}
+	b.subscribers[tenantID][sub] = struct{}{}
+	return sub
+}
+
+// Publish assigns the event an ID and delivers it to the subscribers of its tenant
+func (b *Broker) Publish(event AppointmentEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+
+	history := b.history[event.TenantID]
+	if history == nil {
+		history = &tenantHistory{}
+		b.history[event.TenantID] = history
+	}
+	if len(history.events) == historySize {
+		history.evicted = history.events[0].ID
+		history.events = history.events[1:]
+	}
+	history.events = append(history.events, event)
+
+	for sub := range b.subscribers[event.TenantID] {
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped = true
+			b.remove(sub)
+		}
+	}
+}
+
+// remove closes a subscription. The caller holds the lock.
+func (b *Broker) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.events)
+	delete(b.subscribers[sub.tenant], sub)
+	if len(b.subscribers[sub.tenant]) == 0 {
+		delete(b.subscribers, sub.tenant)
+	}
+}
+
+// Events returns the channel events are delivered on. It is closed when the subscription
+// ends.
+func (s *Subscription) Events() <-chan AppointmentEvent {
+	return s.events
+}
+
+// StartID returns the ID of the last event published before the subscription started.
+// Resuming from it delivers every event since.
+func (s *Subscription) StartID() uint64 {
+	return s.startID
+}
+
+// Dropped reports whether the subscription ended because the subscriber fell behind
+func (s *Subscription) Dropped() bool {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return s.dropped
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// HandleEvent publishes an appointment change read from the domain event outbox, so the
+// subscribers of every replica see the changes made through any of them
+func (b *Broker) HandleEvent(_ context.Context, msg domainevents.Message) error {
+	// Created, updated and cancelled events share a payload
+	var change domainevents.AppointmentUpdated
+	if err := msg.Decode(&change); err != nil {
+		return fmt.Errorf("failed to decode %s event: %w", msg.Type, err)
+	}
+	b.Publish(AppointmentEvent{
+		Type:        msg.Type,
+		TenantID:    msg.TenantID,
+		Appointment: change.Appointment,
+		OccurredAt:  msg.OccurredAt,
+	})
+	return nil
+}
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func publishN(broker *Broker, tenantID string, n int) {
+	for i := 0; i < n; i++ {
+		broker.Publish(AppointmentEvent{Type: AppointmentCreated, TenantID: tenantID, Appointment: models.AppointmentResponse{AppointmentID: "apt"}})
+	}
+}
+
+func TestSubscribeSince(t *testing.T) {
+	broker := NewBroker()
+	first := broker.Subscribe("tenant-1")
+	publishN(broker, "tenant-1", 3)
+	publishN(broker, "tenant-2", 1)
+
+	var received []AppointmentEvent
+	for i := 0; i < 3; i++ {
+		received = append(received, <-first.Events())
+	}
+	first.Close()
+	assert.Less(t, received[0].ID, received[1].ID)
+
+	// A client that saw the first event gets the other two replayed, not other tenants'
+	resumed, missed, complete := broker.SubscribeSince("tenant-1", received[0].ID)
+	assert.True(t, complete)
+	assert.Equal(t, received[1:], missed)
+	resumed.Close()
+
+	// IDs this broker never issued cannot be resumed from
+	_, _, complete = broker.SubscribeSince("tenant-1", received[2].ID+100)
+	assert.False(t, complete)
+
+	// Nor can events that were evicted from the history
+	publishN(broker, "tenant-1", historySize)
+	_, missed, complete = broker.SubscribeSince("tenant-1", received[0].ID)
+	assert.False(t, complete)
+	assert.Empty(t, missed)
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	broker := NewBroker()
+	slow := broker.Subscribe("tenant-1")
+	publishN(broker, "tenant-1", subscriptionBuffer+1)
+
+	count := 0
+	for range slow.Events() {
+		count++
+	}
+	assert.Equal(t, subscriptionBuffer, count)
+	assert.True(t, slow.Dropped())
+	slow.Close() // Closing a dropped subscription is harmless
+}
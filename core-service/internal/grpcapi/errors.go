@@ -0,0 +1,81 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// errorDomain identifies core-service in the ErrorInfo of returned statuses
+const errorDomain = "core-service"
+
+// kindCode maps domain error kinds to gRPC status codes, like kindStatus does for HTTP
+var kindCode = map[apperrors.Kind]codes.Code{
+	apperrors.KindValidation:   codes.InvalidArgument,
+	apperrors.KindUnauthorized: codes.Unauthenticated,
+	apperrors.KindForbidden:    codes.PermissionDenied,
+	apperrors.KindNotFound:     codes.NotFound,
+	apperrors.KindConflict:     codes.FailedPrecondition,
+	apperrors.KindPrecondition: codes.Aborted,
+	apperrors.KindUnavailable:  codes.Unavailable,
+	apperrors.KindInternal:     codes.Internal,
+}
+
+// toStatus converts an error returned by a service to a gRPC status. The error code is sent
+// as an ErrorInfo reason and invalid fields as a BadRequest, so clients need not parse
+// messages. Messages of unexpected errors are not exposed.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	domainErr, ok := apperrors.As(err)
+	if !ok {
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	code, ok := kindCode[domainErr.Kind]
+	if !ok {
+		code = codes.Internal
+	}
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: domainErr.Code, Domain: errorDomain}}
+	if len(domainErr.Fields) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, field := range domainErr.Fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Message,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	st, detailErr := status.New(code, domainErr.Message).WithDetails(details...)
+	if detailErr != nil {
+		return status.Error(code, domainErr.Message)
+	}
+	return st.Err()
+}
+
+// statusError logs the cause of server errors, which toStatus hides from clients, and
+// returns the status to send
+func statusError(ctx context.Context, logger *zap.Logger, err error) error {
+	domainErr, ok := apperrors.As(err)
+	if _, isStatus := status.FromError(err); !isStatus && (!ok || domainErr.Kind == apperrors.KindInternal || domainErr.Kind == apperrors.KindUnavailable) {
+		logging.WithContext(ctx, logger).Error("gRPC request failed", zap.Error(err))
+	}
+	return toStatus(err)
+}
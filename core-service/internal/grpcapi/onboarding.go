@@ -0,0 +1,160 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/corepb"
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/onboardingsvc"
+	"go.uber.org/zap"
+)
+
+// onboardingServer implements corepb.OnboardingServiceServer on top of onboardingsvc.Service
+type onboardingServer struct {
+	corepb.UnimplementedOnboardingServiceServer
+	registry registry.ServiceRegistry
+	logger   *zap.Logger
+}
+
+// service retrieves the onboarding service from the registry
+func (s *onboardingServer) service() (onboardingsvc.Service, error) {
+	service, ok := s.registry.Get(registry.OnboardingService).(onboardingsvc.Service)
+	if !ok {
+		return nil, errServiceNotRegistered
+	}
+	return service, nil
+}
+
+func (s *onboardingServer) OnboardTenant(ctx context.Context, req *corepb.OnboardTenantRequest) (*corepb.OnboardTenantResponse, error) {
+	var fields []apperrors.FieldError
+	for _, required := range []struct{ name, value string }{
+		{"organization_name", req.GetOrganizationName()},
+		{"email", req.GetEmail()},
+		{"role", req.GetRole()},
+	} {
+		if required.value == "" {
+			fields = append(fields, apperrors.FieldError{Field: required.name, Message: "is required"})
+		}
+	}
+	if len(fields) > 0 {
+		return nil, statusError(ctx, s.logger, apperrors.Validation("missing_fields", "Missing required fields", fields...))
+	}
+
+	service, err := s.service()
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	logging.WithContext(ctx, s.logger).Info("Received OnboardTenant request",
+		zap.String("Organization Name", req.GetOrganizationName()),
+		zap.String("Email", req.GetEmail()),
+		zap.String("role", req.GetRole()))
+
+	receipt, err := service.OnboardTenant(ctx, models.OnboardingRequest{
+		OrganizationName:   req.GetOrganizationName(),
+		Email:              req.GetEmail(),
+		Role:               req.GetRole(),
+		Address:            req.GetAddress(),
+		PhoneNumber:        req.GetPhoneNumber(),
+		BusinessIdentifier: req.GetBusinessIdentifier(),
+		WebhookURL:         req.GetWebhookUrl(),
+	})
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	return &corepb.OnboardTenantResponse{RequestId: receipt.RequestID, WebhookSecret: receipt.WebhookSecret}, nil
+}
+
+func (s *onboardingServer) ListTenants(ctx context.Context, req *corepb.ListTenantsRequest) (*corepb.ListTenantsResponse, error) {
+	service, err := s.service()
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+
+	response := &corepb.ListTenantsResponse{}
+	err = service.StreamTenants(ctx, req.GetState(), func(tenant map[string]interface{}) error {
+		response.Tenants = append(response.Tenants, toTenant(tenant))
+		return nil
+	})
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	return response, nil
+}
+
+func (s *onboardingServer) GetTenant(ctx context.Context, req *corepb.GetTenantRequest) (*corepb.Tenant, error) {
+	if req.GetRequestId() == "" {
+		return nil, statusError(ctx, s.logger, apperrors.Validation("missing_fields", "Missing required fields",
+			apperrors.FieldError{Field: "request_id", Message: "is required"}))
+	}
+	service, err := s.service()
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+
+	tenant, err := service.GetTenantByID(ctx, req.GetRequestId())
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	tenantMap, ok := tenant.(map[string]interface{})
+	if !ok {
+		return nil, statusError(ctx, s.logger, apperrors.Internal("invalid_tenant", "internal service error",
+			fmt.Errorf("unexpected tenant type %T", tenant)))
+	}
+	return toTenant(tenantMap), nil
+}
+
+func (s *onboardingServer) CheckTenant(ctx context.Context, req *corepb.CheckTenantRequest) (*corepb.CheckTenantResponse, error) {
+	if req.GetTenantId() == "" {
+		return nil, statusError(ctx, s.logger, apperrors.Validation("missing_fields", "Missing required fields",
+			apperrors.FieldError{Field: "tenant_id", Message: "is required"}))
+	}
+	service, err := s.service()
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+
+	exists, err := service.GetTenantCheckByID(ctx, req.GetTenantId())
+	if errors.Is(err, onboardingsvc.ErrTenantNotFound) {
+		return &corepb.CheckTenantResponse{Exists: false}, nil
+	}
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	return &corepb.CheckTenantResponse{Exists: exists}, nil
+}
+
+// toTenant converts a stored onboarding request or tenant to its protobuf message
+func toTenant(tenant map[string]interface{}) *corepb.Tenant {
+	return &corepb.Tenant{
+		RequestId:        stringField(tenant, "request_id"),
+		TenantId:         stringField(tenant, "tenant_id"),
+		OrganizationName: stringField(tenant, "organization_name"),
+		Email:            stringField(tenant, "email"),
+		Role:             stringField(tenant, "role"),
+		Status:           stringField(tenant, "status"),
+		CreatedAt:        stringField(tenant, "created_at"),
+		ApprovedAt:       stringField(tenant, "approved_at"),
+	}
+}
+
+// stringField formats a document field as a string, times as RFC 3339
+func stringField(document map[string]interface{}, key string) string {
+	switch value := document[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case time.Time:
+		return value.UTC().Format(time.RFC3339)
+	case interface{ Time() time.Time }: // primitive.DateTime
+		return value.Time().UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,237 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/authn"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/inspection"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+	"github.com/mrityunjay-vashisth/core-service/internal/ratelimit"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Protection holds what the REST API uses to limit and inspect requests, so gRPC methods
+// get the rate limits and inspection rules of the REST operation they serve
+type Protection struct {
+	RateLimits     ratelimit.Store         // Buckets shared with the REST API, nil disables limiting
+	Security       config.SecurityPolicies // Policies of the REST operations
+	InspectionMode string                  // Default mode of operations that do not declare one
+}
+
+// rateLimitsOf returns the limits of an operation, using the default for operations
+// missing from the spec
+func (p Protection) rateLimitsOf(operationID string, public bool) []config.RateLimitPolicy {
+	if policy, ok := p.Security[operationID]; ok {
+		return policy.RateLimits
+	}
+	if public {
+		return []config.RateLimitPolicy{config.DefaultPublicRateLimit}
+	}
+	return []config.RateLimitPolicy{config.DefaultAuthenticatedRateLimit}
+}
+
+// inspectionOf returns the inspection policy and mode of an operation. Operations missing
+// from the spec still get operator detection on their fields.
+func (p Protection) inspectionOf(operationID string) (config.InspectionPolicy, string) {
+	policy := config.InspectionPolicy{InspectBody: true}
+	if declared, ok := p.Security[operationID]; ok {
+		policy = declared.Inspection
+	}
+	mode := policy.Mode
+	if mode == "" {
+		mode = p.InspectionMode
+	}
+	// The request message stands in for the JSON body
+	policy.InspectBody = true
+	return policy, mode
+}
+
+// inspectionRequest lays out the fields of a request message like the REST request:
+// fields carrying a declared parameter are checked as that parameter, the rest as the body
+func inspectionRequest(policy config.InspectionPolicy, method methodPolicy, fields map[string]interface{}) *inspection.Request {
+	req := &inspection.Request{Query: url.Values{}, Path: map[string]string{}}
+	body := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		body[name] = value
+	}
+	for _, param := range policy.Params {
+		field := param.Name
+		if renamed, ok := method.params[param.Name]; ok {
+			field = renamed
+		}
+		value, ok := body[field].(string)
+		if !ok {
+			continue
+		}
+		delete(body, field)
+		if param.In == inspection.LocationPath {
+			req.Path[param.Name] = value
+		} else {
+			req.Query.Set(param.Name, value)
+		}
+	}
+	req.Body = body
+	return req
+}
+
+// unary rate limits and inspects calls to methods mapped to a REST operation. It must run
+// after authentication so user and tenant keys can be read from the claims.
+func (p Protection) unary(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		policy := methodPolicies[info.FullMethod]
+		if policy.operationID == "" {
+			return handler(ctx, req)
+		}
+
+		fields := messageFields(req)
+		if err := p.limit(ctx, logger, policy, fields); err != nil {
+			return nil, err
+		}
+		if err := p.inspect(ctx, logger, policy, fields); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// limit takes a token from every bucket of the operation, failing open when the store is
+// unavailable like the REST middleware. A rejected call gets back the tokens it took.
+func (p Protection) limit(ctx context.Context, logger *zap.Logger, policy methodPolicy, fields map[string]interface{}) error {
+	if p.RateLimits == nil {
+		return nil
+	}
+	var taken []ratelimit.Taken
+	for _, rateLimit := range p.rateLimitsOf(policy.operationID, policy.public) {
+		key := policy.operationID + ":" + callerKey(ctx, rateLimit.Key, fields)
+		limit := ratelimit.Limit{Rate: rateLimit.Rate(), Burst: rateLimit.Burst}
+		result, err := p.RateLimits.Take(ctx, key, limit)
+		if err != nil {
+			logging.WithContext(ctx, logger).Warn("Rate limit check failed, allowing call",
+				zap.String("operationId", policy.operationID),
+				zap.String("key", rateLimit.Key),
+				zap.Error(err))
+			continue
+		}
+		if !result.Allowed {
+			metrics.RateLimitedRequests.WithLabelValues(policy.operationID, rateLimit.Key).Inc()
+			if err := ratelimit.RefundAll(ctx, p.RateLimits, taken); err != nil {
+				logging.WithContext(ctx, logger).Warn("Failed to refund rate limit tokens",
+					zap.String("operationId", policy.operationID),
+					zap.Error(err))
+			}
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds())))))
+			return status.Error(codes.ResourceExhausted, "too many requests, retry later")
+		}
+		taken = append(taken, ratelimit.Taken{Key: key, Limit: limit})
+	}
+	return nil
+}
+
+// inspect applies the inspection rules of the operation to the fields of the request.
+// Findings reject the call in block mode and are logged or counted otherwise.
+func (p Protection) inspect(ctx context.Context, logger *zap.Logger, method methodPolicy, fields map[string]interface{}) error {
+	operationID := method.operationID
+	policy, mode := p.inspectionOf(operationID)
+	findings := inspection.NewRuleSet(policy).Inspect(inspectionRequest(policy, method, fields))
+	if len(findings) == 0 {
+		return nil
+	}
+
+	for _, finding := range findings {
+		metrics.InspectionFindings.WithLabelValues(operationID, finding.Rule, mode).Inc()
+	}
+
+	callLogger := logging.WithContext(ctx, logger).With(
+		zap.String("operationId", operationID),
+		zap.String("mode", mode),
+		zap.Any("findings", findings))
+
+	switch mode {
+	case config.InspectionMonitor:
+		return nil
+	case config.InspectionLog:
+		callLogger.Warn("Request inspection findings")
+		return nil
+	default:
+		callLogger.Warn("Request rejected by inspection")
+		violations := make([]apperrors.FieldError, 0, len(findings))
+		for _, finding := range findings {
+			violations = append(violations, apperrors.FieldError{Field: finding.Field, Message: finding.Reason})
+		}
+		return toStatus(apperrors.Validation("request_rejected", "Request failed inspection", violations...))
+	}
+}
+
+// callerKey identifies the caller a bucket belongs to, falling back to the peer address
+// when the call lacks the configured identity
+func callerKey(ctx context.Context, keyType string, fields map[string]interface{}) string {
+	switch keyType {
+	case config.RateLimitKeyUser:
+		if claims, ok := authn.ClaimsFromContext(ctx); ok && claims.Username != "" {
+			return "user:" + claims.Username
+		}
+	case config.RateLimitKeyTenant:
+		if claims, ok := authn.ClaimsFromContext(ctx); ok && claims.TenantID != "" {
+			return "tenant:" + claims.TenantID
+		}
+	case config.RateLimitKeyAPIKey:
+		if keyID, ok := authn.APIKeyFromContext(ctx); ok {
+			return "api_key:" + keyID
+		}
+	case config.RateLimitKeyUsername:
+		if username, ok := fields["username"].(string); ok && strings.TrimSpace(username) != "" {
+			return ratelimit.UsernameKey(username)
+		}
+	}
+	return "ip:" + peerIP(ctx)
+}
+
+// peerIP returns the address of the caller
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// messageFields decodes a request message into the JSON shape of the REST body, keyed by
+// the proto field names
+func messageFields(req interface{}) map[string]interface{} {
+	message, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil
+	}
+	return fields
+}
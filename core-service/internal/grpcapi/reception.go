@@ -0,0 +1,315 @@
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/corepb"
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/authn"
+	"github.com/mrityunjay-vashisth/core-service/internal/events"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/receptionsvc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// errServiceNotRegistered is returned when a service is missing from the registry
+var errServiceNotRegistered = apperrors.Internal("service_not_registered", "internal service error", nil)
+
+// errMissingAppointmentID is returned when a request lacks the appointment ID
+var errMissingAppointmentID = apperrors.Validation("missing_appointment_id", "Missing appointment ID",
+	apperrors.FieldError{Field: "appointment_id", Message: "is required"})
+
+// receptionServer implements corepb.ReceptionServiceServer on top of receptionsvc.Service
+type receptionServer struct {
+	corepb.UnimplementedReceptionServiceServer
+	registry registry.ServiceRegistry
+	logger   *zap.Logger
+}
+
+// service retrieves the reception service from the registry
+func (s *receptionServer) service() (receptionsvc.Service, error) {
+	service, ok := s.registry.Get(registry.ReceptionService).(receptionsvc.Service)
+	if !ok {
+		return nil, errServiceNotRegistered
+	}
+	return service, nil
+}
+
+// caller returns the tenant and username of the authenticated caller
+func caller(ctx context.Context) (string, string, error) {
+	claims, ok := authn.ClaimsFromContext(ctx)
+	if !ok || claims.TenantID == "" {
+		return "", "", apperrors.Unauthorized("missing_tenant", "tenant ID not found in token")
+	}
+	return claims.TenantID, claims.Username, nil
+}
+
+func (s *receptionServer) ListAppointments(ctx context.Context, req *corepb.ListAppointmentsRequest) (*corepb.ListAppointmentsResponse, error) {
+	tenantID, _, err := caller(ctx)
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	service, err := s.service()
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+
+	// Same filters as the query parameters of the REST API
+	filters := make(map[string]interface{})
+	for name, value := range map[string]string{
+		"doctor_id":  req.GetDoctorId(),
+		"patient_id": req.GetPatientId(),
+		"date_from":  req.GetDateFrom(),
+		"date_to":    req.GetDateTo(),
+		"status":     req.GetStatus(),
+	} {
+		if value != "" {
+			filters[name] = value
+		}
+	}
+
+	response := &corepb.ListAppointmentsResponse{}
+	err = service.StreamAppointments(ctx, filters, tenantID, func(appointment models.AppointmentResponse) error {
+		response.Appointments = append(response.Appointments, toAppointment(&appointment))
+		return nil
+	})
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	return response, nil
+}
+
+func (s *receptionServer) GetAppointment(ctx context.Context, req *corepb.GetAppointmentRequest) (*corepb.Appointment, error) {
+	tenantID, _, err := caller(ctx)
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	if req.GetAppointmentId() == "" {
+		return nil, statusError(ctx, s.logger, errMissingAppointmentID)
+	}
+	service, err := s.service()
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+
+	appointment, err := service.GetAppointmentByID(ctx, req.GetAppointmentId(), tenantID)
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	return toAppointment(appointment), nil
+}
+
+func (s *receptionServer) CreateAppointment(ctx context.Context, req *corepb.CreateAppointmentRequest) (*corepb.Appointment, error) {
+	tenantID, username, err := caller(ctx)
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+
+	create := models.AppointmentCreateRequest{
+		PatientID:   req.GetPatientId(),
+		PatientName: req.GetPatientName(),
+		DoctorID:    req.GetDoctorId(),
+		DoctorName:  req.GetDoctorName(),
+		Duration:    int(req.GetDurationMinutes()),
+		Type:        models.AppointmentType(req.GetAppointmentType()),
+		Notes:       req.GetNotes(),
+	}
+	if req.GetScheduledTime() == nil {
+		return nil, statusError(ctx, s.logger, apperrors.Validation("missing_fields", "Missing required fields",
+			apperrors.FieldError{Field: "scheduled_time", Message: "is required"}))
+	}
+	create.ScheduledTime = req.GetScheduledTime().AsTime()
+	if err := receptionsvc.ValidateCreateRequest(create); err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+
+	service, err := s.service()
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	appointment, err := service.CreateAppointment(ctx, create, tenantID, username)
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	return toAppointment(appointment), nil
+}
+
+func (s *receptionServer) UpdateAppointment(ctx context.Context, req *corepb.UpdateAppointmentRequest) (*corepb.Appointment, error) {
+	tenantID, _, err := caller(ctx)
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	if req.GetAppointmentId() == "" {
+		return nil, statusError(ctx, s.logger, errMissingAppointmentID)
+	}
+
+	// Only the fields that are set are changed
+	var update models.AppointmentUpdateRequest
+	if req.ScheduledTime != nil {
+		scheduledTime := req.GetScheduledTime().AsTime()
+		update.ScheduledTime = &scheduledTime
+	}
+	if req.DurationMinutes != nil {
+		duration := int(req.GetDurationMinutes())
+		update.Duration = &duration
+	}
+	if req.AppointmentType != nil {
+		appointmentType := models.AppointmentType(req.GetAppointmentType())
+		update.Type = &appointmentType
+	}
+	if req.Status != nil {
+		appointmentStatus := models.AppointmentStatus(req.GetStatus())
+		update.Status = &appointmentStatus
+	}
+	update.Notes = req.Notes
+	update.ExpectedVersion = req.ExpectedVersion
+
+	service, err := s.service()
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	appointment, err := service.UpdateAppointment(ctx, req.GetAppointmentId(), update, tenantID)
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	return toAppointment(appointment), nil
+}
+
+func (s *receptionServer) CancelAppointment(ctx context.Context, req *corepb.CancelAppointmentRequest) (*corepb.Appointment, error) {
+	tenantID, _, err := caller(ctx)
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	if req.GetAppointmentId() == "" {
+		return nil, statusError(ctx, s.logger, errMissingAppointmentID)
+	}
+	if req.GetReason() == "" {
+		return nil, statusError(ctx, s.logger, apperrors.Validation("missing_fields", "Cancellation reason is required",
+			apperrors.FieldError{Field: "reason", Message: "is required"}))
+	}
+
+	service, err := s.service()
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	cancel := models.AppointmentCancelRequest{Reason: req.GetReason(), ExpectedVersion: req.ExpectedVersion}
+	appointment, err := service.CancelAppointment(ctx, req.GetAppointmentId(), cancel, tenantID)
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	return toAppointment(appointment), nil
+}
+
+func (s *receptionServer) GetDoctorAvailability(ctx context.Context, req *corepb.GetDoctorAvailabilityRequest) (*corepb.GetDoctorAvailabilityResponse, error) {
+	tenantID, _, err := caller(ctx)
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+
+	var fields []apperrors.FieldError
+	if req.GetDoctorId() == "" {
+		fields = append(fields, apperrors.FieldError{Field: "doctor_id", Message: "is required"})
+	}
+	date, err := time.Parse("2006-01-02", req.GetDate())
+	if req.GetDate() == "" {
+		fields = append(fields, apperrors.FieldError{Field: "date", Message: "is required"})
+	} else if err != nil {
+		fields = append(fields, apperrors.FieldError{Field: "date", Message: "must be formatted as YYYY-MM-DD"})
+	}
+	if len(fields) > 0 {
+		return nil, statusError(ctx, s.logger, apperrors.Validation("invalid_query", "Invalid availability query", fields...))
+	}
+
+	service, err := s.service()
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+	availability, err := service.GetDoctorAvailability(ctx, req.GetDoctorId(), date, tenantID)
+	if err != nil {
+		return nil, statusError(ctx, s.logger, err)
+	}
+
+	response := &corepb.GetDoctorAvailabilityResponse{}
+	for _, slot := range availability {
+		timeSlot := &corepb.TimeSlot{}
+		if start, ok := slot["start_time"].(time.Time); ok {
+			timeSlot.StartTime = timestamppb.New(start)
+		}
+		if end, ok := slot["end_time"].(time.Time); ok {
+			timeSlot.EndTime = timestamppb.New(end)
+		}
+		timeSlot.Available, _ = slot["is_available"].(bool)
+		response.Slots = append(response.Slots, timeSlot)
+	}
+	return response, nil
+}
+
+// WatchAppointments streams the appointment events of the caller's tenant until the client
+// goes away or falls too far behind
+func (s *receptionServer) WatchAppointments(req *corepb.WatchAppointmentsRequest, stream corepb.ReceptionService_WatchAppointmentsServer) error {
+	ctx := stream.Context()
+	tenantID, _, err := caller(ctx)
+	if err != nil {
+		return statusError(ctx, s.logger, err)
+	}
+	broker, ok := s.registry.Get(registry.AppointmentEvents).(*events.Broker)
+	if !ok {
+		return statusError(ctx, s.logger, errServiceNotRegistered)
+	}
+
+	sub := broker.Subscribe(tenantID)
+	defer sub.Close()
+	metrics.EventStreamConnections.WithLabelValues("grpc").Inc()
+	defer metrics.EventStreamConnections.WithLabelValues("grpc").Dec()
+
+	// Send headers right away so the client knows the watch is established
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "watcher fell behind, list appointments and watch again")
+			}
+			if req.GetDoctorId() != "" && event.Appointment.DoctorID != req.GetDoctorId() {
+				continue
+			}
+			if err := stream.Send(&corepb.AppointmentEvent{
+				Type:        event.Type,
+				Appointment: toAppointment(&event.Appointment),
+				OccurredAt:  timestamppb.New(event.OccurredAt),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toAppointment converts an appointment to its protobuf message
+func toAppointment(appointment *models.AppointmentResponse) *corepb.Appointment {
+	return &corepb.Appointment{
+		AppointmentId:   appointment.AppointmentID,
+		PatientId:       appointment.PatientID,
+		PatientName:     appointment.PatientName,
+		DoctorId:        appointment.DoctorID,
+		DoctorName:      appointment.DoctorName,
+		ScheduledTime:   timestamppb.New(appointment.ScheduledTime),
+		DurationMinutes: int32(appointment.Duration),
+		AppointmentType: string(appointment.Type),
+		Status:          string(appointment.Status),
+		Notes:           appointment.Notes,
+		CreatedAt:       timestamppb.New(appointment.CreatedAt),
+		UpdatedAt:       timestamppb.New(appointment.UpdatedAt),
+		Version:         appointment.Version,
+	}
+}
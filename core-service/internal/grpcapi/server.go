@@ -0,0 +1,226 @@
+// Package grpcapi serves the reception and onboarding services over gRPC, next to the REST
+// API. Both APIs call the same services from the registry and accept the same tokens.
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjay-vashisth/core-service/corepb"
+	"github.com/mrityunjay-vashisth/core-service/internal/authn"
+	"github.com/mrityunjay-vashisth/core-service/internal/common"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDKey is the metadata key carrying the request ID, the gRPC form of X-Request-ID
+const requestIDKey = "x-request-id"
+
+// methodPolicy is the gRPC counterpart of the security declared on REST operations. Calls
+// get the rate limits and request inspection of the REST operation serving the same data.
+type methodPolicy struct {
+	public      bool
+	roles       []string          // Any of these roles grants access
+	operationID string            // REST operation whose limits and inspection apply
+	params      map[string]string // Message fields carrying REST parameters of another name
+}
+
+var (
+	readRoles  = []string{"admin", "receptionist", "doctor"}
+	writeRoles = []string{"admin", "receptionist"}
+)
+
+// methodPolicies lists who may call each method. Methods missing from it are denied.
+var methodPolicies = map[string]methodPolicy{
+	corepb.ReceptionService_ListAppointments_FullMethodName:      {roles: readRoles, operationID: "listAppointments"},
+	corepb.ReceptionService_GetAppointment_FullMethodName:        {roles: readRoles, operationID: "getAppointmentById", params: map[string]string{"id": "appointment_id"}},
+	corepb.ReceptionService_CreateAppointment_FullMethodName:     {roles: writeRoles, operationID: "createAppointment"},
+	corepb.ReceptionService_UpdateAppointment_FullMethodName:     {roles: writeRoles, operationID: "updateAppointment", params: map[string]string{"id": "appointment_id"}},
+	corepb.ReceptionService_CancelAppointment_FullMethodName:     {roles: writeRoles, operationID: "cancelAppointment", params: map[string]string{"id": "appointment_id"}},
+	corepb.ReceptionService_GetDoctorAvailability_FullMethodName: {roles: readRoles, operationID: "getAvailability"},
+	corepb.ReceptionService_WatchAppointments_FullMethodName:     {roles: readRoles},
+
+	corepb.OnboardingService_OnboardTenant_FullMethodName: {public: true, operationID: "onboardTenant"},
+	corepb.OnboardingService_ListTenants_FullMethodName:   {roles: []string{"superuser"}, operationID: "getTenants"},
+	corepb.OnboardingService_GetTenant_FullMethodName:     {roles: []string{"superuser"}, operationID: "getTenantById", params: map[string]string{"id": "request_id"}},
+	corepb.OnboardingService_CheckTenant_FullMethodName:   {public: true, operationID: "checkTenantById", params: map[string]string{"id": "tenant_id"}},
+
+	healthpb.Health_Check_FullMethodName: {public: true},
+	healthpb.Health_Watch_FullMethodName: {public: true},
+}
+
+// NewServer creates the gRPC server of the core API. Every call gets a request ID, is
+// logged, measured and authenticated against methodPolicies, and unary calls are rate
+// limited and inspected like their REST operation before reaching the services.
+func NewServer(serviceRegistry registry.ServiceRegistry, jwtSecret []byte, protection Protection, logger *zap.Logger, opts ...grpc.ServerOption) *grpc.Server {
+	auth := &authenticator{jwtSecret: jwtSecret, logger: logger}
+	options := append([]grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			requestIDUnaryInterceptor(logger),
+			metrics.UnaryServerInterceptor(),
+			recoveryUnaryInterceptor(logger),
+			auth.unary,
+			protection.unary(logger),
+		),
+		grpc.ChainStreamInterceptor(
+			requestIDStreamInterceptor(logger),
+			metrics.StreamServerInterceptor(),
+			recoveryStreamInterceptor(logger),
+			auth.stream,
+		),
+	}, opts...)
+
+	server := grpc.NewServer(options...)
+	corepb.RegisterReceptionServiceServer(server, &receptionServer{registry: serviceRegistry, logger: logger})
+	corepb.RegisterOnboardingServiceServer(server, &onboardingServer{registry: serviceRegistry, logger: logger})
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	return server
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// withRequestID accepts the caller's request ID or generates one, stores it in the context
+// and echoes it in the response headers
+func withRequestID(ctx context.Context) context.Context {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDKey); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" || len(requestID) > 128 {
+		requestID = uuid.NewString()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID))
+	return common.WithRequestID(ctx, requestID)
+}
+
+// logCall logs a finished call with its status code
+func logCall(ctx context.Context, logger *zap.Logger, method string, start time.Time, err error) {
+	logging.WithContext(ctx, logger).Info("gRPC call",
+		zap.String("method", method),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("duration", time.Since(start)))
+}
+
+func requestIDUnaryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx = withRequestID(ctx)
+		resp, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func requestIDStreamInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := withRequestID(stream.Context())
+		err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// recovered turns a panic into an Internal error so one bad call cannot stop the server
+func recovered(ctx context.Context, logger *zap.Logger, method string, err *error) {
+	if r := recover(); r != nil {
+		logging.WithContext(ctx, logger).Error("Panic in gRPC handler",
+			zap.String("method", method),
+			zap.Any("panic", r),
+			zap.ByteString("stack", debug.Stack()))
+		*err = status.Error(codes.Internal, fmt.Sprintf("internal error in %s", method))
+	}
+}
+
+func recoveryUnaryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer recovered(ctx, logger, info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+func recoveryStreamInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recovered(stream.Context(), logger, info.FullMethod, &err)
+		return handler(srv, stream)
+	}
+}
+
+// authenticator checks the bearer token in the "authorization" metadata and the roles
+// allowed to call the method
+type authenticator struct {
+	jwtSecret []byte
+	logger    *zap.Logger
+}
+
+// authorize returns the context carrying the caller's claims, or the context unchanged
+// for public methods
+func (a *authenticator) authorize(ctx context.Context, method string) (context.Context, error) {
+	policy, ok := methodPolicies[method]
+	if !ok {
+		logging.WithContext(ctx, a.logger).Warn("No policy declared for gRPC method, denying", zap.String("method", method))
+		return nil, status.Error(codes.PermissionDenied, "method is not available")
+	}
+	if policy.public {
+		return ctx, nil
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = values[0]
+		}
+	}
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+
+	claims, err := authn.ParseToken(token, a.jwtSecret)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if len(policy.roles) > 0 && !slices.Contains(policy.roles, claims.Role) {
+		return nil, status.Error(codes.PermissionDenied, "insufficient permissions")
+	}
+	return authn.WithClaims(ctx, claims), nil
+}
+
+func (a *authenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authorize(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
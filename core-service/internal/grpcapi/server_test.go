@@ -0,0 +1,160 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mrityunjay-vashisth/core-service/corepb"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/events"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/ratelimit"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/receptionsvc"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var testSecret = []byte("test-secret")
+
+// fakeReception serves a single appointment of tenant-1
+type fakeReception struct {
+	receptionsvc.Service
+}
+
+func (fakeReception) GetAppointmentByID(ctx context.Context, appointmentID string, tenantID string) (*models.AppointmentResponse, error) {
+	if appointmentID != "apt-1" || tenantID != "tenant-1" {
+		return nil, receptionsvc.ErrAppointmentNotFound
+	}
+	return &models.AppointmentResponse{AppointmentID: "apt-1", DoctorID: "doc-1", Duration: 30, Version: 2}, nil
+}
+
+// newTestClient serves the API over an in-memory connection
+func newTestClient(t *testing.T) (corepb.ReceptionServiceClient, *events.Broker) {
+	return newProtectedTestClient(t, Protection{})
+}
+
+// newProtectedTestClient serves the API with rate limits and inspection
+func newProtectedTestClient(t *testing.T, protection Protection) (corepb.ReceptionServiceClient, *events.Broker) {
+	broker := events.NewBroker()
+	serviceRegistry := registry.NewServiceRegistry()
+	serviceRegistry.Register(registry.ReceptionService, fakeReception{})
+	serviceRegistry.Register(registry.AppointmentEvents, broker)
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(serviceRegistry, testSecret, protection, zap.NewNop())
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return corepb.NewReceptionServiceClient(conn), broker
+}
+
+// withToken returns a context sending a token for a user of tenant-1
+func withToken(t *testing.T, role string) context.Context {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, models.UserClaims{
+		Username:         "jane",
+		Role:             role,
+		TenantID:         "tenant-1",
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}).SignedString(testSecret)
+	assert.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuthentication(t *testing.T) {
+	client, _ := newTestClient(t)
+	request := &corepb.GetAppointmentRequest{AppointmentId: "apt-1"}
+
+	_, err := client.GetAppointment(context.Background(), request)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Doctors may read appointments but not book them
+	appointment, err := client.GetAppointment(withToken(t, "doctor"), request)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(30), appointment.GetDurationMinutes())
+	_, err = client.CreateAppointment(withToken(t, "doctor"), &corepb.CreateAppointmentRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.GetAppointment(withToken(t, "superuser"), request)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestDomainErrors(t *testing.T) {
+	client, _ := newTestClient(t)
+
+	// The error code travels as the ErrorInfo reason
+	_, err := client.GetAppointment(withToken(t, "receptionist"), &corepb.GetAppointmentRequest{AppointmentId: "apt-2"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	details := status.Convert(err).Details()
+	if assert.Len(t, details, 1) {
+		assert.Equal(t, "appointment_not_found", details[0].(*errdetails.ErrorInfo).GetReason())
+	}
+
+	// Invalid fields are listed in a BadRequest
+	_, err = client.CreateAppointment(withToken(t, "receptionist"), &corepb.CreateAppointmentRequest{
+		PatientId: "pat-1", PatientName: "John", DoctorName: "Dr. Who",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestWatchAppointments(t *testing.T) {
+	client, broker := newTestClient(t)
+	ctx, cancel := context.WithCancel(withToken(t, "doctor"))
+	defer cancel()
+
+	stream, err := client.WatchAppointments(ctx, &corepb.WatchAppointmentsRequest{DoctorId: "doc-1"})
+	assert.NoError(t, err)
+	// Headers arrive once the server has subscribed
+	_, err = stream.Header()
+	assert.NoError(t, err)
+
+	broker.Publish(events.AppointmentEvent{Type: events.AppointmentCreated, TenantID: "tenant-2",
+		Appointment: models.AppointmentResponse{AppointmentID: "other-tenant", DoctorID: "doc-1"}})
+	broker.Publish(events.AppointmentEvent{Type: events.AppointmentCreated, TenantID: "tenant-1",
+		Appointment: models.AppointmentResponse{AppointmentID: "other-doctor", DoctorID: "doc-2"}})
+	broker.Publish(events.AppointmentEvent{Type: events.AppointmentCancelled, TenantID: "tenant-1",
+		Appointment: models.AppointmentResponse{AppointmentID: "apt-1", DoctorID: "doc-1"}, OccurredAt: time.Now()})
+
+	event, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, events.AppointmentCancelled, event.GetType())
+	assert.Equal(t, "apt-1", event.GetAppointment().GetAppointmentId())
+}
+
+func TestProtection(t *testing.T) {
+	client, _ := newProtectedTestClient(t, Protection{
+		RateLimits: ratelimit.NewMemoryStore(),
+		Security: config.SecurityPolicies{
+			"getAppointmentById": {
+				RateLimits: []config.RateLimitPolicy{{Key: config.RateLimitKeyUser, Requests: 2, Period: time.Minute, Burst: 2}},
+				Inspection: config.InspectionPolicy{Params: []config.ParamRule{{Name: "id", In: "path", Type: config.ParamID}}},
+			},
+		},
+		InspectionMode: config.InspectionBlock,
+	})
+	ctx := withToken(t, "doctor")
+
+	// Fields carrying a REST parameter are checked against its type
+	_, err := client.GetAppointment(ctx, &corepb.GetAppointmentRequest{AppointmentId: "$ne"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// The REST limit of the operation applies, and the rejected call above used a token
+	_, err = client.GetAppointment(ctx, &corepb.GetAppointmentRequest{AppointmentId: "apt-1"})
+	assert.NoError(t, err)
+	_, err = client.GetAppointment(ctx, &corepb.GetAppointmentRequest{AppointmentId: "apt-1"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
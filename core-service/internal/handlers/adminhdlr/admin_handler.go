@@ -1,14 +1,48 @@
 package adminhdlr
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
 	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/auditsvc"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/onboardingsvc"
 	"go.uber.org/zap"
 )
 
+// Page sizes of the audit query API
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// Page sizes of the recovery run list
+const (
+	defaultRecoveryRunLimit = 20
+	maxRecoveryRunLimit     = 100
+)
+
+// recoveryRunsPath is where recovery runs are reported, followed by the run ID
+const recoveryRunsPath = "/apis/core/v1/admin/recovery/runs/"
+
 type AdminHandlerInterface interface {
 	ServeHTTP(w http.ResponseWriter, r *http.Request)
+	ListAuditEntries(w http.ResponseWriter, r *http.Request)
+	VerifyAuditChain(w http.ResponseWriter, r *http.Request)
+	GetRecoveryStatus(w http.ResponseWriter, r *http.Request)
+	RunRecovery(w http.ResponseWriter, r *http.Request)
+	ListRecoveryRuns(w http.ResponseWriter, r *http.Request)
+	GetRecoveryRun(w http.ResponseWriter, r *http.Request)
+	ListDeadLetters(w http.ResponseWriter, r *http.Request)
+	GetDeadLetter(w http.ResponseWriter, r *http.Request)
+	RequeueDeadLetter(w http.ResponseWriter, r *http.Request)
 }
 
 type adminHandler struct {
@@ -25,3 +59,300 @@ func NewAdminHandler(registry registry.ServiceRegistry, logger *zap.Logger) Admi
 
 func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
+
+// getAuditService retrieves the audit service from the registry
+func (h *adminHandler) getAuditService() (auditsvc.Service, error) {
+	service, ok := h.registry.Get(registry.AuditService).(auditsvc.Service)
+	if !ok {
+		h.logger.Error("Failed to get audit service from registry")
+		return nil, apperrors.Internal("service_not_registered", "internal service error", nil)
+	}
+	return service, nil
+}
+
+// getRecovery retrieves the stuck request recovery from the registry
+func (h *adminHandler) getRecovery() (*onboardingsvc.StuckRequestRecovery, error) {
+	recovery, ok := h.registry.Get(registry.OnbardingRecoveryService).(*onboardingsvc.StuckRequestRecovery)
+	if !ok {
+		h.logger.Error("Failed to get stuck request recovery from registry")
+		return nil, apperrors.Internal("service_not_registered", "internal service error", nil)
+	}
+	return recovery, nil
+}
+
+// getOnboardingService retrieves the onboarding service from the registry
+func (h *adminHandler) getOnboardingService() (onboardingsvc.Service, error) {
+	service, ok := h.registry.Get(registry.OnboardingService).(onboardingsvc.Service)
+	if !ok {
+		h.logger.Error("Failed to get onboarding service from registry")
+		return nil, apperrors.Internal("service_not_registered", "internal service error", nil)
+	}
+	return service, nil
+}
+
+// ListAuditEntries returns audit entries filtered by actor, resource and time range.
+// Admins only see entries of their own tenant.
+func (h *adminHandler) ListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	if role, _ := r.Context().Value("role").(string); role != "superuser" {
+		tenantID, _ := r.Context().Value("tenantID").(string)
+		if tenantID == "" {
+			utility.RespondWithProblem(w, r, h.logger, apperrors.Unauthorized("missing_tenant", "tenant ID not found in token"))
+			return
+		}
+		filter.TenantID = tenantID
+	}
+
+	service, err := h.getAuditService()
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	entries, err := service.Query(r.Context(), filter)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Unavailable("audit_unavailable", "audit trail is temporarily unavailable", err))
+		return
+	}
+	utility.RespondWithJSON(w, http.StatusOK, entries)
+}
+
+// VerifyAuditChain checks the hash chain of the whole audit trail
+func (h *adminHandler) VerifyAuditChain(w http.ResponseWriter, r *http.Request) {
+	service, err := h.getAuditService()
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	result, err := service.Verify(r.Context())
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Unavailable("audit_unavailable", "audit trail is temporarily unavailable", err))
+		return
+	}
+	if !result.Valid {
+		h.logger.Error("Audit chain verification failed",
+			zap.Int64("broken_at", result.BrokenAt),
+			zap.String("reason", result.Reason))
+	}
+	utility.RespondWithJSON(w, http.StatusOK, result)
+}
+
+// GetRecoveryStatus shows the stuck request recovery schedule, the replica leading it and
+// its latest runs
+func (h *adminHandler) GetRecoveryStatus(w http.ResponseWriter, r *http.Request) {
+	recovery, err := h.getRecovery()
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	status, err := recovery.Status(r.Context())
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Unavailable("recovery_unavailable", "recovery status is temporarily unavailable", err))
+		return
+	}
+	utility.RespondWithJSON(w, http.StatusOK, status)
+}
+
+// RunRecovery has the recovery leader recover stuck onboarding requests now rather than at
+// the next scheduled run. A run the leader has not finished within the wait is reported
+// with 202 and the location of its report.
+func (h *adminHandler) RunRecovery(w http.ResponseWriter, r *http.Request) {
+	recovery, err := h.getRecovery()
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	username, _ := r.Context().Value("username").(string)
+	logging.WithContext(r.Context(), h.logger).Info("Stuck request recovery triggered", zap.String("username", username))
+
+	run, err := recovery.RunNow(r.Context(), username)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Unavailable("recovery_unavailable", "stuck request recovery could not be requested", err))
+		return
+	}
+	switch run.Status {
+	case onboardingsvc.RunCompleted:
+		utility.RespondWithJSON(w, http.StatusOK, run)
+	case onboardingsvc.RunFailed:
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Unavailable("recovery_failed", "stuck request recovery did not complete", errors.New(run.Error)))
+	default:
+		w.Header().Set("Location", recoveryRunsPath+run.ID)
+		utility.RespondWithJSON(w, http.StatusAccepted, run)
+	}
+}
+
+// ListRecoveryRuns returns the latest recovery runs of all replicas
+func (h *adminHandler) ListRecoveryRuns(w http.ResponseWriter, r *http.Request) {
+	limit := int64(defaultRecoveryRunLimit)
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 || parsed > maxRecoveryRunLimit {
+			utility.RespondWithProblem(w, r, h.logger, apperrors.Validation("invalid_query", "Invalid recovery run query",
+				apperrors.FieldError{Field: "limit", Message: "must be between 1 and " + strconv.Itoa(maxRecoveryRunLimit)}))
+			return
+		}
+		limit = parsed
+	}
+
+	recovery, err := h.getRecovery()
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	runs, err := recovery.Runs(r.Context(), limit)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Unavailable("recovery_unavailable", "recovery runs are temporarily unavailable", err))
+		return
+	}
+	utility.RespondWithJSON(w, http.StatusOK, runs)
+}
+
+// GetRecoveryRun returns a recovery run with what it did to each stuck request
+func (h *adminHandler) GetRecoveryRun(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Validation("missing_id", "Missing recovery run ID"))
+		return
+	}
+
+	recovery, err := h.getRecovery()
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	run, err := recovery.Run(r.Context(), id)
+	if errors.Is(err, onboardingsvc.ErrRecoveryRunNotFound) {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Unavailable("recovery_unavailable", "recovery runs are temporarily unavailable", err))
+		return
+	}
+	utility.RespondWithJSON(w, http.StatusOK, run)
+}
+
+// ListDeadLetters returns the onboarding requests whose approval failed too often to be
+// retried automatically
+func (h *adminHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	service, err := h.getOnboardingService()
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	requests, err := service.ListDeadLetters(r.Context())
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	utility.RespondWithJSON(w, http.StatusOK, requests)
+}
+
+// GetDeadLetter returns a dead-lettered request with the error of each failed approval
+func (h *adminHandler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Validation("missing_id", "Missing request ID"))
+		return
+	}
+
+	service, err := h.getOnboardingService()
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	request, err := service.GetDeadLetter(r.Context(), id)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	utility.RespondWithJSON(w, http.StatusOK, request)
+}
+
+// RequeueDeadLetter returns a dead-lettered request to the automatic retries
+func (h *adminHandler) RequeueDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Validation("missing_id", "Missing request ID"))
+		return
+	}
+
+	service, err := h.getOnboardingService()
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	username, _ := r.Context().Value("username").(string)
+	request, err := service.RequeueDeadLetter(r.Context(), id, username)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	logging.WithContext(r.Context(), h.logger).Info("Dead-lettered onboarding request requeued",
+		zap.String("request_id", id),
+		zap.String("username", username))
+	utility.RespondWithJSON(w, http.StatusOK, request)
+}
+
+// parseAuditFilter reads the audit query parameters, reporting every invalid one
+func parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Actor:        query.Get("actor"),
+		TenantID:     query.Get("tenant_id"),
+		ResourceType: query.Get("resource_type"),
+		ResourceID:   query.Get("resource_id"),
+		Limit:        defaultAuditLimit,
+	}
+
+	var fields []apperrors.FieldError
+	for _, bound := range []struct {
+		name   string
+		target *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			fields = append(fields, apperrors.FieldError{Field: bound.name, Message: "must be an RFC 3339 date-time"})
+			continue
+		}
+		*bound.target = parsed
+	}
+
+	if value := query.Get("after"); value != "" {
+		after, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || after < 0 {
+			fields = append(fields, apperrors.FieldError{Field: "after", Message: "must be a non-negative integer"})
+		}
+		filter.AfterSequence = after
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			fields = append(fields, apperrors.FieldError{Field: "limit", Message: "must be between 1 and " + strconv.Itoa(maxAuditLimit)})
+		}
+		filter.Limit = limit
+	}
+
+	if len(fields) > 0 {
+		return filter, apperrors.Validation("invalid_query", "Invalid audit query", fields...)
+	}
+	return filter, nil
+}
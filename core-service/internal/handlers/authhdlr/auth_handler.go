@@ -2,16 +2,21 @@ package authhdlr
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
 
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
 	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
 	"github.com/mrityunjay-vashisth/core-service/internal/models"
 	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/auditsvc"
 	"github.com/mrityunjay-vashisth/core-service/internal/services/authsvc"
 	"go.uber.org/zap"
 )
 
+// errInvalidBody is returned when the request body is not valid JSON for the operation
+var errInvalidBody = apperrors.Validation("invalid_body", "Invalid request body")
+
 type AuthHandlerInterface interface {
 	Login(w http.ResponseWriter, r *http.Request)
 	Register(w http.ResponseWriter, r *http.Request)
@@ -34,7 +39,7 @@ func (h *authHandler) getAuthService() (authsvc.Service, error) {
 	service, ok := h.registry.Get(registry.AuthService).(authsvc.Service)
 	if !ok {
 		h.logger.Error("Failed to get auth service from registry")
-		return nil, errors.New("internal service error")
+		return nil, apperrors.Internal("service_not_registered", "internal service error", nil)
 	}
 	return service, nil
 }
@@ -46,20 +51,19 @@ func (a *authHandler) Login(w http.ResponseWriter, r *http.Request) {
 		TenantID string `json:"tenantid"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
-		// http.Error(w, "Invalid request body", http.StatusBadRequest)
+		utility.RespondWithProblem(w, r, a.logger, errInvalidBody)
 		return
 	}
 
 	authService, err := a.getAuthService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, a.logger, err)
 		return
 	}
 
 	authResp, err := authService.Login(r.Context(), req.Username, req.Password, req.TenantID)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, a.logger, err)
 		return
 	}
 
@@ -79,29 +83,56 @@ func (a *authHandler) Register(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req models.AuthRegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		utility.RespondWithProblem(w, r, a.logger, errInvalidBody)
 		return
 	}
 
 	// Validate required fields
-	if req.Username == "" || req.Password == "" || req.Email == "" || req.Role == "" {
-		utility.RespondWithError(w, http.StatusBadRequest, "Missing required fields: username, password, email, and role are required")
+	var fields []apperrors.FieldError
+	for _, required := range []struct{ name, value string }{
+		{"username", req.Username},
+		{"password", req.Password},
+		{"email", req.Email},
+		{"role", req.Role},
+	} {
+		if required.value == "" {
+			fields = append(fields, apperrors.FieldError{Field: required.name, Message: "is required"})
+		}
+	}
+	if len(fields) > 0 {
+		utility.RespondWithProblem(w, r, a.logger, apperrors.Validation("missing_fields", "Missing required fields", fields...))
 		return
 	}
 
 	// Get auth service
 	authService, err := a.getAuthService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, a.logger, err)
 		return
 	}
 	authResp, err := authService.Register(r.Context(), req)
 
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, a.logger, err)
 		return
 	}
 
+	// Users live in auth-service, so the registration is audited here
+	if auditService, ok := a.registry.Get(registry.AuditService).(auditsvc.Service); ok {
+		auditService.Record(r.Context(), audit.Event{
+			Operation:    "user.register",
+			ResourceType: audit.ResourceUser,
+			ResourceID:   req.Username + "." + req.TenantId,
+			TenantID:     req.TenantId,
+			After: map[string]string{
+				"username":  req.Username,
+				"email":     req.Email,
+				"role":      req.Role,
+				"tenant_id": req.TenantId,
+			},
+		})
+	}
+
 	// Return success response
 	utility.RespondWithJSON(w, http.StatusCreated, map[string]string{
 		"message": authResp.Message,
@@ -0,0 +1,69 @@
+package healthhdlr
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/healthsvc"
+	"go.uber.org/zap"
+)
+
+type HealthHandlerInterface interface {
+	Liveness(w http.ResponseWriter, r *http.Request)
+	Readiness(w http.ResponseWriter, r *http.Request)
+}
+
+type healthHandler struct {
+	registry registry.ServiceRegistry
+	logger   *zap.Logger
+}
+
+func NewHealthHandler(registry registry.ServiceRegistry, logger *zap.Logger) HealthHandlerInterface {
+	return &healthHandler{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+// Liveness reports whether the process itself is healthy. Dependencies are not checked
+// so an outage elsewhere never causes the orchestrator to restart this service.
+func (h *healthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	service, ok := h.registry.Get(registry.HealthService).(healthsvc.Service)
+	if !ok {
+		h.logger.Error("Failed to get health service from registry")
+		utility.RespondWithError(w, http.StatusServiceUnavailable, "Health service unavailable")
+		return
+	}
+
+	h.respond(w, service.Liveness(r.Context(), isVerbose(r)))
+}
+
+// Readiness reports whether the service can handle traffic, checking all dependencies
+func (h *healthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	service, ok := h.registry.Get(registry.HealthService).(healthsvc.Service)
+	if !ok {
+		h.logger.Error("Failed to get health service from registry")
+		utility.RespondWithError(w, http.StatusServiceUnavailable, "Health service unavailable")
+		return
+	}
+
+	h.respond(w, service.Readiness(r.Context(), isVerbose(r)))
+}
+
+// respond returns 503 when a critical component is down so traffic is routed elsewhere
+func (h *healthHandler) respond(w http.ResponseWriter, report healthsvc.Report) {
+	status := http.StatusOK
+	if report.Status == healthsvc.StatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	utility.RespondWithJSON(w, status, report)
+}
+
+// isVerbose reports whether the caller asked for error messages and component details
+func isVerbose(r *http.Request) bool {
+	verbose, err := strconv.ParseBool(r.URL.Query().Get("verbose"))
+	return err == nil && verbose
+}
@@ -2,14 +2,12 @@ package onboardinghdlr
 
 import (
 	"encoding/json"
-	"errors"
-	"log"
 	"net/http"
-	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
 	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
 	"github.com/mrityunjay-vashisth/core-service/internal/models"
 	"github.com/mrityunjay-vashisth/core-service/internal/registry"
 	"github.com/mrityunjay-vashisth/core-service/internal/services/authsvc"
@@ -17,14 +15,28 @@ import (
 	"go.uber.org/zap"
 )
 
-var jwtKey = []byte("your-secure-jwt-secret-replace-in-production")
+// errInvalidBody is returned when the request body is not valid JSON for the operation
+var errInvalidBody = apperrors.Validation("invalid_body", "Request body is not valid JSON")
+
+// errServiceNotRegistered is returned when a service is missing from the registry
+var errServiceNotRegistered = apperrors.Internal("service_not_registered", "internal service error", nil)
+
+// errMissingID is returned when the path lacks the request or tenant ID
+var errMissingID = apperrors.Validation("missing_id", "Missing tenant ID")
+
+// approvalsPath is where the progress of approvals is reported, followed by the approval ID
+const approvalsPath = "/apis/core/v1/tenants/approvals/"
 
 type OnboardingHandlerInterface interface {
 	OnboardTenant(w http.ResponseWriter, r *http.Request)
 	GetTenants(w http.ResponseWriter, r *http.Request)
 	GetTenantByRequestID(w http.ResponseWriter, r *http.Request)
 	ApproveOnboarding(w http.ResponseWriter, r *http.Request)
+	GetApproval(w http.ResponseWriter, r *http.Request)
+	RejectOnboarding(w http.ResponseWriter, r *http.Request)
 	GetTenantExistsByRequestID(w http.ResponseWriter, r *http.Request)
+	VerifyEmail(w http.ResponseWriter, r *http.Request)
+	ResendVerification(w http.ResponseWriter, r *http.Request)
 }
 
 // OnboardingHandler handles all onboarding-related requests
@@ -45,7 +57,7 @@ func (h *onboardingHandler) getOnboardingService() (onboardingsvc.Service, error
 	service, ok := h.registry.Get(registry.OnboardingService).(onboardingsvc.Service)
 	if !ok {
 		h.logger.Info("Failed to get onboarding service from registry")
-		return nil, errors.New("internal service error")
+		return nil, errServiceNotRegistered
 	}
 	return service, nil
 }
@@ -55,7 +67,7 @@ func (h *onboardingHandler) getAuthService() (authsvc.Service, error) {
 	service, ok := h.registry.Get(registry.AuthService).(authsvc.Service)
 	if !ok {
 		h.logger.Info("Failed to get auth service from registry")
-		return nil, errors.New("internal service error")
+		return nil, errServiceNotRegistered
 	}
 	return service, nil
 }
@@ -64,12 +76,22 @@ func (h *onboardingHandler) getAuthService() (authsvc.Service, error) {
 func (h *onboardingHandler) OnboardTenant(w http.ResponseWriter, r *http.Request) {
 	var req models.OnboardingRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utility.RespondWithProblem(w, r, h.logger, errInvalidBody)
 		return
 	}
 
-	if req.OrganizationName == "" || req.Email == "" || req.Role == "" {
-		utility.RespondWithError(w, http.StatusBadRequest, "invalid request body")
+	var fields []apperrors.FieldError
+	for _, required := range []struct{ name, value string }{
+		{"organization_name", req.OrganizationName},
+		{"email", req.Email},
+		{"role", req.Role},
+	} {
+		if required.value == "" {
+			fields = append(fields, apperrors.FieldError{Field: required.name, Message: "is required"})
+		}
+	}
+	if len(fields) > 0 {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Validation("missing_fields", "Missing required fields", fields...))
 		return
 	}
 
@@ -81,20 +103,25 @@ func (h *onboardingHandler) OnboardTenant(w http.ResponseWriter, r *http.Request
 
 	service, err := h.getOnboardingService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, "Internal service error")
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
-	requestId, err := service.OnboardTenant(r.Context(), req)
+	receipt, err := service.OnboardTenant(r.Context(), req)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
-	h.logger.Info("Onboarding request submitted", zap.String("request_id", requestId))
-	respData := map[string]string{
-		"message":    "Onboarding request submitted, pending approval",
-		"request_id": requestId,
+	h.logger.Info("Onboarding request submitted", zap.String("request_id", receipt.RequestID))
+	respData := map[string]interface{}{
+		"message":                 "Onboarding request submitted, confirm the email address to have it reviewed",
+		"request_id":              receipt.RequestID,
+		"status":                  receipt.Status,
+		"verification_expires_at": receipt.VerificationExpiresAt,
+	}
+	if receipt.WebhookSecret != "" {
+		respData["webhook_secret"] = receipt.WebhookSecret
 	}
 	utility.RespondWithJSON(w, http.StatusOK, respData)
 }
@@ -105,208 +132,221 @@ func (h *onboardingHandler) GetTenants(w http.ResponseWriter, r *http.Request) {
 		utility.RespondWithError(w, http.StatusMethodNotAllowed, "Invalid request method")
 		return
 	}
-	token := r.Header.Get("Authorization")
-	if token == "" {
-		utility.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-	if !validateServiceToken(w, token) {
-		utility.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
 
 	service, err := h.getOnboardingService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, "Internal service error")
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
-	status := r.URL.Query().Get("state")
-	requests, err := service.GetTenants(r.Context(), status)
+	// Stream the list straight from the cursor, as a JSON array or NDJSON
+	stream := utility.NewJSONStream(w, r, http.StatusOK)
+	err = service.StreamTenants(r.Context(), r.URL.Query().Get("state"), func(tenant map[string]interface{}) error {
+		return stream.Write(tenant)
+	})
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		if !stream.Started() {
+			utility.RespondWithProblem(w, r, h.logger, err)
+			return
+		}
+		logging.WithContext(r.Context(), h.logger).Error("Tenant stream ended early", zap.Error(err))
 		return
 	}
-	if requests == nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, "No pending request for approval")
-		return
-	}
-	utility.RespondWithJSON(w, http.StatusOK, requests)
+	stream.Close()
 }
 
 func (h *onboardingHandler) GetTenantByRequestID(w http.ResponseWriter, r *http.Request) {
-	token := r.Header.Get("Authorization")
-	if token == "" {
-		h.logger.Info("Empty token received")
-		return
-	}
-	if !validateServiceToken(w, token) {
-		h.logger.Info("Token validation failed")
-		return
-	}
-
 	vars := mux.Vars(r)
 	id := vars["id"]
 
 	// Validate the ID parameter
 	if id == "" {
-		utility.RespondWithError(w, http.StatusBadRequest, "Missing tenant ID")
+		utility.RespondWithProblem(w, r, h.logger, errMissingID)
 		return
 	}
 
 	service, err := h.getOnboardingService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, "Internal service error")
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
 	requests, err := service.GetTenantByID(r.Context(), id)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 	json.NewEncoder(w).Encode(requests)
 }
 
-// ApproveOnboarding approves onboarding requests
+// ApproveOnboarding starts approving an onboarding request. The approval runs in the
+// background, so the response points at where its progress can be followed.
 func (h *onboardingHandler) ApproveOnboarding(w http.ResponseWriter, r *http.Request) {
-	token := r.Header.Get("Authorization")
-	if token == "" {
-		utility.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-	if !validateServiceToken(w, token) {
-		utility.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
 	var req struct {
 		RequestID string `json:"request_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		utility.RespondWithProblem(w, r, h.logger, errInvalidBody)
 		return
 	}
 	if req.RequestID == "" {
-		utility.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Validation("missing_fields", "Missing required fields",
+			apperrors.FieldError{Field: "request_id", Message: "is required"}))
 		return
 	}
 	onboardingService, err := h.getOnboardingService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, "Internal service error")
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
-	// Begin the approval process - changes status to "approval_in_progress"
-	tenantData, err := onboardingService.BeginApproval(r.Context(), req.RequestID)
+	approval, err := onboardingService.StartApproval(r.Context(), req.RequestID)
 	if err != nil {
-		h.logger.Info("Failed to begin approval process",
+		h.logger.Info("Failed to start approval",
 			zap.Error(err),
 			zap.String("request_id", req.RequestID))
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
-	// Extract required fields for registration
-	email, _ := tenantData["email"].(string)
-	username, _ := tenantData["username"].(string)
-	role, _ := tenantData["role"].(string)
-	organizationName, _ := tenantData["organization_name"].(string)
-	tenantID, _ := tenantData["tenant_id"].(string)
-
-	if email == "" || username == "" || role == "" || tenantID == "" {
-		h.logger.Info("Missing required tenant data for registration",
-			zap.String("email", email),
-			zap.String("username", username),
-			zap.String("role", role),
-			zap.String("tenantID", tenantID))
-		// Mark approval as failed
-		onboardingService.MarkApprovalFailed(r.Context(), req.RequestID,
-			"Missing required tenant data for registration")
-
-		utility.RespondWithError(w, http.StatusInternalServerError,
-			"Tenant data missing required fields")
+	statusURL := approvalsPath + approval.ID
+	logging.WithContext(r.Context(), h.logger).Info("Onboarding approval started",
+		zap.String("request_id", req.RequestID),
+		zap.String("approval_id", approval.ID))
+	w.Header().Set("Location", statusURL)
+	utility.RespondWithJSON(w, http.StatusAccepted, map[string]string{
+		"message":     "Onboarding approval started",
+		"request_id":  req.RequestID,
+		"approval_id": approval.ID,
+		"status":      approval.Status,
+		"status_url":  statusURL,
+	})
+}
+
+// GetApproval reports the progress of an approval started by ApproveOnboarding
+func (h *onboardingHandler) GetApproval(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Validation("missing_id", "Missing approval ID"))
 		return
 	}
 
-	// Create registration request
-	regRequest := models.AuthRegisterRequest{
-		Username: username,
-		Email:    email,
-		Name:     organizationName,
-		Role:     role,
-		TenantId: tenantID,
+	service, err := h.getOnboardingService()
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
 	}
 
-	// Get auth service
-	authService, err := h.getAuthService()
+	approval, err := service.GetApproval(r.Context(), id)
 	if err != nil {
-		h.logger.Info("Failed to get auth service", zap.Error(err))
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	utility.RespondWithJSON(w, http.StatusOK, approval)
+}
 
-		// Mark approval as failed
-		onboardingService.MarkApprovalFailed(r.Context(), req.RequestID,
-			"Internal service error: "+err.Error())
+// RejectOnboarding rejects an onboarding request on behalf of the signed-in reviewer
+func (h *onboardingHandler) RejectOnboarding(w http.ResponseWriter, r *http.Request) {
+	var req models.OnboardingRejection
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utility.RespondWithProblem(w, r, h.logger, errInvalidBody)
+		return
+	}
 
-		utility.RespondWithError(w, http.StatusInternalServerError,
-			"Internal service error")
+	var fields []apperrors.FieldError
+	for _, required := range []struct{ name, value string }{
+		{"request_id", req.RequestID},
+		{"reason_code", string(req.ReasonCode)},
+	} {
+		if required.value == "" {
+			fields = append(fields, apperrors.FieldError{Field: required.name, Message: "is required"})
+		}
+	}
+	if len(fields) > 0 {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Validation("missing_fields", "Missing required fields", fields...))
 		return
 	}
 
-	// Register user
-	_, err = authService.Register(r.Context(), regRequest)
+	service, err := h.getOnboardingService()
 	if err != nil {
-		h.logger.Info("Failed to register user",
-			zap.Error(err),
-			zap.String("request_id", req.RequestID),
-			zap.String("email", email))
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	reviewer, _ := r.Context().Value("username").(string)
+	request, err := service.RejectRequest(r.Context(), req, reviewer)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
 
-		// Mark approval as failed
-		onboardingService.MarkApprovalFailed(r.Context(), req.RequestID,
-			"User registration failed: "+err.Error())
+	logging.WithContext(r.Context(), h.logger).Info("Onboarding request rejected",
+		zap.String("request_id", req.RequestID),
+		zap.String("reason_code", string(req.ReasonCode)),
+		zap.String("reviewer", reviewer))
+	utility.RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":       "Onboarding request rejected, the applicant will be notified",
+		"request_id":    req.RequestID,
+		"status":        models.OnboardingStatusRejected,
+		"reapply_after": request["reapply_after"],
+	})
+}
 
-		utility.RespondWithError(w, http.StatusInternalServerError,
-			"Failed to register user: "+err.Error())
+// VerifyEmail confirms the email of an onboarding request with the token emailed to it
+func (h *onboardingHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.EmailVerification
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utility.RespondWithProblem(w, r, h.logger, errInvalidBody)
+		return
+	}
+	if req.Token == "" {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Validation("missing_fields", "Missing required fields",
+			apperrors.FieldError{Field: "token", Message: "is required"}))
 		return
 	}
 
-	// Mark user as created - changes status to "user_created"
-	err = onboardingService.MarkUserCreated(r.Context(), req.RequestID)
+	service, err := h.getOnboardingService()
 	if err != nil {
-		h.logger.Info("Failed to mark user as created",
-			zap.Error(err),
-			zap.String("request_id", req.RequestID))
-
-		// This is unusual - user was created but we couldn't update our status
-		// We'll continue with approval anyway, and the recovery system will fix it if needed
-		h.logger.Warn("Continuing with approval despite status update failure",
-			zap.String("request_id", req.RequestID))
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
 	}
 
-	// Complete the approval process
-	err = onboardingService.CompleteApproval(r.Context(), req.RequestID)
+	result, err := service.VerifyEmail(r.Context(), req.Token)
 	if err != nil {
-		h.logger.Info("Failed to complete approval",
-			zap.Error(err),
-			zap.String("request_id", req.RequestID))
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	logging.WithContext(r.Context(), h.logger).Info("Onboarding request email verified",
+		zap.String("request_id", result.RequestID),
+		zap.String("status", string(result.Status)))
+	utility.RespondWithJSON(w, http.StatusOK, result)
+}
 
-		// This is a serious issue - the user was created but we couldn't complete approval
-		// The recovery system will pick this up later
-		h.logger.Info("CRITICAL: User created but approval not completed. "+
-			"Recovery system will attempt to resolve.",
-			zap.String("request_id", req.RequestID),
-			zap.String("email", email))
+// ResendVerification emails the verification link of an unverified request again
+func (h *onboardingHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req models.VerificationResend
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utility.RespondWithProblem(w, r, h.logger, errInvalidBody)
+		return
+	}
+
+	service, err := h.getOnboardingService()
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
 
-		utility.RespondWithError(w, http.StatusInternalServerError,
-			"User created but approval process incomplete - "+
-				"your account will be activated soon")
+	if err := service.ResendVerification(r.Context(), req); err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
-	// Success response
-	utility.RespondWithJSON(w, http.StatusOK, map[string]string{
-		"message":   "Onboarding request approved and user registered",
-		"email":     email,
-		"username":  username,
-		"tenant_id": tenantID,
+	logging.WithContext(r.Context(), h.logger).Info("Verification email resent",
+		zap.String("request_id", req.RequestID))
+	utility.RespondWithJSON(w, http.StatusAccepted, map[string]string{
+		"message":    "A new verification email is on its way",
+		"request_id": req.RequestID,
 	})
 }
 
@@ -316,52 +356,20 @@ func (h *onboardingHandler) GetTenantExistsByRequestID(w http.ResponseWriter, r
 
 	// Validate the ID parameter
 	if id == "" {
-		utility.RespondWithError(w, http.StatusBadRequest, "Missing tenant ID")
+		utility.RespondWithProblem(w, r, h.logger, errMissingID)
 		return
 	}
 
 	service, err := h.getOnboardingService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, "Internal service error")
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
 	exist, err := service.GetTenantCheckByID(r.Context(), id)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 	json.NewEncoder(w).Encode(exist)
 }
-
-func validateServiceToken(w http.ResponseWriter, tokenString string) bool {
-	ownerClaims, err := validateToken(tokenString)
-	if err != nil {
-		log.Println(err.Error())
-		utility.RespondWithError(w, http.StatusUnauthorized, "Invalid owner token")
-		return false
-	}
-	log.Printf("%s", ownerClaims.Role)
-	if ownerClaims.Role != "superuser" {
-		utility.RespondWithError(w, http.StatusForbidden, "Unauthorized: Only superusers can view/approve")
-		return false
-	}
-	return true
-}
-
-func validateToken(tokenString string) (*models.UserClaims, error) {
-	claims := &models.UserClaims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-
-	if err != nil || !token.Valid {
-		log.Println(err.Error())
-		return nil, errors.New("invalid token")
-	}
-	if time.Now().After(claims.ExpiresAt.Time) {
-		return nil, errors.New("token expired")
-	}
-
-	return claims, nil
-}
@@ -0,0 +1,363 @@
+package receptionhdlr
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/authn"
+	"github.com/mrityunjay-vashisth/core-service/internal/events"
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"go.uber.org/zap"
+)
+
+// resetEvent tells a client that events were missed and it should list appointments again
+const resetEvent = "reset"
+
+// sseRetry is how long browsers wait before reconnecting a dropped event stream
+const sseRetry = 3 * time.Second
+
+// AppointmentEventsHandlerInterface streams appointment changes to reception and doctor screens
+type AppointmentEventsHandlerInterface interface {
+	StreamEvents(w http.ResponseWriter, r *http.Request)
+	StreamEventsWebSocket(w http.ResponseWriter, r *http.Request)
+}
+
+type appointmentEventsHandler struct {
+	registry  registry.ServiceRegistry
+	heartbeat time.Duration
+	logger    *zap.Logger
+	upgrader  websocket.Upgrader
+}
+
+// NewAppointmentEventsHandler creates the handler of the appointment event streams. An idle
+// stream gets a heartbeat every heartbeat interval so proxies keep it open.
+func NewAppointmentEventsHandler(registry registry.ServiceRegistry, heartbeat time.Duration, logger *zap.Logger) AppointmentEventsHandlerInterface {
+	return &appointmentEventsHandler{
+		registry:  registry,
+		heartbeat: heartbeat,
+		logger:    logger,
+		upgrader: websocket.Upgrader{
+			// Callers authenticate with a token rather than a cookie, so a page of another
+			// origin gains nothing by opening the socket
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// eventFilter selects the events a client asked for
+type eventFilter struct {
+	DoctorID string `json:"doctor_id,omitempty"`
+	DateFrom string `json:"date_from,omitempty"`
+	DateTo   string `json:"date_to,omitempty"`
+
+	from, to time.Time // Parsed dates, to is exclusive
+}
+
+// parse validates the dates of the filter
+func (f *eventFilter) parse() error {
+	var fields []apperrors.FieldError
+	for _, date := range []struct {
+		name  string
+		value string
+		into  *time.Time
+	}{
+		{"date_from", f.DateFrom, &f.from},
+		{"date_to", f.DateTo, &f.to},
+	} {
+		if date.value == "" {
+			*date.into = time.Time{}
+			continue
+		}
+		parsed, err := time.Parse("2006-01-02", date.value)
+		if err != nil {
+			fields = append(fields, apperrors.FieldError{Field: date.name, Message: "must be formatted as YYYY-MM-DD"})
+			continue
+		}
+		*date.into = parsed
+	}
+	if len(fields) > 0 {
+		return apperrors.Validation("invalid_query", "Invalid event filter", fields...)
+	}
+	if !f.to.IsZero() {
+		f.to = f.to.AddDate(0, 0, 1) // Include the whole day, like listAppointments
+	}
+	return nil
+}
+
+// matches reports whether an event passes the filter
+func (f *eventFilter) matches(event events.AppointmentEvent) bool {
+	if f.DoctorID != "" && event.Appointment.DoctorID != f.DoctorID {
+		return false
+	}
+	scheduled := event.Appointment.ScheduledTime
+	if !f.from.IsZero() && scheduled.Before(f.from) {
+		return false
+	}
+	if !f.to.IsZero() && !scheduled.Before(f.to) {
+		return false
+	}
+	return true
+}
+
+// subscription is an open event stream of the caller's tenant
+type subscription struct {
+	*events.Subscription
+	missed    []events.AppointmentEvent // Events to replay before live ones
+	reset     bool                      // Events were missed and cannot be replayed
+	expiresAt <-chan time.Time          // Fires when the caller's token expires
+	stop      func()
+}
+
+// subscribe validates the request and subscribes to the caller's tenant, resuming after
+// the Last-Event-ID header or last_event_id query parameter when present
+func (h *appointmentEventsHandler) subscribe(r *http.Request) (*subscription, *eventFilter, error) {
+	claims, ok := authn.ClaimsFromContext(r.Context())
+	if !ok || claims.TenantID == "" {
+		return nil, nil, apperrors.Unauthorized("missing_tenant", "tenant ID not found in token")
+	}
+
+	query := r.URL.Query()
+	filter := &eventFilter{DoctorID: query.Get("doctor_id"), DateFrom: query.Get("date_from"), DateTo: query.Get("date_to")}
+	if err := filter.parse(); err != nil {
+		return nil, nil, err
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("last_event_id")
+	}
+	var resumeFrom uint64
+	if lastEventID != "" {
+		parsed, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return nil, nil, apperrors.Validation("invalid_last_event_id", "Last-Event-ID must be an event ID",
+				apperrors.FieldError{Field: "Last-Event-ID", Message: "must be an event ID"})
+		}
+		resumeFrom = parsed
+	}
+
+	broker, ok := h.registry.Get(registry.AppointmentEvents).(*events.Broker)
+	if !ok {
+		h.logger.Error("Failed to get appointment event broker from registry")
+		return nil, nil, apperrors.Internal("service_not_registered", "internal service error", nil)
+	}
+
+	sub := &subscription{}
+	if lastEventID == "" {
+		sub.Subscription = broker.Subscribe(claims.TenantID)
+	} else {
+		var complete bool
+		sub.Subscription, sub.missed, complete = broker.SubscribeSince(claims.TenantID, resumeFrom)
+		sub.reset = !complete
+	}
+
+	// The connection lives no longer than the token that opened it
+	timer := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+	sub.expiresAt = timer.C
+	sub.stop = func() {
+		timer.Stop()
+		sub.Close()
+	}
+	return sub, filter, nil
+}
+
+// StreamEvents streams appointment events as Server-Sent Events
+func (h *appointmentEventsHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	sub, filter, err := h.subscribe(r)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	defer sub.stop()
+	metrics.EventStreamConnections.WithLabelValues("sse").Inc()
+	defer metrics.EventStreamConnections.WithLabelValues("sse").Dec()
+
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+
+	send := func(event events.AppointmentEvent) error {
+		if !filter.matches(event) {
+			return nil
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		return err
+	}
+
+	if sub.reset {
+		fmt.Fprintf(w, "id: %d\nevent: %s\ndata: {}\n\n", sub.StartID(), resetEvent)
+	}
+	for _, event := range sub.missed {
+		if err := send(event); err != nil {
+			return
+		}
+	}
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.expiresAt:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				// The client fell behind; it reconnects with Last-Event-ID and catches up
+				logging.WithContext(r.Context(), h.logger).Warn("Dropped slow appointment event stream")
+				return
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// socketMessage is a message on the appointment event WebSocket. Clients send "filter"
+// messages to change the filter; the server sends events, "reset" and "error" messages.
+type socketMessage struct {
+	Type    string                 `json:"type"`
+	ID      uint64                 `json:"id,omitempty"` // Of a reset, the ID to resume from
+	Message string                 `json:"message,omitempty"`
+	Errors  []apperrors.FieldError `json:"errors,omitempty"`
+	eventFilter
+}
+
+// StreamEventsWebSocket streams appointment events over a WebSocket. Clients may change
+// the filter at any time by sending a filter message.
+func (h *appointmentEventsHandler) StreamEventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	sub, filter, err := h.subscribe(r)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	defer sub.stop()
+
+	conn, err := h.upgrader.Upgrade(hijackableWriter{w}, r, nil)
+	if err != nil {
+		// The upgrader already answered the client
+		return
+	}
+	defer conn.Close()
+	metrics.EventStreamConnections.WithLabelValues("websocket").Inc()
+	defer metrics.EventStreamConnections.WithLabelValues("websocket").Dec()
+
+	// A client that stops answering pings is disconnected
+	readTimeout := 2 * h.heartbeat
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+
+	// Reads run on their own goroutine; filters are handed to the writing loop
+	filters := make(chan eventFilter)
+	closed := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(closed)
+		for {
+			var message socketMessage
+			if err := conn.ReadJSON(&message); err != nil {
+				return
+			}
+			if message.Type != "filter" {
+				continue
+			}
+			select {
+			case filters <- message.eventFilter:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	send := func(event events.AppointmentEvent) error {
+		if !filter.matches(event) {
+			return nil
+		}
+		conn.SetWriteDeadline(time.Now().Add(h.heartbeat))
+		return conn.WriteJSON(event)
+	}
+
+	if sub.reset {
+		conn.WriteJSON(socketMessage{Type: resetEvent, ID: sub.StartID()})
+	}
+	for _, event := range sub.missed {
+		if err := send(event); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-sub.expiresAt:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "token expired"), time.Now().Add(time.Second))
+			return
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.heartbeat)); err != nil {
+				return
+			}
+		case next := <-filters:
+			if err := next.parse(); err != nil {
+				invalid, _ := apperrors.As(err)
+				conn.WriteJSON(socketMessage{Type: "error", Message: invalid.Message, Errors: invalid.Fields})
+				continue
+			}
+			*filter = next
+		case event, ok := <-sub.Events():
+			if !ok {
+				logging.WithContext(r.Context(), h.logger).Warn("Dropped slow appointment event stream")
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(time.Second))
+				return
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// hijackableWriter lets the WebSocket upgrader take over the connection through the
+// middleware response writers, which only expose it via http.ResponseController
+type hijackableWriter struct {
+	http.ResponseWriter
+}
+
+func (w hijackableWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
@@ -2,18 +2,26 @@ package receptionhdlr
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
 	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
 	"github.com/mrityunjay-vashisth/core-service/internal/models"
 	"github.com/mrityunjay-vashisth/core-service/internal/registry"
 	"github.com/mrityunjay-vashisth/core-service/internal/services/receptionsvc"
 	"go.uber.org/zap"
 )
 
+// errInvalidBody is returned when the request body is not valid JSON for the operation
+var errInvalidBody = apperrors.Validation("invalid_body", "Request body is not valid JSON")
+
+// errMissingAppointmentID is returned when the path lacks the appointment ID
+var errMissingAppointmentID = apperrors.Validation("missing_appointment_id", "Missing appointment ID")
+
 type ReceptionHandlerInterface interface {
 	ListAppointments(w http.ResponseWriter, r *http.Request)
 	CreateAppointment(w http.ResponseWriter, r *http.Request)
@@ -40,7 +48,7 @@ func (h *receptionHandler) getReceptionService() (receptionsvc.Service, error) {
 	service, ok := h.registry.Get(registry.ReceptionService).(receptionsvc.Service)
 	if !ok {
 		h.logger.Error("Failed to get reception service from registry")
-		return nil, errors.New("internal service error")
+		return nil, apperrors.Internal("service_not_registered", "internal service error", nil)
 	}
 	return service, nil
 }
@@ -49,7 +57,7 @@ func (h *receptionHandler) getReceptionService() (receptionsvc.Service, error) {
 func (h *receptionHandler) extractTenantIDFromContext(r *http.Request) (string, error) {
 	tenantID, ok := r.Context().Value("tenantID").(string)
 	if !ok || tenantID == "" {
-		return "", errors.New("tenant ID not found in context")
+		return "", apperrors.Unauthorized("missing_tenant", "tenant ID not found in token")
 	}
 	return tenantID, nil
 }
@@ -58,24 +66,57 @@ func (h *receptionHandler) extractTenantIDFromContext(r *http.Request) (string,
 func (h *receptionHandler) extractUsernameFromContext(r *http.Request) (string, error) {
 	username, ok := r.Context().Value("username").(string)
 	if !ok || username == "" {
-		return "", errors.New("username not found in context")
+		return "", apperrors.Unauthorized("missing_username", "username not found in token")
 	}
 	return username, nil
 }
 
+// appointmentETag derives a strong entity tag from the appointment version
+func appointmentETag(appointment *models.AppointmentResponse) string {
+	return `"` + appointment.AppointmentID + "-" + strconv.FormatInt(appointment.Version, 10) + `"`
+}
+
+// setAppointmentValidators sets the headers clients use for conditional requests
+func setAppointmentValidators(w http.ResponseWriter, appointment *models.AppointmentResponse) {
+	w.Header().Set("ETag", appointmentETag(appointment))
+	w.Header().Set("Last-Modified", appointment.UpdatedAt.UTC().Format(http.TimeFormat))
+}
+
+// checkPreconditions evaluates If-Match and If-Unmodified-Since against the stored appointment.
+// It returns the version the change must apply to, nil when the request is unconditional, or
+// false after responding when the request cannot proceed.
+func (h *receptionHandler) checkPreconditions(w http.ResponseWriter, r *http.Request, service receptionsvc.Service, appointmentID, tenantID string) (*int64, bool) {
+	if !utility.HasPreconditions(r) {
+		return nil, true
+	}
+
+	current, err := service.GetAppointmentByID(r.Context(), appointmentID, tenantID)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return nil, false
+	}
+
+	if !utility.PreconditionsMet(r, appointmentETag(current), current.UpdatedAt) {
+		setAppointmentValidators(w, current)
+		utility.RespondWithProblem(w, r, h.logger, receptionsvc.ErrVersionMismatch)
+		return nil, false
+	}
+	return &current.Version, true
+}
+
 // ListAppointments handles requests to list appointments with optional filtering
 func (h *receptionHandler) ListAppointments(w http.ResponseWriter, r *http.Request) {
 	// Extract tenant ID from context
 	tenantID, err := h.extractTenantIDFromContext(r)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
 	// Get reception service
 	service, err := h.getReceptionService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
@@ -104,15 +145,20 @@ func (h *receptionHandler) ListAppointments(w http.ResponseWriter, r *http.Reque
 		filters["status"] = status
 	}
 
-	// Call service to list appointments
-	appointments, err := service.ListAppointments(r.Context(), filters, tenantID)
+	// Stream appointments straight from the cursor, as a JSON array or NDJSON
+	stream := utility.NewJSONStream(w, r, http.StatusOK)
+	err = service.StreamAppointments(r.Context(), filters, tenantID, func(appointment models.AppointmentResponse) error {
+		return stream.Write(appointment)
+	})
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, "Failed to list appointments: "+err.Error())
+		if !stream.Started() {
+			utility.RespondWithProblem(w, r, h.logger, err)
+			return
+		}
+		logging.WithContext(r.Context(), h.logger).Error("Appointment stream ended early", zap.Error(err))
 		return
 	}
-
-	// Respond with appointments
-	utility.RespondWithJSON(w, http.StatusOK, appointments)
+	stream.Close()
 }
 
 // CreateAppointment handles requests to create a new appointment
@@ -120,45 +166,41 @@ func (h *receptionHandler) CreateAppointment(w http.ResponseWriter, r *http.Requ
 	// Extract tenant ID from context
 	tenantID, err := h.extractTenantIDFromContext(r)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
 	// Extract username (creator) from context
 	username, err := h.extractUsernameFromContext(r)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
 	// Parse request body
 	var req models.AppointmentCreateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		utility.RespondWithProblem(w, r, h.logger, errInvalidBody)
 		return
 	}
 
 	// Validate required fields
-	if req.PatientID == "" || req.PatientName == "" || req.DoctorID == "" || req.DoctorName == "" || req.Duration <= 0 {
-		utility.RespondWithError(w, http.StatusBadRequest, "Missing required fields")
+	if err := receptionsvc.ValidateCreateRequest(req); err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
 	// Get reception service
 	service, err := h.getReceptionService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
 	// Call service to create appointment
 	appointment, err := service.CreateAppointment(r.Context(), req, tenantID, username)
 	if err != nil {
-		if err.Error() == "doctor is not available at the requested time" {
-			utility.RespondWithError(w, http.StatusConflict, err.Error())
-		} else {
-			utility.RespondWithError(w, http.StatusInternalServerError, "Failed to create appointment: "+err.Error())
-		}
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
@@ -171,7 +213,7 @@ func (h *receptionHandler) GetAppointmentByID(w http.ResponseWriter, r *http.Req
 	// Extract tenant ID from context
 	tenantID, err := h.extractTenantIDFromContext(r)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
@@ -179,29 +221,26 @@ func (h *receptionHandler) GetAppointmentByID(w http.ResponseWriter, r *http.Req
 	vars := mux.Vars(r)
 	appointmentID := vars["id"]
 	if appointmentID == "" {
-		utility.RespondWithError(w, http.StatusBadRequest, "Missing appointment ID")
+		utility.RespondWithProblem(w, r, h.logger, errMissingAppointmentID)
 		return
 	}
 
 	// Get reception service
 	service, err := h.getReceptionService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
 	// Call service to get appointment
 	appointment, err := service.GetAppointmentByID(r.Context(), appointmentID, tenantID)
 	if err != nil {
-		if err.Error() == "appointment not found" {
-			utility.RespondWithError(w, http.StatusNotFound, err.Error())
-		} else {
-			utility.RespondWithError(w, http.StatusInternalServerError, "Failed to get appointment: "+err.Error())
-		}
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
 	// Respond with appointment
+	setAppointmentValidators(w, appointment)
 	utility.RespondWithJSON(w, http.StatusOK, appointment)
 }
 
@@ -210,7 +249,7 @@ func (h *receptionHandler) UpdateAppointment(w http.ResponseWriter, r *http.Requ
 	// Extract tenant ID from context
 	tenantID, err := h.extractTenantIDFromContext(r)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
@@ -218,38 +257,40 @@ func (h *receptionHandler) UpdateAppointment(w http.ResponseWriter, r *http.Requ
 	vars := mux.Vars(r)
 	appointmentID := vars["id"]
 	if appointmentID == "" {
-		utility.RespondWithError(w, http.StatusBadRequest, "Missing appointment ID")
+		utility.RespondWithProblem(w, r, h.logger, errMissingAppointmentID)
 		return
 	}
 
 	// Parse request body
 	var req models.AppointmentUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		utility.RespondWithProblem(w, r, h.logger, errInvalidBody)
 		return
 	}
 
 	// Get reception service
 	service, err := h.getReceptionService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
+	// Enforce If-Match / If-Unmodified-Since
+	expectedVersion, ok := h.checkPreconditions(w, r, service, appointmentID, tenantID)
+	if !ok {
+		return
+	}
+	req.ExpectedVersion = expectedVersion
+
 	// Call service to update appointment
 	appointment, err := service.UpdateAppointment(r.Context(), appointmentID, req, tenantID)
 	if err != nil {
-		if err.Error() == "appointment not found" {
-			utility.RespondWithError(w, http.StatusNotFound, err.Error())
-		} else if err.Error() == "doctor is not available at the requested time" {
-			utility.RespondWithError(w, http.StatusConflict, err.Error())
-		} else {
-			utility.RespondWithError(w, http.StatusInternalServerError, "Failed to update appointment: "+err.Error())
-		}
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
 	// Respond with updated appointment
+	setAppointmentValidators(w, appointment)
 	utility.RespondWithJSON(w, http.StatusOK, appointment)
 }
 
@@ -258,7 +299,7 @@ func (h *receptionHandler) CancelAppointment(w http.ResponseWriter, r *http.Requ
 	// Extract tenant ID from context
 	tenantID, err := h.extractTenantIDFromContext(r)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
@@ -266,43 +307,46 @@ func (h *receptionHandler) CancelAppointment(w http.ResponseWriter, r *http.Requ
 	vars := mux.Vars(r)
 	appointmentID := vars["id"]
 	if appointmentID == "" {
-		utility.RespondWithError(w, http.StatusBadRequest, "Missing appointment ID")
+		utility.RespondWithProblem(w, r, h.logger, errMissingAppointmentID)
 		return
 	}
 
 	// Parse request body to get cancellation reason
-	var req struct {
-		Reason string `json:"reason"`
-	}
+	var req models.AppointmentCancelRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		utility.RespondWithProblem(w, r, h.logger, errInvalidBody)
 		return
 	}
 
 	if req.Reason == "" {
-		utility.RespondWithError(w, http.StatusBadRequest, "Cancellation reason is required")
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Validation("missing_fields", "Cancellation reason is required",
+			apperrors.FieldError{Field: "reason", Message: "is required"}))
 		return
 	}
 
 	// Get reception service
 	service, err := h.getReceptionService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	// Enforce If-Match / If-Unmodified-Since
+	expectedVersion, ok := h.checkPreconditions(w, r, service, appointmentID, tenantID)
+	if !ok {
 		return
 	}
+	req.ExpectedVersion = expectedVersion
 
 	// Call service to cancel appointment
-	appointment, err := service.CancelAppointment(r.Context(), appointmentID, req.Reason, tenantID)
+	appointment, err := service.CancelAppointment(r.Context(), appointmentID, req, tenantID)
 	if err != nil {
-		if err.Error() == "appointment not found" {
-			utility.RespondWithError(w, http.StatusNotFound, err.Error())
-		} else {
-			utility.RespondWithError(w, http.StatusInternalServerError, "Failed to cancel appointment: "+err.Error())
-		}
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
 	// Respond with cancelled appointment
+	setAppointmentValidators(w, appointment)
 	utility.RespondWithJSON(w, http.StatusOK, appointment)
 }
 
@@ -311,7 +355,7 @@ func (h *receptionHandler) GetDoctorAvailability(w http.ResponseWriter, r *http.
 	// Extract tenant ID from context
 	tenantID, err := h.extractTenantIDFromContext(r)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusUnauthorized, "Unauthorized: "+err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
@@ -320,34 +364,35 @@ func (h *receptionHandler) GetDoctorAvailability(w http.ResponseWriter, r *http.
 	doctorID := query.Get("doctor_id")
 	dateStr := query.Get("date")
 
+	var fields []apperrors.FieldError
 	if doctorID == "" {
-		utility.RespondWithError(w, http.StatusBadRequest, "Doctor ID is required")
-		return
+		fields = append(fields, apperrors.FieldError{Field: "doctor_id", Message: "is required"})
 	}
 
+	// Parse date
+	date, err := time.Parse("2006-01-02", dateStr)
 	if dateStr == "" {
-		utility.RespondWithError(w, http.StatusBadRequest, "Date is required")
-		return
+		fields = append(fields, apperrors.FieldError{Field: "date", Message: "is required"})
+	} else if err != nil {
+		fields = append(fields, apperrors.FieldError{Field: "date", Message: "must be formatted as YYYY-MM-DD"})
 	}
 
-	// Parse date
-	date, err := time.Parse("2006-01-02", dateStr)
-	if err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, "Invalid date format. Expected YYYY-MM-DD")
+	if len(fields) > 0 {
+		utility.RespondWithProblem(w, r, h.logger, apperrors.Validation("invalid_query", "Invalid availability query", fields...))
 		return
 	}
 
 	// Get reception service
 	service, err := h.getReceptionService()
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
 	// Call service to get availability
 	availability, err := service.GetDoctorAvailability(r.Context(), doctorID, date, tenantID)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, "Failed to get doctor availability: "+err.Error())
+		utility.RespondWithProblem(w, r, h.logger, err)
 		return
 	}
 
@@ -0,0 +1,85 @@
+package receptionhdlr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/authn"
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/mrityunjay-vashisth/core-service/internal/middleware"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/receptionsvc"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// listedAppointments streams a fixed list of appointments
+type listedAppointments struct {
+	receptionsvc.Service
+	appointments []models.AppointmentResponse
+}
+
+func (l *listedAppointments) StreamAppointments(_ context.Context, _ map[string]interface{}, _ string, fn func(models.AppointmentResponse) error) error {
+	for _, appointment := range l.appointments {
+		if err := fn(appointment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestListAppointmentsConditionalGet(t *testing.T) {
+	service := &listedAppointments{appointments: []models.AppointmentResponse{{AppointmentID: "appt-1", Version: 1}}}
+	services := registry.NewServiceRegistry()
+	services.Register(registry.ReceptionService, service)
+	handler := middleware.ETagMiddleware()(http.HandlerFunc(NewReceptionHandler(services, zap.NewNop()).ListAppointments))
+
+	list := func(accept, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/appointments", nil)
+		req = req.WithContext(authn.WithClaims(req.Context(), &models.UserClaims{Username: "reception", TenantID: "t1"}))
+		req.Header.Set("Accept", accept)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := list("application/json", "")
+	assert.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// Polling an unchanged list
+	polled := list("application/json", etag)
+	assert.Equal(t, http.StatusNotModified, polled.Code)
+	assert.Empty(t, polled.Body.String())
+
+	service.appointments[0].Version = 2
+	changed := list("application/json", etag)
+	assert.Equal(t, http.StatusOK, changed.Code)
+	assert.NotEqual(t, etag, changed.Header().Get("ETag"))
+
+	// NDJSON is streamed from the first item
+	streamed := list(utility.NDJSONContentType, "")
+	assert.Equal(t, http.StatusOK, streamed.Code)
+	assert.Empty(t, streamed.Header().Get("ETag"))
+
+	// So are long arrays, which still parse as one array
+	service.appointments = nil
+	for i := 0; i < 1500; i++ {
+		service.appointments = append(service.appointments, models.AppointmentResponse{AppointmentID: fmt.Sprintf("appt-%d", i)})
+	}
+	long := list("application/json", "")
+	assert.Equal(t, http.StatusOK, long.Code)
+	assert.Empty(t, long.Header().Get("ETag"))
+	assert.True(t, strings.HasPrefix(long.Body.String(), `[{"appointment_id":"appt-0"`))
+	assert.Equal(t, 1500, strings.Count(long.Body.String(), `"appointment_id"`))
+	assert.True(t, strings.HasSuffix(long.Body.String(), "}\n]\n"))
+}
@@ -0,0 +1,53 @@
+package utility
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagMatches reports whether an If-Match or If-None-Match header value lists the entity tag.
+// If-Match uses strong comparison, If-None-Match uses weak comparison.
+func ETagMatches(header, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+			continue
+		}
+		if !strings.HasPrefix(candidate, "W/") && candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPreconditions reports whether a request carries If-Match or If-Unmodified-Since
+func HasPreconditions(r *http.Request) bool {
+	return r.Header.Get("If-Match") != "" || r.Header.Get("If-Unmodified-Since") != ""
+}
+
+// PreconditionsMet evaluates If-Match and If-Unmodified-Since against the current state of a
+// resource. If-Unmodified-Since is ignored when If-Match is present.
+func PreconditionsMet(r *http.Request, etag string, lastModified time.Time) bool {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		return ETagMatches(ifMatch, etag, false)
+	}
+	if ifUnmodifiedSince := r.Header.Get("If-Unmodified-Since"); ifUnmodifiedSince != "" {
+		since, err := http.ParseTime(ifUnmodifiedSince)
+		if err != nil {
+			return true // An invalid date is ignored
+		}
+		// HTTP dates have second precision
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package utility
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/common"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"go.uber.org/zap"
+)
+
+// problemContentType is the media type of RFC 7807 error bodies
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body extended with a machine-readable code,
+// the request ID and field errors
+type Problem struct {
+	Type      string                 `json:"type"`
+	Title     string                 `json:"title"`
+	Status    int                    `json:"status"`
+	Detail    string                 `json:"detail,omitempty"`
+	Instance  string                 `json:"instance,omitempty"`
+	Code      string                 `json:"code"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Errors    []apperrors.FieldError `json:"errors,omitempty"`
+}
+
+// kindStatus maps domain error kinds to HTTP status codes
+var kindStatus = map[apperrors.Kind]int{
+	apperrors.KindValidation:   http.StatusBadRequest,
+	apperrors.KindUnauthorized: http.StatusUnauthorized,
+	apperrors.KindForbidden:    http.StatusForbidden,
+	apperrors.KindNotFound:     http.StatusNotFound,
+	apperrors.KindConflict:     http.StatusConflict,
+	apperrors.KindPrecondition: http.StatusPreconditionFailed,
+	apperrors.KindUnavailable:  http.StatusServiceUnavailable,
+	apperrors.KindInternal:     http.StatusInternalServerError,
+}
+
+// RespondWithProblem renders an error as problem+json. Domain errors keep their status,
+// code and message; any other error becomes a generic 500 so internals are not leaked.
+// Server errors are logged with their cause.
+func RespondWithProblem(w http.ResponseWriter, r *http.Request, logger *zap.Logger, err error) {
+	domainErr, ok := apperrors.As(err)
+	if !ok {
+		domainErr = apperrors.Internal("internal_error", "An internal error occurred", err)
+	}
+
+	status, ok := kindStatus[domainErr.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	if status >= http.StatusInternalServerError {
+		logging.WithContext(r.Context(), logger).Error("Request failed",
+			zap.String("code", domainErr.Code),
+			zap.Error(err))
+	}
+
+	problem := newProblem(w, status, domainErr.Code, domainErr.Message)
+	problem.Instance = r.URL.Path
+	problem.Errors = domainErr.Fields
+	writeProblem(w, problem)
+}
+
+// newProblem builds a problem for a status, echoing the request ID set by RequestIDMiddleware
+func newProblem(w http.ResponseWriter, status int, code, detail string) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Code:      code,
+		RequestID: w.Header().Get(common.RequestIDHeader),
+	}
+}
+
+// writeProblem writes a problem body with its status
+func writeProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// statusCodeName derives a machine-readable code from a status, e.g. too_many_requests
+func statusCodeName(status int) string {
+	text := strings.ToLower(http.StatusText(status))
+	if text == "" {
+		return "error"
+	}
+	return strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+}
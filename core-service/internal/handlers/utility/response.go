@@ -4,37 +4,31 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
-	"strings"
+	"strconv"
 )
 
+// RespondWithError writes a problem+json body for errors raised outside the domain
+// services, such as malformed requests or failed authentication
 func RespondWithError(w http.ResponseWriter, statusCode int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]string{"message": message})
+	writeProblem(w, newProblem(w, statusCode, statusCodeName(statusCode), message))
 }
 
+// RespondWithJSON encodes data once into a buffer so an encoding failure can still be
+// reported as a 500. A nil payload is answered with a message instead of null.
 func RespondWithJSON(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
 	var buf bytes.Buffer
-
-	// Try encoding to the buffer first
-	err := json.NewEncoder(&buf).Encode(data)
-	if err != nil {
-		http.Error(w, "Encoding error", http.StatusInternalServerError)
+	if err := json.NewEncoder(&buf).Encode(data); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Encoding error")
 		return
 	}
 
-	// Check if the encoded result is just "null" (with possible whitespace/newline)
-	encoded := buf.String()
-	trimmed := strings.TrimSpace(encoded)
-
-	if trimmed == "null" {
-		// Handle the null case - for example, return an empty array instead
-		json.NewEncoder(w).Encode(map[string]string{"message": "No pending requests for approval"})
-	} else {
-		// Not null, write the original encoded data
-		json.NewEncoder(w).Encode(data)
+	if bytes.Equal(bytes.TrimSpace(buf.Bytes()), []byte("null")) {
+		buf.Reset()
+		json.NewEncoder(&buf).Encode(map[string]string{"message": "No pending requests for approval"})
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(statusCode)
+	w.Write(buf.Bytes())
 }
@@ -0,0 +1,138 @@
+package utility
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// NDJSONContentType is the media type of newline delimited JSON
+const NDJSONContentType = "application/x-ndjson"
+
+// streamFlushEvery is how many items are written between flushes
+const streamFlushEvery = 100
+
+// streamBufferItems is how many items of a JSON array are held back before the response
+// is streamed. Shorter arrays are sent whole on Close, so the ETag middleware can still
+// tag them and answer If-None-Match with 304.
+const streamBufferItems = 1000
+
+// JSONStream writes a list one item at a time, as a JSON array or, when the client
+// accepts application/x-ndjson, as one JSON document per line. NDJSON is streamed from
+// the first item, a JSON array once it reaches streamBufferItems. Nothing is sent before
+// that, so an error can still become a problem response.
+type JSONStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+	buf        bytes.Buffer
+	encoder    *json.Encoder
+	statusCode int
+	ndjson     bool
+	count      int
+	started    bool
+}
+
+// NewJSONStream creates a stream answering r with the given status
+func NewJSONStream(w http.ResponseWriter, r *http.Request, statusCode int) *JSONStream {
+	s := &JSONStream{
+		w:          w,
+		controller: http.NewResponseController(w),
+		statusCode: statusCode,
+		ndjson:     AcceptsNDJSON(r),
+	}
+	s.encoder = json.NewEncoder(&s.buf)
+	return s
+}
+
+// AcceptsNDJSON reports whether the Accept header asks for newline delimited JSON
+func AcceptsNDJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil || mediaType != NDJSONContentType {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// Started reports whether the status and part of the body have been sent
+func (s *JSONStream) Started() bool {
+	return s.started
+}
+
+// Write encodes the next item
+func (s *JSONStream) Write(item interface{}) error {
+	if !s.ndjson {
+		separator := ","
+		if s.count == 0 {
+			separator = "["
+		}
+		s.buf.WriteString(separator)
+	}
+	if err := s.encoder.Encode(item); err != nil {
+		return err
+	}
+	s.count++
+	if !s.ndjson && s.count < streamBufferItems {
+		return nil
+	}
+	if err := s.send(); err != nil {
+		return err
+	}
+	if s.count == 1 || s.count == streamBufferItems || s.count%streamFlushEvery == 0 {
+		return s.flush()
+	}
+	return nil
+}
+
+// Close ends the list. A stream that fails after it started is not closed, leaving a
+// JSON array unterminated so clients do not mistake a partial list for a complete one.
+func (s *JSONStream) Close() error {
+	if !s.ndjson {
+		if s.count == 0 {
+			s.buf.WriteString("[")
+		}
+		s.buf.WriteString("]\n")
+	}
+	streamed := s.started
+	if err := s.send(); err != nil {
+		return err
+	}
+	if streamed {
+		return s.flush()
+	}
+	return nil
+}
+
+// send writes the headers once and what was encoded since the last send
+func (s *JSONStream) send() error {
+	if !s.started {
+		s.started = true
+		contentType := "application/json"
+		if s.ndjson {
+			contentType = NDJSONContentType
+		}
+		s.w.Header().Set("Content-Type", contentType)
+		s.w.WriteHeader(s.statusCode)
+	}
+	_, err := s.w.Write(s.buf.Bytes())
+	s.buf.Reset()
+	return err
+}
+
+// flush pushes written output to the client when the writer supports it, which also
+// lets buffering middleware know the response is streamed
+func (s *JSONStream) flush() error {
+	if err := s.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
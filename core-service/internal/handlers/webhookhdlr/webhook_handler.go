@@ -0,0 +1,238 @@
+package webhookhdlr
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/webhooksvc"
+	"github.com/mrityunjay-vashisth/core-service/internal/webhooks"
+	"go.uber.org/zap"
+)
+
+// Page sizes of the delivery log
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// errInvalidBody is returned when the request body is not valid JSON for the operation
+var errInvalidBody = apperrors.Validation("invalid_body", "Request body is not valid JSON")
+
+// WebhookHandlerInterface lets tenant admins manage their webhook endpoints and deliveries
+type WebhookHandlerInterface interface {
+	ListEndpoints(w http.ResponseWriter, r *http.Request)
+	CreateEndpoint(w http.ResponseWriter, r *http.Request)
+	GetEndpoint(w http.ResponseWriter, r *http.Request)
+	UpdateEndpoint(w http.ResponseWriter, r *http.Request)
+	DeleteEndpoint(w http.ResponseWriter, r *http.Request)
+	ListDeliveries(w http.ResponseWriter, r *http.Request)
+	Redeliver(w http.ResponseWriter, r *http.Request)
+}
+
+type webhookHandler struct {
+	registry registry.ServiceRegistry
+	logger   *zap.Logger
+}
+
+func NewWebhookHandler(registry registry.ServiceRegistry, logger *zap.Logger) WebhookHandlerInterface {
+	return &webhookHandler{
+		registry: registry,
+		logger:   logger,
+	}
+}
+
+// getWebhookService retrieves the webhook service from the registry
+func (h *webhookHandler) getWebhookService() (webhooksvc.Service, error) {
+	service, ok := h.registry.Get(registry.WebhookService).(webhooksvc.Service)
+	if !ok {
+		h.logger.Error("Failed to get webhook service from registry")
+		return nil, apperrors.Internal("service_not_registered", "internal service error", nil)
+	}
+	return service, nil
+}
+
+// prepare returns the webhook service and the tenant of the caller
+func (h *webhookHandler) prepare(r *http.Request) (webhooksvc.Service, string, error) {
+	tenantID, ok := r.Context().Value("tenantID").(string)
+	if !ok || tenantID == "" {
+		return nil, "", apperrors.Unauthorized("missing_tenant", "tenant ID not found in token")
+	}
+	service, err := h.getWebhookService()
+	if err != nil {
+		return nil, "", err
+	}
+	return service, tenantID, nil
+}
+
+// ListEndpoints returns the webhook endpoints of the caller's tenant
+func (h *webhookHandler) ListEndpoints(w http.ResponseWriter, r *http.Request) {
+	service, tenantID, err := h.prepare(r)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	endpoints, err := service.ListEndpoints(r.Context(), tenantID)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	utility.RespondWithJSON(w, http.StatusOK, endpoints)
+}
+
+// CreateEndpoint registers a webhook endpoint and returns it with its signing secret
+func (h *webhookHandler) CreateEndpoint(w http.ResponseWriter, r *http.Request) {
+	service, tenantID, err := h.prepare(r)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	var req models.WebhookEndpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utility.RespondWithProblem(w, r, h.logger, errInvalidBody)
+		return
+	}
+
+	endpoint, err := service.CreateEndpoint(r.Context(), tenantID, req)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	h.logger.Info("Webhook endpoint registered",
+		zap.String("endpoint_id", endpoint.ID),
+		zap.String("tenant_id", tenantID),
+		zap.Strings("event_types", endpoint.EventTypes))
+	utility.RespondWithJSON(w, http.StatusCreated, endpoint)
+}
+
+// GetEndpoint returns a webhook endpoint of the caller's tenant
+func (h *webhookHandler) GetEndpoint(w http.ResponseWriter, r *http.Request) {
+	service, tenantID, err := h.prepare(r)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	endpoint, err := service.GetEndpoint(r.Context(), tenantID, mux.Vars(r)["id"])
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	utility.RespondWithJSON(w, http.StatusOK, endpoint)
+}
+
+// UpdateEndpoint changes the URL, event types, description or state of an endpoint
+func (h *webhookHandler) UpdateEndpoint(w http.ResponseWriter, r *http.Request) {
+	service, tenantID, err := h.prepare(r)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	var req models.WebhookEndpointUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utility.RespondWithProblem(w, r, h.logger, errInvalidBody)
+		return
+	}
+
+	endpoint, err := service.UpdateEndpoint(r.Context(), tenantID, mux.Vars(r)["id"], req)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	utility.RespondWithJSON(w, http.StatusOK, endpoint)
+}
+
+// DeleteEndpoint removes a webhook endpoint. Its pending deliveries are not sent.
+func (h *webhookHandler) DeleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	service, tenantID, err := h.prepare(r)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	if err := service.DeleteEndpoint(r.Context(), tenantID, mux.Vars(r)["id"]); err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns the delivery log of an endpoint, newest first
+func (h *webhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	service, tenantID, err := h.prepare(r)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	filter, err := parseDeliveryFilter(r)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	filter.TenantID = tenantID
+	filter.EndpointID = mux.Vars(r)["id"]
+
+	deliveries, err := service.ListDeliveries(r.Context(), filter)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	utility.RespondWithJSON(w, http.StatusOK, deliveries)
+}
+
+// Redeliver queues the event of a delivery again
+func (h *webhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	service, tenantID, err := h.prepare(r)
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	delivery, err := service.Redeliver(r.Context(), tenantID, vars["id"], vars["deliveryId"])
+	if err != nil {
+		utility.RespondWithProblem(w, r, h.logger, err)
+		return
+	}
+	utility.RespondWithJSON(w, http.StatusAccepted, delivery)
+}
+
+// parseDeliveryFilter reads the delivery log query parameters, reporting every invalid one
+func parseDeliveryFilter(r *http.Request) (webhooks.DeliveryFilter, error) {
+	query := r.URL.Query()
+	filter := webhooks.DeliveryFilter{
+		Status:  query.Get("status"),
+		EventID: query.Get("event_id"),
+		Limit:   defaultDeliveryLimit,
+	}
+
+	var fields []apperrors.FieldError
+	if value := query.Get("before"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			fields = append(fields, apperrors.FieldError{Field: "before", Message: "must be an RFC 3339 timestamp"})
+		}
+		filter.Before = parsed
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 1 || limit > maxDeliveryLimit {
+			fields = append(fields, apperrors.FieldError{Field: "limit", Message: "must be between 1 and 500"})
+		}
+		filter.Limit = limit
+	}
+	if len(fields) > 0 {
+		return filter, apperrors.Validation("invalid_query", "Invalid query parameters", fields...)
+	}
+	return filter, nil
+}
@@ -0,0 +1,39 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultTTL is how long a key and its saved response are kept
+const DefaultTTL = 24 * time.Hour
+
+// ReservationLease is how long a key stays reserved while its first request runs. A key
+// left reserved by a replica that stopped mid-request is free again once the lease ends.
+const ReservationLease = time.Minute
+
+// ErrNotFound is returned when completing or releasing a key that was never reserved
+var ErrNotFound = errors.New("idempotency key not found")
+
+// Record is the state saved for an idempotency key
+type Record struct {
+	Fingerprint string // Hash of the request that first used the key
+	Completed   bool   // False while the first request is still being served
+	StatusCode  int
+	ContentType string
+	Headers     map[string]string // Response headers replayed with the body, such as Location
+	Body        []byte
+}
+
+// Store keeps idempotency records. Implementations must be safe for concurrent use.
+type Store interface {
+	// Reserve claims the key for a request with the given fingerprint until the lease ends.
+	// It returns nil when the key was free or its reservation had run out, or the existing
+	// record when the key was already used.
+	Reserve(ctx context.Context, key, fingerprint string, lease time.Duration) (*Record, error)
+	// Complete saves the final response of a reserved key and keeps it for ttl
+	Complete(ctx context.Context, key string, record Record, ttl time.Duration) error
+	// Release frees a reserved key so the request can be retried
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,162 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoStore keeps idempotency records in coredb so every replica sees the same keys
+type MongoStore struct {
+	db  db.DBClientInterface
+	now func() time.Time
+}
+
+// NewMongoStore creates a store backed by the idempotency_keys collection and makes
+// sure records expire
+func NewMongoStore(ctx context.Context, database db.DBClientInterface) (*MongoStore, error) {
+	store := &MongoStore{db: database, now: time.Now}
+	if err := database.EnsureTTLIndex(ctx, "expires_at", 0, store.options()...); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *MongoStore) options() []db.DBOption {
+	return []db.DBOption{
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.IdempotencyKeys),
+	}
+}
+
+// Reserve inserts the key, relying on the unique _id to detect concurrent or repeated use.
+// A reservation whose lease ran out is taken over, as its request never completed.
+func (s *MongoStore) Reserve(ctx context.Context, key, fingerprint string, lease time.Duration) (*Record, error) {
+	// A second attempt covers a record expiring between the insert and the read
+	for attempt := 0; attempt < 2; attempt++ {
+		now := s.now().UTC()
+		_, err := s.db.Create(ctx, map[string]interface{}{
+			"_id":         key,
+			"fingerprint": fingerprint,
+			"completed":   false,
+			"created_at":  now,
+			"expires_at":  now.Add(lease),
+		}, s.options()...)
+		if err == nil {
+			return nil, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+
+		doc, err := s.db.Read(ctx, bson.M{"_id": key}, s.options()...)
+		if err != nil {
+			return nil, err
+		}
+		record, ok := decodeRecord(doc)
+		if !ok {
+			continue
+		}
+		if !record.Completed {
+			// The database only removes expired documents once a minute
+			taken, err := s.db.UpdateOne(ctx,
+				bson.M{"_id": key, "completed": false, "expires_at": bson.M{"$lte": now}},
+				bson.M{"$set": bson.M{"fingerprint": fingerprint, "created_at": now, "expires_at": now.Add(lease)}},
+				s.options()...)
+			if err != nil {
+				return nil, err
+			}
+			if taken > 0 {
+				return nil, nil
+			}
+		}
+		return &record, nil
+	}
+	return nil, errors.New("idempotency key could not be reserved")
+}
+
+// Complete saves the response of a reserved key
+func (s *MongoStore) Complete(ctx context.Context, key string, record Record, ttl time.Duration) error {
+	now := s.now().UTC()
+	modified, err := s.db.UpdateOne(ctx,
+		bson.M{"_id": key},
+		bson.M{"$set": bson.M{
+			"completed":    true,
+			"status_code":  record.StatusCode,
+			"content_type": record.ContentType,
+			"headers":      record.Headers,
+			"body":         string(record.Body),
+			"completed_at": now,
+			"expires_at":   now.Add(ttl),
+		}},
+		s.options()...,
+	)
+	if err != nil {
+		return err
+	}
+	if modified == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Release deletes a reserved key that has not been completed
+func (s *MongoStore) Release(ctx context.Context, key string) error {
+	_, err := s.db.Delete(ctx, bson.M{"_id": key, "completed": false}, s.options()...)
+	return err
+}
+
+// decodeRecord reads a record document returned by the database
+func decodeRecord(doc interface{}) (Record, bool) {
+	fields, ok := doc.(map[string]interface{})
+	if !ok || len(fields) == 0 {
+		return Record{}, false
+	}
+
+	record := Record{}
+	record.Fingerprint, _ = fields["fingerprint"].(string)
+	record.Completed, _ = fields["completed"].(bool)
+	record.ContentType, _ = fields["content_type"].(string)
+	if headers := documentFields(fields["headers"]); len(headers) > 0 {
+		record.Headers = make(map[string]string, len(headers))
+		for name, value := range headers {
+			record.Headers[name], _ = value.(string)
+		}
+	}
+	if body, ok := fields["body"].(string); ok {
+		record.Body = []byte(body)
+	}
+	switch status := fields["status_code"].(type) {
+	case int32:
+		record.StatusCode = int(status)
+	case int64:
+		record.StatusCode = int(status)
+	case int:
+		record.StatusCode = status
+	}
+	return record, true
+}
+
+// documentFields reads an embedded document, which the driver may decode as a map or as
+// an ordered document
+func documentFields(item interface{}) map[string]interface{} {
+	switch doc := item.(type) {
+	case map[string]interface{}:
+		return doc
+	case primitive.M:
+		return doc
+	case primitive.D:
+		fields := make(map[string]interface{}, len(doc))
+		for _, element := range doc {
+			fields[element.Key] = element.Value
+		}
+		return fields
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestReservationLease(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Now().UTC()
+	var doc map[string]interface{} // The one key of the test
+	database := &mocks.MockDBClient{
+		CreateFn: func(_ context.Context, data map[string]interface{}, _ ...db.DBOption) (interface{}, error) {
+			if doc != nil {
+				return nil, mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+			}
+			doc = data
+			return data["_id"], nil
+		},
+		ReadFn: func(context.Context, map[string]interface{}, ...db.DBOption) (interface{}, error) {
+			// Decoded the way the driver does
+			raw, err := bson.Marshal(doc)
+			if err != nil {
+				return nil, err
+			}
+			var decoded map[string]interface{}
+			if err := bson.Unmarshal(raw, &decoded); err != nil {
+				return nil, err
+			}
+			return decoded, nil
+		},
+		UpdateOneFn: func(_ context.Context, filter, update map[string]interface{}, _ ...db.DBOption) (int64, error) {
+			if expired, ok := filter["expires_at"].(bson.M); ok && doc["expires_at"].(time.Time).After(expired["$lte"].(time.Time)) {
+				return 0, nil
+			}
+			for field, value := range update["$set"].(bson.M) {
+				doc[field] = value
+			}
+			return 1, nil
+		},
+	}
+	store := &MongoStore{db: database, now: func() time.Time { return clock }}
+
+	saved, err := store.Reserve(ctx, "key-1", "first", ReservationLease)
+	assert.NoError(t, err)
+	assert.Nil(t, saved)
+
+	// Still in progress within the lease
+	saved, err = store.Reserve(ctx, "key-1", "first", ReservationLease)
+	assert.NoError(t, err)
+	if assert.NotNil(t, saved) {
+		assert.False(t, saved.Completed)
+	}
+
+	// The first request never finished, so a retry after the lease runs it
+	clock = clock.Add(ReservationLease + time.Second)
+	saved, err = store.Reserve(ctx, "key-1", "first", ReservationLease)
+	assert.NoError(t, err)
+	assert.Nil(t, saved)
+
+	// A completed response is kept for the TTL, with its headers
+	assert.NoError(t, store.Complete(ctx, "key-1", Record{StatusCode: 201, Headers: map[string]string{"Location": "/appointments/a1"}}, DefaultTTL))
+	clock = clock.Add(time.Hour)
+	saved, err = store.Reserve(ctx, "key-1", "first", ReservationLease)
+	assert.NoError(t, err)
+	if assert.NotNil(t, saved) {
+		assert.True(t, saved.Completed)
+		assert.Equal(t, "/appointments/a1", saved.Headers["Location"])
+	}
+}
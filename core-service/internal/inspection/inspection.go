@@ -0,0 +1,284 @@
+package inspection
+
+import (
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+)
+
+// Limits applied to every request
+const (
+	MaxBodyBytes          = 1 << 20 // Bodies above this size are not inspected and rejected
+	maxBodyDepth          = 32      // Deepest JSON nesting accepted
+	defaultMaxParamLength = 256     // Longest parameter value unless the schema says otherwise
+)
+
+// Locations of a finding
+const (
+	LocationQuery = "query"
+	LocationPath  = "path"
+	LocationBody  = "body"
+)
+
+var (
+	// operatorPattern matches MongoDB operators such as $ne, $where or $regex
+	operatorPattern = regexp.MustCompile(`^\$[A-Za-z]+$`)
+	idPattern       = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// Finding describes a single part of a request that broke a rule
+type Finding struct {
+	Rule     string `json:"rule"`
+	Location string `json:"location"`
+	Field    string `json:"field"`
+	Reason   string `json:"reason"`
+}
+
+// Request is the part of an HTTP request the rules look at
+type Request struct {
+	Query url.Values
+	Path  map[string]string
+	Body  interface{} // Decoded JSON body, nil when absent or not inspected
+}
+
+// Rule inspects a request and reports what it finds
+type Rule interface {
+	Name() string
+	Inspect(req *Request) []Finding
+}
+
+// RuleSet is the ordered list of rules applied to one operation
+type RuleSet struct {
+	rules       []Rule
+	inspectBody bool
+}
+
+// NewRuleSet builds the rules declared by an inspection policy: a type allowlist for
+// every declared parameter, operator detection in all user input and, when enabled,
+// in the JSON body. Extra rules run after the built-in ones.
+func NewRuleSet(policy config.InspectionPolicy, extra ...Rule) *RuleSet {
+	rules := []Rule{}
+	for _, param := range policy.Params {
+		rules = append(rules, ParamRule{Param: param})
+	}
+	rules = append(rules, OperatorRule{InspectBody: policy.InspectBody})
+	rules = append(rules, extra...)
+	return &RuleSet{rules: rules, inspectBody: policy.InspectBody}
+}
+
+// InspectsBody reports whether the JSON body must be decoded for the rules
+func (s *RuleSet) InspectsBody() bool {
+	return s.inspectBody
+}
+
+// Inspect runs every rule and returns all findings ordered by location and field
+func (s *RuleSet) Inspect(req *Request) []Finding {
+	var findings []Finding
+	for _, rule := range s.rules {
+		findings = append(findings, rule.Inspect(req)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Location != findings[j].Location {
+			return findings[i].Location < findings[j].Location
+		}
+		return findings[i].Field < findings[j].Field
+	})
+	return findings
+}
+
+// ParamRule checks that a declared parameter only holds values allowed by its type
+type ParamRule struct {
+	Param config.ParamRule
+}
+
+// Name identifies the rule in findings and metrics
+func (r ParamRule) Name() string {
+	return "param_type"
+}
+
+// Inspect validates every value of the parameter
+func (r ParamRule) Inspect(req *Request) []Finding {
+	var values []string
+	location := LocationQuery
+	if r.Param.In == LocationPath {
+		location = LocationPath
+		if value, ok := req.Path[r.Param.Name]; ok {
+			values = []string{value}
+		}
+	} else {
+		values = req.Query[r.Param.Name]
+	}
+
+	var findings []Finding
+	for _, value := range values {
+		if reason := r.check(value); reason != "" {
+			findings = append(findings, Finding{Rule: r.Name(), Location: location, Field: r.Param.Name, Reason: reason})
+		}
+	}
+	return findings
+}
+
+// check returns why a value is not allowed, or an empty string
+func (r ParamRule) check(value string) string {
+	maxLength := r.Param.MaxLength
+	if maxLength == 0 {
+		maxLength = defaultMaxParamLength
+	}
+	if len(value) > maxLength {
+		return "must be at most " + strconv.Itoa(maxLength) + " characters"
+	}
+
+	if reason := checkType(r.Param, value); reason != "" {
+		return reason
+	}
+	if r.Param.Pattern != nil && !r.Param.Pattern.MatchString(value) {
+		return "must match " + r.Param.Pattern.String()
+	}
+	return ""
+}
+
+// checkType applies the allowlist of the parameter type
+func checkType(param config.ParamRule, value string) string {
+	switch param.Type {
+	case config.ParamName:
+		for _, c := range value {
+			if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune(" '-.,#&", c) {
+				return "must only contain letters, digits, spaces and ' - . , # &"
+			}
+		}
+	case config.ParamID:
+		if !idPattern.MatchString(value) {
+			return "must only contain letters, digits, hyphens and underscores"
+		}
+	case config.ParamUUID:
+		if !uuidPattern.MatchString(value) {
+			return "must be a UUID"
+		}
+	case config.ParamInteger:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "must be an integer"
+		}
+	case config.ParamNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "must be a number"
+		}
+	case config.ParamBoolean:
+		if value != "true" && value != "false" {
+			return "must be true or false"
+		}
+	case config.ParamDate:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return "must be a date (YYYY-MM-DD)"
+		}
+	case config.ParamDateTime:
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return "must be an RFC 3339 date-time"
+		}
+	case config.ParamEmail:
+		if address, err := mail.ParseAddress(value); err != nil || address.Address != value {
+			return "must be an email address"
+		}
+	case config.ParamEnum:
+		for _, allowed := range param.Enum {
+			if value == allowed {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(param.Enum, ", ")
+	default:
+		for _, c := range value {
+			if unicode.IsControl(c) {
+				return "must not contain control characters"
+			}
+		}
+	}
+	return ""
+}
+
+// OperatorRule rejects MongoDB operators in user input. A $-prefixed key or an
+// operator-like value could turn a field lookup into a query the caller controls.
+type OperatorRule struct {
+	InspectBody bool
+}
+
+// Name identifies the rule in findings and metrics
+func (r OperatorRule) Name() string {
+	return "nosql_operator"
+}
+
+// Inspect checks the query and path parameters and, when enabled, the JSON body
+func (r OperatorRule) Inspect(req *Request) []Finding {
+	var findings []Finding
+	for name, values := range req.Query {
+		if strings.Contains(name, "$") {
+			findings = append(findings, r.finding(LocationQuery, name, "parameter name must not contain $"))
+			continue
+		}
+		for _, value := range values {
+			if operatorPattern.MatchString(value) {
+				findings = append(findings, r.finding(LocationQuery, name, "must not be a database operator"))
+			}
+		}
+	}
+	for name, value := range req.Path {
+		if operatorPattern.MatchString(value) {
+			findings = append(findings, r.finding(LocationPath, name, "must not be a database operator"))
+		}
+	}
+	if r.InspectBody && req.Body != nil {
+		findings = append(findings, r.inspectValue("", req.Body, 0)...)
+	}
+	return findings
+}
+
+// inspectValue walks a decoded JSON value looking for operator keys and values
+func (r OperatorRule) inspectValue(path string, value interface{}, depth int) []Finding {
+	if depth > maxBodyDepth {
+		return []Finding{r.finding(LocationBody, path, "is nested too deeply")}
+	}
+
+	var findings []Finding
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			field := joinPath(path, key)
+			if strings.HasPrefix(key, "$") {
+				findings = append(findings, r.finding(LocationBody, field, "key must not start with $"))
+				continue
+			}
+			if strings.ContainsRune(key, 0) {
+				findings = append(findings, r.finding(LocationBody, field, "key must not contain NUL"))
+				continue
+			}
+			findings = append(findings, r.inspectValue(field, child, depth+1)...)
+		}
+	case []interface{}:
+		for i, child := range v {
+			findings = append(findings, r.inspectValue(path+"["+strconv.Itoa(i)+"]", child, depth+1)...)
+		}
+	case string:
+		if operatorPattern.MatchString(v) {
+			findings = append(findings, r.finding(LocationBody, path, "must not be a database operator"))
+		}
+	}
+	return findings
+}
+
+func (r OperatorRule) finding(location, field, reason string) Finding {
+	return Finding{Rule: r.Name(), Location: location, Field: field, Reason: reason}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,66 @@
+package inspection
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamRuleAllowlists(t *testing.T) {
+	rules := NewRuleSet(config.InspectionPolicy{Params: []config.ParamRule{
+		{Name: "patient_name", In: "query", Type: config.ParamString},
+		{Name: "doctor_id", In: "query", Type: config.ParamID},
+		{Name: "date", In: "query", Type: config.ParamDate},
+		{Name: "status", In: "query", Type: config.ParamEnum, Enum: []string{"scheduled", "cancelled"}},
+		{Name: "id", In: "path", Type: config.ParamID},
+	}})
+
+	// Names with apostrophes and # were rejected by the old SQL patterns
+	findings := rules.Inspect(&Request{
+		Query: url.Values{"patient_name": {"O'Brien #2"}, "doctor_id": {"doc_42"}, "date": {"2025-03-01"}, "status": {"scheduled"}},
+		Path:  map[string]string{"id": "a1B2-c3"},
+	})
+	assert.Empty(t, findings)
+
+	findings = rules.Inspect(&Request{
+		Query: url.Values{"doctor_id": {"doc 42"}, "date": {"01/03/2025"}, "status": {"deleted"}},
+		Path:  map[string]string{"id": "../etc"},
+	})
+	assert.Equal(t, []string{"path.id", "query.date", "query.doctor_id", "query.status"}, fieldsOf(findings))
+}
+
+func TestOperatorRule(t *testing.T) {
+	rules := NewRuleSet(config.InspectionPolicy{InspectBody: true})
+
+	var body interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"patient_name": "Jane",
+		"notes": "$5 copay",
+		"filter": {"status": {"$ne": "cancelled"}},
+		"tags": ["ok", "$where"]
+	}`), &body))
+
+	findings := rules.Inspect(&Request{
+		Query: url.Values{"status[$ne]": {"x"}, "doctor_id": {"$gt"}},
+		Body:  body,
+	})
+	assert.Equal(t, []string{"body.filter.status.$ne", "body.tags[1]", "query.doctor_id", "query.status[$ne]"}, fieldsOf(findings))
+	for _, finding := range findings {
+		assert.Equal(t, "nosql_operator", finding.Rule)
+	}
+
+	// Body inspection can be turned off per operation
+	rules = NewRuleSet(config.InspectionPolicy{InspectBody: false})
+	assert.Empty(t, rules.Inspect(&Request{Body: body}))
+}
+
+func fieldsOf(findings []Finding) []string {
+	fields := []string{}
+	for _, finding := range findings {
+		fields = append(fields, finding.Location+"."+finding.Field)
+	}
+	return fields
+}
@@ -0,0 +1,140 @@
+// Package leader elects one replica to do work that must not run on several at once, such
+// as the stuck request recovery. The leader holds a lease in MongoDB and renews it well
+// before it expires. When the leader stops renewing, because it crashed or lost the
+// database, another replica takes the lease over once it has expired.
+//
+// Expiry is judged by the clock of the replica taking the lease over, so replica clocks
+// must not drift apart by more than a fraction of the lease TTL.
+package leader
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mrityunjay-vashisth/go-idforge/pkg/idforge"
+	"go.uber.org/zap"
+)
+
+// Lease is the right of one replica to lead
+type Lease struct {
+	Name       string    `json:"name"`
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Store keeps leases
+type Store interface {
+	// Acquire gives holder the lease on name until expiresAt if the lease is free, expired
+	// at now or already holder's, and reports whether holder has it
+	Acquire(ctx context.Context, name, holder string, now, expiresAt time.Time) (bool, error)
+	// Release frees the lease on name if holder has it
+	Release(ctx context.Context, name, holder string) error
+	// Get returns the lease on name, nil if it was never taken
+	Get(ctx context.Context, name string) (*Lease, error)
+}
+
+// Elector campaigns for the lease on one name on behalf of this replica
+type Elector struct {
+	store  Store
+	name   string
+	holder string
+	ttl    time.Duration
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu         sync.RWMutex
+	leaseUntil time.Time // Zero while another replica leads
+}
+
+// NewElector creates an elector for the lease on name, held by holder for ttl at a time
+func NewElector(store Store, name, holder string, ttl time.Duration, logger *zap.Logger) *Elector {
+	return &Elector{
+		store:  store,
+		name:   name,
+		holder: holder,
+		ttl:    ttl,
+		logger: logger.With(zap.String("lease", name), zap.String("holder", holder)),
+		now:    time.Now,
+	}
+}
+
+// Campaign takes the lease, or renews it if this replica already leads, and reports
+// whether this replica leads. Campaign at least every third of the TTL to keep the lease.
+func (e *Elector) Campaign(ctx context.Context) (bool, error) {
+	// Counting the lease from before the store is asked errs on the side of ending it early
+	now := e.now()
+	leading, err := e.store.Acquire(ctx, e.name, e.holder, now, now.Add(e.ttl))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	wasLeading := !e.leaseUntil.IsZero()
+	if err != nil {
+		// The lease may still be ours, but without renewing it we cannot tell for how long
+		e.leaseUntil = time.Time{}
+		if wasLeading {
+			e.logger.Warn("Stopped leading, the lease could not be renewed", zap.Error(err))
+		}
+		return false, err
+	}
+	if !leading {
+		e.leaseUntil = time.Time{}
+		if wasLeading {
+			e.logger.Warn("Lost the lease to another replica")
+		}
+		return false, nil
+	}
+	e.leaseUntil = now.Add(e.ttl)
+	if !wasLeading {
+		e.logger.Info("Took the lease, leading")
+	}
+	return true, nil
+}
+
+// IsLeader reports whether this replica holds a lease that has not expired
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return !e.leaseUntil.IsZero() && e.now().Before(e.leaseUntil)
+}
+
+// Resign releases the lease, if this replica holds it, so that another replica can take
+// over without waiting for it to expire
+func (e *Elector) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	wasLeading := !e.leaseUntil.IsZero()
+	e.leaseUntil = time.Time{}
+	e.mu.Unlock()
+	if !wasLeading {
+		return nil
+	}
+	e.logger.Info("Releasing the lease")
+	return e.store.Release(ctx, e.name, e.holder)
+}
+
+// Leader returns the lease of the leading replica, nil if no replica holds one that has
+// not expired
+func (e *Elector) Leader(ctx context.Context) (*Lease, error) {
+	lease, err := e.store.Get(ctx, e.name)
+	if err != nil || lease == nil || !e.now().Before(lease.ExpiresAt) {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// Holder returns the ID this replica holds the lease under
+func (e *Elector) Holder() string {
+	return e.holder
+}
+
+// HolderID returns an ID for this process: its host name, which names the pod in
+// Kubernetes, and a random suffix, as a restarted process may reuse the host name
+func HolderID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "core-service"
+	}
+	return host + "-" + idforge.GenerateWithSize(6)
+}
@@ -0,0 +1,98 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryStore keeps leases in memory for tests
+type memoryStore struct {
+	mu     sync.Mutex
+	leases map[string]Lease
+}
+
+func (s *memoryStore) Acquire(_ context.Context, name, holder string, now, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lease, ok := s.leases[name]
+	if ok && lease.Holder != holder && now.Before(lease.ExpiresAt) {
+		return false, nil
+	}
+	if !ok || lease.Holder != holder {
+		lease = Lease{Name: name, Holder: holder, AcquiredAt: now}
+	}
+	lease.ExpiresAt = expiresAt
+	s.leases[name] = lease
+	return true, nil
+}
+
+func (s *memoryStore) Release(_ context.Context, name, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.leases[name].Holder == holder {
+		delete(s.leases, name)
+	}
+	return nil
+}
+
+func (s *memoryStore) Get(_ context.Context, name string) (*Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lease, ok := s.leases[name]
+	if !ok {
+		return nil, nil
+	}
+	return &lease, nil
+}
+
+func TestElection(t *testing.T) {
+	ctx := context.Background()
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &memoryStore{leases: map[string]Lease{}}
+	elector := func(holder string) *Elector {
+		e := NewElector(store, "recovery", holder, 30*time.Second, zap.NewNop())
+		e.now = func() time.Time { return clock }
+		return e
+	}
+	a, b := elector("a"), elector("b")
+
+	leading, err := a.Campaign(ctx)
+	assert.NoError(t, err)
+	assert.True(t, leading)
+	leading, _ = b.Campaign(ctx)
+	assert.False(t, leading)
+	assert.True(t, a.IsLeader())
+	assert.False(t, b.IsLeader())
+
+	// Renewing keeps the lease past its first expiry
+	clock = clock.Add(20 * time.Second)
+	leading, _ = a.Campaign(ctx)
+	assert.True(t, leading)
+	clock = clock.Add(20 * time.Second)
+	leading, _ = b.Campaign(ctx)
+	assert.False(t, leading)
+	lease, _ := b.Leader(ctx)
+	if assert.NotNil(t, lease) {
+		assert.Equal(t, "a", lease.Holder)
+	}
+
+	// A leader that stops renewing is taken over once its lease expired
+	clock = clock.Add(30 * time.Second)
+	assert.False(t, a.IsLeader())
+	leading, _ = b.Campaign(ctx)
+	assert.True(t, leading)
+	leading, _ = a.Campaign(ctx)
+	assert.False(t, leading)
+
+	// Resigning hands the lease over at once
+	assert.NoError(t, b.Resign(ctx))
+	lease, _ = a.Leader(ctx)
+	assert.Nil(t, lease)
+	leading, _ = a.Campaign(ctx)
+	assert.True(t, leading)
+}
@@ -0,0 +1,106 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoStore keeps leases in coredb, one document per lease name
+type MongoStore struct {
+	db db.DBClientInterface
+}
+
+// NewMongoStore creates a lease store backed by the leases collection
+func NewMongoStore(database db.DBClientInterface) *MongoStore {
+	return &MongoStore{db: database}
+}
+
+func (s *MongoStore) options() []db.DBOption {
+	return []db.DBOption{
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.Leases),
+	}
+}
+
+func (s *MongoStore) Acquire(ctx context.Context, name, holder string, now, expiresAt time.Time) (bool, error) {
+	// MongoDB stores dates with millisecond precision
+	now, expiresAt = now.UTC().Truncate(time.Millisecond), expiresAt.UTC().Truncate(time.Millisecond)
+
+	// Renew the lease we hold
+	renewed, err := s.db.UpdateOne(ctx,
+		bson.M{"_id": name, "holder": holder},
+		bson.M{"$set": bson.M{"expires_at": expiresAt, "renewed_at": now}},
+		s.options()...)
+	if err != nil || renewed > 0 {
+		return renewed > 0, err
+	}
+
+	// Take over an expired lease; of several replicas trying at once, one modifies it
+	taken, err := s.db.UpdateOne(ctx,
+		bson.M{"_id": name, "expires_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"holder": holder, "acquired_at": now, "renewed_at": now, "expires_at": expiresAt}},
+		s.options()...)
+	if err != nil || taken > 0 {
+		return taken > 0, err
+	}
+
+	// Take a lease that was never taken
+	_, err = s.db.Create(ctx, map[string]interface{}{
+		"_id":         name,
+		"holder":      holder,
+		"acquired_at": now,
+		"renewed_at":  now,
+		"expires_at":  expiresAt,
+	}, s.options()...)
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, err
+	}
+	// The lease exists; renewing modified nothing if it was ours with the same expiry
+	lease, err := s.Get(ctx, name)
+	if err != nil || lease == nil {
+		return false, err
+	}
+	return lease.Holder == holder && now.Before(lease.ExpiresAt), nil
+}
+
+func (s *MongoStore) Release(ctx context.Context, name, holder string) error {
+	_, err := s.db.Delete(ctx, bson.M{"_id": name, "holder": holder}, s.options()...)
+	return err
+}
+
+func (s *MongoStore) Get(ctx context.Context, name string) (*Lease, error) {
+	result, err := s.db.Read(ctx, bson.M{"_id": name}, s.options()...)
+	if err != nil {
+		return nil, err
+	}
+	doc, _ := result.(map[string]interface{})
+	if len(doc) == 0 {
+		return nil, nil
+	}
+	lease := &Lease{
+		Name:       name,
+		AcquiredAt: timeField(doc["acquired_at"]),
+		ExpiresAt:  timeField(doc["expires_at"]),
+	}
+	lease.Holder, _ = doc["holder"].(string)
+	return lease, nil
+}
+
+func timeField(value interface{}) time.Time {
+	switch t := value.(type) {
+	case time.Time:
+		return t.UTC()
+	case primitive.DateTime:
+		return t.Time().UTC()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/common"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// WithContext returns the logger annotated with the request ID and the trace and
+// span IDs of the span active in ctx, so log lines can be correlated with requests
+// and traces
+func WithContext(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	var fields []zap.Field
+	if requestID := common.GetRequestIDFromContext(ctx); requestID != "" {
+		fields = append(fields, zap.String("request_id", requestID))
+	}
+
+	spanContext := trace.SpanContextFromContext(ctx)
+	if spanContext.IsValid() {
+		fields = append(fields,
+			zap.String("trace_id", spanContext.TraceID().String()),
+			zap.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+
+	if len(fields) == 0 {
+		return logger
+	}
+	return logger.With(fields...)
+}
+
+// CertificateReloads returns a function logging the reloads of the TLS certificates read
+// from certFile, as reported by a certificate store
+func CertificateReloads(logger *zap.Logger, certFile string) func(error) {
+	return func(err error) {
+		if err != nil {
+			logger.Error("Failed to reload TLS certificates", zap.String("cert_file", certFile), zap.Error(err))
+			return
+		}
+		logger.Info("Reloaded TLS certificates", zap.String("cert_file", certFile))
+	}
+}
@@ -0,0 +1,196 @@
+// Package mailer sends email to people outside Medusa, such as onboarding applicants.
+// The smtp driver hands messages to a mail relay; the file driver writes each one to a
+// directory instead, so development and tests need no relay.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/go-idforge/pkg/idforge"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer of the configured driver
+func New(cfg config.MailConfig) (Mailer, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+	switch cfg.Driver {
+	case "smtp":
+		return &smtpMailer{from: from, settings: cfg.SMTP}, nil
+	case "file":
+		return &fileMailer{from: from, dir: cfg.Dir}, nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+}
+
+// smtpMailer sends messages through a mail relay
+type smtpMailer struct {
+	from     *mail.Address
+	settings config.SMTPConfig
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg Message) error {
+	to, err := recipients(msg)
+	if err != nil {
+		return err
+	}
+	data, err := compose(m.from, to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	host, _, err := net.SplitHostPort(m.settings.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid mail relay address: %w", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.settings.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+	if m.settings.Username != "" {
+		// PlainAuth refuses to send the password over a connection without TLS
+		if err := client.Auth(smtp.PlainAuth("", m.settings.Username, string(m.settings.Password), host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(m.from.Address); err != nil {
+		return err
+	}
+	for _, address := range to {
+		if err := client.Rcpt(address.Address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// fileMailer writes every message to a file of its own, named so they sort by time
+type fileMailer struct {
+	from *mail.Address
+	dir  string
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg Message) error {
+	to, err := recipients(msg)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	data, err := compose(m.from, to, msg, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return err
+	}
+	name := now.UTC().Format("20060102T150405.000000000Z") + "-" + idforge.GenerateWithSize(8) + ".eml"
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o600)
+}
+
+// compose formats a message as it is sent. The body is quoted-printable, which also ends
+// its lines with CRLF, so that any text survives relays.
+func compose(from *mail.Address, to []*mail.Address, msg Message, date time.Time) ([]byte, error) {
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("subject must be a single line")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	header := make([]string, 0, len(to))
+	for _, address := range to {
+		header = append(header, address.String())
+	}
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(header, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", idforge.GenerateWithSize(20), domain(from.Address))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	body := quotedprintable.NewWriter(&buf)
+	if _, err := body.Write([]byte(msg.Body)); err != nil {
+		return nil, err
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// recipients parses the recipients of a message
+func recipients(msg Message) ([]*mail.Address, error) {
+	if len(msg.To) == 0 {
+		return nil, errors.New("message has no recipients")
+	}
+	to := make([]*mail.Address, 0, len(msg.To))
+	for _, recipient := range msg.To {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", recipient, err)
+		}
+		to = append(to, address)
+	}
+	return to, nil
+}
+
+// domain returns the domain of an email address
+func domain(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return address[at+1:]
+	}
+	return "localhost"
+}
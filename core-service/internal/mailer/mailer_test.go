@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := New(config.MailConfig{Driver: "file", From: "Medusa <no-reply@medusa.test>", Dir: dir})
+	assert.NoError(t, err)
+
+	err = mailer.Send(context.Background(), Message{
+		To:      []string{"Clinic Owner <owner@clinic.test>"},
+		Subject: "Your application – update",
+		Body:    "Hello,\nyour request was reviewed.\n",
+	})
+	assert.NoError(t, err)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if !assert.Len(t, files, 1) {
+		return
+	}
+	f, _ := os.Open(files[0])
+	defer f.Close()
+	msg, err := mail.ReadMessage(f)
+	assert.NoError(t, err)
+	assert.Equal(t, `"Clinic Owner" <owner@clinic.test>`, msg.Header.Get("To"))
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.Equal(t, "Your application – update", subject)
+	body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	assert.Equal(t, "Hello,\r\nyour request was reviewed.\r\n", string(body))
+
+	// Headers cannot be smuggled in through the subject or the recipients
+	assert.Error(t, mailer.Send(context.Background(), Message{To: []string{"owner@clinic.test"}, Subject: "Hi\r\nBcc: x@evil.test"}))
+	assert.Error(t, mailer.Send(context.Background(), Message{To: []string{"owner@clinic.test\r\nBcc: x@evil.test"}, Subject: "Hi"}))
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor records count and latency of outgoing unary gRPC calls
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		GRPCClientRequestsTotal.WithLabelValues(method, status.Code(err).String()).Inc()
+		GRPCClientRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// UnaryServerInterceptor records count and latency of handled unary gRPC calls
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		GRPCServerRequestsInFlight.Inc()
+		defer GRPCServerRequestsInFlight.Dec()
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		GRPCServerRequestsTotal.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		GRPCServerRequestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records count and duration of handled gRPC streams
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		GRPCServerRequestsInFlight.Inc()
+		defer GRPCServerRequestsInFlight.Dec()
+
+		start := time.Now()
+		err := handler(srv, stream)
+
+		GRPCServerRequestsTotal.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		GRPCServerRequestDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
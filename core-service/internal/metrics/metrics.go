@@ -0,0 +1,172 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// HTTP server metrics
+var (
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total HTTP requests by operation, method and status code",
+	}, []string{"operation", "method", "status"})
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds by operation, method and status code",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "method", "status"})
+	HTTPRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being served",
+	})
+)
+
+// RateLimitedRequests counts requests rejected by rate limiting
+var RateLimitedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_requests_rate_limited_total",
+	Help: "HTTP requests rejected by rate limiting by operation and key type",
+}, []string{"operation", "key"})
+
+// gRPC server metrics for the core gRPC API
+var (
+	GRPCServerRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_requests_total",
+		Help: "Total gRPC calls handled by method and status code",
+	}, []string{"method", "code"})
+	GRPCServerRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_request_duration_seconds",
+		Help:    "gRPC call latency in seconds by method, streams last until they end",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+	GRPCServerRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "grpc_server_requests_in_flight",
+		Help: "gRPC calls and streams currently being handled",
+	})
+)
+
+// gRPC client metrics for calls to other services
+var (
+	GRPCClientRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_client_requests_total",
+		Help: "Total outgoing gRPC calls by method and status code",
+	}, []string{"method", "code"})
+	GRPCClientRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_client_request_duration_seconds",
+		Help:    "Outgoing gRPC call latency in seconds by method",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// Database metrics
+var (
+	DBOperationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_operations_total",
+		Help: "Total database operations by operation, collection and result",
+	}, []string{"operation", "collection", "result"})
+	DBOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_operation_duration_seconds",
+		Help:    "Database operation latency in seconds by operation and collection",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "collection"})
+)
+
+// Business metrics
+var (
+	AppointmentsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "appointments_created_total",
+		Help: "Appointments created per tenant",
+	}, []string{"tenant_id"})
+	AppointmentsCancelled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "appointments_cancelled_total",
+		Help: "Appointments cancelled per tenant",
+	}, []string{"tenant_id"})
+	OnboardingRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "onboarding_requests_total",
+		Help: "Onboarding requests that entered each status",
+	}, []string{"status"})
+	RecoveryActions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "recovery_actions_total",
+		Help: "Actions taken by the stuck request recovery job",
+	}, []string{"action"})
+	ApprovalRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "onboarding_approval_retries_total",
+		Help: "Automatic retries of failed onboarding approvals by outcome",
+	}, []string{"outcome"})
+	UnverifiedRequestsExpired = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "onboarding_unverified_expired_total",
+		Help: "Onboarding requests removed because their email was not verified in time",
+	})
+	RecoveryLeader = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "recovery_leader",
+		Help: "1 while this replica holds the stuck request recovery lease, 0 otherwise",
+	})
+	LoginAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "login_attempts_total",
+		Help: "Login attempts by result",
+	}, []string{"result"})
+)
+
+// AuditWriteFailures counts mutations whose audit entry could not be written
+var AuditWriteFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "audit_write_failures_total",
+	Help: "Audit entries that could not be written by resource type",
+}, []string{"resource_type"})
+
+// InspectionFindings counts request inspection findings by operation, rule and mode
+var InspectionFindings = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_request_inspection_findings_total",
+	Help: "Request inspection findings by operation, rule and mode",
+}, []string{"operation", "rule", "mode"})
+
+// EventStreamConnections tracks open appointment event streams by transport
+var EventStreamConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "appointment_event_streams",
+	Help: "Open appointment event streams by transport",
+}, []string{"transport"})
+
+// Webhook delivery metrics
+var (
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_delivery_attempts_total",
+		Help: "Webhook delivery attempts by outcome: succeeded, retrying or failed",
+	}, []string{"outcome"})
+	WebhookEndpointsDisabled = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "webhook_endpoints_disabled_total",
+		Help: "Webhook endpoints disabled after failing for too long",
+	})
+)
+
+// Domain event outbox metrics
+var (
+	DomainEventsDispatched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "domain_events_dispatched_total",
+		Help: "Domain event dispatches by event type and outcome: delivered, retrying or failed",
+	}, []string{"event_type", "outcome"})
+)
+
+// SagaSteps counts saga steps run by saga, step and outcome: done, retrying, failed,
+// compensated or compensation_failed
+var SagaSteps = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "saga_steps_total",
+	Help: "Saga steps run by saga, step and outcome",
+}, []string{"saga", "step", "outcome"})
+
+// Handler serves all registered metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveDBOperation records the latency and outcome of a database operation
+func ObserveDBOperation(operation, collection string, duration time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	DBOperationsTotal.WithLabelValues(operation, collection, result).Inc()
+	DBOperationDuration.WithLabelValues(operation, collection).Observe(duration.Seconds())
+}
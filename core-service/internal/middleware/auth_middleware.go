@@ -1,13 +1,10 @@
 package middleware
 
 import (
-	"context"
-	"errors"
 	"log"
 	"net/http"
-	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/mrityunjay-vashisth/core-service/internal/authn"
 	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
 	"github.com/mrityunjay-vashisth/core-service/internal/models"
 	"github.com/mrityunjay-vashisth/core-service/internal/registry"
@@ -16,11 +13,9 @@ import (
 	"go.uber.org/zap"
 )
 
-// JWT secret key - should be loaded from env in production
-var jwtKey = []byte("your-secure-jwt-secret-replace-in-production")
-
-// AuthRequiredMiddleware creates a middleware that checks for valid auth token
-func AuthRequiredMiddleware(serviceRegistry registry.ServiceRegistry) func(http.Handler) http.Handler {
+// AuthRequiredMiddleware creates a middleware that checks for valid auth token.
+// Tokens are verified locally with the secret shared with the auth service.
+func AuthRequiredMiddleware(serviceRegistry registry.ServiceRegistry, jwtSecret []byte) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Extract token from Authorization header
@@ -34,16 +29,10 @@ func AuthRequiredMiddleware(serviceRegistry registry.ServiceRegistry) func(http.
 			}
 
 			// First try JWT verification (faster, doesn't require gRPC call)
-			claims, err := validateToken(authHeader)
+			claims, err := authn.ParseToken(authHeader, jwtSecret)
 			if err == nil {
-				// Create context with user claims
-				ctx := context.WithValue(r.Context(), "claims", claims)
-				ctx = context.WithValue(ctx, "username", claims.Username)
-				ctx = context.WithValue(ctx, "role", claims.Role)
-				ctx = context.WithValue(ctx, "tenantID", claims.TenantID)
-
-				// Call next handler with enriched context
-				next.ServeHTTP(w, r.WithContext(ctx))
+				// Call next handler with the user claims in the context
+				next.ServeHTTP(w, r.WithContext(authn.WithClaims(r.Context(), claims)))
 				return
 			}
 
@@ -67,26 +56,6 @@ func AuthRequiredMiddleware(serviceRegistry registry.ServiceRegistry) func(http.
 	}
 }
 
-// validateToken validates the JWT token and returns the claims
-func validateToken(tokenString string) (*models.UserClaims, error) {
-	claims := &models.UserClaims{}
-
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-
-	if err != nil || !token.Valid {
-		return nil, errors.New("invalid token")
-	}
-
-	// Check token expiration
-	if time.Now().After(claims.ExpiresAt.Time) {
-		return nil, errors.New("token expired")
-	}
-
-	return claims, nil
-}
-
 // RoleRequiredMiddleware creates a middleware that checks for specific roles
 func RoleRequiredMiddleware(requiredRoles []string, logger *zap.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
+)
+
+// ClientIPMiddleware stores the address of the caller in the request context so services
+// can record it in the audit trail
+func ClientIPMiddleware(trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := audit.WithClientIP(r.Context(), clientIP(r, trustProxy))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
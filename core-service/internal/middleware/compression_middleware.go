@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings offered by CompressionMiddleware
+const (
+	encodingGzip = "gzip"
+	encodingZstd = "zstd"
+)
+
+// compressibleTypes lists the media types worth compressing
+var compressibleTypes = map[string]bool{
+	"application/json":         true,
+	"application/problem+json": true,
+	"application/x-ndjson":     true,
+	"application/xml":          true,
+	"application/javascript":   true,
+	"application/yaml":         true,
+}
+
+// encoder is the part of the gzip and zstd writers used by the middleware
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Encoders are expensive to allocate, so they are reused across responses
+var encoderPools = map[string]*sync.Pool{
+	encodingGzip: {New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	}},
+	encodingZstd: {New: func() interface{} {
+		writer, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+		return writer
+	}},
+}
+
+// CompressionMiddleware compresses responses with zstd or gzip, whichever the client
+// prefers. Bodies smaller than minSize are sent as is, since compressing them costs more
+// than it saves. Streamed responses are compressed from their first flush.
+func CompressionMiddleware(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			compressed := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize, statusCode: http.StatusOK}
+			next.ServeHTTP(compressed, r)
+			compressed.Close()
+		})
+	}
+}
+
+// negotiateEncoding picks the coding with the highest quality in an Accept-Encoding
+// header, preferring zstd on a tie. It returns an empty string when neither is accepted.
+func negotiateEncoding(header string) string {
+	quality := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if coding == "*" {
+			wildcard = q
+			continue
+		}
+		quality[coding] = q
+	}
+
+	best, bestQuality := "", 0.0
+	for _, coding := range []string{encodingZstd, encodingGzip} {
+		q, ok := quality[coding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQuality {
+			best, bestQuality = coding, q
+		}
+	}
+	return best
+}
+
+// compressWriter holds back the start of the body until it knows whether compressing is
+// worthwhile, then writes either through an encoder or as is
+type compressWriter struct {
+	http.ResponseWriter
+	encoding   string
+	minSize    int
+	statusCode int
+
+	buffer      bytes.Buffer
+	wroteHeader bool // The handler set a status
+	decided     bool // The status was sent and the body goes through or around the encoder
+	encoder     encoder
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// WriteHeader captures the status. Responses that have no body are sent right away.
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided || cw.wroteHeader {
+		return
+	}
+	cw.statusCode = code
+	cw.wroteHeader = true
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.start(false)
+	}
+}
+
+// Write buffers the body until minSize bytes are known
+func (cw *compressWriter) Write(data []byte) (int, error) {
+	cw.wroteHeader = true
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(data)
+		}
+		return cw.ResponseWriter.Write(data)
+	}
+
+	cw.buffer.Write(data)
+	if cw.buffer.Len() >= cw.minSize {
+		if err := cw.start(cw.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// Flush sends what was written so far. A flushed response is streamed, so it is
+// compressed regardless of its size so far.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.wroteHeader = true
+		cw.start(cw.compressible())
+	}
+	if cw.encoder != nil {
+		cw.encoder.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Close finishes the response, sending small bodies uncompressed
+func (cw *compressWriter) Close() {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			return
+		}
+		cw.start(cw.buffer.Len() >= cw.minSize && cw.compressible())
+	}
+	if cw.encoder != nil {
+		cw.encoder.Close()
+		cw.encoder.Reset(io.Discard)
+		encoderPools[cw.encoding].Put(cw.encoder)
+		cw.encoder = nil
+	}
+}
+
+// compressible reports whether the response may be compressed: it must not already be
+// encoded and its content type must be text-like
+func (cw *compressWriter) compressible() bool {
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" || cw.statusCode < http.StatusOK ||
+		cw.statusCode == http.StatusNoContent || cw.statusCode == http.StatusNotModified {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(cw.buffer.Bytes())
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || compressibleTypes[mediaType]
+}
+
+// start sends the status and the buffered body, through an encoder when compress is set
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+	header := cw.Header()
+	if compress {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", cw.encoding)
+		// The compressed bytes differ from the ones the ETag was computed on
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		cw.encoder = encoderPools[cw.encoding].Get().(encoder)
+		cw.encoder.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+	if cw.buffer.Len() == 0 {
+		return nil
+	}
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(cw.buffer.Bytes())
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buffer.Bytes())
+	}
+	cw.buffer.Reset()
+	return err
+}
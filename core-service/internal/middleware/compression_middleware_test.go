@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	assert.Equal(t, "zstd", negotiateEncoding("gzip, zstd"))
+	assert.Equal(t, "gzip", negotiateEncoding("gzip;q=1, zstd;q=0.5"))
+	assert.Equal(t, "gzip", negotiateEncoding("*, zstd;q=0"))
+	assert.Equal(t, "", negotiateEncoding("br, identity"))
+	assert.Equal(t, "", negotiateEncoding(""))
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := `{"notes":"` + strings.Repeat("a", 2048) + `"}`
+	handler := CompressionMiddleware(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := large
+		if r.URL.Query().Get("small") != "" {
+			body = `{"ok":true}`
+		}
+		utility.RespondWithJSON(w, http.StatusOK, jsonRaw(body))
+	}))
+
+	// Large bodies are compressed with the preferred coding
+	req := httptest.NewRequest(http.MethodGet, "/appointments", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Empty(t, rec.Header().Get("Content-Length"))
+	reader, err := gzip.NewReader(rec.Body)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(reader)
+	assert.JSONEq(t, large, string(body))
+
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "zstd", rec.Header().Get("Content-Encoding"))
+	decoder, err := zstd.NewReader(rec.Body)
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(decoder)
+	decoder.Close()
+	assert.JSONEq(t, large, string(body))
+
+	// Small bodies are not worth compressing
+	req = httptest.NewRequest(http.MethodGet, "/appointments?small=1", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, `{"ok":true}`+"\n", rec.Body.String())
+	assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+}
+
+func TestCompressionMiddlewareStreams(t *testing.T) {
+	handler := CompressionMiddleware(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream := utility.NewJSONStream(w, r, http.StatusOK)
+		stream.Write(map[string]string{"id": "a1"})
+		stream.Write(map[string]string{"id": "a2"})
+		stream.Close()
+	}))
+
+	// A flushed stream is compressed even though it is below the threshold
+	req := httptest.NewRequest(http.MethodGet, "/appointments", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Accept", utility.NDJSONContentType)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, utility.NDJSONContentType, rec.Header().Get("Content-Type"))
+	assert.True(t, rec.Flushed)
+	reader, err := gzip.NewReader(rec.Body)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(reader)
+	assert.Equal(t, "{\"id\":\"a1\"}\n{\"id\":\"a2\"}\n", string(body))
+}
+
+// jsonRaw is a body that RespondWithJSON encodes verbatim
+type jsonRaw string
+
+func (j jsonRaw) MarshalJSON() ([]byte, error) {
+	return []byte(j), nil
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+)
+
+// ETagMiddleware adds a strong ETag to successful GET responses and answers If-None-Match
+// with 304 when the representation is unchanged. Handlers may set their own ETag, e.g. from a
+// document version; otherwise the ETag is a hash of the response body. Streamed responses
+// are passed through without an ETag once the handler flushes, as are protocol upgrades.
+func ETagMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buffered := &bufferedWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(buffered, r)
+			if buffered.streaming {
+				return
+			}
+
+			if buffered.statusCode != http.StatusOK {
+				buffered.flush()
+				return
+			}
+
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				sum := sha256.Sum256(buffered.body.Bytes())
+				etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+				w.Header().Set("ETag", etag)
+			}
+
+			if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && utility.ETagMatches(ifNoneMatch, etag, true) {
+				w.Header().Del("Content-Type")
+				w.Header().Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			buffered.flush()
+		})
+	}
+}
+
+// bufferedWriter holds back the status and body until the ETag is known
+type bufferedWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+	streaming  bool // The handler flushed, so writes go straight through
+}
+
+// WriteHeader captures the response status code
+func (bw *bufferedWriter) WriteHeader(code int) {
+	if !bw.streaming {
+		bw.statusCode = code
+	}
+}
+
+// Write buffers the response body
+func (bw *bufferedWriter) Write(data []byte) (int, error) {
+	if bw.streaming {
+		return bw.ResponseWriter.Write(data)
+	}
+	return bw.body.Write(data)
+}
+
+// Flush gives up on the ETag: the buffered part is sent and later writes go straight through
+func (bw *bufferedWriter) Flush() {
+	if !bw.streaming {
+		bw.streaming = true
+		bw.flush()
+	}
+	http.NewResponseController(bw.ResponseWriter).Flush()
+}
+
+// flush writes the buffered response through
+func (bw *bufferedWriter) flush() {
+	bw.ResponseWriter.WriteHeader(bw.statusCode)
+	bw.ResponseWriter.Write(bw.body.Bytes())
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETagMiddleware(t *testing.T) {
+	handler := ETagMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"appointment_id":"a1"}]`))
+	}))
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/appointments", nil))
+	etag := first.Header().Get("ETag")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.NotEmpty(t, etag, "GET responses should carry an ETag")
+	assert.Equal(t, `[{"appointment_id":"a1"}]`, first.Body.String())
+
+	// An unchanged representation is not sent again
+	req := httptest.NewRequest(http.MethodGet, "/appointments", nil)
+	req.Header.Set("If-None-Match", etag)
+	cached := httptest.NewRecorder()
+	handler.ServeHTTP(cached, req)
+	assert.Equal(t, http.StatusNotModified, cached.Code)
+	assert.Empty(t, cached.Body.String())
+	assert.Equal(t, etag, cached.Header().Get("ETag"))
+
+	// A stale tag gets the full response
+	req = httptest.NewRequest(http.MethodGet, "/appointments", nil)
+	req.Header.Set("If-None-Match", `"stale"`)
+	stale := httptest.NewRecorder()
+	handler.ServeHTTP(stale, req)
+	assert.Equal(t, http.StatusOK, stale.Code)
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/mrityunjay-vashisth/core-service/internal/idempotency"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"go.uber.org/zap"
+)
+
+// maxIdempotencyKeyLength bounds the Idempotency-Key header
+const maxIdempotencyKeyLength = 255
+
+// replayedHeaders are the response headers saved with an idempotent response
+var replayedHeaders = []string{"Location", "ETag", "Last-Modified"}
+
+// IdempotencyMiddleware replays the saved response when a POST, PUT or PATCH request is
+// retried with the same Idempotency-Key. Reusing a key with a different payload is
+// rejected with 422, and a retry arriving while the first request runs gets 409. A key
+// whose first request never finished is free again after idempotency.ReservationLease.
+// Server errors are not saved so the request can be retried.
+func IdempotencyMiddleware(store idempotency.Store, operationID string, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			idempotencyKey := r.Header.Get("Idempotency-Key")
+			if idempotencyKey == "" || !isMutating(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(idempotencyKey) > maxIdempotencyKeyLength {
+				utility.RespondWithError(w, http.StatusBadRequest, "Idempotency-Key must be at most "+strconv.Itoa(maxIdempotencyKeyLength)+" characters")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				utility.RespondWithError(w, http.StatusBadRequest, "Failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			requestLogger := logging.WithContext(r.Context(), logger).With(zap.String("operationId", operationID))
+			key := idempotencyScope(r, operationID) + ":" + idempotencyKey
+			fingerprint := requestFingerprint(r, body)
+
+			saved, err := store.Reserve(r.Context(), key, fingerprint, idempotency.ReservationLease)
+			if err != nil {
+				// Fail closed, running the request could create a duplicate
+				requestLogger.Error("Failed to reserve idempotency key", zap.Error(err))
+				utility.RespondWithError(w, http.StatusServiceUnavailable, "Idempotency check unavailable, retry later")
+				return
+			}
+
+			if saved != nil {
+				switch {
+				case saved.Fingerprint != fingerprint:
+					utility.RespondWithError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+				case !saved.Completed:
+					utility.RespondWithError(w, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+				default:
+					requestLogger.Info("Replaying saved response for idempotency key")
+					if saved.ContentType != "" {
+						w.Header().Set("Content-Type", saved.ContentType)
+					}
+					for name, value := range saved.Headers {
+						w.Header().Set(name, value)
+					}
+					w.Header().Set("Idempotent-Replayed", "true")
+					w.WriteHeader(saved.StatusCode)
+					w.Write(saved.Body)
+				}
+				return
+			}
+
+			// Detach from the request so a client disconnect does not leave the key reserved
+			ctx := context.WithoutCancel(r.Context())
+			release := func() {
+				if err := store.Release(ctx, key); err != nil {
+					requestLogger.Error("Failed to release idempotency key", zap.Error(err))
+				}
+			}
+
+			// A panicking handler must not hold the key until it expires. The panic is
+			// passed on to the recovery middleware.
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					release()
+					panic(recovered)
+				}
+			}()
+
+			recorder := &recordingWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			if recorder.statusCode >= http.StatusInternalServerError {
+				release()
+				return
+			}
+
+			record := idempotency.Record{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				StatusCode:  recorder.statusCode,
+				ContentType: recorder.Header().Get("Content-Type"),
+				Headers:     make(map[string]string),
+				Body:        recorder.body.Bytes(),
+			}
+			for _, name := range replayedHeaders {
+				if value := recorder.Header().Get(name); value != "" {
+					record.Headers[name] = value
+				}
+			}
+			if err := store.Complete(ctx, key, record, idempotency.DefaultTTL); err != nil {
+				requestLogger.Error("Failed to save idempotent response", zap.Error(err))
+			}
+		})
+	}
+}
+
+// isMutating reports whether requests with the method honour Idempotency-Key
+func isMutating(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
+
+// idempotencyScope keeps keys of different operations and callers apart. Anonymous
+// callers are told apart by their address so they cannot replay each other's responses.
+func idempotencyScope(r *http.Request, operationID string) string {
+	if claims, ok := r.Context().Value("claims").(*models.UserClaims); ok && claims.Username != "" {
+		return operationID + ":user:" + claims.Username
+	}
+	ip := audit.ClientIPFromContext(r.Context())
+	if ip == "" {
+		ip = clientIP(r, false)
+	}
+	return operationID + ":anonymous:" + ip
+}
+
+// requestFingerprint hashes the parts of a request that must match on a retry
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// recordingWriter captures the status code and body while writing them through
+type recordingWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// WriteHeader captures the response status code
+func (rw *recordingWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write captures the response body
+func (rw *recordingWriter) Write(data []byte) (int, error) {
+	rw.body.Write(data)
+	return rw.ResponseWriter.Write(data)
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/idempotency"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// fakeIdempotencyStore keeps records in memory
+type fakeIdempotencyStore struct {
+	mutex   sync.Mutex
+	records map[string]idempotency.Record
+}
+
+func (s *fakeIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, lease time.Duration) (*idempotency.Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if record, ok := s.records[key]; ok {
+		return &record, nil
+	}
+	s.records[key] = idempotency.Record{Fingerprint: fingerprint}
+	return nil, nil
+}
+
+func (s *fakeIdempotencyStore) Complete(ctx context.Context, key string, record idempotency.Record, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records[key] = record
+	return nil
+}
+
+func (s *fakeIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	store := &fakeIdempotencyStore{records: make(map[string]idempotency.Record)}
+	calls := 0
+	handler := IdempotencyMiddleware(store, "createAppointment", zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/appointments/a1")
+		w.Header().Set("ETag", `"v1"`)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"appointment_id":"a1"}`))
+	}))
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/appointments", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "key-1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send(`{"patient":"p1"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	// A retry with the same payload replays the saved response without calling the handler
+	retry := send(`{"patient":"p1"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "/appointments/a1", retry.Header().Get("Location"))
+	assert.Equal(t, `"v1"`, retry.Header().Get("ETag"))
+	assert.Equal(t, 1, calls, "the handler should run once")
+
+	// Reusing the key with another payload is rejected
+	reused := send(`{"patient":"p2"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyMiddlewareReleasesKeyOnPanic(t *testing.T) {
+	store := &fakeIdempotencyStore{records: make(map[string]idempotency.Record)}
+	handler := IdempotencyMiddleware(store, "createAppointment", zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failed")
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/appointments", strings.NewReader(`{"patient":"p1"}`))
+	req.Header.Set("Idempotency-Key", "key-1")
+	assert.PanicsWithValue(t, "handler failed", func() { handler.ServeHTTP(httptest.NewRecorder(), req) })
+	assert.Empty(t, store.records, "the key should be free for a retry")
+}
+
+func TestIdempotencyMiddlewareScopesAnonymousCallers(t *testing.T) {
+	store := &fakeIdempotencyStore{records: make(map[string]idempotency.Record)}
+	calls := 0
+	handler := IdempotencyMiddleware(store, "onboardTenant", zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	for _, ip := range []string{"192.0.2.1:1234", "192.0.2.2:1234"} {
+		req := httptest.NewRequest(http.MethodPost, "/onboard", strings.NewReader(`{"email":"a@example.com"}`))
+		req.RemoteAddr = ip
+		req.Header.Set("Idempotency-Key", "key-1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Empty(t, rec.Header().Get("Idempotent-Replayed"))
+	}
+	assert.Equal(t, 2, calls, "another caller's key must not replay the first response")
+}
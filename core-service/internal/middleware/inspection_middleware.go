@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/mrityunjay-vashisth/core-service/internal/inspection"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// InspectionMiddleware applies the inspection rules of an operation to its parameters and
+// JSON body. In block mode a request with findings is rejected with 400 listing them, in
+// log mode the findings are logged and in monitor mode they are only counted. It must run
+// after routing so path parameters are available.
+func InspectionMiddleware(rules *inspection.RuleSet, mode, operationID string, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := &inspection.Request{Query: r.URL.Query(), Path: mux.Vars(r)}
+
+			var findings []inspection.Finding
+			if rules.InspectsBody() && isJSON(r) {
+				body, tooLarge, err := readBody(r)
+				if err != nil {
+					utility.RespondWithProblem(w, r, logger, apperrors.Validation("invalid_body", "Failed to read request body"))
+					return
+				}
+				if tooLarge {
+					findings = append(findings, inspection.Finding{
+						Rule:     "body_size",
+						Location: inspection.LocationBody,
+						Reason:   "must not exceed 1 MiB",
+					})
+				} else if len(body) > 0 {
+					// Malformed JSON is left for the handler to reject
+					decoder := json.NewDecoder(bytes.NewReader(body))
+					decoder.UseNumber()
+					_ = decoder.Decode(&req.Body)
+				}
+			}
+			findings = append(findings, rules.Inspect(req)...)
+
+			if len(findings) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for _, finding := range findings {
+				metrics.InspectionFindings.WithLabelValues(operationID, finding.Rule, mode).Inc()
+			}
+
+			requestLogger := logging.WithContext(r.Context(), logger).With(
+				zap.String("operationId", operationID),
+				zap.String("mode", mode),
+				zap.Any("findings", findings))
+
+			switch mode {
+			case config.InspectionMonitor:
+				next.ServeHTTP(w, r)
+			case config.InspectionLog:
+				requestLogger.Warn("Request inspection findings")
+				next.ServeHTTP(w, r)
+			default:
+				requestLogger.Warn("Request rejected by inspection")
+				fields := make([]apperrors.FieldError, 0, len(findings))
+				for _, finding := range findings {
+					field := finding.Location
+					if finding.Field != "" {
+						field += "." + finding.Field
+					}
+					fields = append(fields, apperrors.FieldError{Field: field, Message: finding.Reason})
+				}
+				utility.RespondWithProblem(w, r, logger, apperrors.Validation("request_rejected", "Request failed inspection", fields...))
+			}
+		})
+	}
+}
+
+// isJSON reports whether the request carries a JSON body
+func isJSON(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		// Handlers decode bodies regardless of the declared type
+		return true
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// readBody reads up to the inspection limit and restores the body for the handler,
+// reporting whether it was larger than the limit
+func readBody(r *http.Request) ([]byte, bool, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, inspection.MaxBodyBytes+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(body) > inspection.MaxBodyBytes {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		return body, true, nil
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, false, nil
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
 	"go.uber.org/zap"
 )
 
@@ -22,17 +23,19 @@ func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 			// Create a response writer to capture the status code
 			wrappedWriter := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
-			// Attach logger to context
-			ctx := context.WithValue(r.Context(), LoggerKey, logger)
-			r = r.WithContext(ctx)
+			// Attach a logger carrying the trace ID to context
+			requestLogger := logging.WithContext(r.Context(), logger)
+			ctx := context.WithValue(r.Context(), LoggerKey, requestLogger)
+			r, info := withRequestInfo(r.WithContext(ctx))
 
 			// Call the next handler
 			next.ServeHTTP(wrappedWriter, r)
 
 			// Log request details
-			logger.Info("HTTP Request",
+			requestLogger.Info("HTTP Request",
 				zap.String("method", r.Method),
 				zap.String("path", r.URL.Path),
+				zap.String("operation", info.operationID),
 				zap.Int("status", wrappedWriter.statusCode),
 				zap.String("remote_addr", r.RemoteAddr),
 				zap.Duration("duration_ms", time.Since(startTime)),
@@ -47,6 +50,11 @@ type responseWriter struct {
 	statusCode int
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // WriteHeader captures the response status code
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
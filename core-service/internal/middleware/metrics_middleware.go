@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+)
+
+// unmatchedOperation labels requests that did not reach an operation route
+const unmatchedOperation = "unmatched"
+
+// MetricsMiddleware records request count, latency and in-flight requests.
+// Requests are labelled with the operation ID set by OperationMiddleware.
+func MetricsMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			startTime := time.Now()
+			metrics.HTTPRequestsInFlight.Inc()
+			defer metrics.HTTPRequestsInFlight.Dec()
+
+			r, info := withRequestInfo(r)
+			wrappedWriter := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(wrappedWriter, r)
+
+			operation := info.operationID
+			if operation == "" {
+				operation = unmatchedOperation
+			}
+			status := strconv.Itoa(wrappedWriter.statusCode)
+			metrics.HTTPRequestsTotal.WithLabelValues(operation, r.Method, status).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(operation, r.Method, status).Observe(time.Since(startTime).Seconds())
+		})
+	}
+}
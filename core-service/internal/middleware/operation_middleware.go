@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OperationIDKey holds the OpenAPI operation ID of the matched route
+const OperationIDKey ContextKey = "operation_id"
+
+const requestInfoKey ContextKey = "request_info"
+
+// requestInfo is filled in by route-level middleware so outer middleware
+// can label the request once the handler has run
+type requestInfo struct {
+	operationID string
+}
+
+// withRequestInfo attaches a mutable requestInfo to the request context
+func withRequestInfo(r *http.Request) (*http.Request, *requestInfo) {
+	if info, ok := r.Context().Value(requestInfoKey).(*requestInfo); ok {
+		return r, info
+	}
+	info := &requestInfo{}
+	return r.WithContext(context.WithValue(r.Context(), requestInfoKey, info)), info
+}
+
+// OperationMiddleware tags the request with the operation ID of the route it matched
+// and names the request span after it
+func OperationMiddleware(operationID string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if info, ok := r.Context().Value(requestInfoKey).(*requestInfo); ok {
+				info.operationID = operationID
+			}
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(operationID)
+			span.SetAttributes(attribute.String("operation.id", operationID))
+
+			ctx := context.WithValue(r.Context(), OperationIDKey, operationID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetOperationID returns the operation ID of the current request, if any
+func GetOperationID(ctx context.Context) string {
+	operationID, _ := ctx.Value(OperationIDKey).(string)
+	return operationID
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// accessTokenParam is the query parameter carrying a token on routes that accept one
+const accessTokenParam = "access_token"
+
+// QueryTokenMiddleware accepts the bearer token in the access_token query parameter when
+// no Authorization header is sent. Browsers cannot set headers on EventSource and WebSocket
+// requests, so only the event stream routes use it. The parameter is removed from the URL
+// so it does not reach request inspection or handlers.
+func QueryTokenMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query := r.URL.Query()
+			token := query.Get(accessTokenParam)
+			if token == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r = r.Clone(r.Context())
+			query.Del(accessTokenParam)
+			r.URL.RawQuery = query.Encode()
+			if r.Header.Get("Authorization") == "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
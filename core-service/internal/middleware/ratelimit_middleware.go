@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/authn"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+	"github.com/mrityunjay-vashisth/core-service/internal/ratelimit"
+	"go.uber.org/zap"
+)
+
+// RateLimiter enforces per-operation token bucket limits
+type RateLimiter struct {
+	store      ratelimit.Store
+	trustProxy bool // Use X-Forwarded-For for the client address
+	logger     *zap.Logger
+}
+
+// NewRateLimiter creates a rate limiter keeping its buckets in the given store
+func NewRateLimiter(store ratelimit.Store, trustProxy bool, logger *zap.Logger) *RateLimiter {
+	return &RateLimiter{
+		store:      store,
+		trustProxy: trustProxy,
+		logger:     logger,
+	}
+}
+
+// Middleware limits requests to an operation according to its policies, each of which
+// must allow the request. A rejected request gets back the tokens it took from the other
+// policies. It must run after authentication so user and tenant keys can be read from the
+// claims.
+func (l *RateLimiter) Middleware(operationID string, policies []config.RateLimitPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var advertised *ratelimit.Result
+			var taken []ratelimit.Taken
+			for _, policy := range policies {
+				key := operationID + ":" + l.key(r, policy.Key)
+				limit := ratelimit.Limit{Rate: policy.Rate(), Burst: policy.Burst}
+				result, err := l.store.Take(r.Context(), key, limit)
+				if err != nil {
+					// Fail open so an unavailable store does not take the API down
+					logging.WithContext(r.Context(), l.logger).Warn("Rate limit check failed, allowing request",
+						zap.String("operationId", operationID),
+						zap.String("key", policy.Key),
+						zap.Error(err))
+					continue
+				}
+
+				if !result.Allowed {
+					metrics.RateLimitedRequests.WithLabelValues(operationID, policy.Key).Inc()
+					if err := ratelimit.RefundAll(r.Context(), l.store, taken); err != nil {
+						logging.WithContext(r.Context(), l.logger).Warn("Failed to refund rate limit tokens",
+							zap.String("operationId", operationID),
+							zap.Error(err))
+					}
+					setRateLimitHeaders(w, policy, result)
+					w.Header().Set("Retry-After", seconds(result.RetryAfter))
+					utility.RespondWithError(w, http.StatusTooManyRequests, "Too many requests, retry later")
+					return
+				}
+
+				taken = append(taken, ratelimit.Taken{Key: key, Limit: limit})
+
+				// Advertise the limit closest to running out
+				if advertised == nil || result.Remaining < advertised.Remaining {
+					advertised = &result
+					setRateLimitHeaders(w, policy, result)
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setRateLimitHeaders advertises a limit and the state of its bucket
+func setRateLimitHeaders(w http.ResponseWriter, policy config.RateLimitPolicy, result ratelimit.Result) {
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", policy.Requests, int(policy.Period.Seconds()), policy.Burst))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", seconds(result.ResetAfter))
+}
+
+// key identifies the caller a bucket belongs to. Requests lacking the configured
+// identity fall back to the client address.
+func (l *RateLimiter) key(r *http.Request, keyType string) string {
+	switch keyType {
+	case config.RateLimitKeyUser:
+		if claims, ok := authn.ClaimsFromContext(r.Context()); ok && claims.Username != "" {
+			return "user:" + claims.Username
+		}
+	case config.RateLimitKeyTenant:
+		if claims, ok := authn.ClaimsFromContext(r.Context()); ok && claims.TenantID != "" {
+			return "tenant:" + claims.TenantID
+		}
+	case config.RateLimitKeyAPIKey:
+		// Only keys that authentication accepted, a raw header can change with every request
+		if keyID, ok := authn.APIKeyFromContext(r.Context()); ok {
+			return "api_key:" + keyID
+		}
+	case config.RateLimitKeyUsername:
+		if username := bodyUsername(r); username != "" {
+			return ratelimit.UsernameKey(username)
+		}
+	}
+	return "ip:" + clientIP(r, l.trustProxy)
+}
+
+// bodyUsername reads the username field of a JSON request body, restoring the body for
+// the handler. Bodies over the inspection limit are not decoded.
+func bodyUsername(r *http.Request) string {
+	if !isJSON(r) {
+		return ""
+	}
+	body, tooLarge, err := readBody(r)
+	if err != nil || tooLarge {
+		return ""
+	}
+	var credentials struct {
+		Username string `json:"username"`
+	}
+	if err := json.Unmarshal(body, &credentials); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(credentials.Username)
+}
+
+// clientIP returns the address of the caller, honouring X-Forwarded-For only behind a trusted proxy
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// seconds formats a duration as whole seconds, rounded up
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRateLimiterKeysLoginsByUsername(t *testing.T) {
+	limiter := NewRateLimiter(ratelimit.NewMemoryStore(), true, zap.NewNop())
+	policies := []config.RateLimitPolicy{
+		{Key: config.RateLimitKeyIP, Requests: 100, Period: time.Minute, Burst: 100},
+		{Key: config.RateLimitKeyUsername, Requests: 2, Period: time.Minute, Burst: 2},
+	}
+	handler := limiter.Middleware("loginUser", policies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Contains(t, string(body), "password", "the handler still reads the body")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	login := func(ip, username string) int {
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"`+username+`","password":"guess"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", ip)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Spreading attempts over addresses does not reset the username bucket
+	assert.Equal(t, http.StatusOK, login("10.0.0.1", "alice"))
+	assert.Equal(t, http.StatusOK, login("10.0.0.2", "Alice"))
+	assert.Equal(t, http.StatusTooManyRequests, login("10.0.0.3", "alice"))
+	assert.Equal(t, http.StatusOK, login("10.0.0.3", "bob"))
+}
+
+func TestRateLimiterIgnoresUnvalidatedAPIKeys(t *testing.T) {
+	limiter := NewRateLimiter(ratelimit.NewMemoryStore(), false, zap.NewNop())
+	policies := []config.RateLimitPolicy{{Key: config.RateLimitKeyAPIKey, Requests: 1, Period: time.Minute, Burst: 1}}
+	handler := limiter.Middleware("listWebhooks", policies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	codes := []int{}
+	for _, apiKey := range []string{"first", "second"} {
+		req := httptest.NewRequest(http.MethodGet, "/webhooks", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, codes, "a fresh header must not get a fresh bucket")
+}
+
+func TestRateLimiterRefundsRejectedRequests(t *testing.T) {
+	limiter := NewRateLimiter(ratelimit.NewMemoryStore(), true, zap.NewNop())
+	policies := []config.RateLimitPolicy{
+		{Key: config.RateLimitKeyIP, Requests: 3, Period: time.Hour, Burst: 3},
+		{Key: config.RateLimitKeyUsername, Requests: 1, Period: time.Hour, Burst: 1},
+	}
+	handler := limiter.Middleware("loginUser", policies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	login := func(username string) int {
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"`+username+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, login("alice"))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusTooManyRequests, login("alice"))
+	}
+	// Rejected attempts did not use up the address bucket
+	assert.Equal(t, http.StatusOK, login("bob"))
+	assert.Equal(t, http.StatusOK, login("carol"))
+}
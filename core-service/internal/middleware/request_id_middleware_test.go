@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/common"
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := RequestIDMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = common.GetRequestIDFromContext(r.Context())
+		utility.RespondWithError(w, http.StatusNotFound, "appointment not found")
+	}))
+
+	// A valid caller ID is kept and echoed in the header and the error body
+	req := httptest.NewRequest(http.MethodGet, "/appointments/a1", nil)
+	req.Header.Set(common.RequestIDHeader, "desk-42.retry-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var body utility.Problem
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "desk-42.retry-1", seen)
+	assert.Equal(t, "desk-42.retry-1", rec.Header().Get(common.RequestIDHeader))
+	assert.Equal(t, "desk-42.retry-1", body.RequestID)
+
+	// An unsafe caller ID is replaced with a generated one
+	req = httptest.NewRequest(http.MethodGet, "/appointments/a1", nil)
+	req.Header.Set(common.RequestIDHeader, "bad id\nInjected: 1")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.NotEqual(t, "bad id\nInjected: 1", seen)
+	assert.Len(t, seen, 36, "a UUID should be generated")
+	assert.Equal(t, seen, rec.Header().Get(common.RequestIDHeader))
+}
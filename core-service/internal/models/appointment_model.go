@@ -42,6 +42,7 @@ type Appointment struct {
 	CreatedAt     time.Time         `json:"created_at" bson:"created_at"`
 	UpdatedAt     time.Time         `json:"updated_at" bson:"updated_at"`
 	TenantID      string            `json:"tenant_id" bson:"tenant_id"`
+	Version       int64             `json:"version" bson:"version"` // Incremented on every change
 }
 
 // AppointmentCreateRequest is used to create a new appointment
@@ -63,6 +64,17 @@ type AppointmentUpdateRequest struct {
 	Type          *AppointmentType   `json:"appointment_type,omitempty"`
 	Status        *AppointmentStatus `json:"status,omitempty"`
 	Notes         *string            `json:"notes,omitempty"`
+
+	// ExpectedVersion makes the update conditional on the stored version when set
+	ExpectedVersion *int64 `json:"-"`
+}
+
+// AppointmentCancelRequest is used to cancel an existing appointment
+type AppointmentCancelRequest struct {
+	Reason string `json:"reason"`
+
+	// ExpectedVersion makes the cancellation conditional on the stored version when set
+	ExpectedVersion *int64 `json:"-"`
 }
 
 // AppointmentResponse is the response returned after appointment operations
@@ -78,4 +90,6 @@ type AppointmentResponse struct {
 	Status        AppointmentStatus `json:"status"`
 	Notes         string            `json:"notes,omitempty"`
 	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Version       int64             `json:"version"`
 }
@@ -9,28 +9,107 @@ import (
 type OnboardingStatus string
 
 const (
+	OnboardingStatusUnverified         OnboardingStatus = "unverified" // Waiting for the applicant to confirm their email
 	OnboardingStatusPending            OnboardingStatus = "pending"
 	OnboardingStatusApprovalInProgress OnboardingStatus = "approval_in_progress"
 	OnboardingStatusUserCreated        OnboardingStatus = "user_created"
 	OnboardingStatusActive             OnboardingStatus = "active"
 	OnboardingStatusFailed             OnboardingStatus = "failed"
+	OnboardingStatusRejected           OnboardingStatus = "rejected"
+	OnboardingStatusDeadLetter         OnboardingStatus = "dead_letter" // Failed too often to be retried automatically
 )
 
+// RejectionReason says why a reviewer turned an onboarding request down
+type RejectionReason string
+
+const (
+	RejectionReasonIncompleteInformation    RejectionReason = "incomplete_information"
+	RejectionReasonUnverifiableOrganization RejectionReason = "unverifiable_organization"
+	RejectionReasonDuplicate                RejectionReason = "duplicate"
+	RejectionReasonNotEligible              RejectionReason = "not_eligible"
+	RejectionReasonOther                    RejectionReason = "other"
+)
+
+// RejectionReasons lists the reasons a request can be rejected for
+var RejectionReasons = []RejectionReason{
+	RejectionReasonIncompleteInformation,
+	RejectionReasonUnverifiableOrganization,
+	RejectionReasonDuplicate,
+	RejectionReasonNotEligible,
+	RejectionReasonOther,
+}
+
 // OnboardingRequest represents a new onboarding request
 type OnboardingRequest struct {
-	OrganizationName   string           `json:"organization_name" bson:"organization_name"`
-	Email              string           `json:"email" bson:"email"`
-	Role               string           `json:"role" bson:"role"`
-	Address            string           `json:"address" bson:"address"`                         // Add this
-	PhoneNumber        string           `json:"phone_number" bson:"phone_number"`               // Add this
-	BusinessIdentifier string           `json:"business_identifier" bson:"business_identifier"` // Add this (tax ID, registration number, etc.)
-	Status             OnboardingStatus `json:"status" bson:"status"`
-	ApprovalStartedAt  *time.Time       `json:"approval_started_at,omitempty" bson:"approval_started_at,omitempty"`
-	UserCreatedAt      *time.Time       `json:"user_created_at,omitempty" bson:"user_created_at,omitempty"`
-	ApprovedAt         *time.Time       `json:"approved_at,omitempty" bson:"approved_at,omitempty"`
-	FailureReason      string           `json:"failure_reason,omitempty" bson:"failure_reason,omitempty"`
-	RetryCount         int              `json:"retry_count,omitempty" bson:"retry_count,omitempty"`
-	LastRetryAt        *time.Time       `json:"last_retry_at,omitempty" bson:"last_retry_at,omitempty"`
+	OrganizationName   string            `json:"organization_name" bson:"organization_name"`
+	Email              string            `json:"email" bson:"email"`
+	Role               string            `json:"role" bson:"role"`
+	Address            string            `json:"address" bson:"address"`                             // Add this
+	PhoneNumber        string            `json:"phone_number" bson:"phone_number"`                   // Add this
+	BusinessIdentifier string            `json:"business_identifier" bson:"business_identifier"`     // Add this (tax ID, registration number, etc.)
+	WebhookURL         string            `json:"webhook_url,omitempty" bson:"webhook_url,omitempty"` // Notified when the request is approved
+	Status             OnboardingStatus  `json:"status" bson:"status"`
+	VerificationSends  int               `json:"verification_sends,omitempty" bson:"verification_sends,omitempty"` // Verification emails sent so far
+	VerificationSentAt *time.Time        `json:"verification_sent_at,omitempty" bson:"verification_sent_at,omitempty"`
+	VerificationExpiry *time.Time        `json:"verification_expires_at,omitempty" bson:"verification_expires_at,omitempty"` // When an unverified request is removed
+	VerifiedAt         *time.Time        `json:"verified_at,omitempty" bson:"verified_at,omitempty"`
+	ApprovalID         string            `json:"approval_id,omitempty" bson:"approval_id,omitempty"` // The latest approval saga of the request
+	ApprovalStartedAt  *time.Time        `json:"approval_started_at,omitempty" bson:"approval_started_at,omitempty"`
+	UserCreatedAt      *time.Time        `json:"user_created_at,omitempty" bson:"user_created_at,omitempty"`
+	ApprovedAt         *time.Time        `json:"approved_at,omitempty" bson:"approved_at,omitempty"`
+	FailureReason      string            `json:"failure_reason,omitempty" bson:"failure_reason,omitempty"`
+	RetryCount         int               `json:"retry_count,omitempty" bson:"retry_count,omitempty"`
+	LastRetryAt        *time.Time        `json:"last_retry_at,omitempty" bson:"last_retry_at,omitempty"`
+	NextRetryAt        *time.Time        `json:"next_retry_at,omitempty" bson:"next_retry_at,omitempty"` // When a failed approval is retried
+	DeadLetteredAt     *time.Time        `json:"dead_lettered_at,omitempty" bson:"dead_lettered_at,omitempty"`
+	FailureHistory     []ApprovalFailure `json:"failure_history,omitempty" bson:"failure_history,omitempty"`
+	RejectedAt         *time.Time        `json:"rejected_at,omitempty" bson:"rejected_at,omitempty"`
+	RejectedBy         string            `json:"rejected_by,omitempty" bson:"rejected_by,omitempty"` // Username of the reviewer
+	RejectionReason    RejectionReason   `json:"rejection_reason,omitempty" bson:"rejection_reason,omitempty"`
+	RejectionComment   string            `json:"rejection_comment,omitempty" bson:"rejection_comment,omitempty"`
+	ReapplyAfter       *time.Time        `json:"reapply_after,omitempty" bson:"reapply_after,omitempty"` // When the email may apply again
+}
+
+// ApprovalFailure records one failed approval of an onboarding request
+type ApprovalFailure struct {
+	Attempt    int       `json:"attempt" bson:"attempt"`
+	ApprovalID string    `json:"approval_id,omitempty" bson:"approval_id,omitempty"`
+	Reason     string    `json:"reason" bson:"reason"`
+	FailedAt   time.Time `json:"failed_at" bson:"failed_at"`
+}
+
+// OnboardingRejection turns an onboarding request down
+type OnboardingRejection struct {
+	RequestID  string          `json:"request_id"`
+	ReasonCode RejectionReason `json:"reason_code"`
+	Comment    string          `json:"comment,omitempty"` // Shown to the applicant
+}
+
+// OnboardingReceipt acknowledges a submitted onboarding request
+type OnboardingReceipt struct {
+	RequestID             string           `json:"request_id"`
+	Status                OnboardingStatus `json:"status"`
+	VerificationExpiresAt time.Time        `json:"verification_expires_at"`  // The email must be verified before then
+	WebhookSecret         string           `json:"webhook_secret,omitempty"` // Signs deliveries to the webhook URL, shown only once
+}
+
+// EmailVerification confirms the email of an onboarding request with the token sent to it
+type EmailVerification struct {
+	Token string `json:"token"`
+}
+
+// EmailVerificationResult is the state of an onboarding request once its email is verified
+type EmailVerificationResult struct {
+	RequestID  string           `json:"request_id"`
+	Status     OnboardingStatus `json:"status"`
+	VerifiedAt *time.Time       `json:"verified_at,omitempty"`
+}
+
+// VerificationResend asks for the verification email of an onboarding request again. The
+// email must match the request, so request IDs alone cannot be used to send mail.
+type VerificationResend struct {
+	RequestID string `json:"request_id"`
+	Email     string `json:"email"`
 }
 
 // EntityMetadata represents the structure of an onboarding request stored in MongoDB
@@ -0,0 +1,17 @@
+package models
+
+// WebhookEndpointRequest registers a webhook endpoint of a tenant
+type WebhookEndpointRequest struct {
+	URL         string   `json:"url"`
+	EventTypes  []string `json:"event_types"`
+	Description string   `json:"description,omitempty"`
+}
+
+// WebhookEndpointUpdateRequest changes a webhook endpoint. Omitted fields keep their value;
+// enabling a disabled endpoint clears its failures.
+type WebhookEndpointUpdateRequest struct {
+	URL         *string  `json:"url,omitempty"`
+	EventTypes  []string `json:"event_types,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Enabled     *bool    `json:"enabled,omitempty"`
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval controls how often idle buckets are dropped from memory
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory. Each replica limits independently.
+type MemoryStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	fullAt time.Time // After this time the bucket is full and can be forgotten
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]memoryBucket),
+		now:     time.Now,
+	}
+}
+
+// Take consumes a token from the bucket of the given key
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	updated, result := s.buckets[key].take(limit, now)
+	s.buckets[key] = memoryBucket{bucket: updated, fullAt: now.Add(result.ResetAfter)}
+	return result, nil
+}
+
+// Refund gives back a token to the bucket of the given key
+func (s *MemoryStore) Refund(ctx context.Context, key string, limit Limit) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, ok := s.buckets[key]
+	if !ok {
+		return nil // Forgotten buckets are full
+	}
+	now := s.now()
+	updated, resetAfter := current.refund(limit, now)
+	s.buckets[key] = memoryBucket{bucket: updated, fullAt: now.Add(resetAfter)}
+	return nil
+}
+
+// sweep removes buckets that have refilled completely, since they are
+// indistinguishable from a new bucket
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.After(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
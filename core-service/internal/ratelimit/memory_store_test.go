@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+
+	// The burst is available straight away
+	for i := 0; i < 2; i++ {
+		result, err := store.Take(context.Background(), "key", limit)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed, "request %d should be allowed", i)
+	}
+
+	result, err := store.Take(context.Background(), "key", limit)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed, "request beyond the burst should be rejected")
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	// Other keys have their own bucket
+	result, _ = store.Take(context.Background(), "other", limit)
+	assert.True(t, result.Allowed, "a different key should not be limited")
+
+	// Tokens refill over time
+	now = now.Add(time.Second)
+	result, _ = store.Take(context.Background(), "key", limit)
+	assert.True(t, result.Allowed, "a refilled token should be allowed")
+}
@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxAttempts bounds the retries when concurrent requests update the same bucket
+const maxAttempts = 5
+
+// MongoStore keeps buckets in a shared collection so every replica enforces the same limit.
+// Updates use optimistic concurrency on the bucket's last update time.
+type MongoStore struct {
+	db  db.DBClientInterface
+	now func() time.Time
+}
+
+// NewMongoStore creates a store backed by the rate_limits collection and makes
+// sure idle buckets expire
+func NewMongoStore(ctx context.Context, database db.DBClientInterface) (*MongoStore, error) {
+	store := &MongoStore{db: database, now: time.Now}
+	if err := database.EnsureTTLIndex(ctx, "expires_at", 0, store.options()...); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *MongoStore) options() []db.DBOption {
+	return []db.DBOption{
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.RateLimits),
+	}
+}
+
+// Take consumes a token from the shared bucket of the given key
+func (s *MongoStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		// MongoDB stores dates with millisecond precision
+		now := s.now().UTC().Truncate(time.Millisecond)
+
+		doc, err := s.db.Read(ctx, bson.M{"_id": key}, s.options()...)
+		if err != nil {
+			return Result{}, err
+		}
+
+		current, found := decodeBucket(doc)
+		updated, result := current.take(limit, now)
+		state := map[string]interface{}{
+			"tokens":     updated.Tokens,
+			"updated_at": updated.UpdatedAt,
+			"expires_at": now.Add(result.ResetAfter),
+		}
+
+		if !found {
+			state["_id"] = key
+			_, err := s.db.Create(ctx, state, s.options()...)
+			if mongo.IsDuplicateKeyError(err) {
+				continue // Another replica created the bucket first
+			}
+			if err != nil {
+				return Result{}, err
+			}
+			return result, nil
+		}
+
+		modified, err := s.db.UpdateOne(ctx,
+			bson.M{"_id": key, "updated_at": current.UpdatedAt},
+			bson.M{"$set": state},
+			s.options()...,
+		)
+		if err != nil {
+			return Result{}, err
+		}
+		if modified == 1 {
+			return result, nil
+		}
+	}
+	return Result{}, errors.New("rate limit bucket is under contention")
+}
+
+// Refund gives back a token to the shared bucket of the given key
+func (s *MongoStore) Refund(ctx context.Context, key string, limit Limit) error {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		now := s.now().UTC().Truncate(time.Millisecond)
+		doc, err := s.db.Read(ctx, bson.M{"_id": key}, s.options()...)
+		if err != nil {
+			return err
+		}
+		current, found := decodeBucket(doc)
+		if !found {
+			return nil // Expired buckets are full
+		}
+		updated, resetAfter := current.refund(limit, now)
+		modified, err := s.db.UpdateOne(ctx,
+			bson.M{"_id": key, "updated_at": current.UpdatedAt},
+			bson.M{"$set": bson.M{
+				"tokens":     updated.Tokens,
+				"updated_at": updated.UpdatedAt,
+				"expires_at": now.Add(resetAfter),
+			}},
+			s.options()...,
+		)
+		if err != nil {
+			return err
+		}
+		if modified == 1 {
+			return nil
+		}
+	}
+	return errors.New("rate limit bucket is under contention")
+}
+
+// decodeBucket reads a bucket document returned by the database
+func decodeBucket(doc interface{}) (bucket, bool) {
+	fields, ok := doc.(map[string]interface{})
+	if !ok || len(fields) == 0 {
+		return bucket{}, false
+	}
+
+	tokens, _ := fields["tokens"].(float64)
+	updatedAt, ok := fields["updated_at"].(primitive.DateTime)
+	if !ok {
+		return bucket{}, false
+	}
+	return bucket{Tokens: tokens, UpdatedAt: updatedAt.Time().UTC()}, true
+}
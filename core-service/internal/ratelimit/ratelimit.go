@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"strings"
+	"time"
+)
+
+// Limit describes a token bucket holding Burst tokens that refills at Rate tokens per second
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result reports the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int           // Bucket capacity
+	Remaining  int           // Tokens left after this request
+	ResetAfter time.Duration // Time until the bucket is full again
+	RetryAfter time.Duration // Time until a token is available when the request was rejected
+}
+
+// Store keeps the buckets of all keys. Implementations must be safe for concurrent use.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Refund gives back a token taken for a request that another limit then rejected
+	Refund(ctx context.Context, key string, limit Limit) error
+}
+
+// Taken is a token a request took from the bucket of a key
+type Taken struct {
+	Key   string
+	Limit Limit
+}
+
+// RefundAll gives back the tokens a request took before another of its limits rejected it,
+// so a rejected request costs nothing. It tries every bucket and returns the first error.
+func RefundAll(ctx context.Context, store Store, taken []Taken) error {
+	var first error
+	for _, token := range taken {
+		if err := store.Refund(ctx, token.Key, token.Limit); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// UsernameKey returns the bucket key of a username sent in a request. Case is folded so
+// variants of one username share a bucket, and the name is hashed to keep it out of the store.
+func UsernameKey(username string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(username))))
+	return "username:" + hex.EncodeToString(sum[:8])
+}
+
+// bucket is the persisted state of a single token bucket
+type bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// take refills the bucket for the time elapsed since its last update and
+// consumes one token if available
+func (b bucket) take(limit Limit, now time.Time) (bucket, Result) {
+	capacity := float64(limit.Burst)
+	tokens := b.refilled(limit, now)
+
+	result := Result{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = durationFor(1-tokens, limit.Rate)
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.ResetAfter = durationFor(capacity-tokens, limit.Rate)
+	return bucket{Tokens: tokens, UpdatedAt: now}, result
+}
+
+// refund refills the bucket for the time elapsed since its last update and gives back
+// one token. It returns the bucket and the time until it is full again.
+func (b bucket) refund(limit Limit, now time.Time) (bucket, time.Duration) {
+	capacity := float64(limit.Burst)
+	tokens := math.Min(capacity, b.refilled(limit, now)+1)
+	return bucket{Tokens: tokens, UpdatedAt: now}, durationFor(capacity-tokens, limit.Rate)
+}
+
+// refilled returns the tokens in the bucket at the given time. A new bucket is full.
+func (b bucket) refilled(limit Limit, now time.Time) float64 {
+	capacity := float64(limit.Burst)
+	if b.UpdatedAt.IsZero() {
+		return capacity
+	}
+	elapsed := now.Sub(b.UpdatedAt).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(capacity, b.Tokens+elapsed*limit.Rate)
+}
+
+// durationFor returns how long the bucket takes to gain the given number of tokens
+func durationFor(tokens float64, rate float64) time.Duration {
+	if tokens <= 0 || rate <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / rate * float64(time.Second))
+}
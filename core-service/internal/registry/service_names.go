@@ -9,4 +9,10 @@ const (
 	AdminService             = "admin_service"
 	OnbardingRecoveryService = "onboarding_recovery_service"
 	ReceptionService         = "reception_service"
+	HealthService            = "health_service"
+	AuditService             = "audit_service"
+	AppointmentEvents        = "appointment_events"
+	WebhookService           = "webhook_service"
+	DomainEvents             = "domain_events"
+	Sagas                    = "sagas"
 )
@@ -0,0 +1,131 @@
+package saga
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MongoStore keeps sagas in coredb, keyed by saga ID
+type MongoStore struct {
+	db db.DBClientInterface
+}
+
+// NewMongoStore creates a saga store backed by the sagas collection
+func NewMongoStore(database db.DBClientInterface) *MongoStore {
+	return &MongoStore{db: database}
+}
+
+// EnsureRetention makes the database expire sagas once they finished longer than
+// retention ago. Unfinished sagas have no completion time and are kept.
+func (s *MongoStore) EnsureRetention(ctx context.Context, retention time.Duration) error {
+	return s.db.EnsureTTLIndex(ctx, "completed_at", retention, s.sagas()...)
+}
+
+func (s *MongoStore) sagas(extra ...db.DBOption) []db.DBOption {
+	return append([]db.DBOption{
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.Sagas),
+	}, extra...)
+}
+
+func (s *MongoStore) Create(ctx context.Context, saga Instance) error {
+	doc, err := encodeInstance(saga)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Create(ctx, doc, s.sagas()...)
+	return err
+}
+
+func (s *MongoStore) Get(ctx context.Context, id string) (*Instance, error) {
+	doc, err := s.db.Read(ctx, bson.M{"_id": id}, s.sagas()...)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := doc.(map[string]interface{})
+	if len(result) == 0 {
+		return nil, ErrNotFound
+	}
+	return decodeInstance(result)
+}
+
+func (s *MongoStore) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int64) ([]Instance, error) {
+	results, err := s.db.ReadAll(ctx,
+		bson.M{
+			"status":          bson.M{"$in": []string{StatusRunning, StatusCompensating}},
+			"next_attempt_at": bson.M{"$lte": now},
+		},
+		s.sagas(db.WithSort("next_attempt_at", 1), db.WithLimit(limit))...)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, _ := results.([]map[string]interface{})
+	claimed := make([]Instance, 0, len(docs))
+	for _, doc := range docs {
+		// Moving the next attempt past the lease claims the saga; an orchestrator that read
+		// the same document first leaves nothing to modify
+		until := now.Add(lease).UTC()
+		leased, err := s.db.UpdateOne(ctx,
+			bson.M{"_id": doc["_id"], "next_attempt_at": doc["next_attempt_at"]},
+			bson.M{"$set": bson.M{"next_attempt_at": until}},
+			s.sagas()...)
+		if err != nil {
+			return claimed, err
+		}
+		if leased == 0 {
+			continue
+		}
+		saga, err := decodeInstance(doc)
+		if err != nil {
+			return claimed, err
+		}
+		// Saving progress keeps the saga leased
+		saga.NextAttemptAt = until
+		claimed = append(claimed, *saga)
+	}
+	return claimed, nil
+}
+
+func (s *MongoStore) Save(ctx context.Context, saga Instance) error {
+	doc, err := encodeInstance(saga)
+	if err != nil {
+		return err
+	}
+	delete(doc, "_id")
+	_, err = s.db.UpdateOne(ctx, bson.M{"_id": saga.ID}, bson.M{"$set": doc}, s.sagas()...)
+	return err
+}
+
+// encodeInstance turns a saga into the document stored for it
+func encodeInstance(saga Instance) (map[string]interface{}, error) {
+	data, err := bson.Marshal(saga)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// decodeInstance reads a saga document returned by the database
+func decodeInstance(doc map[string]interface{}) (*Instance, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var saga Instance
+	if err := bson.Unmarshal(data, &saga); err != nil {
+		return nil, err
+	}
+	saga.NextAttemptAt = saga.NextAttemptAt.UTC()
+	saga.CreatedAt = saga.CreatedAt.UTC()
+	saga.UpdatedAt = saga.UpdatedAt.UTC()
+	return &saga, nil
+}
@@ -0,0 +1,248 @@
+package saga
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+	"github.com/mrityunjay-vashisth/core-service/internal/worker"
+	"github.com/mrityunjay-vashisth/go-idforge/pkg/idforge"
+	"go.uber.org/zap"
+)
+
+// runBatch is how many due sagas an orchestrator claims at a time
+const runBatch = 20
+
+// stepTimeout bounds the time one attempt of a step or compensation may take
+const stepTimeout = 30 * time.Second
+
+// claimLease keeps a claimed batch from other orchestrators while its sagas run one after
+// another
+const claimLease = 5 * time.Minute
+
+// maxBackoff caps the wait between two attempts of a step
+const maxBackoff = 10 * time.Minute
+
+// Orchestrator runs the sagas in its store. Several orchestrators may share a store; each
+// batch of sagas is leased to one of them.
+type Orchestrator struct {
+	// Runs RunDue. Sagas being run when it stops are leased and run again by a later run.
+	*worker.Loop
+
+	store       Store
+	logger      *zap.Logger
+	maxAttempts int
+	retryDelay  time.Duration // Before the first retry of a step, doubling with every further one
+	now         func() time.Time
+
+	definitions map[string]Definition
+}
+
+// NewOrchestrator creates an orchestrator of the sagas in store
+func NewOrchestrator(store Store, settings config.SagasConfig, logger *zap.Logger) *Orchestrator {
+	o := &Orchestrator{
+		store:       store,
+		logger:      logger,
+		maxAttempts: settings.MaxAttempts,
+		retryDelay:  settings.RetryBaseDelay,
+		now:         time.Now,
+		definitions: make(map[string]Definition),
+	}
+	o.Loop = worker.NewLoop("Saga orchestrator", settings.PollInterval, o.RunDue, logger)
+	return o
+}
+
+// Register makes a saga definition runnable. Definitions are registered before Start.
+func (o *Orchestrator) Register(definition Definition) {
+	o.definitions[definition.Name] = definition
+}
+
+// Begin stores a new saga of a registered definition and has it run right away. The saga
+// runs in the background; Get reports its progress.
+func (o *Orchestrator) Begin(ctx context.Context, name, key string, data map[string]string) (*Instance, error) {
+	definition, ok := o.definitions[name]
+	if !ok {
+		return nil, fmt.Errorf("saga %q is not registered", name)
+	}
+
+	now := o.now().UTC()
+	saga := Instance{
+		ID:            idforge.GenerateWithSize(20),
+		Saga:          name,
+		Key:           key,
+		Status:        StatusRunning,
+		Data:          data,
+		Steps:         make([]StepState, 0, len(definition.Steps)),
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if saga.Data == nil {
+		saga.Data = map[string]string{}
+	}
+	for _, step := range definition.Steps {
+		saga.Steps = append(saga.Steps, StepState{Name: step.Name, Status: StepPending})
+	}
+	if err := o.store.Create(ctx, saga); err != nil {
+		return nil, err
+	}
+
+	o.Wake()
+	return &saga, nil
+}
+
+// Get returns a saga, ErrNotFound if there is none with the ID
+func (o *Orchestrator) Get(ctx context.Context, id string) (*Instance, error) {
+	return o.store.Get(ctx, id)
+}
+
+// RunDue runs every saga whose next attempt is due until it finishes or has to wait
+func (o *Orchestrator) RunDue(ctx context.Context) error {
+	for {
+		due, err := o.store.ClaimDue(ctx, o.now(), claimLease, runBatch)
+		if err != nil {
+			return fmt.Errorf("failed to claim due sagas: %w", err)
+		}
+		for _, saga := range due {
+			o.advance(ctx, saga)
+		}
+		if len(due) < runBatch {
+			break
+		}
+	}
+	return nil
+}
+
+// advance runs the steps, or compensations, of a claimed saga one after another, saving
+// it after each, until it finishes or a step has to be retried later
+func (o *Orchestrator) advance(ctx context.Context, saga Instance) {
+	logger := o.logger.With(zap.String("saga_id", saga.ID), zap.String("saga", saga.Saga), zap.String("key", saga.Key))
+	definition, ok := o.definitions[saga.Saga]
+	if !ok || len(definition.Steps) != len(saga.Steps) {
+		// Left for a replica that knows the saga; a deployment may be rolling out
+		logger.Error("Saga is not registered on this replica")
+		return
+	}
+
+	for !saga.Finished() {
+		wait := o.step(ctx, logger, definition, &saga)
+		saga.UpdatedAt = o.now().UTC()
+		if wait {
+			saga.NextAttemptAt = saga.UpdatedAt.Add(o.backoff(saga))
+		}
+		if saga.Finished() {
+			saga.CompletedAt = &saga.UpdatedAt
+		}
+		// Saving failed means the saga runs again from its last saved step once its lease
+		// ends, which idempotent steps allow
+		if err := o.store.Save(ctx, saga); err != nil {
+			logger.Error("Failed to save saga progress", zap.Error(err))
+			return
+		}
+		if wait {
+			return
+		}
+	}
+
+	if saga.Status == StatusCompleted {
+		logger.Info("Saga completed")
+	} else {
+		logger.Warn("Saga finished without completing", zap.String("status", saga.Status), zap.String("error", saga.Error))
+	}
+}
+
+// step runs the next step or compensation of a saga, reporting whether it failed and has
+// to be attempted again later
+func (o *Orchestrator) step(ctx context.Context, logger *zap.Logger, definition Definition, saga *Instance) bool {
+	now := o.now().UTC()
+
+	if saga.Status == StatusRunning {
+		if saga.Current >= len(definition.Steps) {
+			saga.Status = StatusCompleted
+			return false
+		}
+		step, state := definition.Steps[saga.Current], &saga.Steps[saga.Current]
+		state.Attempts++
+		err := o.call(ctx, step.Action, saga)
+		if err == nil {
+			state.Status = StepDone
+			state.LastError = ""
+			state.CompletedAt = &now
+			saga.Current++
+			metrics.SagaSteps.WithLabelValues(saga.Saga, step.Name, "done").Inc()
+			return false
+		}
+
+		state.LastError = err.Error()
+		if !IsPermanent(err) && state.Attempts < o.maxAttempts {
+			logger.Warn("Saga step failed, retrying later", zap.String("step", step.Name), zap.Int("attempts", state.Attempts), zap.Error(err))
+			metrics.SagaSteps.WithLabelValues(saga.Saga, step.Name, "retrying").Inc()
+			return true
+		}
+		logger.Error("Saga step failed, compensating", zap.String("step", step.Name), zap.Int("attempts", state.Attempts), zap.Error(err))
+		metrics.SagaSteps.WithLabelValues(saga.Saga, step.Name, "failed").Inc()
+		state.Status = StepFailed
+		saga.Status = StatusCompensating
+		saga.Error = step.Name + ": " + err.Error()
+		saga.Current--
+		return false
+	}
+
+	// Compensating: undo the steps that are done, the latest first
+	if saga.Current < 0 {
+		saga.Status = StatusCompensated
+		return false
+	}
+	step, state := definition.Steps[saga.Current], &saga.Steps[saga.Current]
+	if step.Compensate == nil || state.Status != StepDone {
+		saga.Current--
+		return false
+	}
+	state.CompensationAttempts++
+	err := o.call(ctx, step.Compensate, saga)
+	if err == nil {
+		state.Status = StepCompensated
+		state.LastError = ""
+		saga.Current--
+		metrics.SagaSteps.WithLabelValues(saga.Saga, step.Name, "compensated").Inc()
+		return false
+	}
+
+	state.LastError = err.Error()
+	if !IsPermanent(err) && state.CompensationAttempts < o.maxAttempts {
+		logger.Warn("Saga compensation failed, retrying later", zap.String("step", step.Name), zap.Int("attempts", state.CompensationAttempts), zap.Error(err))
+		metrics.SagaSteps.WithLabelValues(saga.Saga, step.Name, "retrying").Inc()
+		return true
+	}
+	logger.Error("Saga compensation failed for good", zap.String("step", step.Name), zap.Error(err))
+	metrics.SagaSteps.WithLabelValues(saga.Saga, step.Name, "compensation_failed").Inc()
+	saga.Status = StatusFailed
+	saga.Error += "; compensating " + step.Name + ": " + err.Error()
+	return false
+}
+
+// call runs an action or compensation, turning a panic into an error so it cannot stop
+// the orchestrator
+func (o *Orchestrator) call(ctx context.Context, fn func(context.Context, *Instance) error, saga *Instance) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, stepTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("step panicked: %v", r)
+		}
+	}()
+	return fn(ctx, saga)
+}
+
+// backoff returns the wait before the next attempt of the current step of a saga: the
+// base delay doubled for every attempt after the first
+func (o *Orchestrator) backoff(saga Instance) time.Duration {
+	state := saga.Steps[saga.Current]
+	attempts := state.Attempts
+	if saga.Status == StatusCompensating {
+		attempts = state.CompensationAttempts
+	}
+	return worker.Backoff(o.retryDelay, maxBackoff, attempts)
+}
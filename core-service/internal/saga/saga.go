@@ -0,0 +1,118 @@
+// Package saga runs processes that span several services as a sequence of steps. The
+// state of every saga is stored after each step, so a saga carries on where it stopped
+// after a failure or a restart, on whichever replica claims it next.
+//
+// A step that fails is retried with backoff, so actions must be idempotent: running one
+// again after it took effect must succeed without repeating the effect. A step that fails
+// for good, because it returned a Permanent error or ran out of attempts, makes the saga
+// compensate: the steps done so far are undone in reverse order.
+package saga
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Saga statuses
+const (
+	StatusRunning      = "running"
+	StatusCompensating = "compensating" // A step failed for good; earlier steps are being undone
+	StatusCompleted    = "completed"
+	StatusCompensated  = "compensated" // A step failed for good and earlier steps were undone
+	StatusFailed       = "failed"      // A compensation failed for good; an operator has to step in
+)
+
+// Step statuses
+const (
+	StepPending     = "pending"
+	StepDone        = "done"
+	StepFailed      = "failed"
+	StepCompensated = "compensated"
+)
+
+// ErrNotFound is returned for a saga that does not exist
+var ErrNotFound = errors.New("saga not found")
+
+// Step is one action of a saga and the compensation that undoes it
+type Step struct {
+	Name string
+	// Action takes the step's effect. It may change the saga's Data, which is saved with
+	// the step.
+	Action func(ctx context.Context, saga *Instance) error
+	// Compensate undoes the action of a step that is done. Nil when there is nothing to
+	// undo.
+	Compensate func(ctx context.Context, saga *Instance) error
+}
+
+// Definition names a saga and lists its steps in order
+type Definition struct {
+	Name  string
+	Steps []Step
+}
+
+// StepState is the progress of a step of a saga
+type StepState struct {
+	Name                 string     `json:"name" bson:"name"`
+	Status               string     `json:"status" bson:"status"`
+	Attempts             int        `json:"attempts" bson:"attempts"`
+	CompensationAttempts int        `json:"compensation_attempts,omitempty" bson:"compensation_attempts"`
+	LastError            string     `json:"last_error,omitempty" bson:"last_error"`
+	CompletedAt          *time.Time `json:"completed_at,omitempty" bson:"completed_at"`
+}
+
+// Instance is a running or finished saga
+type Instance struct {
+	ID            string            `json:"id" bson:"_id"`
+	Saga          string            `json:"saga" bson:"saga"`
+	Key           string            `json:"key" bson:"key"` // What the saga works on, such as a request ID
+	Status        string            `json:"status" bson:"status"`
+	Data          map[string]string `json:"data,omitempty" bson:"data"`
+	Steps         []StepState       `json:"steps" bson:"steps"`
+	Current       int               `json:"current_step" bson:"current_step"` // Index of the step to run or compensate next
+	Error         string            `json:"error,omitempty" bson:"error"`     // Why the saga compensated or failed
+	NextAttemptAt time.Time         `json:"next_attempt_at" bson:"next_attempt_at"`
+	CreatedAt     time.Time         `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at" bson:"updated_at"`
+	CompletedAt   *time.Time        `json:"completed_at,omitempty" bson:"completed_at"`
+}
+
+// Finished reports whether the saga has nothing left to do
+func (i Instance) Finished() bool {
+	return i.Status != StatusRunning && i.Status != StatusCompensating
+}
+
+// Store keeps sagas
+type Store interface {
+	Create(ctx context.Context, saga Instance) error
+	// Get returns ErrNotFound for an unknown ID
+	Get(ctx context.Context, id string) (*Instance, error)
+	// ClaimDue leases up to limit unfinished sagas whose next attempt is due, oldest
+	// first, so no other orchestrator runs them until the lease ends
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int64) ([]Instance, error)
+	// Save stores the progress of a claimed saga
+	Save(ctx context.Context, saga Instance) error
+}
+
+// permanentError is an error that retrying the step cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error of a step as one retrying cannot fix, so the saga compensates
+// without retrying the step
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
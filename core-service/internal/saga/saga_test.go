@@ -0,0 +1,169 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryStore keeps sagas in memory for tests
+type memoryStore struct {
+	mu    sync.Mutex
+	sagas map[string]Instance
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{sagas: map[string]Instance{}}
+}
+
+func (s *memoryStore) Create(_ context.Context, saga Instance) error {
+	return s.Save(context.Background(), saga)
+}
+
+func (s *memoryStore) Get(_ context.Context, id string) (*Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saga, ok := s.sagas[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &saga, nil
+}
+
+func (s *memoryStore) ClaimDue(_ context.Context, now time.Time, lease time.Duration, limit int64) ([]Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []Instance
+	for id, saga := range s.sagas {
+		if int64(len(due)) == limit {
+			break
+		}
+		if !saga.Finished() && !saga.NextAttemptAt.After(now) {
+			saga.NextAttemptAt = now.Add(lease)
+			s.sagas[id] = saga
+			due = append(due, saga)
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) Save(_ context.Context, saga Instance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saga.Steps = append([]StepState(nil), saga.Steps...)
+	s.sagas[saga.ID] = saga
+	return nil
+}
+
+// newTestOrchestrator returns an orchestrator whose clock is controlled by the test
+func newTestOrchestrator(clock *time.Time) *Orchestrator {
+	settings := config.SagasConfig{
+		PollInterval:   time.Second,
+		MaxAttempts:    3,
+		RetryBaseDelay: time.Minute,
+	}
+	orchestrator := NewOrchestrator(newMemoryStore(), settings, zap.NewNop())
+	orchestrator.now = func() time.Time { return *clock }
+	return orchestrator
+}
+
+func TestOrchestratorRetriesAndCompletes(t *testing.T) {
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	orchestrator := newTestOrchestrator(&clock)
+	ctx := context.Background()
+
+	calls := 0
+	orchestrator.Register(Definition{Name: "test", Steps: []Step{
+		{Name: "first", Action: func(_ context.Context, saga *Instance) error {
+			saga.Data["first"] = "done"
+			return nil
+		}},
+		{Name: "second", Action: func(_ context.Context, saga *Instance) error {
+			calls++
+			if calls == 1 {
+				return errors.New("unavailable")
+			}
+			return nil
+		}},
+	}})
+
+	started, err := orchestrator.Begin(ctx, "test", "key", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, orchestrator.RunDue(ctx))
+
+	saga, _ := orchestrator.Get(ctx, started.ID)
+	assert.Equal(t, StatusRunning, saga.Status)
+	assert.Equal(t, 1, saga.Current)
+	assert.Equal(t, "unavailable", saga.Steps[1].LastError)
+	assert.Equal(t, clock.Add(time.Minute), saga.NextAttemptAt)
+
+	// Not due before the backoff ends
+	assert.NoError(t, orchestrator.RunDue(ctx))
+	assert.Equal(t, 1, calls)
+
+	clock = clock.Add(time.Minute)
+	assert.NoError(t, orchestrator.RunDue(ctx))
+	saga, _ = orchestrator.Get(ctx, started.ID)
+	assert.Equal(t, StatusCompleted, saga.Status)
+	assert.Equal(t, "done", saga.Data["first"])
+	assert.Equal(t, 2, saga.Steps[1].Attempts)
+	assert.NotNil(t, saga.CompletedAt)
+
+	_, err = orchestrator.Begin(ctx, "unknown", "key", nil)
+	assert.Error(t, err)
+}
+
+func TestOrchestratorCompensates(t *testing.T) {
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	orchestrator := newTestOrchestrator(&clock)
+	ctx := context.Background()
+
+	var undone []string
+	undo := func(name string) func(context.Context, *Instance) error {
+		return func(context.Context, *Instance) error {
+			undone = append(undone, name)
+			return nil
+		}
+	}
+	orchestrator.Register(Definition{Name: "test", Steps: []Step{
+		{Name: "first", Action: func(context.Context, *Instance) error { return nil }, Compensate: undo("first")},
+		{Name: "second", Action: func(context.Context, *Instance) error { return nil }, Compensate: undo("second")},
+		{Name: "third", Action: func(context.Context, *Instance) error {
+			return Permanent(errors.New("rejected"))
+		}, Compensate: undo("third")},
+	}})
+
+	started, _ := orchestrator.Begin(ctx, "test", "key", nil)
+	assert.NoError(t, orchestrator.RunDue(ctx))
+
+	saga, _ := orchestrator.Get(ctx, started.ID)
+	assert.Equal(t, StatusCompensated, saga.Status)
+	assert.Equal(t, "third: rejected", saga.Error)
+	assert.Equal(t, []string{"second", "first"}, undone)
+	assert.Equal(t, 1, saga.Steps[2].Attempts)
+	assert.Equal(t, StepFailed, saga.Steps[2].Status)
+	assert.Equal(t, StepCompensated, saga.Steps[0].Status)
+
+	// A compensation that keeps failing leaves the saga to an operator
+	orchestrator.Register(Definition{Name: "stuck", Steps: []Step{
+		{Name: "first", Action: func(context.Context, *Instance) error { return nil }, Compensate: func(context.Context, *Instance) error {
+			panic("boom")
+		}},
+		{Name: "second", Action: func(context.Context, *Instance) error { return Permanent(errors.New("rejected")) }},
+	}})
+	started, _ = orchestrator.Begin(ctx, "stuck", "key", nil)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, orchestrator.RunDue(ctx))
+		clock = clock.Add(time.Hour)
+	}
+	saga, _ = orchestrator.Get(ctx, started.ID)
+	assert.Equal(t, StatusFailed, saga.Status)
+	assert.Equal(t, 3, saga.Steps[0].CompensationAttempts)
+	assert.Contains(t, saga.Error, "step panicked: boom")
+}
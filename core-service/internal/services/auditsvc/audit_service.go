@@ -0,0 +1,149 @@
+package auditsvc
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
+	"github.com/mrityunjay-vashisth/core-service/internal/common"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/domainevents"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/mrityunjay-vashisth/core-service/internal/services/auditsvc")
+
+// Service records mutations in the tamper-evident audit trail and reads them back
+type Service interface {
+	// Record queues an entry for a mutation that has already happened. A failed write is
+	// logged and counted rather than returned, so callers are not left half done.
+	Record(ctx context.Context, event audit.Event)
+	// HandleEvent appends an entry queued by Record to the trail
+	HandleEvent(ctx context.Context, msg domainevents.Message) error
+	// DroppedEntries is how many entries could not be queued since the process started
+	DroppedEntries() int64
+	Query(ctx context.Context, filter audit.Filter) ([]audit.Entry, error)
+	Verify(ctx context.Context) (*audit.Verification, error)
+}
+
+type auditService struct {
+	store       audit.Store
+	logger      *zap.Logger
+	svcRegistry registry.ServiceRegistry
+	now         func() time.Time
+	dropped     atomic.Int64
+}
+
+func NewService(db db.DBClientInterface, registry registry.ServiceRegistry, logger *zap.Logger) Service {
+	return &auditService{
+		store:       audit.NewMongoStore(db),
+		svcRegistry: registry,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Record builds an entry from the event and the caller in ctx and publishes it to the
+// outbox. The entry is appended when the event is delivered, and retried while other
+// writers hold the end of the chain. Without a bus, e.g. in tests, it is appended right away.
+func (s *auditService) Record(ctx context.Context, event audit.Event) {
+	ctx, span := tracer.Start(ctx, "auditsvc.Record")
+	defer span.End()
+
+	entry := audit.Entry{
+		Timestamp:    s.now(),
+		Actor:        "anonymous",
+		TenantID:     event.TenantID,
+		Operation:    event.Operation,
+		ResourceType: event.ResourceType,
+		ResourceID:   event.ResourceID,
+		Changes:      audit.Diff(audit.Snapshot(event.Before), audit.Snapshot(event.After)),
+		IP:           audit.ClientIPFromContext(ctx),
+		RequestID:    common.GetRequestIDFromContext(ctx),
+	}
+	if claims, ok := ctx.Value("claims").(*models.UserClaims); ok && claims.Username != "" {
+		entry.Actor = claims.Username
+		entry.Role = claims.Role
+		if entry.TenantID == "" {
+			entry.TenantID = claims.TenantID
+		}
+	}
+
+	// The mutation has happened, so the entry is written even if the request was cancelled
+	writeCtx := context.WithoutCancel(ctx)
+	var err error
+	if bus, ok := s.svcRegistry.Get(registry.DomainEvents).(*domainevents.Bus); ok {
+		err = bus.Publish(writeCtx, domainevents.AuditRecorded{Entry: entry})
+	} else {
+		_, err = s.store.Append(writeCtx, entry)
+	}
+	if err != nil {
+		s.drop(ctx, entry, err)
+	}
+}
+
+// HandleEvent links an entry published by Record into the trail. An entry appended by an
+// earlier delivery of the event is not appended again.
+func (s *auditService) HandleEvent(ctx context.Context, msg domainevents.Message) error {
+	ctx, span := tracer.Start(ctx, "auditsvc.HandleEvent")
+	defer span.End()
+
+	var event domainevents.AuditRecorded
+	if err := msg.Decode(&event); err != nil {
+		// Retrying cannot fix a payload that does not decode
+		s.drop(ctx, audit.Entry{}, err)
+		return nil
+	}
+	entry := event.Entry
+	entry.EventID = msg.ID
+
+	appended, err := s.store.Query(ctx, audit.Filter{EventID: msg.ID, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(appended) > 0 {
+		return nil
+	}
+	_, err = s.store.Append(ctx, entry)
+	return err
+}
+
+// DroppedEntries is how many entries could not be queued since the process started
+func (s *auditService) DroppedEntries() int64 {
+	return s.dropped.Load()
+}
+
+// drop counts and logs an entry that will not reach the trail
+func (s *auditService) drop(ctx context.Context, entry audit.Entry, err error) {
+	s.dropped.Add(1)
+	metrics.AuditWriteFailures.WithLabelValues(entry.ResourceType).Inc()
+	logging.WithContext(ctx, s.logger).Error("Failed to write audit entry",
+		zap.Error(err),
+		zap.String("operation", entry.Operation),
+		zap.String("resource_type", entry.ResourceType),
+		zap.String("resource_id", entry.ResourceID),
+		zap.String("actor", entry.Actor),
+	)
+}
+
+// Query returns the audit entries matching filter in sequence order
+func (s *auditService) Query(ctx context.Context, filter audit.Filter) ([]audit.Entry, error) {
+	ctx, span := tracer.Start(ctx, "auditsvc.Query")
+	defer span.End()
+
+	return s.store.Query(ctx, filter)
+}
+
+// Verify checks the hash chain of the whole audit trail
+func (s *auditService) Verify(ctx context.Context) (*audit.Verification, error) {
+	ctx, span := tracer.Start(ctx, "auditsvc.Verify")
+	defer span.End()
+
+	return audit.Verify(ctx, s.store)
+}
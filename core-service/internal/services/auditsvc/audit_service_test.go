@@ -0,0 +1,86 @@
+package auditsvc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
+	"github.com/mrityunjay-vashisth/core-service/internal/domainevents"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryTrail is an in-memory audit store
+type memoryTrail struct {
+	entries []audit.Entry
+}
+
+func (m *memoryTrail) Append(_ context.Context, entry audit.Entry) (audit.Entry, error) {
+	var previous *audit.Entry
+	if len(m.entries) > 0 {
+		previous = &m.entries[len(m.entries)-1]
+	}
+	linked := audit.Link(entry, previous)
+	m.entries = append(m.entries, linked)
+	return linked, nil
+}
+
+func (m *memoryTrail) Query(_ context.Context, filter audit.Filter) ([]audit.Entry, error) {
+	var entries []audit.Entry
+	for _, entry := range m.entries {
+		if filter.EventID == "" || entry.EventID == filter.EventID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// memoryOutbox keeps the events published on the bus
+type memoryOutbox struct {
+	records []domainevents.Record
+}
+
+func (m *memoryOutbox) Append(_ context.Context, records ...domainevents.Record) error {
+	m.records = append(m.records, records...)
+	return nil
+}
+
+func (m *memoryOutbox) ClaimDue(context.Context, time.Time, time.Duration, int64) ([]domainevents.Record, error) {
+	return nil, nil
+}
+
+func (m *memoryOutbox) Save(context.Context, domainevents.Record) error { return nil }
+
+func TestRecordThroughOutbox(t *testing.T) {
+	ctx := context.Background()
+	trail := &memoryTrail{}
+	outbox := &memoryOutbox{}
+	services := registry.NewServiceRegistry()
+	services.Register(registry.DomainEvents, domainevents.NewBus(outbox))
+	service := &auditService{store: trail, svcRegistry: services, logger: zap.NewNop(), now: time.Now}
+
+	service.Record(ctx, audit.Event{
+		Operation:    "appointment.create",
+		ResourceType: audit.ResourceAppointment,
+		ResourceID:   "appt-1",
+		TenantID:     "t1",
+		After:        map[string]interface{}{"status": "scheduled"},
+	})
+	assert.Empty(t, trail.entries, "the entry waits in the outbox")
+	if !assert.Len(t, outbox.records, 1) {
+		return
+	}
+
+	// Delivered twice, appended once
+	msg := outbox.records[0].Message
+	assert.NoError(t, service.HandleEvent(ctx, msg))
+	assert.NoError(t, service.HandleEvent(ctx, msg))
+	if assert.Len(t, trail.entries, 1) {
+		assert.Equal(t, "appt-1", trail.entries[0].ResourceID)
+		assert.Equal(t, msg.ID, trail.entries[0].EventID)
+	}
+	assert.NoError(t, audit.VerifyChain(trail.entries, nil))
+	assert.Zero(t, service.DroppedEntries())
+}
@@ -5,13 +5,43 @@ import (
 	"crypto/rand"
 	"errors"
 	"log"
+	"time"
 
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/common"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
 	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
 	"github.com/mrityunjay-vashisth/core-service/internal/models"
 	"github.com/mrityunjay-vashisth/medusa-proto/authpb"
+	"github.com/mrityunjay-vashisth/medusa-shared/certs"
+	"github.com/mrityunjay-vashisth/medusa-shared/userpb"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var tracer = otel.Tracer("github.com/mrityunjay-vashisth/core-service/internal/services/authsvc")
+
+var (
+	// ErrMissingCredentials is returned when the username or password is empty
+	ErrMissingCredentials = apperrors.Validation("missing_credentials", "Username and password are required")
+	// ErrInvalidCredentials is returned when auth-service rejects the credentials
+	ErrInvalidCredentials = apperrors.Unauthorized("invalid_credentials", "Invalid username or password")
+	// ErrUserExists is returned when the user is already registered
+	ErrUserExists = apperrors.Conflict("user_exists", "User is already registered")
+	// ErrUserNotFound is returned when auth-service has no such user
+	ErrUserNotFound = apperrors.NotFound("user_not_found", "User not found")
+	// ErrAuthUnavailable is returned when auth-service cannot be reached
+	ErrAuthUnavailable = apperrors.Unavailable("auth_unavailable", "Authentication service is unavailable", nil)
 )
 
 type Service interface {
@@ -19,6 +49,10 @@ type Service interface {
 	Login(context.Context, string, string, string) (*authpb.LoginResponse, error)
 	CreateSession(claims *models.UserClaims) (string, error)
 	Register(ctx context.Context, req models.AuthRegisterRequest) (*authpb.RegisterUserResponse, error)
+	DeleteUser(ctx context.Context, username, tenantID string) (bool, error)
+	GetUser(ctx context.Context, username, email, tenantID string) (*userpb.User, error)
+	UserExists(ctx context.Context, username, email, tenantID string) (bool, error)
+	CheckHealth(ctx context.Context) error
 }
 
 // AuthClient wraps gRPC AuthService
@@ -26,28 +60,68 @@ type authService struct {
 	db     db.DBClientInterface
 	Logger *zap.Logger
 	client authpb.AuthServiceClient
+	users  userpb.UserServiceClient
+	health healthpb.HealthClient
 }
 
-// NewAuthClient initializes gRPC client connection
-func NewService(db db.DBClientInterface, authServiceAddr string, logger *zap.Logger) Service {
-	conn, err := grpc.Dial(authServiceAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewAuthClient initializes gRPC client connection. Extra dial options, such as the dialer
+// of an in-process server in tests, come after the default ones.
+func NewService(db db.DBClientInterface, authServiceAddr string, creds credentials.TransportCredentials, logger *zap.Logger, opts ...grpc.DialOption) Service {
+	conn, err := grpc.Dial(authServiceAddr, append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+		grpc.WithChainUnaryInterceptor(metrics.UnaryClientInterceptor(), propagateRequestID()),
+	}, opts...)...)
 	if err != nil {
 		log.Fatalf("Failed to connect to auth-service: %v", err)
 	}
-	return &authService{db: db, client: authpb.NewAuthServiceClient(conn), Logger: logger}
+	return &authService{
+		db:     db,
+		client: authpb.NewAuthServiceClient(conn),
+		users:  userpb.NewUserServiceClient(conn),
+		health: healthpb.NewHealthClient(conn),
+		Logger: logger,
+	}
+}
+
+// TransportCredentials secures the connection to auth-service as configured: plaintext when
+// TLS is disabled, otherwise TLS with the client certificate, if any, for mutual TLS. The
+// certificate files are reloaded every reloadInterval.
+func TransportCredentials(cfg config.ClientTLSConfig, reloadInterval time.Duration, logger *zap.Logger) (credentials.TransportCredentials, error) {
+	if !cfg.Enabled {
+		return insecure.NewCredentials(), nil
+	}
+	store, err := certs.NewStore(cfg.CertFile, cfg.KeyFile, cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	go store.Watch(context.Background(), reloadInterval, logging.CertificateReloads(logger, store.CertFile()))
+	return credentials.NewTLS(certs.ClientConfig(store, cfg.ServerName)), nil
+}
+
+// propagateRequestID forwards the request ID of the HTTP request as gRPC metadata
+func propagateRequestID() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if requestID := common.GetRequestIDFromContext(ctx); requestID != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, common.RequestIDMetadataKey, requestID)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
 }
 
 func (a *authService) Login(ctx context.Context, username, password, tenantid string) (*authpb.LoginResponse, error) {
-	a.Logger.Info("Got called in auth service")
+	ctx, span := tracer.Start(ctx, "authsvc.Login")
+	defer span.End()
+
+	logging.WithContext(ctx, a.Logger).Info("Got called in auth service")
 	if username == "" || password == "" {
-		return nil, errors.New("invalid request body")
+		return nil, ErrMissingCredentials
 	}
 	if tenantid != "" {
 		username = username + "." + tenantid
 	}
-	a.Logger.Info("Info",
+	logging.WithContext(ctx, a.Logger).Info("Info",
 		zap.String("username", username),
-		zap.String("password", password),
 		zap.String("tenantid", tenantid),
 	)
 	authReq := &authpb.LoginRequest{
@@ -56,16 +130,24 @@ func (a *authService) Login(ctx context.Context, username, password, tenantid st
 	}
 
 	authResp, err := a.client.Login(ctx, authReq)
-	return authResp, err
+	if err != nil {
+		metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		return nil, fromStatus(err)
+	}
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
+	return authResp, nil
 }
 
 func (a *authService) Register(ctx context.Context, req models.AuthRegisterRequest) (*authpb.RegisterUserResponse, error) {
+	ctx, span := tracer.Start(ctx, "authsvc.Register")
+	defer span.End()
+
 	// Call the auth service via gRPC
 	var err error
 	if req.Password == "" {
 		req.Password = generateRandomPassword()
 	}
-	username := req.Username + "." + req.TenantId
+	username := qualifiedUsername(req.Username, req.TenantId)
 
 	client := a.GetClient()
 	resp, err := client.RegisterUser(ctx, &authpb.RegisterUserRequest{
@@ -77,20 +159,119 @@ func (a *authService) Register(ctx context.Context, req models.AuthRegisterReque
 	})
 
 	if err != nil {
-		a.Logger.Error("Failed to register user", zap.Error(err))
-		return nil, err
+		logging.WithContext(ctx, a.Logger).Error("Failed to register user", zap.Error(err))
+		return nil, fromStatus(err)
 	}
 
-	a.Logger.Info("Credentials prepared for",
+	logging.WithContext(ctx, a.Logger).Info("Credentials prepared for",
 		zap.String("email", req.Email),
 		zap.String("username", req.Username),
 		zap.String("role", req.Role),
-		zap.String("password", req.Password),
 	)
 
 	return resp, nil
 }
 
+// DeleteUser removes a user registered with Register, reporting whether there was one.
+// The username is the one given to Register, without the tenant suffix.
+func (a *authService) DeleteUser(ctx context.Context, username, tenantID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "authsvc.DeleteUser")
+	defer span.End()
+
+	resp, err := a.users.DeleteUser(ctx, &userpb.DeleteUserRequest{
+		Username: qualifiedUsername(username, tenantID),
+		TenantId: tenantID,
+	})
+	if err != nil {
+		logging.WithContext(ctx, a.Logger).Error("Failed to delete user", zap.Error(err))
+		return false, fromStatus(err)
+	}
+	logging.WithContext(ctx, a.Logger).Info("User deleted",
+		zap.String("username", username),
+		zap.String("tenant_id", tenantID),
+		zap.Bool("existed", resp.Deleted))
+	return resp.Deleted, nil
+}
+
+// GetUser returns the user of a tenant with the given username, email or both, or
+// ErrUserNotFound. The username is the one given to Register, without the tenant suffix.
+func (a *authService) GetUser(ctx context.Context, username, email, tenantID string) (*userpb.User, error) {
+	ctx, span := tracer.Start(ctx, "authsvc.GetUser")
+	defer span.End()
+
+	user, err := a.users.GetUser(ctx, &userpb.GetUserRequest{
+		Username: qualifiedUsername(username, tenantID),
+		Email:    email,
+		TenantId: tenantID,
+	})
+	if err != nil {
+		if status.Code(err) != codes.NotFound {
+			logging.WithContext(ctx, a.Logger).Error("Failed to get user", zap.Error(err))
+		}
+		return nil, fromStatus(err)
+	}
+	return user, nil
+}
+
+// UserExists reports whether a tenant has a user with the given username, email or both.
+// The username is the one given to Register, without the tenant suffix.
+func (a *authService) UserExists(ctx context.Context, username, email, tenantID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "authsvc.UserExists")
+	defer span.End()
+
+	resp, err := a.users.UserExists(ctx, &userpb.UserExistsRequest{
+		Username: qualifiedUsername(username, tenantID),
+		Email:    email,
+		TenantId: tenantID,
+	})
+	if err != nil {
+		logging.WithContext(ctx, a.Logger).Error("Failed to look up user", zap.Error(err))
+		return false, fromStatus(err)
+	}
+	return resp.Exists, nil
+}
+
+// qualifiedUsername returns the username auth-service knows a user of a tenant by
+func qualifiedUsername(username, tenantID string) string {
+	if username == "" {
+		return ""
+	}
+	return username + "." + tenantID
+}
+
+// fromStatus maps a gRPC status returned by auth-service to a domain error
+func fromStatus(err error) error {
+	st, _ := status.FromError(err)
+	switch st.Code() {
+	case codes.Unauthenticated:
+		return ErrInvalidCredentials.Wrap(err)
+	case codes.AlreadyExists:
+		return ErrUserExists.Wrap(err)
+	case codes.NotFound:
+		return ErrUserNotFound.Wrap(err)
+	case codes.InvalidArgument:
+		return apperrors.Validation("invalid_request", st.Message())
+	case codes.PermissionDenied:
+		return apperrors.Forbidden("access_denied", st.Message())
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return ErrAuthUnavailable.Wrap(err)
+	default:
+		return apperrors.Internal("auth_error", "Authentication request failed", err)
+	}
+}
+
+// CheckHealth queries the standard gRPC health service exposed by auth-service
+func (a *authService) CheckHealth(ctx context.Context) error {
+	resp, err := a.health.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return errors.New("auth-service reported status " + resp.GetStatus().String())
+	}
+	return nil
+}
+
 // GetClient returns the gRPC AuthServiceClient
 func (a *authService) GetClient() authpb.AuthServiceClient {
 	return a.client
@@ -0,0 +1,204 @@
+package healthsvc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Status is the outcome of a health check or of a whole report
+type Status string
+
+const (
+	StatusOK          Status = "ok"
+	StatusDegraded    Status = "degraded"
+	StatusUnavailable Status = "unavailable"
+)
+
+// defaultCheckTimeout bounds how long a single dependency check may take
+const defaultCheckTimeout = 2 * time.Second
+
+// CheckFunc probes a single component. The returned details are only shown in verbose reports.
+type CheckFunc func(ctx context.Context) (map[string]interface{}, error)
+
+// ComponentResult is the outcome of one check
+type ComponentResult struct {
+	Name       string                 `json:"name"`
+	Status     Status                 `json:"status"`
+	Critical   bool                   `json:"critical"`
+	DurationMs float64                `json:"duration_ms"`
+	Error      string                 `json:"error,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+}
+
+// Report aggregates the results of all checks of a probe
+type Report struct {
+	Status    Status            `json:"status"`
+	Checks    []ComponentResult `json:"checks"`
+	Uptime    string            `json:"uptime"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+type Service interface {
+	// RegisterLivenessCheck adds a check that decides whether the process should be restarted
+	RegisterLivenessCheck(name string, check CheckFunc)
+	// RegisterReadinessCheck adds a check that decides whether the process should receive traffic
+	RegisterReadinessCheck(name string, critical bool, check CheckFunc)
+	Liveness(ctx context.Context, verbose bool) Report
+	Readiness(ctx context.Context, verbose bool) Report
+}
+
+type registeredCheck struct {
+	name     string
+	critical bool
+	check    CheckFunc
+}
+
+type healthService struct {
+	logger          *zap.Logger
+	startedAt       time.Time
+	timeout         time.Duration
+	mutex           sync.RWMutex
+	livenessChecks  []registeredCheck
+	readinessChecks []registeredCheck
+}
+
+func NewService(logger *zap.Logger) Service {
+	return &healthService{
+		logger:    logger,
+		startedAt: time.Now(),
+		timeout:   defaultCheckTimeout,
+	}
+}
+
+func (s *healthService) RegisterLivenessCheck(name string, check CheckFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.livenessChecks = append(s.livenessChecks, registeredCheck{name: name, critical: true, check: check})
+}
+
+func (s *healthService) RegisterReadinessCheck(name string, critical bool, check CheckFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.readinessChecks = append(s.readinessChecks, registeredCheck{name: name, critical: critical, check: check})
+}
+
+// Liveness only runs liveness checks so a dependency outage never restarts the process
+func (s *healthService) Liveness(ctx context.Context, verbose bool) Report {
+	s.mutex.RLock()
+	checks := append([]registeredCheck(nil), s.livenessChecks...)
+	s.mutex.RUnlock()
+	return s.run(ctx, checks, verbose)
+}
+
+// Readiness runs liveness and readiness checks
+func (s *healthService) Readiness(ctx context.Context, verbose bool) Report {
+	s.mutex.RLock()
+	checks := append(append([]registeredCheck(nil), s.livenessChecks...), s.readinessChecks...)
+	s.mutex.RUnlock()
+	return s.run(ctx, checks, verbose)
+}
+
+// run executes checks concurrently, each bounded by the check timeout
+func (s *healthService) run(ctx context.Context, checks []registeredCheck, verbose bool) Report {
+	results := make([]ComponentResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c registeredCheck) {
+			defer wg.Done()
+			results[i] = s.runCheck(ctx, c, verbose)
+		}(i, c)
+	}
+	wg.Wait()
+
+	status := StatusOK
+	for _, result := range results {
+		if result.Status == StatusOK {
+			continue
+		}
+		if result.Critical {
+			status = StatusUnavailable
+		} else if status == StatusOK {
+			status = StatusDegraded
+		}
+	}
+
+	return Report{
+		Status:    status,
+		Checks:    results,
+		Uptime:    time.Since(s.startedAt).Round(time.Second).String(),
+		Timestamp: time.Now(),
+	}
+}
+
+func (s *healthService) runCheck(ctx context.Context, c registeredCheck, verbose bool) (result ComponentResult) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	result = ComponentResult{Name: c.name, Critical: c.critical, Status: StatusOK}
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			s.logger.Error("Health check panicked", zap.String("check", c.name), zap.Any("panic", p))
+			result.Status = StatusUnavailable
+			if verbose {
+				result.Error = "check panicked"
+			}
+		}
+		result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	}()
+
+	details, err := c.check(ctx)
+	if err != nil {
+		s.logger.Warn("Health check failed", zap.String("check", c.name), zap.Error(err))
+		result.Status = StatusUnavailable
+		if !c.critical {
+			result.Status = StatusDegraded
+		}
+		if verbose {
+			result.Error = err.Error()
+		}
+	}
+	if verbose {
+		result.Details = details
+	}
+	return result
+}
+
+// BackgroundJob is implemented by periodic workers whose progress should be visible in readiness
+type BackgroundJob interface {
+	LastSuccessfulRun() time.Time
+	Interval() time.Duration
+}
+
+// JobCheck reports a job as failing once it has missed the given number of runs.
+// Before the first successful run the job is measured from the time the check was created.
+func JobCheck(job BackgroundJob, missedRuns int) CheckFunc {
+	registeredAt := time.Now()
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		lastRun := job.LastSuccessfulRun()
+		maxAge := time.Duration(missedRuns) * job.Interval()
+
+		details := map[string]interface{}{
+			"interval": job.Interval().String(),
+			"max_age":  maxAge.String(),
+		}
+
+		since := registeredAt
+		if !lastRun.IsZero() {
+			since = lastRun
+			details["last_success_at"] = lastRun
+			details["age"] = time.Since(lastRun).Round(time.Second).String()
+		}
+
+		if time.Since(since) > maxAge {
+			return details, errors.New("job has not completed successfully within its expected interval")
+		}
+		return details, nil
+	}
+}
@@ -0,0 +1,47 @@
+package healthsvc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeJob struct {
+	lastRun  time.Time
+	interval time.Duration
+}
+
+func (j *fakeJob) LastSuccessfulRun() time.Time { return j.lastRun }
+func (j *fakeJob) Interval() time.Duration      { return j.interval }
+
+func TestReadinessAggregatesComponentStatus(t *testing.T) {
+	service := NewService(zap.NewNop())
+	service.RegisterReadinessCheck("mongodb", true, func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, nil
+	})
+	service.RegisterReadinessCheck("stale_job", false, JobCheck(&fakeJob{
+		lastRun:  time.Now().Add(-time.Hour),
+		interval: time.Minute,
+	}, 3))
+
+	// A failing non-critical component only degrades the service
+	report := service.Readiness(context.Background(), false)
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Empty(t, report.Checks[1].Error, "Errors should only be shown in verbose mode")
+
+	// A failing critical component makes the service unavailable
+	service.RegisterReadinessCheck("auth_service", true, func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, errors.New("connection refused")
+	})
+	report = service.Readiness(context.Background(), true)
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, "connection refused", report.Checks[2].Error)
+
+	// Liveness ignores dependencies
+	assert.Equal(t, StatusOK, service.Liveness(context.Background(), true).Status)
+}
@@ -0,0 +1,195 @@
+package onboardingsvc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/saga"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/authsvc"
+	"go.uber.org/zap"
+)
+
+// ApprovalSaga is the name of the saga approving an onboarding request
+const ApprovalSaga = "tenant_approval"
+
+// errSagasNotRegistered is returned when no saga orchestrator is registered
+var errSagasNotRegistered = apperrors.Internal("sagas_not_registered", "approvals are not available", nil)
+
+// NewApprovalSaga returns the saga approving an onboarding request, keyed by request ID:
+// the request is marked in progress, its user registered with auth-service, the request
+// marked user created and the tenant activated. When a step fails for good the user is
+// deleted again and the request marked failed, so it can be retried.
+func NewApprovalSaga(service Service, authService authsvc.Service) saga.Definition {
+	return saga.Definition{
+		Name: ApprovalSaga,
+		Steps: []saga.Step{
+			{
+				Name: "begin_approval",
+				Action: func(ctx context.Context, approval *saga.Instance) error {
+					request, err := service.BeginApproval(ctx, approval.Key, approval.ID)
+					if err != nil {
+						return approvalError(err)
+					}
+					for _, field := range []string{"tenant_id", "email", "username", "role", "organization_name"} {
+						approval.Data[field], _ = request[field].(string)
+					}
+					if approval.Data["tenant_id"] == "" || approval.Data["email"] == "" ||
+						approval.Data["username"] == "" || approval.Data["role"] == "" {
+						return saga.Permanent(errors.New("request lacks the data needed to register its user"))
+					}
+					return nil
+				},
+				Compensate: func(ctx context.Context, approval *saga.Instance) error {
+					return approvalError(service.MarkApprovalFailed(ctx, approval.Key, approval.Error))
+				},
+			},
+			{
+				Name: "create_user",
+				Action: func(ctx context.Context, approval *saga.Instance) error {
+					_, err := authService.Register(ctx, models.AuthRegisterRequest{
+						Username: approval.Data["username"],
+						Email:    approval.Data["email"],
+						Name:     approval.Data["organization_name"],
+						Role:     approval.Data["role"],
+						TenantId: approval.Data["tenant_id"],
+					})
+					// An earlier attempt may have registered the user without hearing back;
+					// otherwise the email belongs to another user
+					if errors.Is(err, authsvc.ErrUserExists) {
+						exists, existsErr := authService.UserExists(ctx,
+							approval.Data["username"], approval.Data["email"], approval.Data["tenant_id"])
+						if existsErr != nil {
+							return approvalError(existsErr)
+						}
+						if exists {
+							return nil
+						}
+					}
+					return approvalError(err)
+				},
+				Compensate: func(ctx context.Context, approval *saga.Instance) error {
+					_, err := authService.DeleteUser(ctx, approval.Data["username"], approval.Data["tenant_id"])
+					return approvalError(err)
+				},
+			},
+			{
+				Name: "mark_user_created",
+				Action: func(ctx context.Context, approval *saga.Instance) error {
+					err := service.MarkUserCreated(ctx, approval.Key)
+					if errors.Is(err, ErrNotInProgress) {
+						// Done by an earlier attempt whose progress was not saved
+						request, _ := service.GetTenantByID(ctx, approval.Key)
+						if requestMap, ok := request.(map[string]interface{}); ok &&
+							requestMap["status"] == string(models.OnboardingStatusUserCreated) &&
+							requestMap["approval_id"] == approval.ID {
+							return nil
+						}
+					}
+					return approvalError(err)
+				},
+			},
+			{
+				Name: "activate_tenant",
+				Action: func(ctx context.Context, approval *saga.Instance) error {
+					err := service.CompleteApproval(ctx, approval.Key)
+					if errors.Is(err, ErrNotUserCreated) {
+						// Done by an earlier attempt whose progress was not saved
+						if exists, _ := service.GetTenantCheckByID(ctx, approval.Data["tenant_id"]); exists {
+							return nil
+						}
+					}
+					return approvalError(err)
+				},
+			},
+		},
+	}
+}
+
+// approvalError marks the errors of approval steps that retrying cannot fix as permanent:
+// all domain errors but those of an unavailable dependency or an unexpected failure
+func approvalError(err error) error {
+	if domainErr, ok := apperrors.As(err); ok {
+		switch domainErr.Kind {
+		case apperrors.KindUnavailable, apperrors.KindInternal:
+		default:
+			return saga.Permanent(err)
+		}
+	}
+	return err
+}
+
+// StartApproval begins approving a pending onboarding request. The approval runs in the
+// background; GetApproval reports its progress.
+func (h *onboardingService) StartApproval(ctx context.Context, requestID string) (*saga.Instance, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.StartApproval")
+	defer span.End()
+
+	// Checked again by the saga's first step, this turns away most requests that cannot be
+	// approved before a saga is stored for them
+	request, err := h.GetTenantByID(ctx, requestID)
+	if errors.Is(err, ErrRequestNotFound) {
+		return nil, ErrNotPending
+	}
+	if err != nil {
+		return nil, err
+	}
+	requestMap, _ := request.(map[string]interface{})
+	if requestMap["status"] != string(models.OnboardingStatusPending) {
+		return nil, ErrNotPending
+	}
+
+	orchestrator, err := h.sagas()
+	if err != nil {
+		return nil, err
+	}
+	approval, err := orchestrator.Begin(ctx, ApprovalSaga, requestID, nil)
+	if err != nil {
+		logging.WithContext(ctx, h.Logger).Error("Failed to start approval",
+			zap.Error(err),
+			zap.String("request_id", requestID))
+		return nil, ErrDatabase.Wrap(err)
+	}
+
+	tenantID, _ := requestMap["tenant_id"].(string)
+	h.recordAudit(ctx, audit.Event{
+		Operation:    "onboarding.approve",
+		ResourceType: audit.ResourceOnboardingRequest,
+		ResourceID:   requestID,
+		TenantID:     tenantID,
+		After:        map[string]interface{}{"approval_id": approval.ID},
+	})
+	return approval, nil
+}
+
+// GetApproval returns an approval started with StartApproval
+func (h *onboardingService) GetApproval(ctx context.Context, approvalID string) (*saga.Instance, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.GetApproval")
+	defer span.End()
+
+	orchestrator, err := h.sagas()
+	if err != nil {
+		return nil, err
+	}
+	approval, err := orchestrator.Get(ctx, approvalID)
+	if errors.Is(err, saga.ErrNotFound) || (err == nil && approval.Saga != ApprovalSaga) {
+		return nil, ErrApprovalNotFound
+	}
+	if err != nil {
+		return nil, ErrDatabase.Wrap(err)
+	}
+	return approval, nil
+}
+
+// sagas returns the orchestrator running approvals
+func (h *onboardingService) sagas() (*saga.Orchestrator, error) {
+	orchestrator, ok := h.svcRegistry.Get(registry.Sagas).(*saga.Orchestrator)
+	if !ok {
+		return nil, errSagasNotRegistered
+	}
+	return orchestrator, nil
+}
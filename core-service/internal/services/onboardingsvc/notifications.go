@@ -0,0 +1,132 @@
+package onboardingsvc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/domainevents"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/mailer"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"go.uber.org/zap"
+)
+
+// rejectionReasonText explains each rejection reason to the applicant
+var rejectionReasonText = map[models.RejectionReason]string{
+	models.RejectionReasonIncompleteInformation:    "The request did not include all the information we need.",
+	models.RejectionReasonUnverifiableOrganization: "We could not verify the organization.",
+	models.RejectionReasonDuplicate:                "The organization already applied or is already a tenant.",
+	models.RejectionReasonNotEligible:              "The organization is not eligible for Medusa.",
+	models.RejectionReasonOther:                    "The request was not accepted.",
+}
+
+// ApplicantNotifier emails onboarding applicants the link verifying their email and the
+// outcome of their request. It subscribes to domain events, so an applicant may be emailed
+// again when an event is delivered more than once.
+type ApplicantNotifier struct {
+	mailer          mailer.Mailer
+	secret          []byte
+	verificationURL string
+	logger          *zap.Logger
+}
+
+// NewApplicantNotifier returns a notifier sending email with the given mailer. Verification
+// links open the verification URL of settings with a token signed by its secret.
+func NewApplicantNotifier(mailer mailer.Mailer, settings config.OnboardingConfig, logger *zap.Logger) *ApplicantNotifier {
+	return &ApplicantNotifier{
+		mailer:          mailer,
+		secret:          []byte(settings.VerificationSecret),
+		verificationURL: settings.VerificationURL,
+		logger:          logger,
+	}
+}
+
+// HandleEvent emails the applicant of an onboarding request to be verified or rejected
+func (n *ApplicantNotifier) HandleEvent(ctx context.Context, msg domainevents.Message) error {
+	switch msg.Type {
+	case domainevents.TypeOnboardingVerificationRequested:
+		return n.sendVerification(ctx, msg)
+	case domainevents.TypeOnboardingRejected:
+		return n.sendRejection(ctx, msg)
+	}
+	return nil
+}
+
+// sendVerification emails the applicant the link verifying their email
+func (n *ApplicantNotifier) sendVerification(ctx context.Context, msg domainevents.Message) error {
+	var event domainevents.OnboardingVerificationRequested
+	if err := msg.Decode(&event); err != nil {
+		// Retrying cannot fix a payload that does not decode
+		logging.WithContext(ctx, n.logger).Error("Dropping undecodable verification event",
+			zap.Error(err), zap.String("event_id", msg.ID))
+		return nil
+	}
+
+	link, err := url.Parse(n.verificationURL)
+	if err != nil {
+		return fmt.Errorf("invalid verification URL: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", signVerificationToken(n.secret, verificationToken{
+		RequestID: event.RequestID,
+		Send:      event.Send,
+		ExpiresAt: event.ExpiresAt,
+	}))
+	link.RawQuery = query.Encode()
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello,\n\nplease confirm this email address to submit the request to onboard %s to Medusa:\n\n%s\n\n",
+		event.OrganizationName, link.String())
+	fmt.Fprintf(&body, "The link is valid until %s. Once the address is confirmed, the request is reviewed. ",
+		event.ExpiresAt.Format("January 2, 2006 15:04 MST"))
+	body.WriteString("If you did not apply, ignore this email and the request is removed.\n")
+	fmt.Fprintf(&body, "\nRequest ID: %s\n", event.RequestID)
+
+	err = n.mailer.Send(ctx, mailer.Message{
+		To:      []string{event.Email},
+		Subject: "Confirm your email for the Medusa onboarding of " + strings.Join(strings.Fields(event.OrganizationName), " "),
+		Body:    body.String(),
+	})
+	if err != nil {
+		logging.WithContext(ctx, n.logger).Warn("Failed to email verification link",
+			zap.Error(err), zap.String("request_id", event.RequestID))
+		return err
+	}
+	return nil
+}
+
+// sendRejection emails the applicant of a rejected onboarding request
+func (n *ApplicantNotifier) sendRejection(ctx context.Context, msg domainevents.Message) error {
+	var event domainevents.OnboardingRejected
+	if err := msg.Decode(&event); err != nil {
+		// Retrying cannot fix a payload that does not decode
+		logging.WithContext(ctx, n.logger).Error("Dropping undecodable rejection event",
+			zap.Error(err), zap.String("event_id", msg.ID))
+		return nil
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello,\n\nthe request to onboard %s to Medusa was not approved.\n\n", event.OrganizationName)
+	body.WriteString(rejectionReasonText[models.RejectionReason(event.ReasonCode)])
+	body.WriteString("\n")
+	if event.Comment != "" {
+		fmt.Fprintf(&body, "\nThe reviewer noted:\n\n%s\n", event.Comment)
+	}
+	fmt.Fprintf(&body, "\nYou can apply again from %s.\n\nRequest ID: %s\n",
+		event.ReapplyAfter.Format("January 2, 2006 15:04 MST"), event.RequestID)
+
+	err := n.mailer.Send(ctx, mailer.Message{
+		To:      []string{event.Email},
+		Subject: "Your Medusa onboarding request for " + strings.Join(strings.Fields(event.OrganizationName), " "),
+		Body:    body.String(),
+	})
+	if err != nil {
+		logging.WithContext(ctx, n.logger).Warn("Failed to email rejected applicant",
+			zap.Error(err), zap.String("request_id", event.RequestID))
+		return err
+	}
+	return nil
+}
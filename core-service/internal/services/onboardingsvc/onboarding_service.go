@@ -3,133 +3,281 @@ package onboardingsvc
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
 	"github.com/mrityunjay-vashisth/core-service/internal/config"
 	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/domainevents"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
 	"github.com/mrityunjay-vashisth/core-service/internal/models"
 	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/saga"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/auditsvc"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/webhooksvc"
+	"github.com/mrityunjay-vashisth/core-service/internal/webhooks"
+	"github.com/mrityunjay-vashisth/core-service/internal/worker"
 	"github.com/mrityunjay-vashisth/go-idforge/pkg/idforge"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("github.com/mrityunjay-vashisth/core-service/internal/services/onboardingsvc")
+
+// Errors returned by the onboarding service
+var (
+	ErrOnboardingExists = apperrors.Conflict("onboarding_request_exists", "onboarding request already exists")
+	ErrRequestNotFound  = apperrors.NotFound("onboarding_request_not_found", "onboarding request not found")
+	ErrTenantNotFound   = apperrors.NotFound("tenant_not_found", "tenant not found")
+	ErrNotPending       = apperrors.NotFound("pending_request_not_found", "no pending request found with the given ID")
+	ErrNotInProgress    = apperrors.NotFound("in_progress_request_not_found", "no in-progress request found with the given ID")
+	ErrNotUserCreated   = apperrors.NotFound("user_created_request_not_found", "no user-created request found with the given ID")
+	ErrNotRetriable     = apperrors.NotFound("retriable_request_not_found", "no eligible request found with the given ID")
+	ErrNotRejectable    = apperrors.NotFound("rejectable_request_not_found", "no pending or failed request found with the given ID")
+	ErrApprovalNotFound = apperrors.NotFound("approval_not_found", "approval not found")
+	ErrDatabase         = apperrors.Unavailable("database_unavailable", "onboarding data is temporarily unavailable", nil)
+)
+
 type Service interface {
-	OnboardTenant(ctx context.Context, req models.OnboardingRequest) (string, error)
+	OnboardTenant(ctx context.Context, req models.OnboardingRequest) (*models.OnboardingReceipt, error)
 	GetTenants(ctx context.Context, status string) (interface{}, error)
+	StreamTenants(ctx context.Context, status string, fn func(map[string]interface{}) error) error
 	GetTenantByID(ctx context.Context, id string) (interface{}, error)
-	BeginApproval(ctx context.Context, requestID string) (map[string]interface{}, error)
+	BeginApproval(ctx context.Context, requestID, approvalID string) (map[string]interface{}, error)
 	MarkUserCreated(ctx context.Context, requestID string) error
 	CompleteApproval(ctx context.Context, requestID string) error
 	MarkApprovalFailed(ctx context.Context, requestID string, reason string) error
 	RevertToRetriable(ctx context.Context, requestID string) error
+	RejectRequest(ctx context.Context, rejection models.OnboardingRejection, reviewer string) (map[string]interface{}, error)
 	GetTenantCheckByID(ctx context.Context, id string) (bool, error)
+	StartApproval(ctx context.Context, requestID string) (*saga.Instance, error)
+	GetApproval(ctx context.Context, approvalID string) (*saga.Instance, error)
+	RetryApproval(ctx context.Context, requestID string) (*saga.Instance, error)
+	ListDeadLetters(ctx context.Context) ([]map[string]interface{}, error)
+	GetDeadLetter(ctx context.Context, requestID string) (map[string]interface{}, error)
+	RequeueDeadLetter(ctx context.Context, requestID, operator string) (map[string]interface{}, error)
+	VerifyEmail(ctx context.Context, token string) (*models.EmailVerificationResult, error)
+	ResendVerification(ctx context.Context, resend models.VerificationResend) error
+	ExpireUnverified(ctx context.Context, requestID string) error
 }
 
+// maxRejectionComment is the longest comment a rejection can carry, in bytes
+const maxRejectionComment = 2000
+
 type onboardingService struct {
 	db          db.DBClientInterface
 	Logger      *zap.Logger
 	svcRegistry registry.ServiceRegistry
+	settings    config.OnboardingConfig
 }
 
-func NewService(db db.DBClientInterface, registry registry.ServiceRegistry, logger *zap.Logger) Service {
+func NewService(db db.DBClientInterface, registry registry.ServiceRegistry, logger *zap.Logger, settings config.OnboardingConfig) Service {
 	return &onboardingService{
 		db:          db,
 		svcRegistry: registry,
 		Logger:      logger,
+		settings:    settings,
 	}
 }
 
-func (h *onboardingService) OnboardTenant(ctx context.Context, req models.OnboardingRequest) (string, error) {
+func (h *onboardingService) OnboardTenant(ctx context.Context, req models.OnboardingRequest) (*models.OnboardingReceipt, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.OnboardTenant")
+	defer span.End()
+
 	requestId := idforge.GenerateWithSize(20)
 	tenantId := idforge.GenerateWithSize(10)
 	username := idforge.GenerateWithSize(10)
 
-	filter := bson.M{"email": req.Email}
+	// Rejected requests do not block the email once their cooldown is over, and unverified
+	// ones not at all, so nobody can hold an email they do not own
+	filter := bson.M{"email": req.Email, "status": bson.M{"$nin": []string{
+		string(models.OnboardingStatusUnverified),
+		string(models.OnboardingStatusRejected),
+	}}}
 	existingReq, err := h.db.Read(ctx, filter,
 		db.WithDatabaseName(config.DatabaseNames.CoreDB),
 		db.WithCollectionName(config.CollectionNames.OnboardingRequests))
 
 	// First check for database errors
 	if err != nil {
-		return "", errors.New("database error while checking for existing requests")
+		return nil, ErrDatabase.Wrap(err)
 	}
 
 	if existingReq != nil {
 		if reqMap, ok := existingReq.(map[string]interface{}); ok && len(reqMap) > 0 {
-			return "", errors.New("onboarding request already exists")
+			return nil, ErrOnboardingExists
 		}
 	}
 
-	existingReq, err = h.db.Read(ctx, filter,
+	rejected, err := h.db.Read(ctx,
+		bson.M{"email": req.Email, "status": models.OnboardingStatusRejected, "reapply_after": bson.M{"$gt": time.Now()}},
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.OnboardingRequests))
+	if err != nil {
+		return nil, ErrDatabase.Wrap(err)
+	}
+	if rejectedMap, ok := rejected.(map[string]interface{}); ok && len(rejectedMap) > 0 {
+		message := "a request with this email was rejected recently"
+		if reapplyAfter := timeField(rejectedMap, "reapply_after"); !reapplyAfter.IsZero() {
+			message += ", apply again after " + reapplyAfter.UTC().Format(time.RFC3339)
+		}
+		return nil, apperrors.Conflict("reapply_cooldown", message)
+	}
+
+	existingReq, err = h.db.Read(ctx, bson.M{"email": req.Email},
 		db.WithDatabaseName(config.DatabaseNames.CoreDB),
 		db.WithCollectionName(config.CollectionNames.OnboardedTenants))
 
 	// First check for database errors
 	if err != nil {
-		return "", errors.New("database error while checking for existing requests")
+		return nil, ErrDatabase.Wrap(err)
 	}
 
 	if existingReq != nil {
 		if reqMap, ok := existingReq.(map[string]interface{}); ok && len(reqMap) > 0 {
-			return "", errors.New("onboarding request already exists")
+			return nil, ErrOnboardingExists
 		}
 	}
 
+	// The webhook is registered up front so the URL is validated before the request is
+	// stored; it only receives the outcome of the review until the tenant subscribes to more
+	now := time.Now()
+	expiresAt := now.Add(h.settings.VerificationTTL)
+	receipt := &models.OnboardingReceipt{
+		RequestID:             requestId,
+		Status:                models.OnboardingStatusUnverified,
+		VerificationExpiresAt: expiresAt.UTC(),
+	}
+	var webhookEndpoint *webhooks.Endpoint
+	if req.WebhookURL != "" {
+		webhookEndpoint, err = h.registerWebhook(ctx, tenantId, req.WebhookURL)
+		if err != nil {
+			return nil, err
+		}
+		receipt.WebhookSecret = webhookEndpoint.Secret
+	}
+
 	dataMap := map[string]interface{}{
-		"organization_name": req.OrganizationName,
-		"email":             req.Email,
-		"status":            "pending",
-		"tenant_id":         tenantId,
-		"created_at":        time.Now().String(),
-		"request_id":        requestId,
-		"role":              req.Role,
-		"username":          username,
-		"geo_location":      "",
-		"entitlements":      "",
-	}
-	_, err = h.db.Create(ctx, dataMap,
-		db.WithDatabaseName(config.DatabaseNames.CoreDB),
-		db.WithCollectionName(config.CollectionNames.OnboardingRequests))
+		"organization_name":       req.OrganizationName,
+		"email":                   req.Email,
+		"status":                  models.OnboardingStatusUnverified,
+		"tenant_id":               tenantId,
+		"created_at":              now.String(),
+		"request_id":              requestId,
+		"role":                    req.Role,
+		"username":                username,
+		"geo_location":            "",
+		"entitlements":            "",
+		"verification_sends":      1,
+		"verification_sent_at":    now,
+		"verification_expires_at": expiresAt,
+	}
+	if req.WebhookURL != "" {
+		dataMap["webhook_url"] = req.WebhookURL
+		// Removed with the request if its email is never verified
+		dataMap["webhook_endpoint_id"] = webhookEndpoint.ID
+	}
+	// The request is stored with the email verifying it
+	err = h.db.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := h.db.Create(ctx, dataMap,
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.OnboardingRequests)); err != nil {
+			return err
+		}
+		return h.publishVerification(ctx, dataMap, 1, expiresAt)
+	})
 	if err != nil {
-		return "", errors.New("failed to onboard tenant")
+		if webhookEndpoint != nil {
+			h.removeWebhook(ctx, tenantId, webhookEndpoint.ID)
+		}
+		return nil, ErrDatabase.Wrap(err)
 	}
-	return requestId, nil
+	metrics.OnboardingRequests.WithLabelValues(string(models.OnboardingStatusUnverified)).Inc()
+	h.recordAudit(ctx, audit.Event{
+		Operation:    "onboarding.submit",
+		ResourceType: audit.ResourceOnboardingRequest,
+		ResourceID:   requestId,
+		TenantID:     tenantId,
+		After:        dataMap,
+	})
+	return receipt, nil
 }
 
 // GetPendingRequests fetches pending onboarding requests
 func (h *onboardingService) GetTenants(ctx context.Context, status string) (interface{}, error) {
-	var filter bson.M
-	var dbName string
-	var collectionName string
-	if status == "pending" {
-		filter = bson.M{"status": "pending"}
-		dbName = config.DatabaseNames.CoreDB
-		collectionName = config.CollectionNames.OnboardingRequests
-	} else {
-		filter = bson.M{"status": "active"}
-		dbName = config.DatabaseNames.CoreDB
-		collectionName = config.CollectionNames.OnboardedTenants
-	}
+	ctx, span := tracer.Start(ctx, "onboardingsvc.GetTenants")
+	defer span.End()
+
+	filter, dbName, collectionName := tenantsQuery(status)
 	requests, err := h.db.ReadAll(ctx, filter,
 		db.WithDatabaseName(dbName),
 		db.WithCollectionName(collectionName))
 	if err != nil {
-		h.Logger.Info("Error reading pending", zap.String("err", err.Error()))
-		return nil, errors.New("failed to fetch pending requests")
+		logging.WithContext(ctx, h.Logger).Info("Error reading pending", zap.String("err", err.Error()))
+		return nil, ErrDatabase.Wrap(err)
 	}
 	return requests, nil
 }
 
+// StreamTenants calls fn with every onboarding request or active tenant as it is read from
+// the database. An error returned by fn stops the stream and is returned as is.
+func (h *onboardingService) StreamTenants(ctx context.Context, status string, fn func(map[string]interface{}) error) error {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.StreamTenants")
+	defer span.End()
+
+	filter, dbName, collectionName := tenantsQuery(status)
+	var writeErr error
+	err := h.db.Stream(ctx, filter,
+		func(tenant map[string]interface{}) error {
+			if err := fn(tenant); err != nil {
+				writeErr = err
+				return err
+			}
+			return nil
+		},
+		db.WithDatabaseName(dbName),
+		db.WithCollectionName(collectionName))
+	if writeErr != nil {
+		return writeErr
+	}
+	if err != nil {
+		logging.WithContext(ctx, h.Logger).Error("Failed to stream tenants", zap.Error(err))
+		return ErrDatabase.Wrap(err)
+	}
+	return nil
+}
+
+// tenantsQuery selects onboarding requests in the given status, or active tenants when the
+// status is active or empty
+func tenantsQuery(status string) (bson.M, string, string) {
+	switch models.OnboardingStatus(status) {
+	case models.OnboardingStatusUnverified, models.OnboardingStatusPending, models.OnboardingStatusApprovalInProgress,
+		models.OnboardingStatusUserCreated, models.OnboardingStatusFailed, models.OnboardingStatusRejected,
+		models.OnboardingStatusDeadLetter:
+		return bson.M{"status": status}, config.DatabaseNames.CoreDB, config.CollectionNames.OnboardingRequests
+	}
+	return bson.M{"status": "active"}, config.DatabaseNames.CoreDB, config.CollectionNames.OnboardedTenants
+}
+
 // GetPendingRequests fetches pending onboarding requests
 func (h *onboardingService) GetTenantByID(ctx context.Context, id string) (interface{}, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.GetTenantByID")
+	defer span.End()
+
 	filter := bson.M{"request_id": id}
 	requests, err := h.db.Read(ctx, filter,
 		db.WithDatabaseName(config.DatabaseNames.CoreDB),
 		db.WithCollectionName(config.CollectionNames.OnboardingRequests))
 
 	if err != nil {
-		return nil, errors.New("failed to fetch pending requests")
+		return nil, ErrDatabase.Wrap(err)
 	}
 
 	// Now let's explicitly handle empty map case
@@ -139,20 +287,23 @@ func (h *onboardingService) GetTenantByID(ctx context.Context, id string) (inter
 	}
 	// Check if the map is empty
 	if len(requestMap) == 0 {
-		return nil, errors.New("request id not approved, please contact support")
+		return nil, ErrRequestNotFound
 	}
 	return requests, nil
 }
 
 // GetPendingRequests fetches pending onboarding requests
 func (h *onboardingService) GetTenantCheckByID(ctx context.Context, id string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.GetTenantCheckByID")
+	defer span.End()
+
 	filter := bson.M{"tenant_id": id}
 	requests, err := h.db.Read(ctx, filter,
 		db.WithDatabaseName(config.DatabaseNames.CoreDB),
 		db.WithCollectionName(config.CollectionNames.OnboardedTenants))
 
 	if err != nil {
-		return false, errors.New("failed to fetch pending requests")
+		return false, ErrDatabase.Wrap(err)
 	}
 
 	// Now let's explicitly handle empty map case
@@ -162,18 +313,23 @@ func (h *onboardingService) GetTenantCheckByID(ctx context.Context, id string) (
 	}
 	// Check if the map is empty
 	if len(requestMap) == 0 {
-		return false, errors.New("request id not approved, please contact support")
+		return false, ErrTenantNotFound
 	}
 	return true, nil
 }
 
-// BeginApproval marks an onboarding request as "in progress"
-func (h *onboardingService) BeginApproval(ctx context.Context, requestID string) (map[string]interface{}, error) {
+// BeginApproval marks a pending onboarding request as "in progress" under the approval
+// with the given ID. Beginning an approval that already began returns the request again.
+func (h *onboardingService) BeginApproval(ctx context.Context, requestID, approvalID string) (map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.BeginApproval")
+	defer span.End()
+
 	now := time.Now()
 	filter := bson.M{"request_id": requestID, "status": models.OnboardingStatusPending}
 	update := bson.M{
 		"$set": bson.M{
 			"status":              models.OnboardingStatusApprovalInProgress,
+			"approval_id":         approvalID,
 			"approval_started_at": now,
 		},
 	}
@@ -188,33 +344,54 @@ func (h *onboardingService) BeginApproval(ctx context.Context, requestID string)
 	)
 
 	if err != nil {
-		h.Logger.Error("Failed to begin approval process",
+		logging.WithContext(ctx, h.Logger).Error("Failed to begin approval process",
 			zap.Error(err),
 			zap.String("request_id", requestID))
-		return nil, errors.New("database error while beginning approval")
+		return nil, ErrDatabase.Wrap(err)
 	}
 
 	if result == 0 {
-		// Document wasn't updated - might not exist or not be in pending state
-		h.Logger.Warn("No pending request found for approval",
+		// Document wasn't updated - might not exist, not be in pending state or already be
+		// in progress under this approval
+		request, err := h.GetTenantByID(ctx, requestID)
+		if requestMap, ok := request.(map[string]interface{}); err == nil && ok &&
+			requestMap["status"] == string(models.OnboardingStatusApprovalInProgress) &&
+			requestMap["approval_id"] == approvalID {
+			return requestMap, nil
+		}
+		logging.WithContext(ctx, h.Logger).Warn("No pending request found for approval",
 			zap.String("request_id", requestID))
-		return nil, errors.New("no pending request found with the given ID")
+		return nil, ErrNotPending
 	}
+	metrics.OnboardingRequests.WithLabelValues(string(models.OnboardingStatusApprovalInProgress)).Inc()
 
 	// Get the updated request data
 	request, err := h.GetTenantByID(ctx, requestID)
 	if err != nil {
-		h.Logger.Error("Failed to retrieve request after beginning approval",
+		logging.WithContext(ctx, h.Logger).Error("Failed to retrieve request after beginning approval",
 			zap.Error(err),
 			zap.String("request_id", requestID))
 		return nil, errors.New("failed to retrieve request data")
 	}
 
-	return request.(map[string]interface{}), nil
+	requestMap := request.(map[string]interface{})
+	tenantID, _ := requestMap["tenant_id"].(string)
+	h.recordAudit(ctx, audit.Event{
+		Operation:    "onboarding.begin_approval",
+		ResourceType: audit.ResourceOnboardingRequest,
+		ResourceID:   requestID,
+		TenantID:     tenantID,
+		Before:       map[string]interface{}{"status": models.OnboardingStatusPending},
+		After:        map[string]interface{}{"status": models.OnboardingStatusApprovalInProgress},
+	})
+	return requestMap, nil
 }
 
 // MarkUserCreated updates the request to indicate the user was created
 func (h *onboardingService) MarkUserCreated(ctx context.Context, requestID string) error {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.MarkUserCreated")
+	defer span.End()
+
 	now := time.Now()
 	filter := bson.M{"request_id": requestID, "status": models.OnboardingStatusApprovalInProgress}
 	update := bson.M{
@@ -234,23 +411,34 @@ func (h *onboardingService) MarkUserCreated(ctx context.Context, requestID strin
 	)
 
 	if err != nil {
-		h.Logger.Error("Failed to mark user as created",
+		logging.WithContext(ctx, h.Logger).Error("Failed to mark user as created",
 			zap.Error(err),
 			zap.String("request_id", requestID))
-		return errors.New("database error while updating user creation status")
+		return ErrDatabase.Wrap(err)
 	}
 
 	if result == 0 {
 		// Document wasn't updated
-		h.Logger.Warn("No in-progress request found for marking user created",
+		logging.WithContext(ctx, h.Logger).Warn("No in-progress request found for marking user created",
 			zap.String("request_id", requestID))
-		return errors.New("no in-progress request found with the given ID")
+		return ErrNotInProgress
 	}
 
+	metrics.OnboardingRequests.WithLabelValues(string(models.OnboardingStatusUserCreated)).Inc()
+	h.recordAudit(ctx, audit.Event{
+		Operation:    "onboarding.user_created",
+		ResourceType: audit.ResourceOnboardingRequest,
+		ResourceID:   requestID,
+		Before:       map[string]interface{}{"status": models.OnboardingStatusApprovalInProgress},
+		After:        map[string]interface{}{"status": models.OnboardingStatusUserCreated},
+	})
 	return nil
 }
 
 func (h *onboardingService) CompleteApproval(ctx context.Context, requestID string) error {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.CompleteApproval")
+	defer span.End()
+
 	// Get the request data with user_created status
 	filter := bson.M{"request_id": requestID, "status": models.OnboardingStatusUserCreated}
 	request, err := h.db.Read(
@@ -261,73 +449,54 @@ func (h *onboardingService) CompleteApproval(ctx context.Context, requestID stri
 	)
 
 	if err != nil {
-		h.Logger.Error("Failed to retrieve request for completion",
+		logging.WithContext(ctx, h.Logger).Error("Failed to retrieve request for completion",
 			zap.Error(err),
 			zap.String("request_id", requestID))
-		return errors.New("database error while retrieving request")
-	}
-
-	if request == nil {
-		h.Logger.Warn("No user-created request found for completion",
-			zap.String("request_id", requestID))
-		return errors.New("no user-created request found with the given ID")
+		return ErrDatabase.Wrap(err)
 	}
 
 	// Convert to map if not already
 	requestMap, ok := request.(map[string]interface{})
 	if !ok {
-		h.Logger.Error("Invalid request data format",
+		logging.WithContext(ctx, h.Logger).Error("Invalid request data format",
 			zap.Any("request", request))
 		return errors.New("invalid request data format")
 	}
 
-	// Create a new map for approved tenant
-	now := time.Now()
-	approvedRequest := make(map[string]interface{})
-
-	// Copy all existing fields except MongoDB internal _id
-	for k, v := range requestMap {
-		if k != "_id" {
-			approvedRequest[k] = v
-		}
+	// A missing request comes back as an empty map
+	if len(requestMap) == 0 {
+		logging.WithContext(ctx, h.Logger).Warn("No user-created request found for completion",
+			zap.String("request_id", requestID))
+		return ErrNotUserCreated
 	}
 
-	// Update status and timestamps
-	approvedRequest["status"] = models.OnboardingStatusActive
-	approvedRequest["approved_at"] = now.String()
-
-	// Insert into onboarded_tenants collection
-	_, err = h.db.Create(
-		ctx,
-		approvedRequest,
-		db.WithDatabaseName(config.DatabaseNames.CoreDB),
-		db.WithCollectionName(config.CollectionNames.OnboardedTenants),
-	)
-
-	if err != nil {
-		h.Logger.Error("Failed to create approved tenant record",
-			zap.Error(err),
+	// The tenant is created, the request removed and TenantApproved published together, so
+	// a failure leaves the request user_created for the recovery to complete
+	approvedRequest, err := activateTenant(ctx, h.db, h.domainEvents(), requestMap)
+	if errors.Is(err, ErrNotUserCreated) {
+		logging.WithContext(ctx, h.Logger).Warn("Onboarding request was completed concurrently",
 			zap.String("request_id", requestID))
-		return errors.New("failed to complete approval process")
+		return err
 	}
-
-	// Delete from onboarding_requests collection
-	_, err = h.db.Delete(
-		ctx,
-		filter,
-		db.WithDatabaseName(config.DatabaseNames.CoreDB),
-		db.WithCollectionName(config.CollectionNames.OnboardingRequests),
-	)
-
 	if err != nil {
-		h.Logger.Warn("Failed to delete original request after approval",
+		logging.WithContext(ctx, h.Logger).Error("Failed to create approved tenant record",
 			zap.Error(err),
 			zap.String("request_id", requestID))
-		// We won't return an error here as the tenant is already approved
-		// This will be handled by cleanup processes
+		return ErrDatabase.Wrap(err)
 	}
-
-	h.Logger.Info("Onboarding approval completed successfully",
+	metrics.OnboardingRequests.WithLabelValues(string(models.OnboardingStatusActive)).Inc()
+
+	tenantID, _ := approvedRequest["tenant_id"].(string)
+	h.recordAudit(ctx, audit.Event{
+		Operation:    "tenant.approve",
+		ResourceType: audit.ResourceTenant,
+		ResourceID:   tenantID,
+		TenantID:     tenantID,
+		Before:       requestMap,
+		After:        approvedRequest,
+	})
+
+	logging.WithContext(ctx, h.Logger).Info("Onboarding approval completed successfully",
 		zap.String("request_id", requestID),
 		zap.String("tenant_id", approvedRequest["tenant_id"].(string)),
 		zap.String("email", approvedRequest["email"].(string)),
@@ -336,50 +505,166 @@ func (h *onboardingService) CompleteApproval(ctx context.Context, requestID stri
 	return nil
 }
 
-// MarkApprovalFailed records a failure during the approval process
+// MarkApprovalFailed records a failed approval of an in-progress request in its failure
+// history. The request is retried after a backoff until it failed RetryMaxAttempts times,
+// and is then dead-lettered for an operator. Marking a request that already failed again
+// does nothing, as the compensation calling it may be retried.
 func (h *onboardingService) MarkApprovalFailed(ctx context.Context, requestID string, reason string) error {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.MarkApprovalFailed")
+	defer span.End()
+
+	err := h.failApproval(ctx, requestID, reason,
+		models.OnboardingStatusApprovalInProgress,
+		models.OnboardingStatusUserCreated)
+
+	if errors.Is(err, ErrNotInProgress) {
+		request, getErr := h.GetTenantByID(ctx, requestID)
+		if requestMap, ok := request.(map[string]interface{}); getErr == nil && ok &&
+			(requestMap["status"] == string(models.OnboardingStatusFailed) ||
+				requestMap["status"] == string(models.OnboardingStatusDeadLetter)) {
+			return nil
+		}
+		logging.WithContext(ctx, h.Logger).Warn("No in-progress request found to mark as failed",
+			zap.String("request_id", requestID))
+		return ErrNotInProgress
+	}
+	if err != nil {
+		logging.WithContext(ctx, h.Logger).Error("Failed to mark approval as failed",
+			zap.Error(err),
+			zap.String("request_id", requestID))
+		return ErrDatabase.Wrap(err)
+	}
+	return nil
+}
+
+// failApproval counts a failed attempt to approve a request in one of the given statuses:
+// it adds the failure to its history, schedules the next retry or dead-letters the request,
+// and publishes OnboardingFailed. It returns ErrNotInProgress when the request is in none
+// of the statuses.
+func (h *onboardingService) failApproval(ctx context.Context, requestID, reason string, statuses ...models.OnboardingStatus) error {
 	now := time.Now()
-	filter := bson.M{"request_id": requestID}
-	update := bson.M{
-		"$set": bson.M{
+	matched := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		matched = append(matched, string(status))
+	}
+	filter := bson.M{
+		"request_id": requestID,
+		"status":     bson.M{"$in": matched},
+	}
+
+	// Update the document, publishing OnboardingFailed with it
+	var changes bson.M
+	err := h.db.WithTransaction(ctx, func(ctx context.Context) error {
+		request, err := h.db.Read(
+			ctx,
+			filter,
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.OnboardingRequests),
+		)
+		if err != nil {
+			return err
+		}
+		requestMap, _ := request.(map[string]interface{})
+		if len(requestMap) == 0 {
+			return ErrNotInProgress
+		}
+
+		attempt := retryCount(requestMap) + 1
+		var approvalID string
+		if requestMap["status"] != string(models.OnboardingStatusPending) {
+			// A pending request failed to start its approval, so the ID is of an earlier one
+			approvalID, _ = requestMap["approval_id"].(string)
+		}
+		changes = bson.M{
 			"status":         models.OnboardingStatusFailed,
 			"failure_reason": reason,
 			"failed_at":      now,
-		},
-		"$inc": bson.M{
-			"retry_count": 1,
-		},
-	}
+			"retry_count":    attempt,
+		}
+		var nextRetryAt *time.Time
+		if attempt >= h.settings.RetryMaxAttempts {
+			changes["status"] = models.OnboardingStatusDeadLetter
+			changes["dead_lettered_at"] = now
+		} else {
+			retryAt := now.Add(worker.Backoff(h.settings.RetryBaseDelay, h.settings.RetryMaxDelay, attempt))
+			changes["next_retry_at"] = retryAt
+			nextRetryAt = &retryAt
+		}
+		update := bson.M{
+			"$set": changes,
+			"$push": bson.M{"failure_history": models.ApprovalFailure{
+				Attempt:    attempt,
+				ApprovalID: approvalID,
+				Reason:     reason,
+				FailedAt:   now,
+			}},
+		}
 
-	// Update the document
-	_, err := h.db.UpdateOne(
-		ctx,
-		filter,
-		update,
-		db.WithDatabaseName(config.DatabaseNames.CoreDB),
-		db.WithCollectionName(config.CollectionNames.OnboardingRequests),
-	)
+		// The status is matched again in case the request moved on since it was read
+		updated, err := h.db.UpdateOne(
+			ctx,
+			filter,
+			update,
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.OnboardingRequests),
+		)
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return ErrNotInProgress
+		}
 
+		bus := h.domainEvents()
+		if bus == nil {
+			return nil
+		}
+		tenantID, _ := requestMap["tenant_id"].(string)
+		email, _ := requestMap["email"].(string)
+		return bus.Publish(ctx, domainevents.OnboardingFailed{
+			TenantID:     tenantID,
+			RequestID:    requestID,
+			Email:        email,
+			Reason:       reason,
+			RetryCount:   attempt,
+			DeadLettered: nextRetryAt == nil,
+			NextRetryAt:  nextRetryAt,
+			FailedAt:     now.UTC(),
+		})
+	})
 	if err != nil {
-		h.Logger.Error("Failed to mark approval as failed",
-			zap.Error(err),
-			zap.String("request_id", requestID))
-		return errors.New("database error while recording failure")
+		return err
 	}
 
+	status, _ := changes["status"].(models.OnboardingStatus)
+	metrics.OnboardingRequests.WithLabelValues(string(status)).Inc()
+	if status == models.OnboardingStatusDeadLetter {
+		logging.WithContext(ctx, h.Logger).Warn("Onboarding request dead-lettered after repeated approval failures",
+			zap.String("request_id", requestID),
+			zap.Any("retry_count", changes["retry_count"]))
+	}
+	h.recordAudit(ctx, audit.Event{
+		Operation:    "onboarding.fail",
+		ResourceType: audit.ResourceOnboardingRequest,
+		ResourceID:   requestID,
+		After:        changes,
+	})
 	return nil
 }
 
-// RevertToRetriable reverts a failed request back to pending status for retry
+// RevertToRetriable returns a failed request that is due for a retry to pending, so that it
+// can be approved again. Of several replicas reverting the request at once, one succeeds
+// and the others get ErrNotRetriable.
 func (h *onboardingService) RevertToRetriable(ctx context.Context, requestID string) error {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.RevertToRetriable")
+	defer span.End()
+
 	now := time.Now()
 	filter := bson.M{
 		"request_id": requestID,
-		"status": bson.M{"$in": []string{
-			string(models.OnboardingStatusApprovalInProgress),
-			string(models.OnboardingStatusUserCreated),
-			string(models.OnboardingStatusFailed),
-		}},
+		"status":     models.OnboardingStatusFailed,
+		// Requests that failed before retries were scheduled have no retry time
+		"next_retry_at": bson.M{"$not": bson.M{"$gt": now}},
 	}
 
 	update := bson.M{
@@ -387,6 +672,9 @@ func (h *onboardingService) RevertToRetriable(ctx context.Context, requestID str
 			"status":        models.OnboardingStatusPending,
 			"last_retry_at": now,
 		},
+		"$unset": bson.M{
+			"next_retry_at": "",
+		},
 	}
 
 	// Update the document
@@ -399,21 +687,145 @@ func (h *onboardingService) RevertToRetriable(ctx context.Context, requestID str
 	)
 
 	if err != nil {
-		h.Logger.Error("Failed to revert request to pending status",
+		logging.WithContext(ctx, h.Logger).Error("Failed to revert request to pending status",
 			zap.Error(err),
 			zap.String("request_id", requestID))
-		return errors.New("database error while reverting status")
+		return ErrDatabase.Wrap(err)
 	}
 
 	if result == 0 {
-		h.Logger.Warn("No request found for reversion",
+		logging.WithContext(ctx, h.Logger).Warn("No request found for reversion",
 			zap.String("request_id", requestID))
-		return errors.New("no eligible request found with the given ID")
+		return ErrNotRetriable
 	}
 
+	metrics.OnboardingRequests.WithLabelValues(string(models.OnboardingStatusPending)).Inc()
+	h.recordAudit(ctx, audit.Event{
+		Operation:    "onboarding.retry",
+		ResourceType: audit.ResourceOnboardingRequest,
+		ResourceID:   requestID,
+		Before:       map[string]interface{}{"status": models.OnboardingStatusFailed},
+		After:        map[string]interface{}{"status": models.OnboardingStatusPending},
+	})
 	return nil
 }
 
+// RejectRequest turns down a pending or failed onboarding request on behalf of reviewer.
+// The request is kept as rejected, blocking its email until the re-application cooldown
+// is over, and OnboardingRejected is published with the change.
+func (h *onboardingService) RejectRequest(ctx context.Context, rejection models.OnboardingRejection, reviewer string) (map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.RejectRequest")
+	defer span.End()
+
+	if !slices.Contains(models.RejectionReasons, rejection.ReasonCode) {
+		reasons := make([]string, 0, len(models.RejectionReasons))
+		for _, reason := range models.RejectionReasons {
+			reasons = append(reasons, string(reason))
+		}
+		return nil, apperrors.Validation("invalid_rejection", "Invalid rejection",
+			apperrors.FieldError{Field: "reason_code", Message: "must be one of " + strings.Join(reasons, ", ")})
+	}
+	if len(rejection.Comment) > maxRejectionComment {
+		return nil, apperrors.Validation("invalid_rejection", "Invalid rejection",
+			apperrors.FieldError{Field: "comment", Message: fmt.Sprintf("must be at most %d bytes", maxRejectionComment)})
+	}
+
+	now := time.Now()
+	reapplyAfter := now.Add(h.settings.ReapplyCooldown)
+	filter := bson.M{
+		"request_id": rejection.RequestID,
+		"status": bson.M{"$in": []string{
+			string(models.OnboardingStatusPending),
+			string(models.OnboardingStatusFailed),
+		}},
+	}
+	changes := bson.M{
+		"status":           models.OnboardingStatusRejected,
+		"rejected_at":      now,
+		"rejected_by":      reviewer,
+		"rejection_reason": rejection.ReasonCode,
+		"reapply_after":    reapplyAfter,
+	}
+	if rejection.Comment != "" {
+		changes["rejection_comment"] = rejection.Comment
+	}
+
+	var requestMap map[string]interface{}
+	err := h.db.WithTransaction(ctx, func(ctx context.Context) error {
+		request, err := h.db.Read(ctx, filter,
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.OnboardingRequests))
+		if err != nil {
+			return err
+		}
+		requestMap, _ = request.(map[string]interface{})
+		if len(requestMap) == 0 {
+			return ErrNotRejectable
+		}
+
+		// The status is matched again in case the request moved on since it was read
+		updated, err := h.db.UpdateOne(ctx, filter, bson.M{"$set": changes},
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.OnboardingRequests))
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return ErrNotRejectable
+		}
+
+		bus := h.domainEvents()
+		if bus == nil {
+			return nil
+		}
+		tenantID, _ := requestMap["tenant_id"].(string)
+		organizationName, _ := requestMap["organization_name"].(string)
+		email, _ := requestMap["email"].(string)
+		return bus.Publish(ctx, domainevents.OnboardingRejected{
+			TenantID:         tenantID,
+			RequestID:        rejection.RequestID,
+			OrganizationName: organizationName,
+			Email:            email,
+			ReasonCode:       string(rejection.ReasonCode),
+			Comment:          rejection.Comment,
+			RejectedAt:       now.UTC(),
+			ReapplyAfter:     reapplyAfter.UTC(),
+		})
+	})
+	if errors.Is(err, ErrNotRejectable) {
+		logging.WithContext(ctx, h.Logger).Warn("No rejectable request found",
+			zap.String("request_id", rejection.RequestID))
+		return nil, ErrNotRejectable
+	}
+	if err != nil {
+		logging.WithContext(ctx, h.Logger).Error("Failed to reject onboarding request",
+			zap.Error(err),
+			zap.String("request_id", rejection.RequestID))
+		return nil, ErrDatabase.Wrap(err)
+	}
+
+	metrics.OnboardingRequests.WithLabelValues(string(models.OnboardingStatusRejected)).Inc()
+	tenantID, _ := requestMap["tenant_id"].(string)
+	rejectedRequest := make(map[string]interface{}, len(requestMap)+len(changes))
+	for k, v := range requestMap {
+		if k != "_id" {
+			rejectedRequest[k] = v
+		}
+	}
+	for k, v := range changes {
+		rejectedRequest[k] = v
+	}
+	h.recordAudit(ctx, audit.Event{
+		Operation:    "onboarding.reject",
+		ResourceType: audit.ResourceOnboardingRequest,
+		ResourceID:   rejection.RequestID,
+		TenantID:     tenantID,
+		Before:       map[string]interface{}{"status": requestMap["status"]},
+		After:        changes,
+	})
+	return rejectedRequest, nil
+}
+
 // func (h *onboardingService) ApproveOnboarding(ctx context.Context, requestID string) error {
 // 	filter := bson.M{"request_id": requestID, "status": "pending"}
 // 	request, err := h.db.Read(ctx, filter,
@@ -422,20 +834,20 @@ func (h *onboardingService) RevertToRetriable(ctx context.Context, requestID str
 
 // 	// Check if request is nil regardless of error
 // 	if request == nil {
-// 		h.Logger.Warn("Request is nil", zap.Error(err), zap.String("request_id", requestID))
+// 		logging.WithContext(ctx, h.Logger).Warn("Request is nil", zap.Error(err), zap.String("request_id", requestID))
 // 		return errors.New("no pending request found with the given ID")
 // 	}
 
 // 	// Now let's explicitly handle empty map case
 // 	requestMap, ok := request.(map[string]interface{})
 // 	if !ok {
-// 		h.Logger.Info("Failed to convert request to map", zap.Any("request", request))
+// 		logging.WithContext(ctx, h.Logger).Info("Failed to convert request to map", zap.Any("request", request))
 // 		return errors.New("failed to process request data: type conversion failed")
 // 	}
 
 // 	// Check if the map is empty
 // 	if len(requestMap) == 0 {
-// 		h.Logger.Info("Request map is empty", zap.Any("request", request))
+// 		logging.WithContext(ctx, h.Logger).Info("Request map is empty", zap.Any("request", request))
 // 		return errors.New("failed to process request data: empty data returned")
 // 	}
 
@@ -457,7 +869,7 @@ func (h *onboardingService) RevertToRetriable(ctx context.Context, requestID str
 // 		db.WithDatabaseName(config.DatabaseNames.CoreDB),
 // 		db.WithCollectionName(config.CollectionNames.OnboardedTenants))
 // 	if err != nil {
-// 		h.Logger.Info("Failed to create approved tenant", zap.Error(err))
+// 		logging.WithContext(ctx, h.Logger).Info("Failed to create approved tenant", zap.Error(err))
 // 		return err
 // 	}
 
@@ -466,11 +878,11 @@ func (h *onboardingService) RevertToRetriable(ctx context.Context, requestID str
 // 		db.WithDatabaseName(config.DatabaseNames.CoreDB),
 // 		db.WithCollectionName(config.CollectionNames.OnboardingRequests))
 // 	if err != nil {
-// 		h.Logger.Info("Failed to delete pending request", zap.Error(err))
+// 		logging.WithContext(ctx, h.Logger).Info("Failed to delete pending request", zap.Error(err))
 // 		// Continue despite error
 // 	}
 
-// 	h.Logger.Info("Approved for",
+// 	logging.WithContext(ctx, h.Logger).Info("Approved for",
 // 		zap.String("email", newRequestMap["email"].(string)),
 // 		zap.String("request_id", newRequestMap["request_id"].(string)),
 // 	)
@@ -480,12 +892,160 @@ func (h *onboardingService) RevertToRetriable(ctx context.Context, requestID str
 
 // GetPendingRequests fetches pending onboarding requests
 func (h *onboardingService) GetActiveOrg(ctx context.Context) (interface{}, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.GetActiveOrg")
+	defer span.End()
+
 	filter := bson.M{"status": "active"}
 	requests, err := h.db.ReadAll(ctx, filter,
 		db.WithDatabaseName(config.DatabaseNames.CoreDB),
 		db.WithCollectionName(config.CollectionNames.OnboardedTenants))
 	if err != nil {
-		return nil, errors.New("failed to fetch pending requests")
+		return nil, ErrDatabase.Wrap(err)
 	}
 	return requests, nil
 }
+
+// registerWebhook registers the webhook endpoint given with an onboarding request for the
+// tenant it will create. Invalid URLs are reported against the webhook_url field.
+func (h *onboardingService) registerWebhook(ctx context.Context, tenantID, webhookURL string) (*webhooks.Endpoint, error) {
+	webhookService, ok := h.svcRegistry.Get(registry.WebhookService).(webhooksvc.Service)
+	if !ok {
+		return nil, apperrors.Internal("service_not_registered", "internal service error", nil)
+	}
+	endpoint, err := webhookService.CreateEndpoint(ctx, tenantID, models.WebhookEndpointRequest{
+		URL:         webhookURL,
+		EventTypes:  []string{webhooks.TenantApproved, webhooks.OnboardingRejected},
+		Description: "Registered with the onboarding request",
+	})
+	if invalid, ok := apperrors.As(err); ok && invalid.Kind == apperrors.KindValidation {
+		var fields []apperrors.FieldError
+		for _, field := range invalid.Fields {
+			fields = append(fields, apperrors.FieldError{Field: "webhook_url", Message: field.Message})
+		}
+		return nil, apperrors.Validation("invalid_webhook_url", "Invalid webhook URL", fields...)
+	}
+	return endpoint, err
+}
+
+// removeWebhook deletes a webhook endpoint registered for a request that was not stored
+func (h *onboardingService) removeWebhook(ctx context.Context, tenantID, endpointID string) {
+	if webhookService, ok := h.svcRegistry.Get(registry.WebhookService).(webhooksvc.Service); ok {
+		if err := webhookService.DeleteEndpoint(ctx, tenantID, endpointID); err != nil {
+			logging.WithContext(ctx, h.Logger).Warn("Failed to remove webhook of unsaved onboarding request",
+				zap.Error(err), zap.String("endpoint_id", endpointID))
+		}
+	}
+}
+
+// domainEvents returns the bus domain events are published on, nil if none is registered
+func (h *onboardingService) domainEvents() *domainevents.Bus {
+	bus, _ := h.svcRegistry.Get(registry.DomainEvents).(*domainevents.Bus)
+	return bus
+}
+
+// activateTenant moves a user-created onboarding request to the onboarded tenants and
+// publishes TenantApproved, in one transaction. It returns the tenant record, or
+// ErrNotUserCreated if the request was completed in the meantime.
+func activateTenant(ctx context.Context, database db.DBClientInterface, bus *domainevents.Bus, request map[string]interface{}) (map[string]interface{}, error) {
+	// Create a new map for approved tenant
+	now := time.Now()
+	approvedRequest := make(map[string]interface{})
+
+	// Copy all existing fields except MongoDB internal _id
+	for k, v := range request {
+		if k != "_id" {
+			approvedRequest[k] = v
+		}
+	}
+
+	// Update status and timestamps
+	approvedRequest["status"] = models.OnboardingStatusActive
+	approvedRequest["approved_at"] = now.String()
+
+	requestID, _ := request["request_id"].(string)
+	err := database.WithTransaction(ctx, func(ctx context.Context) error {
+		// Removing the request claims it, so a concurrent completer aborts instead of
+		// creating the tenant and publishing TenantApproved a second time
+		result, err := database.Delete(
+			ctx,
+			bson.M{"request_id": requestID, "status": models.OnboardingStatusUserCreated},
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.OnboardingRequests),
+		)
+		if err != nil {
+			return err
+		}
+		if deleted, _ := result.(int64); deleted == 0 {
+			return ErrNotUserCreated
+		}
+
+		// Insert into onboarded_tenants collection
+		if _, err := database.Create(
+			ctx,
+			approvedRequest,
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.OnboardedTenants),
+		); err != nil {
+			return err
+		}
+
+		if bus == nil {
+			return nil
+		}
+		tenantID, _ := approvedRequest["tenant_id"].(string)
+		organizationName, _ := approvedRequest["organization_name"].(string)
+		email, _ := approvedRequest["email"].(string)
+		username, _ := approvedRequest["username"].(string)
+		return bus.Publish(ctx, domainevents.TenantApproved{
+			TenantID:         tenantID,
+			RequestID:        requestID,
+			OrganizationName: organizationName,
+			Email:            email,
+			Username:         username,
+			ApprovedAt:       now.UTC(),
+		})
+	})
+	return approvedRequest, err
+}
+
+// retryCount returns how often an onboarding request has failed so far
+func retryCount(request map[string]interface{}) int {
+	return intField(request, "retry_count")
+}
+
+// intField returns a number of a document, whichever integer type it was decoded as
+func intField(doc map[string]interface{}, key string) int {
+	switch n := doc[key].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
+
+// timeField returns a time of a document, whether it was decoded from MongoDB as a
+// primitive.DateTime or set in memory as a time.Time, or the zero time
+func timeField(doc map[string]interface{}, key string) time.Time {
+	switch t := doc[key].(type) {
+	case time.Time:
+		return t.UTC()
+	case primitive.DateTime:
+		return t.Time().UTC()
+	}
+	return time.Time{}
+}
+
+// recordAudit records a mutation in the audit trail
+func (h *onboardingService) recordAudit(ctx context.Context, event audit.Event) {
+	auditService, ok := h.svcRegistry.Get(registry.AuditService).(auditsvc.Service)
+	if !ok {
+		logging.WithContext(ctx, h.Logger).Error("Audit service not registered, mutation not audited",
+			zap.String("operation", event.Operation),
+			zap.String("resource_id", event.ResourceID))
+		return
+	}
+	auditService.Record(ctx, event)
+}
@@ -0,0 +1,38 @@
+package onboardingsvc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+func TestReapplyCooldown(t *testing.T) {
+	reapplyAfter := time.Now().Add(48 * time.Hour).Truncate(time.Millisecond)
+	requests := &memoryRequests{docs: []map[string]interface{}{{
+		"request_id": "req-1",
+		"email":      "owner@clinic.test",
+		"status":     string(models.OnboardingStatusRejected),
+		// Dates come back from MongoDB as primitive.DateTime
+		"reapply_after": primitive.NewDateTimeFromTime(reapplyAfter),
+	}}}
+	service := NewService(requests, registry.NewServiceRegistry(), zap.NewNop(), config.OnboardingConfig{})
+
+	_, err := service.OnboardTenant(context.Background(), models.OnboardingRequest{
+		OrganizationName: "Clinic",
+		Email:            "owner@clinic.test",
+		Role:             "admin",
+	})
+	domainErr, ok := apperrors.As(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "reapply_cooldown", domainErr.Code)
+		assert.Contains(t, domainErr.Message, reapplyAfter.UTC().Format(time.RFC3339), "the date the email can apply again is reported")
+	}
+}
@@ -3,92 +3,394 @@ package onboardingsvc
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/mrityunjay-vashisth/core-service/internal/config"
 	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/domainevents"
+	"github.com/mrityunjay-vashisth/core-service/internal/leader"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
 	"github.com/mrityunjay-vashisth/core-service/internal/models"
 	"github.com/mrityunjay-vashisth/core-service/internal/services/authsvc"
+	"github.com/mrityunjay-vashisth/go-idforge/pkg/idforge"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.uber.org/zap"
 )
 
-// StuckRequestRecovery periodically checks for and fixes stuck onboarding requests
+// RecoveryLease is the name of the lease held by the replica running the recovery
+const RecoveryLease = "stuck_request_recovery"
+
+// How long RunNow waits for the leader to finish a requested run, and how often it checks
+const (
+	manualRunWait = 20 * time.Second
+	manualRunPoll = 250 * time.Millisecond
+)
+
+// StuckRequestRecovery periodically checks for and fixes stuck onboarding requests. Requests
+// approved by a saga are left to the saga, which retries and compensates its own steps.
+//
+// Every replica runs the recovery loop, but only the replica holding the recovery lease
+// recovers requests; the others stand by to take the lease over. Runs requested on any
+// replica are stored for the leader to pick up, and every run stores a report of the
+// requests it examined.
 type StuckRequestRecovery struct {
 	db                db.DBClientInterface
 	authService       authsvc.Service
+	bus               *domainevents.Bus // Where TenantApproved is published, if set
 	logger            *zap.Logger
 	inProgressMaxAge  time.Duration // How long a request can be "in progress" before we consider it stuck
 	userCreatedMaxAge time.Duration // How long a request can be in "user created" state before we consider it stuck
-	ticker            *time.Ticker
+	interval          time.Duration // How often the recovery runs
+	leaseTTL          time.Duration // How long the leader's lease lasts without renewal
+	elector           *leader.Elector
+	runs              RunStore
+	wake              chan struct{}
 	stopChan          chan struct{}
+
+	// When the leader last started a scheduled run, or took the lease; only the loop uses it
+	lastScheduled time.Time
+
+	statusMutex   sync.RWMutex
+	lastSuccessAt time.Time
+}
+
+// Recovery run triggers
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// Recovery run statuses
+const (
+	RunRequested = "requested" // Waiting for the leader to pick it up
+	RunRunning   = "running"
+	RunCompleted = "completed"
+	RunFailed    = "failed"
+)
+
+// Actions taken on a stuck request, also the labels of the recovery_actions_total metric
+const (
+	ActionPromotedToUserCreated = "promoted_to_user_created"
+	ActionRevertedToPending     = "reverted_to_pending"
+	ActionApprovalCompleted     = "approval_completed"
+	ActionSkipped               = "skipped" // Left for a later run after an error
+)
+
+// RecoveryRun reports what a recovery run did
+type RecoveryRun struct {
+	ID                    string           `json:"id" bson:"_id"`
+	Trigger               string           `json:"trigger" bson:"trigger"`
+	Status                string           `json:"status" bson:"status"`
+	RequestedBy           string           `json:"requested_by,omitempty" bson:"requested_by,omitempty"`
+	Replica               string           `json:"replica,omitempty" bson:"replica,omitempty"` // The replica that ran it
+	RequestedAt           time.Time        `json:"requested_at" bson:"requested_at"`
+	StartedAt             *time.Time       `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	FinishedAt            *time.Time       `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+	PromotedToUserCreated int              `json:"promoted_to_user_created" bson:"promoted_to_user_created"`
+	RevertedToPending     int              `json:"reverted_to_pending" bson:"reverted_to_pending"`
+	ApprovalsCompleted    int              `json:"approvals_completed" bson:"approvals_completed"`
+	Skipped               int              `json:"skipped" bson:"skipped"` // Stuck requests left for a later run after an error
+	Requests              []RecoveryAction `json:"requests" bson:"requests"`
+	Error                 string           `json:"error,omitempty" bson:"error,omitempty"`
 }
 
-// NewStuckRequestRecovery creates a new recovery system
+// Finished reports whether the run completed or failed
+func (r RecoveryRun) Finished() bool {
+	return r.Status == RunCompleted || r.Status == RunFailed
+}
+
+// RecoveryAction reports what a run did with one stuck request
+type RecoveryAction struct {
+	RequestID string `json:"request_id" bson:"request_id"`
+	TenantID  string `json:"tenant_id,omitempty" bson:"tenant_id,omitempty"`
+	Status    string `json:"status" bson:"status"` // The status the request was stuck in
+	Action    string `json:"action" bson:"action"`
+	Error     string `json:"error,omitempty" bson:"error,omitempty"` // Why the request was skipped
+}
+
+// RecoveryStatus describes the recovery schedule, its leader and its latest runs
+type RecoveryStatus struct {
+	Interval          string        `json:"interval"`
+	InProgressMaxAge  string        `json:"in_progress_max_age"`
+	UserCreatedMaxAge string        `json:"user_created_max_age"`
+	LeaseTTL          string        `json:"lease_ttl"`
+	Replica           string        `json:"replica"`          // The replica answering
+	Leader            *leader.Lease `json:"leader,omitempty"` // Unset while no replica leads
+	LastSuccessfulRun *time.Time    `json:"last_successful_run,omitempty"`
+	LastRun           *RecoveryRun  `json:"last_run,omitempty"`
+}
+
+// NewStuckRequestRecovery creates a new recovery system, which recovers requests while
+// elector holds the recovery lease and stores its runs in runs. Approvals it completes are
+// published on bus, which may be nil.
 func NewStuckRequestRecovery(
 	db db.DBClientInterface,
 	authService authsvc.Service,
+	bus *domainevents.Bus,
 	logger *zap.Logger,
+	settings config.RecoveryConfig,
+	elector *leader.Elector,
+	runs RunStore,
 ) *StuckRequestRecovery {
 	return &StuckRequestRecovery{
 		db:                db,
 		authService:       authService,
+		bus:               bus,
 		logger:            logger,
-		inProgressMaxAge:  3 * time.Minute, // Configurable
-		userCreatedMaxAge: 3 * time.Minute, // Configurable
+		inProgressMaxAge:  settings.InProgressMaxAge,
+		userCreatedMaxAge: settings.UserCreatedMaxAge,
+		interval:          settings.Interval,
+		leaseTTL:          settings.LeaseTTL,
+		elector:           elector,
+		runs:              runs,
+		wake:              make(chan struct{}, 1),
 		stopChan:          make(chan struct{}),
 	}
 }
 
-// Start begins the periodic recovery process
+// Start begins campaigning for the recovery lease every third of its TTL and, while
+// leading, running requested runs and a scheduled run every interval
 func (r *StuckRequestRecovery) Start() {
-	r.ticker = time.NewTicker(1 * time.Minute) // Run every 15 minutes
+	ticker := time.NewTicker(r.leaseTTL / 3)
 
 	go func() {
 		for {
+			r.tick(context.Background())
 			select {
-			case <-r.ticker.C:
-				if err := r.RecoverStuckRequests(context.Background()); err != nil {
-					r.logger.Error("Error recovering stuck requests", zap.Error(err))
-				}
+			case <-ticker.C:
+			case <-r.wake:
 			case <-r.stopChan:
-				r.ticker.Stop()
+				ticker.Stop()
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := r.elector.Resign(ctx); err != nil {
+					r.logger.Warn("Failed to release the recovery lease", zap.Error(err))
+				}
+				cancel()
 				return
 			}
 		}
 	}()
 
-	r.logger.Info("Stuck request recovery system started")
+	r.logger.Info("Stuck request recovery system started", zap.String("replica", r.elector.Holder()))
 }
 
-// Stop halts the recovery process
+// Stop halts the recovery process and hands the lease over to another replica
 func (r *StuckRequestRecovery) Stop() {
 	close(r.stopChan)
 	r.logger.Info("Stuck request recovery system stopped")
 }
 
-// RecoverStuckRequests finds and fixes stuck requests
+// tick keeps or takes the lease and, if leading, performs the runs that are due
+func (r *StuckRequestRecovery) tick(ctx context.Context) {
+	wasLeading := r.elector.IsLeader()
+	leading, err := r.elector.Campaign(ctx)
+	if err != nil {
+		r.logger.Error("Failed to campaign for the recovery lease", zap.Error(err))
+	}
+	if !leading {
+		metrics.RecoveryLeader.Set(0)
+		if err == nil {
+			// Standing by is all a follower has to do
+			r.succeeded(time.Now())
+		}
+		return
+	}
+	metrics.RecoveryLeader.Set(1)
+	if !wasLeading {
+		// The previous leader ran on schedule until it lost the lease
+		r.lastScheduled = time.Now()
+	}
+
+	if err := r.RecoverStuckRequests(ctx); err != nil {
+		r.logger.Error("Error recovering stuck requests", zap.Error(err))
+	}
+}
+
+// RecoverStuckRequests performs the runs requested through RunNow, then a scheduled run if
+// one is due. Only the leader calls it.
 func (r *StuckRequestRecovery) RecoverStuckRequests(ctx context.Context) error {
+	for {
+		run, err := r.runs.ClaimRequested(ctx, r.elector.Holder())
+		if err != nil {
+			return errors.New("failed to claim requested recovery runs: " + err.Error())
+		}
+		if run == nil {
+			break
+		}
+		if err := r.execute(ctx, run); err != nil {
+			return err
+		}
+	}
+
+	if time.Since(r.lastScheduled) < r.interval {
+		return nil
+	}
+	r.lastScheduled = time.Now()
+	run := newRecoveryRun(TriggerSchedule, "")
+	run.Status = RunRunning
+	run.Replica = r.elector.Holder()
+	if err := r.runs.Create(ctx, run); err != nil {
+		return errors.New("failed to store recovery run: " + err.Error())
+	}
+	return r.execute(ctx, &run)
+}
+
+// RunNow asks the leader to recover stuck requests at once rather than at the next
+// scheduled run. It returns the run when the leader finished it, or as far as it got once
+// the wait is over; the run can then be looked up with Run.
+func (r *StuckRequestRecovery) RunNow(ctx context.Context, requestedBy string) (RecoveryRun, error) {
+	run := newRecoveryRun(TriggerManual, requestedBy)
+	if err := r.runs.Create(ctx, run); err != nil {
+		return run, err
+	}
+	// Spares waiting for the next tick when this replica leads
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, manualRunWait)
+	defer cancel()
+	ticker := time.NewTicker(manualRunPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-waitCtx.Done():
+			return run, nil
+		case <-ticker.C:
+		}
+		stored, err := r.runs.Get(ctx, run.ID)
+		if err != nil {
+			return run, err
+		}
+		run = *stored
+		if run.Finished() {
+			return run, nil
+		}
+	}
+}
+
+// Run returns a stored recovery run, ErrRecoveryRunNotFound if there is none with the ID
+func (r *StuckRequestRecovery) Run(ctx context.Context, id string) (*RecoveryRun, error) {
+	return r.runs.Get(ctx, id)
+}
+
+// Runs returns up to limit recovery runs of all replicas, latest first
+func (r *StuckRequestRecovery) Runs(ctx context.Context, limit int64) ([]RecoveryRun, error) {
+	return r.runs.List(ctx, limit)
+}
+
+// Status returns the recovery schedule, the replica leading it and its latest runs
+func (r *StuckRequestRecovery) Status(ctx context.Context) (RecoveryStatus, error) {
+	status := RecoveryStatus{
+		Interval:          r.interval.String(),
+		InProgressMaxAge:  r.inProgressMaxAge.String(),
+		UserCreatedMaxAge: r.userCreatedMaxAge.String(),
+		LeaseTTL:          r.leaseTTL.String(),
+		Replica:           r.elector.Holder(),
+	}
+
+	lease, err := r.elector.Leader(ctx)
+	if err != nil {
+		return status, err
+	}
+	status.Leader = lease
+
+	runs, err := r.runs.List(ctx, 20)
+	if err != nil {
+		return status, err
+	}
+	if len(runs) > 0 {
+		status.LastRun = &runs[0]
+	}
+	for _, run := range runs {
+		if run.Status == RunCompleted {
+			status.LastSuccessfulRun = run.FinishedAt
+			break
+		}
+	}
+	return status, nil
+}
+
+// newRecoveryRun returns a run waiting to be picked up
+func newRecoveryRun(trigger, requestedBy string) RecoveryRun {
+	return RecoveryRun{
+		ID:          idforge.GenerateWithSize(20),
+		Trigger:     trigger,
+		Status:      RunRequested,
+		RequestedBy: requestedBy,
+		RequestedAt: time.Now().UTC(),
+		Requests:    []RecoveryAction{},
+	}
+}
+
+// execute performs a run claimed by this replica and stores its report
+func (r *StuckRequestRecovery) execute(ctx context.Context, run *RecoveryRun) error {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.RecoverStuckRequests")
+	defer span.End()
+
+	startedAt := time.Now().UTC()
+	run.StartedAt = &startedAt
+	err := r.recoverAll(ctx, run)
+	finishedAt := time.Now().UTC()
+	run.FinishedAt = &finishedAt
+	run.Status = RunCompleted
+	if err != nil {
+		run.Status = RunFailed
+		run.Error = err.Error()
+	}
+
+	if saveErr := r.runs.Save(ctx, *run); saveErr != nil {
+		r.logger.Error("Failed to store recovery run", zap.Error(saveErr), zap.String("run_id", run.ID))
+	}
+	if err == nil {
+		r.succeeded(finishedAt)
+	}
+	return err
+}
+
+// succeeded records that the recovery did its work without error at the given time
+func (r *StuckRequestRecovery) succeeded(at time.Time) {
+	r.statusMutex.Lock()
+	r.lastSuccessAt = at
+	r.statusMutex.Unlock()
+}
+
+// recoverAll fixes stuck requests, reporting what was done in run
+func (r *StuckRequestRecovery) recoverAll(ctx context.Context, run *RecoveryRun) error {
 	// Check for requests stuck in "approval_in_progress" state
-	if err := r.recoverInProgressRequests(ctx); err != nil {
+	if err := r.recoverInProgressRequests(ctx, run); err != nil {
 		return err
 	}
 
 	// Check for requests stuck in "user_created" state
-	if err := r.recoverUserCreatedRequests(ctx); err != nil {
-		return err
-	}
+	return r.recoverUserCreatedRequests(ctx, run)
+}
 
-	return nil
+// LastSuccessfulRun returns when the recovery last did its work without error: standing by
+// for a follower, a run for the leader
+func (r *StuckRequestRecovery) LastSuccessfulRun() time.Time {
+	r.statusMutex.RLock()
+	defer r.statusMutex.RUnlock()
+	return r.lastSuccessAt
+}
+
+// Interval returns how often the recovery runs
+func (r *StuckRequestRecovery) Interval() time.Duration {
+	return r.interval
 }
 
 // recoverInProgressRequests handles requests stuck in the initial approval stage
-func (r *StuckRequestRecovery) recoverInProgressRequests(ctx context.Context) error {
+func (r *StuckRequestRecovery) recoverInProgressRequests(ctx context.Context, run *RecoveryRun) error {
 	cutoffTime := time.Now().Add(-r.inProgressMaxAge)
 
+	// Requests approved by a saga are left to the saga
 	filter := bson.M{
 		"status":              models.OnboardingStatusApprovalInProgress,
 		"approval_started_at": bson.M{"$lt": cutoffTime},
+		"approval_id":         bson.M{"$exists": false},
 	}
 
 	stuckRequests, err := r.db.ReadAll(
@@ -107,19 +409,21 @@ func (r *StuckRequestRecovery) recoverInProgressRequests(ctx context.Context) er
 		return errors.New("invalid response format from database")
 	}
 
-	r.logger.Info("Found stuck in-progress requests", zap.Int("count", len(requestsArr)))
+	logging.WithContext(ctx, r.logger).Info("Found stuck in-progress requests", zap.Int("count", len(requestsArr)))
 
 	for _, req := range requestsArr {
 		requestID, _ := req["request_id"].(string)
 		email, _ := req["email"].(string)
 		username, _ := req["username"].(string)
+		tenantID, _ := req["tenant_id"].(string)
 
 		// Check if the user was actually created in auth service
-		userExists, err := r.checkUserExists(ctx, email, username)
+		userExists, err := r.checkUserExists(ctx, email, username, tenantID)
 		if err != nil {
-			r.logger.Error("Error checking user existence",
+			logging.WithContext(ctx, r.logger).Error("Error checking user existence",
 				zap.Error(err),
 				zap.String("request_id", requestID))
+			run.record(req, models.OnboardingStatusApprovalInProgress, ActionSkipped, err)
 			continue
 		}
 
@@ -143,13 +447,16 @@ func (r *StuckRequestRecovery) recoverInProgressRequests(ctx context.Context) er
 			)
 
 			if err != nil {
-				r.logger.Error("Failed to update stuck request to user_created state",
+				logging.WithContext(ctx, r.logger).Error("Failed to update stuck request to user_created state",
 					zap.Error(err),
 					zap.String("request_id", requestID))
+				run.record(req, models.OnboardingStatusApprovalInProgress, ActionSkipped, err)
 				continue
 			}
 
-			r.logger.Info("Recovered stuck in-progress request - user exists",
+			run.record(req, models.OnboardingStatusApprovalInProgress, ActionPromotedToUserCreated, nil)
+			metrics.OnboardingRequests.WithLabelValues(string(models.OnboardingStatusUserCreated)).Inc()
+			logging.WithContext(ctx, r.logger).Info("Recovered stuck in-progress request - user exists",
 				zap.String("request_id", requestID))
 		} else {
 			// User doesn't exist, revert to pending
@@ -173,13 +480,16 @@ func (r *StuckRequestRecovery) recoverInProgressRequests(ctx context.Context) er
 			)
 
 			if err != nil {
-				r.logger.Error("Failed to revert stuck request to pending state",
+				logging.WithContext(ctx, r.logger).Error("Failed to revert stuck request to pending state",
 					zap.Error(err),
 					zap.String("request_id", requestID))
+				run.record(req, models.OnboardingStatusApprovalInProgress, ActionSkipped, err)
 				continue
 			}
 
-			r.logger.Info("Recovered stuck in-progress request - reverted to pending",
+			run.record(req, models.OnboardingStatusApprovalInProgress, ActionRevertedToPending, nil)
+			metrics.OnboardingRequests.WithLabelValues(string(models.OnboardingStatusPending)).Inc()
+			logging.WithContext(ctx, r.logger).Info("Recovered stuck in-progress request - reverted to pending",
 				zap.String("request_id", requestID))
 		}
 	}
@@ -188,12 +498,14 @@ func (r *StuckRequestRecovery) recoverInProgressRequests(ctx context.Context) er
 }
 
 // recoverUserCreatedRequests handles requests where the user was created but approval wasn't completed
-func (r *StuckRequestRecovery) recoverUserCreatedRequests(ctx context.Context) error {
+func (r *StuckRequestRecovery) recoverUserCreatedRequests(ctx context.Context, run *RecoveryRun) error {
 	cutoffTime := time.Now().Add(-r.userCreatedMaxAge)
 
+	// Requests approved by a saga are left to the saga
 	filter := bson.M{
 		"status":          models.OnboardingStatusUserCreated,
 		"user_created_at": bson.M{"$lt": cutoffTime},
+		"approval_id":     bson.M{"$exists": false},
 	}
 
 	stuckRequests, err := r.db.ReadAll(
@@ -212,79 +524,64 @@ func (r *StuckRequestRecovery) recoverUserCreatedRequests(ctx context.Context) e
 		return errors.New("invalid response format from database")
 	}
 
-	r.logger.Info("Found stuck user-created requests", zap.Int("count", len(requestsArr)))
+	logging.WithContext(ctx, r.logger).Info("Found stuck user-created requests", zap.Int("count", len(requestsArr)))
 
 	for _, req := range requestsArr {
 		requestID, _ := req["request_id"].(string)
 
 		// Complete the approval process
-		updateFilter := bson.M{"request_id": requestID}
-
-		// Create a new map for approved tenant
-		approvedRequest := make(map[string]interface{})
-
-		// Copy all existing fields except MongoDB internal _id
-		for k, v := range req {
-			if k != "_id" {
-				approvedRequest[k] = v
-			}
-		}
-
-		// Update status and timestamps
-		now := time.Now()
-		approvedRequest["status"] = models.OnboardingStatusActive
-		approvedRequest["approved_at"] = now.String()
-
-		// Insert into onboarded_tenants collection
-		_, err = r.db.Create(
-			ctx,
-			approvedRequest,
-			db.WithDatabaseName(config.DatabaseNames.CoreDB),
-			db.WithCollectionName(config.CollectionNames.OnboardedTenants),
-		)
-
-		if err != nil {
-			r.logger.Info("Failed to create approved tenant record during recovery",
-				zap.Error(err),
-				zap.String("request_id", requestID))
+		_, err := activateTenant(ctx, r.db, r.bus, req)
+		if errors.Is(err, ErrNotUserCreated) {
+			// Completed by the saga or another replica since it was found
+			run.record(req, models.OnboardingStatusUserCreated, ActionSkipped, nil)
 			continue
 		}
-
-		// Delete from onboarding_requests collection
-		_, err = r.db.Delete(
-			ctx,
-			updateFilter,
-			db.WithDatabaseName(config.DatabaseNames.CoreDB),
-			db.WithCollectionName(config.CollectionNames.OnboardingRequests),
-		)
-
 		if err != nil {
-			r.logger.Warn("Failed to delete original request after recovery approval",
+			logging.WithContext(ctx, r.logger).Info("Failed to create approved tenant record during recovery",
 				zap.Error(err),
 				zap.String("request_id", requestID))
-			// Continue anyway as the tenant is approved
+			run.record(req, models.OnboardingStatusUserCreated, ActionSkipped, err)
+			continue
 		}
 
-		r.logger.Info("Recovered stuck user-created request - approval completed",
+		run.record(req, models.OnboardingStatusUserCreated, ActionApprovalCompleted, nil)
+		metrics.OnboardingRequests.WithLabelValues(string(models.OnboardingStatusActive)).Inc()
+		logging.WithContext(ctx, r.logger).Info("Recovered stuck user-created request - approval completed",
 			zap.String("request_id", requestID))
 	}
 
 	return nil
 }
 
-// checkUserExists verifies if a user exists in the auth service
-func (r *StuckRequestRecovery) checkUserExists(ctx context.Context, email, username string) (bool, error) {
-	// This would typically call your auth service's user verification endpoint
-	// For now, we'll implement a simple check
-
-	// You'd implement this based on your auth service API
-	// For example:
-	// resp, err := r.authService.GetClient().CheckUserExists(ctx, &authpb.CheckUserRequest{
-	//     Email: email,
-	//     Username: username,
-	// })
-	// return resp.Exists, err
-
-	// For now, return a mock implementation
-	return true, nil
+// record adds what was done with a request stuck in the given status to the report of run
+func (run *RecoveryRun) record(req map[string]interface{}, stuckIn models.OnboardingStatus, action string, err error) {
+	entry := RecoveryAction{Status: string(stuckIn), Action: action}
+	entry.RequestID, _ = req["request_id"].(string)
+	entry.TenantID, _ = req["tenant_id"].(string)
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	run.Requests = append(run.Requests, entry)
+
+	switch action {
+	case ActionPromotedToUserCreated:
+		run.PromotedToUserCreated++
+	case ActionRevertedToPending:
+		run.RevertedToPending++
+	case ActionApprovalCompleted:
+		run.ApprovalsCompleted++
+	default:
+		run.Skipped++
+		return
+	}
+	metrics.RecoveryActions.WithLabelValues(action).Inc()
+}
+
+// checkUserExists asks auth-service whether the login of a request was created. Usernames
+// carry the tenant ID, so only the request's own user matches.
+func (r *StuckRequestRecovery) checkUserExists(ctx context.Context, email, username, tenantID string) (bool, error) {
+	if username == "" || tenantID == "" {
+		return false, errors.New("request lacks the username or tenant ID of its user")
+	}
+	return r.authService.UserExists(ctx, username, email, tenantID)
 }
@@ -0,0 +1,159 @@
+package onboardingsvc
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrRecoveryRunNotFound is returned for an unknown recovery run ID
+var ErrRecoveryRunNotFound = apperrors.NotFound("recovery_run_not_found", "recovery run not found")
+
+// RunStore keeps the reports of recovery runs, shared by all replicas
+type RunStore interface {
+	Create(ctx context.Context, run RecoveryRun) error
+	Save(ctx context.Context, run RecoveryRun) error
+	// Get returns ErrRecoveryRunNotFound for an unknown ID
+	Get(ctx context.Context, id string) (*RecoveryRun, error)
+	// List returns up to limit runs, latest requested first
+	List(ctx context.Context, limit int64) ([]RecoveryRun, error)
+	// ClaimRequested marks the oldest requested run as running on replica and returns it,
+	// nil if no run is requested
+	ClaimRequested(ctx context.Context, replica string) (*RecoveryRun, error)
+}
+
+// MongoRunStore keeps recovery runs in coredb, keyed by run ID
+type MongoRunStore struct {
+	db db.DBClientInterface
+}
+
+// NewMongoRunStore creates a run store backed by the recovery_runs collection
+func NewMongoRunStore(database db.DBClientInterface) *MongoRunStore {
+	return &MongoRunStore{db: database}
+}
+
+// EnsureRetention makes the database expire runs once they finished longer than retention
+// ago
+func (s *MongoRunStore) EnsureRetention(ctx context.Context, retention time.Duration) error {
+	return s.db.EnsureTTLIndex(ctx, "finished_at", retention, s.runs()...)
+}
+
+func (s *MongoRunStore) runs(extra ...db.DBOption) []db.DBOption {
+	return append([]db.DBOption{
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.RecoveryRuns),
+	}, extra...)
+}
+
+func (s *MongoRunStore) Create(ctx context.Context, run RecoveryRun) error {
+	doc, err := encodeRun(run)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Create(ctx, doc, s.runs()...)
+	return err
+}
+
+func (s *MongoRunStore) Save(ctx context.Context, run RecoveryRun) error {
+	doc, err := encodeRun(run)
+	if err != nil {
+		return err
+	}
+	delete(doc, "_id")
+	_, err = s.db.UpdateOne(ctx, bson.M{"_id": run.ID}, bson.M{"$set": doc}, s.runs()...)
+	return err
+}
+
+func (s *MongoRunStore) Get(ctx context.Context, id string) (*RecoveryRun, error) {
+	doc, err := s.db.Read(ctx, bson.M{"_id": id}, s.runs()...)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := doc.(map[string]interface{})
+	if len(result) == 0 {
+		return nil, ErrRecoveryRunNotFound
+	}
+	return decodeRun(result)
+}
+
+func (s *MongoRunStore) List(ctx context.Context, limit int64) ([]RecoveryRun, error) {
+	results, err := s.db.ReadAll(ctx, bson.M{}, s.runs(db.WithSort("requested_at", -1), db.WithLimit(limit))...)
+	if err != nil {
+		return nil, err
+	}
+	docs, _ := results.([]map[string]interface{})
+	runs := make([]RecoveryRun, 0, len(docs))
+	for _, doc := range docs {
+		run, err := decodeRun(doc)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
+
+func (s *MongoRunStore) ClaimRequested(ctx context.Context, replica string) (*RecoveryRun, error) {
+	results, err := s.db.ReadAll(ctx, bson.M{"status": RunRequested},
+		s.runs(db.WithSort("requested_at", 1), db.WithLimit(1))...)
+	if err != nil {
+		return nil, err
+	}
+	docs, _ := results.([]map[string]interface{})
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	// Only the leader claims runs, but a replica that just lost the lease may still be
+	// claiming; the status condition lets one of them have the run
+	claimed, err := s.db.UpdateOne(ctx,
+		bson.M{"_id": docs[0]["_id"], "status": RunRequested},
+		bson.M{"$set": bson.M{"status": RunRunning, "replica": replica}},
+		s.runs()...)
+	if err != nil || claimed == 0 {
+		return nil, err
+	}
+	run, err := decodeRun(docs[0])
+	if err != nil {
+		return nil, err
+	}
+	run.Status = RunRunning
+	run.Replica = replica
+	return run, nil
+}
+
+// encodeRun turns a recovery run into the document stored for it
+func encodeRun(run RecoveryRun) (map[string]interface{}, error) {
+	data, err := bson.Marshal(run)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// decodeRun reads a recovery run document returned by the database
+func decodeRun(doc map[string]interface{}) (*RecoveryRun, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var run RecoveryRun
+	if err := bson.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	run.RequestedAt = run.RequestedAt.UTC()
+	for _, at := range []*time.Time{run.StartedAt, run.FinishedAt} {
+		if at != nil {
+			*at = at.UTC()
+		}
+	}
+	return &run, nil
+}
@@ -0,0 +1,382 @@
+package onboardingsvc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/leader"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/authsvc"
+	"github.com/mrityunjay-vashisth/medusa-shared/userpb"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// memoryRequests keeps onboarding requests and tenants in memory, in one list, supporting
+// the queries of the recovery. Documents are stored the way MongoDB returns them.
+type memoryRequests struct {
+	db.DBClientInterface
+	mu   sync.Mutex
+	docs []map[string]interface{}
+}
+
+func (m *memoryRequests) ReadAll(_ context.Context, filter map[string]interface{}, _ ...db.DBOption) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	found := []map[string]interface{}{}
+	for _, doc := range m.docs {
+		if matches(doc, filter) {
+			found = append(found, doc)
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryRequests) Read(_ context.Context, filter map[string]interface{}, _ ...db.DBOption) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, doc := range m.docs {
+		if matches(doc, filter) {
+			return doc, nil
+		}
+	}
+	return map[string]interface{}{}, nil
+}
+
+func (m *memoryRequests) Create(_ context.Context, doc map[string]interface{}, _ ...db.DBOption) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := map[string]interface{}{}
+	for key, value := range doc {
+		stored[key] = storedValue(value)
+	}
+	m.docs = append(m.docs, stored)
+	return stored, nil
+}
+
+func (m *memoryRequests) Delete(_ context.Context, filter map[string]interface{}, _ ...db.DBOption) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.docs[:0]
+	for _, doc := range m.docs {
+		if !matches(doc, filter) {
+			kept = append(kept, doc)
+		}
+	}
+	deleted := int64(len(m.docs) - len(kept))
+	m.docs = kept
+	return deleted, nil
+}
+
+func (m *memoryRequests) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (m *memoryRequests) UpdateOne(_ context.Context, filter map[string]interface{}, update map[string]interface{}, _ ...db.DBOption) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, doc := range m.docs {
+		if !matches(doc, filter) {
+			continue
+		}
+		set, _ := update["$set"].(bson.M)
+		for key, value := range set {
+			doc[key] = storedValue(value)
+		}
+		unset, _ := update["$unset"].(bson.M)
+		for key := range unset {
+			delete(doc, key)
+		}
+		push, _ := update["$push"].(bson.M)
+		for key, value := range push {
+			list, _ := doc[key].([]interface{})
+			doc[key] = append(list, value)
+		}
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// storedValue returns a value as it is read back from MongoDB: statuses as strings and
+// times as primitive.DateTime
+func storedValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case models.OnboardingStatus:
+		return string(v)
+	case time.Time:
+		return primitive.NewDateTimeFromTime(v)
+	}
+	return value
+}
+
+// request returns the request with the given ID
+func (m *memoryRequests) request(id string) map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, doc := range m.docs {
+		if doc["request_id"] == id {
+			return doc
+		}
+	}
+	return nil
+}
+
+// matches supports equality, $in, $nin, $exists, $gt, $lt and $lte on times and $not of $gt
+// on times. Times may be stored as time.Time or, like MongoDB returns them, primitive.DateTime.
+func matches(doc, filter map[string]interface{}) bool {
+	for key, want := range filter {
+		value, present := doc[key]
+		switch want := want.(type) {
+		case bson.M:
+			if exists, ok := want["$exists"].(bool); ok && exists != present {
+				return false
+			}
+			if after, ok := want["$gt"].(time.Time); ok {
+				if t, ok := docTime(value); !ok || !t.After(after) {
+					return false
+				}
+			}
+			if before, ok := want["$lt"].(time.Time); ok {
+				if t, _ := docTime(value); !t.Before(before) {
+					return false
+				}
+			}
+			if until, ok := want["$lte"].(time.Time); ok {
+				if t, ok := docTime(value); !ok || t.After(until) {
+					return false
+				}
+			}
+			if in, ok := want["$in"].([]string); ok && !slices.Contains(in, fmt.Sprint(value)) {
+				return false
+			}
+			if nin, ok := want["$nin"].([]string); ok && slices.Contains(nin, fmt.Sprint(value)) {
+				return false
+			}
+			if not, ok := want["$not"].(bson.M); ok {
+				if after, ok := not["$gt"].(time.Time); ok {
+					if t, _ := docTime(value); present && t.After(after) {
+						return false
+					}
+				}
+			}
+		default:
+			if !present || fmt.Sprint(value) != fmt.Sprint(want) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// docTime reads a stored time
+func docTime(value interface{}) (time.Time, bool) {
+	switch t := value.(type) {
+	case time.Time:
+		return t, true
+	case primitive.DateTime:
+		return t.Time(), true
+	}
+	return time.Time{}, false
+}
+
+// fakeUsers answers UserExists from a fixed list; tenant "tenant-down" fails as if the
+// database of auth-service was down
+type fakeUsers struct {
+	userpb.UnimplementedUserServiceServer
+	users []*userpb.User
+}
+
+func (f *fakeUsers) UserExists(_ context.Context, req *userpb.UserExistsRequest) (*userpb.UserExistsResponse, error) {
+	if req.TenantId == "tenant-down" {
+		return nil, status.Error(codes.Unavailable, "database unavailable")
+	}
+	for _, user := range f.users {
+		if user.TenantId == req.TenantId &&
+			(req.Username == "" || user.Username == req.Username) &&
+			(req.Email == "" || user.Email == req.Email) {
+			return &userpb.UserExistsResponse{Exists: true}, nil
+		}
+	}
+	return &userpb.UserExistsResponse{}, nil
+}
+
+// memoryRuns keeps recovery runs in memory
+type memoryRuns struct {
+	mu   sync.Mutex
+	runs []RecoveryRun
+}
+
+func (m *memoryRuns) Create(_ context.Context, run RecoveryRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs = append(m.runs, run)
+	return nil
+}
+
+func (m *memoryRuns) Save(_ context.Context, run RecoveryRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.runs {
+		if m.runs[i].ID == run.ID {
+			m.runs[i] = run
+		}
+	}
+	return nil
+}
+
+func (m *memoryRuns) Get(_ context.Context, id string) (*RecoveryRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, run := range m.runs {
+		if run.ID == id {
+			return &run, nil
+		}
+	}
+	return nil, ErrRecoveryRunNotFound
+}
+
+func (m *memoryRuns) List(_ context.Context, limit int64) ([]RecoveryRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var runs []RecoveryRun
+	for i := len(m.runs) - 1; i >= 0 && int64(len(runs)) < limit; i-- {
+		runs = append(runs, m.runs[i])
+	}
+	return runs, nil
+}
+
+func (m *memoryRuns) ClaimRequested(_ context.Context, replica string) (*RecoveryRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.runs {
+		if m.runs[i].Status == RunRequested {
+			m.runs[i].Status = RunRunning
+			m.runs[i].Replica = replica
+			run := m.runs[i]
+			return &run, nil
+		}
+	}
+	return nil, nil
+}
+
+// soleReplica grants the lease to whoever asks, as if no other replica ran
+type soleReplica struct{}
+
+func (soleReplica) Acquire(context.Context, string, string, time.Time, time.Time) (bool, error) {
+	return true, nil
+}
+func (soleReplica) Release(context.Context, string, string) error { return nil }
+func (soleReplica) Get(context.Context, string) (*leader.Lease, error) {
+	return nil, nil
+}
+
+// newTestAuthService returns an auth service client of an in-process auth-service
+func newTestAuthService(t *testing.T, users ...*userpb.User) authsvc.Service {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	userpb.RegisterUserServiceServer(server, &fakeUsers{users: users})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return authsvc.NewService(nil, "passthrough:///bufnet", insecure.NewCredentials(), zap.NewNop(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }))
+}
+
+func TestRecoverInProgressRequests(t *testing.T) {
+	stuckSince := time.Now().Add(-time.Hour)
+	inProgress := func(requestID, tenantID string) map[string]interface{} {
+		return map[string]interface{}{
+			"request_id":          requestID,
+			"tenant_id":           tenantID,
+			"username":            "owner",
+			"email":               requestID + "@clinic.test",
+			"status":              string(models.OnboardingStatusApprovalInProgress),
+			"approval_started_at": stuckSince,
+		}
+	}
+	sagaOwned := inProgress("saga", "tenant-4")
+	sagaOwned["approval_id"] = "approval-1"
+	recent := inProgress("recent", "tenant-5")
+	recent["approval_started_at"] = time.Now()
+	requests := &memoryRequests{docs: []map[string]interface{}{
+		inProgress("created", "tenant-1"),
+		inProgress("missing", "tenant-2"),
+		inProgress("down", "tenant-down"),
+		sagaOwned,
+		recent,
+	}}
+
+	authService := newTestAuthService(t,
+		&userpb.User{Username: "owner.tenant-1", Email: "created@clinic.test", TenantId: "tenant-1"},
+		// Same email and username, but not the tenant of any stuck request
+		&userpb.User{Username: "owner.tenant-3", Email: "missing@clinic.test", TenantId: "tenant-3"},
+	)
+	runs := &memoryRuns{}
+	elector := leader.NewElector(soleReplica{}, RecoveryLease, "replica-1", 3*time.Second, zap.NewNop())
+	recovery := NewStuckRequestRecovery(requests, authService, nil, zap.NewNop(), config.RecoveryConfig{
+		Interval:          time.Hour,
+		InProgressMaxAge:  3 * time.Minute,
+		UserCreatedMaxAge: 2 * time.Hour,
+		LeaseTTL:          3 * time.Second,
+	}, elector, runs)
+	recovery.Start()
+	defer recovery.Stop()
+
+	run, err := recovery.RunNow(context.Background(), "root")
+	assert.NoError(t, err)
+	assert.Equal(t, RunCompleted, run.Status)
+	assert.Equal(t, "replica-1", run.Replica)
+	assert.Equal(t, 1, run.PromotedToUserCreated)
+	assert.Equal(t, 1, run.RevertedToPending)
+	assert.Equal(t, 1, run.Skipped)
+
+	actions := map[string]string{}
+	for _, action := range run.Requests {
+		actions[action.RequestID] = action.Action
+	}
+	assert.Equal(t, map[string]string{
+		"created": ActionPromotedToUserCreated,
+		"missing": ActionRevertedToPending,
+		"down":    ActionSkipped,
+	}, actions)
+
+	assert.Equal(t, string(models.OnboardingStatusUserCreated), requests.request("created")["status"])
+	assert.Equal(t, string(models.OnboardingStatusPending), requests.request("missing")["status"])
+	assert.NotContains(t, requests.request("missing"), "approval_started_at")
+	assert.Equal(t, string(models.OnboardingStatusApprovalInProgress), requests.request("down")["status"])
+	assert.Equal(t, string(models.OnboardingStatusApprovalInProgress), requests.request("saga")["status"])
+	assert.Equal(t, string(models.OnboardingStatusApprovalInProgress), requests.request("recent")["status"])
+}
+
+func TestActivateTenantOnlyOnce(t *testing.T) {
+	request := map[string]interface{}{
+		"request_id": "req-1",
+		"tenant_id":  "tenant-1",
+		"email":      "owner@clinic.test",
+		"status":     string(models.OnboardingStatusUserCreated),
+	}
+	requests := &memoryRequests{docs: []map[string]interface{}{request}}
+
+	// Two completers that both read the user-created request
+	_, err := activateTenant(context.Background(), requests, nil, request)
+	assert.NoError(t, err)
+	_, err = activateTenant(context.Background(), requests, nil, request)
+	assert.ErrorIs(t, err, ErrNotUserCreated, "the second completer must abort")
+
+	found, _ := requests.ReadAll(context.Background(), bson.M{"status": models.OnboardingStatusActive})
+	assert.Len(t, found, 1, "the tenant is created once")
+}
@@ -0,0 +1,194 @@
+package onboardingsvc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/saga"
+	"github.com/mrityunjay-vashisth/core-service/internal/worker"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+)
+
+// ErrNotDeadLettered is returned for a request ID that names no dead-lettered request
+var ErrNotDeadLettered = apperrors.NotFound("dead_letter_not_found", "no dead-lettered request found with the given ID")
+
+// retryBatch is how many failed approvals a replica retries per round
+const retryBatch = 20
+
+// RetryApproval approves a failed request again once it is due for a retry. It returns
+// ErrNotRetriable when the request is not due, or another replica retried it first.
+func (h *onboardingService) RetryApproval(ctx context.Context, requestID string) (*saga.Instance, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.RetryApproval")
+	defer span.End()
+
+	if err := h.RevertToRetriable(ctx, requestID); err != nil {
+		return nil, err
+	}
+	approval, err := h.StartApproval(ctx, requestID)
+	if err == nil {
+		return approval, nil
+	}
+
+	// Without an approval the request would wait for a reviewer. It counts as a failed
+	// attempt instead, so a request whose approval never starts is dead-lettered in the end.
+	failErr := h.failApproval(ctx, requestID, "start_approval: "+err.Error(), models.OnboardingStatusPending)
+	if failErr != nil {
+		logging.WithContext(ctx, h.Logger).Error("Failed to return request to failed after its retry could not start",
+			zap.Error(failErr),
+			zap.String("request_id", requestID))
+	}
+	return nil, err
+}
+
+// ListDeadLetters returns the dead-lettered requests, longest dead-lettered first
+func (h *onboardingService) ListDeadLetters(ctx context.Context) ([]map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.ListDeadLetters")
+	defer span.End()
+
+	results, err := h.db.ReadAll(ctx,
+		bson.M{"status": models.OnboardingStatusDeadLetter},
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.OnboardingRequests),
+		db.WithSort("dead_lettered_at", 1))
+	if err != nil {
+		logging.WithContext(ctx, h.Logger).Error("Failed to list dead-lettered requests", zap.Error(err))
+		return nil, ErrDatabase.Wrap(err)
+	}
+	requests, _ := results.([]map[string]interface{})
+	return requests, nil
+}
+
+// GetDeadLetter returns a dead-lettered request with the history of its failed approvals
+func (h *onboardingService) GetDeadLetter(ctx context.Context, requestID string) (map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.GetDeadLetter")
+	defer span.End()
+
+	request, err := h.GetTenantByID(ctx, requestID)
+	if errors.Is(err, ErrRequestNotFound) {
+		return nil, ErrNotDeadLettered
+	}
+	if err != nil {
+		return nil, err
+	}
+	requestMap, _ := request.(map[string]interface{})
+	if requestMap["status"] != string(models.OnboardingStatusDeadLetter) {
+		return nil, ErrNotDeadLettered
+	}
+	return requestMap, nil
+}
+
+// RequeueDeadLetter returns a dead-lettered request to the automatic retries on behalf of
+// operator. Its approval is retried at once, with the full number of attempts; its failure
+// history is kept.
+func (h *onboardingService) RequeueDeadLetter(ctx context.Context, requestID, operator string) (map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.RequeueDeadLetter")
+	defer span.End()
+
+	now := time.Now()
+	changes := bson.M{
+		"status":        models.OnboardingStatusFailed,
+		"retry_count":   0,
+		"next_retry_at": now,
+		"requeued_at":   now,
+		"requeued_by":   operator,
+	}
+	updated, err := h.db.UpdateOne(ctx,
+		bson.M{"request_id": requestID, "status": models.OnboardingStatusDeadLetter},
+		bson.M{"$set": changes, "$unset": bson.M{"dead_lettered_at": ""}},
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.OnboardingRequests))
+	if err != nil {
+		logging.WithContext(ctx, h.Logger).Error("Failed to requeue dead-lettered request",
+			zap.Error(err),
+			zap.String("request_id", requestID))
+		return nil, ErrDatabase.Wrap(err)
+	}
+	if updated == 0 {
+		return nil, ErrNotDeadLettered
+	}
+
+	metrics.OnboardingRequests.WithLabelValues(string(models.OnboardingStatusFailed)).Inc()
+	h.recordAudit(ctx, audit.Event{
+		Operation:    "onboarding.requeue",
+		ResourceType: audit.ResourceOnboardingRequest,
+		ResourceID:   requestID,
+		Before:       map[string]interface{}{"status": models.OnboardingStatusDeadLetter},
+		After:        changes,
+	})
+
+	request, err := h.GetTenantByID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	requestMap, _ := request.(map[string]interface{})
+	return requestMap, nil
+}
+
+// ApprovalRetrier approves failed onboarding requests again once their backoff is over.
+// Every replica runs it; reverting a request to pending claims its retry.
+type ApprovalRetrier struct {
+	*worker.Loop // Runs RetryDue
+
+	db      db.DBClientInterface
+	service Service
+	logger  *zap.Logger
+}
+
+// NewApprovalRetrier creates a retrier of the failed approvals of service
+func NewApprovalRetrier(db db.DBClientInterface, service Service, logger *zap.Logger, settings config.OnboardingConfig) *ApprovalRetrier {
+	r := &ApprovalRetrier{
+		db:      db,
+		service: service,
+		logger:  logger,
+	}
+	r.Loop = worker.NewLoop("Approval retrier", settings.RetryInterval, r.RetryDue, logger)
+	return r
+}
+
+// RetryDue starts a new approval of every failed request whose backoff is over
+func (r *ApprovalRetrier) RetryDue(ctx context.Context) error {
+	results, err := r.db.ReadAll(ctx,
+		bson.M{
+			"status":        models.OnboardingStatusFailed,
+			"next_retry_at": bson.M{"$not": bson.M{"$gt": time.Now()}},
+		},
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.OnboardingRequests),
+		db.WithSort("next_retry_at", 1),
+		db.WithLimit(retryBatch))
+	if err != nil {
+		return errors.New("failed to query failed approvals: " + err.Error())
+	}
+
+	requests, _ := results.([]map[string]interface{})
+	for _, request := range requests {
+		requestID, _ := request["request_id"].(string)
+		approval, err := r.service.RetryApproval(ctx, requestID)
+		if errors.Is(err, ErrNotRetriable) {
+			// Retried by another replica
+			continue
+		}
+		if err != nil {
+			metrics.ApprovalRetries.WithLabelValues("error").Inc()
+			logging.WithContext(ctx, r.logger).Error("Failed to retry approval",
+				zap.Error(err),
+				zap.String("request_id", requestID))
+			continue
+		}
+		metrics.ApprovalRetries.WithLabelValues("started").Inc()
+		logging.WithContext(ctx, r.logger).Info("Retrying failed approval",
+			zap.String("request_id", requestID),
+			zap.String("approval_id", approval.ID),
+			zap.Int("retry_count", retryCount(request)))
+	}
+	return nil
+}
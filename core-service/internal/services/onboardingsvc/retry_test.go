@@ -0,0 +1,105 @@
+package onboardingsvc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestFailedApprovalsAreRetriedThenDeadLettered(t *testing.T) {
+	ctx := context.Background()
+	requests := &memoryRequests{docs: []map[string]interface{}{{
+		"request_id":  "req-1",
+		"tenant_id":   "tenant-1",
+		"status":      string(models.OnboardingStatusApprovalInProgress),
+		"approval_id": "approval-1",
+	}}}
+	service := NewService(requests, registry.NewServiceRegistry(), zap.NewNop(), config.OnboardingConfig{
+		RetryMaxAttempts: 2,
+		RetryBaseDelay:   time.Minute,
+		RetryMaxDelay:    time.Hour,
+	})
+	request := func() map[string]interface{} { return requests.request("req-1") }
+
+	assert.NoError(t, service.MarkApprovalFailed(ctx, "req-1", "create_user: unavailable"))
+	assert.Equal(t, string(models.OnboardingStatusFailed), request()["status"])
+	assert.Equal(t, 1, request()["retry_count"])
+	assert.WithinDuration(t, time.Now().Add(time.Minute), timeField(request(), "next_retry_at"), 5*time.Second)
+	// A compensation retried after it took effect changes nothing
+	assert.NoError(t, service.MarkApprovalFailed(ctx, "req-1", "create_user: unavailable"))
+	assert.Len(t, request()["failure_history"], 1)
+
+	// Not retried before the backoff is over
+	assert.ErrorIs(t, service.RevertToRetriable(ctx, "req-1"), ErrNotRetriable)
+	request()["next_retry_at"] = time.Now().Add(-time.Second)
+	assert.NoError(t, service.RevertToRetriable(ctx, "req-1"))
+	assert.Equal(t, string(models.OnboardingStatusPending), request()["status"])
+	assert.NotContains(t, request(), "next_retry_at")
+
+	// The second failure uses up the attempts
+	request()["status"] = string(models.OnboardingStatusUserCreated)
+	request()["approval_id"] = "approval-2"
+	assert.NoError(t, service.MarkApprovalFailed(ctx, "req-1", "activate_tenant: conflict"))
+	assert.Equal(t, string(models.OnboardingStatusDeadLetter), request()["status"])
+	assert.NotContains(t, request(), "next_retry_at")
+	if history, ok := request()["failure_history"].([]interface{}); assert.True(t, ok) && assert.Len(t, history, 2) {
+		assert.Equal(t, models.ApprovalFailure{Attempt: 2, ApprovalID: "approval-2", Reason: "activate_tenant: conflict",
+			FailedAt: history[1].(models.ApprovalFailure).FailedAt}, history[1])
+	}
+
+	deadLetters, err := service.ListDeadLetters(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, deadLetters, 1)
+
+	// Requeuing gives the request its attempts back, due at once
+	_, err = service.RequeueDeadLetter(ctx, "req-1", "root")
+	assert.NoError(t, err)
+	assert.Equal(t, string(models.OnboardingStatusFailed), request()["status"])
+	assert.Equal(t, 0, request()["retry_count"])
+	assert.NoError(t, service.RevertToRetriable(ctx, "req-1"))
+	_, err = service.RequeueDeadLetter(ctx, "req-1", "root")
+	assert.ErrorIs(t, err, ErrNotDeadLettered)
+}
+
+func TestApprovalsThatCannotStartAreDeadLettered(t *testing.T) {
+	ctx := context.Background()
+	requests := &memoryRequests{docs: []map[string]interface{}{{
+		"request_id":  "req-1",
+		"tenant_id":   "tenant-1",
+		"status":      string(models.OnboardingStatusFailed),
+		"approval_id": "approval-1",
+		"retry_count": 1,
+	}}}
+	// Without a saga orchestrator no approval can start
+	service := NewService(requests, registry.NewServiceRegistry(), zap.NewNop(), config.OnboardingConfig{
+		RetryMaxAttempts: 3,
+		RetryBaseDelay:   time.Minute,
+		RetryMaxDelay:    time.Hour,
+	})
+	request := func() map[string]interface{} { return requests.request("req-1") }
+
+	_, err := service.RetryApproval(ctx, "req-1")
+	assert.Error(t, err)
+	assert.Equal(t, string(models.OnboardingStatusFailed), request()["status"])
+	assert.Equal(t, 2, request()["retry_count"])
+	// The backoff grows with the failures like for approvals that started
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), timeField(request(), "next_retry_at"), 5*time.Second)
+	if history, ok := request()["failure_history"].([]interface{}); assert.True(t, ok) && assert.Len(t, history, 1) {
+		failure := history[0].(models.ApprovalFailure)
+		assert.Equal(t, 2, failure.Attempt)
+		assert.Empty(t, failure.ApprovalID, "no approval was started")
+		assert.Contains(t, failure.Reason, "start_approval")
+	}
+
+	request()["next_retry_at"] = time.Now().Add(-time.Second)
+	_, err = service.RetryApproval(ctx, "req-1")
+	assert.Error(t, err)
+	assert.Equal(t, string(models.OnboardingStatusDeadLetter), request()["status"])
+	assert.Len(t, request()["failure_history"], 2)
+}
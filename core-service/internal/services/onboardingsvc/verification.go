@@ -0,0 +1,410 @@
+package onboardingsvc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/domainevents"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/worker"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+)
+
+// Errors returned while verifying the email of an onboarding request
+var (
+	ErrNotUnverified           = apperrors.NotFound("unverified_request_not_found", "no unverified request found with the given ID and email")
+	ErrInvalidVerificationLink = apperrors.Validation("invalid_verification_token", "the verification token is invalid")
+	ErrVerificationExpired     = apperrors.Validation("verification_token_expired", "the verification token expired, submit the request again")
+	ErrVerificationSuperseded  = apperrors.Validation("verification_token_superseded", "a newer verification email was sent, use the link in it")
+	ErrVerificationSendLimit   = apperrors.Conflict("verification_send_limit", "no more verification emails can be sent for this request, submit it again")
+)
+
+// expiryBatch is how many expired unverified requests a replica removes per round
+const expiryBatch = 50
+
+// verificationToken is what a verification link proves: the request it was sent for, which
+// of its verification emails carried it, and until when it is valid. Only the latest email
+// of a request verifies it.
+type verificationToken struct {
+	RequestID string
+	Send      int
+	ExpiresAt time.Time
+}
+
+// signVerificationToken encodes a token as its payload and HMAC-SHA256 signature, both
+// base64url encoded and joined by a dot
+func signVerificationToken(secret []byte, token verificationToken) string {
+	payload := token.RequestID + "." + strconv.Itoa(token.Send) + "." + strconv.FormatInt(token.ExpiresAt.Unix(), 10)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(verificationSignature(secret, encoded))
+}
+
+// parseVerificationToken checks the signature of a token and decodes it. Whether it
+// expired is left to the caller.
+func parseVerificationToken(secret []byte, value string) (verificationToken, error) {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return verificationToken{}, ErrInvalidVerificationLink
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, verificationSignature(secret, encoded)) {
+		return verificationToken{}, ErrInvalidVerificationLink
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return verificationToken{}, ErrInvalidVerificationLink
+	}
+	fields := strings.Split(string(payload), ".")
+	if len(fields) != 3 || fields[0] == "" {
+		return verificationToken{}, ErrInvalidVerificationLink
+	}
+	send, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return verificationToken{}, ErrInvalidVerificationLink
+	}
+	expiresAt, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return verificationToken{}, ErrInvalidVerificationLink
+	}
+	return verificationToken{RequestID: fields[0], Send: send, ExpiresAt: time.Unix(expiresAt, 0)}, nil
+}
+
+func verificationSignature(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// publishVerification publishes the send-th verification email of a request. It joins the
+// transaction of ctx.
+func (h *onboardingService) publishVerification(ctx context.Context, request map[string]interface{}, send int, expiresAt time.Time) error {
+	bus := h.domainEvents()
+	if bus == nil {
+		return nil
+	}
+	requestID, _ := request["request_id"].(string)
+	tenantID, _ := request["tenant_id"].(string)
+	organizationName, _ := request["organization_name"].(string)
+	email, _ := request["email"].(string)
+	return bus.Publish(ctx, domainevents.OnboardingVerificationRequested{
+		TenantID:         tenantID,
+		RequestID:        requestID,
+		OrganizationName: organizationName,
+		Email:            email,
+		Send:             send,
+		ExpiresAt:        expiresAt.UTC(),
+	})
+}
+
+// VerifyEmail moves the unverified request a verification token was sent for to pending,
+// where it waits for a reviewer. Verifying a request again returns it as it is now.
+func (h *onboardingService) VerifyEmail(ctx context.Context, token string) (*models.EmailVerificationResult, error) {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.VerifyEmail")
+	defer span.End()
+
+	verification, err := parseVerificationToken([]byte(h.settings.VerificationSecret), token)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if now.After(verification.ExpiresAt) {
+		return nil, ErrVerificationExpired
+	}
+
+	filter := bson.M{
+		"request_id":         verification.RequestID,
+		"status":             models.OnboardingStatusUnverified,
+		"verification_sends": verification.Send,
+	}
+	var requestMap map[string]interface{}
+	verified := false
+	err = h.db.WithTransaction(ctx, func(ctx context.Context) error {
+		request, err := h.db.Read(ctx, bson.M{"request_id": verification.RequestID},
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.OnboardingRequests))
+		if err != nil {
+			return err
+		}
+		requestMap, _ = request.(map[string]interface{})
+		if len(requestMap) == 0 {
+			// Removed once it expired
+			return ErrVerificationExpired
+		}
+		if requestMap["status"] != string(models.OnboardingStatusUnverified) {
+			return nil
+		}
+		if intField(requestMap, "verification_sends") != verification.Send {
+			return ErrVerificationSuperseded
+		}
+
+		// Several unverified requests may share an email; the first one verified wins
+		email, _ := requestMap["email"].(string)
+		for _, query := range []struct {
+			filter     bson.M
+			collection string
+		}{
+			{bson.M{"email": email, "status": bson.M{"$nin": []string{
+				string(models.OnboardingStatusUnverified),
+				string(models.OnboardingStatusRejected),
+			}}}, config.CollectionNames.OnboardingRequests},
+			{bson.M{"email": email}, config.CollectionNames.OnboardedTenants},
+		} {
+			existing, err := h.db.Read(ctx, query.filter,
+				db.WithDatabaseName(config.DatabaseNames.CoreDB),
+				db.WithCollectionName(query.collection))
+			if err != nil {
+				return err
+			}
+			if existingMap, ok := existing.(map[string]interface{}); ok && len(existingMap) > 0 {
+				return ErrOnboardingExists
+			}
+		}
+
+		updated, err := h.db.UpdateOne(ctx, filter,
+			bson.M{
+				"$set":   bson.M{"status": models.OnboardingStatusPending, "verified_at": now},
+				"$unset": bson.M{"verification_expires_at": ""},
+			},
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.OnboardingRequests))
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return ErrVerificationSuperseded
+		}
+		requestMap["status"] = string(models.OnboardingStatusPending)
+		requestMap["verified_at"] = now
+		verified = true
+		return nil
+	})
+	if err != nil {
+		if _, ok := apperrors.As(err); ok {
+			return nil, err
+		}
+		logging.WithContext(ctx, h.Logger).Error("Failed to verify onboarding request",
+			zap.Error(err),
+			zap.String("request_id", verification.RequestID))
+		return nil, ErrDatabase.Wrap(err)
+	}
+
+	status, _ := requestMap["status"].(string)
+	result := &models.EmailVerificationResult{
+		RequestID: verification.RequestID,
+		Status:    models.OnboardingStatus(status),
+	}
+	if verifiedAt := timeField(requestMap, "verified_at"); !verifiedAt.IsZero() {
+		result.VerifiedAt = &verifiedAt
+	}
+	if !verified {
+		return result, nil
+	}
+
+	metrics.OnboardingRequests.WithLabelValues(string(models.OnboardingStatusPending)).Inc()
+	tenantID, _ := requestMap["tenant_id"].(string)
+	h.recordAudit(ctx, audit.Event{
+		Operation:    "onboarding.verify",
+		ResourceType: audit.ResourceOnboardingRequest,
+		ResourceID:   verification.RequestID,
+		TenantID:     tenantID,
+		Before:       map[string]interface{}{"status": models.OnboardingStatusUnverified},
+		After:        map[string]interface{}{"status": models.OnboardingStatusPending, "verified_at": now},
+	})
+	return result, nil
+}
+
+// ResendVerification sends the verification email of an unverified request again, with a
+// new token that replaces the earlier ones. Emails are at least the resend interval apart
+// and limited to the configured number per request.
+func (h *onboardingService) ResendVerification(ctx context.Context, resend models.VerificationResend) error {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.ResendVerification")
+	defer span.End()
+
+	var fields []apperrors.FieldError
+	if resend.RequestID == "" {
+		fields = append(fields, apperrors.FieldError{Field: "request_id", Message: "is required"})
+	}
+	if resend.Email == "" {
+		fields = append(fields, apperrors.FieldError{Field: "email", Message: "is required"})
+	}
+	if len(fields) > 0 {
+		return apperrors.Validation("invalid_resend", "Invalid verification resend", fields...)
+	}
+
+	now := time.Now()
+	err := h.db.WithTransaction(ctx, func(ctx context.Context) error {
+		request, err := h.db.Read(ctx, bson.M{
+			"request_id":              resend.RequestID,
+			"email":                   resend.Email,
+			"status":                  models.OnboardingStatusUnverified,
+			"verification_expires_at": bson.M{"$gt": now},
+		},
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.OnboardingRequests))
+		if err != nil {
+			return err
+		}
+		requestMap, _ := request.(map[string]interface{})
+		if len(requestMap) == 0 {
+			return ErrNotUnverified
+		}
+
+		sends := intField(requestMap, "verification_sends")
+		if sends >= h.settings.VerificationMaxSends {
+			return ErrVerificationSendLimit
+		}
+		if sentAt := timeField(requestMap, "verification_sent_at"); !sentAt.IsZero() {
+			if next := sentAt.Add(h.settings.VerificationResendInterval); now.Before(next) {
+				return resendThrottled(next)
+			}
+		}
+
+		// Matching the sends claims this email against a concurrent resend
+		updated, err := h.db.UpdateOne(ctx,
+			bson.M{"request_id": resend.RequestID, "status": models.OnboardingStatusUnverified, "verification_sends": sends},
+			bson.M{"$set": bson.M{"verification_sends": sends + 1, "verification_sent_at": now}},
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.OnboardingRequests))
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return resendThrottled(now.Add(h.settings.VerificationResendInterval))
+		}
+		return h.publishVerification(ctx, requestMap, sends+1, timeField(requestMap, "verification_expires_at"))
+	})
+	if err != nil {
+		if _, ok := apperrors.As(err); ok {
+			return err
+		}
+		logging.WithContext(ctx, h.Logger).Error("Failed to resend verification email",
+			zap.Error(err),
+			zap.String("request_id", resend.RequestID))
+		return ErrDatabase.Wrap(err)
+	}
+	return nil
+}
+
+// resendThrottled reports that a verification email may only be sent again from next
+func resendThrottled(next time.Time) error {
+	return apperrors.Conflict("verification_resend_throttled",
+		"a verification email was sent recently, ask again after "+next.UTC().Format(time.RFC3339))
+}
+
+// ExpireUnverified removes an unverified request whose verification expired, with the
+// webhook registered for it
+func (h *onboardingService) ExpireUnverified(ctx context.Context, requestID string) error {
+	ctx, span := tracer.Start(ctx, "onboardingsvc.ExpireUnverified")
+	defer span.End()
+
+	filter := bson.M{
+		"request_id":              requestID,
+		"status":                  models.OnboardingStatusUnverified,
+		"verification_expires_at": bson.M{"$lte": time.Now()},
+	}
+	request, err := h.db.Read(ctx, filter,
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.OnboardingRequests))
+	if err != nil {
+		return ErrDatabase.Wrap(err)
+	}
+	requestMap, _ := request.(map[string]interface{})
+	if len(requestMap) == 0 {
+		return ErrNotUnverified
+	}
+
+	// The filter is matched again in case the request was verified since it was read
+	result, err := h.db.Delete(ctx, filter,
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.OnboardingRequests))
+	if err != nil {
+		return ErrDatabase.Wrap(err)
+	}
+	if deleted, _ := result.(int64); deleted == 0 {
+		return ErrNotUnverified
+	}
+
+	tenantID, _ := requestMap["tenant_id"].(string)
+	if endpointID, _ := requestMap["webhook_endpoint_id"].(string); endpointID != "" {
+		h.removeWebhook(ctx, tenantID, endpointID)
+	}
+	h.recordAudit(ctx, audit.Event{
+		Operation:    "onboarding.expire",
+		ResourceType: audit.ResourceOnboardingRequest,
+		ResourceID:   requestID,
+		TenantID:     tenantID,
+		Before:       map[string]interface{}{"status": models.OnboardingStatusUnverified},
+	})
+	return nil
+}
+
+// VerificationExpirer removes onboarding requests whose email was not verified in time.
+// Every replica runs it; deleting a request claims its expiry.
+type VerificationExpirer struct {
+	*worker.Loop // Runs ExpireDue
+
+	db      db.DBClientInterface
+	service Service
+	logger  *zap.Logger
+}
+
+// NewVerificationExpirer creates an expirer of the unverified requests of service
+func NewVerificationExpirer(db db.DBClientInterface, service Service, logger *zap.Logger, settings config.OnboardingConfig) *VerificationExpirer {
+	e := &VerificationExpirer{
+		db:      db,
+		service: service,
+		logger:  logger,
+	}
+	e.Loop = worker.NewLoop("Verification expirer", settings.VerificationSweepInterval, e.ExpireDue, logger)
+	return e
+}
+
+// ExpireDue removes the unverified requests whose verification expired
+func (e *VerificationExpirer) ExpireDue(ctx context.Context) error {
+	results, err := e.db.ReadAll(ctx,
+		bson.M{
+			"status":                  models.OnboardingStatusUnverified,
+			"verification_expires_at": bson.M{"$lte": time.Now()},
+		},
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.OnboardingRequests),
+		db.WithSort("verification_expires_at", 1),
+		db.WithLimit(expiryBatch))
+	if err != nil {
+		return fmt.Errorf("failed to query expired unverified requests: %w", err)
+	}
+
+	requests, _ := results.([]map[string]interface{})
+	for _, request := range requests {
+		requestID, _ := request["request_id"].(string)
+		err := e.service.ExpireUnverified(ctx, requestID)
+		if errors.Is(err, ErrNotUnverified) {
+			// Verified in the meantime, or expired by another replica
+			continue
+		}
+		if err != nil {
+			logging.WithContext(ctx, e.logger).Error("Failed to expire unverified request",
+				zap.Error(err),
+				zap.String("request_id", requestID))
+			continue
+		}
+		metrics.UnverifiedRequestsExpired.Inc()
+		logging.WithContext(ctx, e.logger).Info("Expired unverified onboarding request",
+			zap.String("request_id", requestID))
+	}
+	return nil
+}
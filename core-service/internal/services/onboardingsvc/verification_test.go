@@ -0,0 +1,199 @@
+package onboardingsvc
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/domainevents"
+	"github.com/mrityunjay-vashisth/core-service/internal/mailer"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// withoutTenants stands in for a database holding no onboarded tenant. The memory fake
+// keeps every collection in one list, so looking a tenant up by email alone would find the
+// request with that email.
+type withoutTenants struct {
+	*memoryRequests
+}
+
+func (w withoutTenants) Read(ctx context.Context, filter map[string]interface{}, opts ...db.DBOption) (interface{}, error) {
+	if _, ok := filter["email"]; ok && len(filter) == 1 {
+		return map[string]interface{}{}, nil
+	}
+	return w.memoryRequests.Read(ctx, filter, opts...)
+}
+
+// memoryOutbox keeps the events published on the bus
+type memoryOutbox struct {
+	mu      sync.Mutex
+	records []domainevents.Record
+}
+
+func (m *memoryOutbox) Append(_ context.Context, records ...domainevents.Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, records...)
+	return nil
+}
+
+func (m *memoryOutbox) ClaimDue(context.Context, time.Time, time.Duration, int64) ([]domainevents.Record, error) {
+	return nil, nil
+}
+
+func (m *memoryOutbox) Save(context.Context, domainevents.Record) error { return nil }
+
+// memoryMailer keeps the email it is asked to send
+type memoryMailer struct {
+	sent []mailer.Message
+}
+
+func (m *memoryMailer) Send(_ context.Context, msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestVerificationToken(t *testing.T) {
+	secret := []byte("verification-secret")
+	token := verificationToken{RequestID: "8fKq2xLm0aPz", Send: 2, ExpiresAt: time.Unix(1761000000, 0)}
+	signed := signVerificationToken(secret, token)
+
+	parsed, err := parseVerificationToken(secret, signed)
+	assert.NoError(t, err)
+	assert.Equal(t, token.RequestID, parsed.RequestID)
+	assert.Equal(t, 2, parsed.Send)
+	assert.True(t, token.ExpiresAt.Equal(parsed.ExpiresAt))
+
+	// A token only verifies with the key that signed it
+	_, err = parseVerificationToken([]byte("another-secret"), signed)
+	assert.ErrorIs(t, err, ErrInvalidVerificationLink)
+
+	// Changing the payload breaks the signature
+	payload, signature, _ := strings.Cut(signed, ".")
+	forged := signVerificationToken([]byte("another-secret"), verificationToken{RequestID: token.RequestID, Send: 3, ExpiresAt: token.ExpiresAt})
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	_, err = parseVerificationToken(secret, forgedPayload+"."+signature)
+	assert.ErrorIs(t, err, ErrInvalidVerificationLink)
+
+	for _, malformed := range []string{"", payload, payload + ".", "." + signature, "not base64!." + signature} {
+		_, err = parseVerificationToken(secret, malformed)
+		assert.ErrorIs(t, err, ErrInvalidVerificationLink, malformed)
+	}
+}
+
+func errorCode(err error) string {
+	if domainErr, ok := apperrors.As(err); ok {
+		return domainErr.Code
+	}
+	return ""
+}
+
+func TestResendVerification(t *testing.T) {
+	ctx := context.Background()
+	settings := config.OnboardingConfig{
+		VerificationSecret:         "verification-secret",
+		VerificationURL:            "https://medusa.test/onboarding/verify",
+		VerificationTTL:            24 * time.Hour,
+		VerificationResendInterval: time.Minute,
+		VerificationMaxSends:       2,
+	}
+	requests := &memoryRequests{}
+	outbox := &memoryOutbox{}
+	services := registry.NewServiceRegistry()
+	services.Register(registry.DomainEvents, domainevents.NewBus(outbox))
+	service := NewService(withoutTenants{requests}, services, zap.NewNop(), settings)
+
+	// emailedToken delivers the latest event to the applicant email and returns the token
+	// of the link it carries
+	mail := &memoryMailer{}
+	notifier := NewApplicantNotifier(mail, settings, zap.NewNop())
+	emailedToken := func() string {
+		record := outbox.records[len(outbox.records)-1]
+		assert.NotContains(t, string(record.Payload), "token", "no token is kept in the outbox")
+		assert.NoError(t, notifier.HandleEvent(ctx, record.Message))
+		body := mail.sent[len(mail.sent)-1].Body
+		for _, line := range strings.Split(body, "\n") {
+			if strings.HasPrefix(line, settings.VerificationURL) {
+				link, err := url.Parse(line)
+				assert.NoError(t, err)
+				return link.Query().Get("token")
+			}
+		}
+		t.Fatalf("no verification link in %q", body)
+		return ""
+	}
+
+	receipt, err := service.OnboardTenant(ctx, models.OnboardingRequest{
+		OrganizationName: "Clinic",
+		Email:            "owner@clinic.test",
+		Role:             "admin",
+	})
+	assert.NoError(t, err)
+	first := emailedToken()
+	resend := models.VerificationResend{RequestID: receipt.RequestID, Email: "owner@clinic.test"}
+
+	// The request was emailed just now
+	assert.Equal(t, "verification_resend_throttled", errorCode(service.ResendVerification(ctx, resend)))
+
+	request := requests.request(receipt.RequestID)
+	request["verification_sent_at"] = primitive.NewDateTimeFromTime(time.Now().Add(-2 * time.Minute))
+	assert.NoError(t, service.ResendVerification(ctx, resend))
+	second := emailedToken()
+
+	request["verification_sent_at"] = primitive.NewDateTimeFromTime(time.Now().Add(-2 * time.Minute))
+	assert.ErrorIs(t, service.ResendVerification(ctx, resend), ErrVerificationSendLimit)
+
+	_, err = service.VerifyEmail(ctx, first)
+	assert.ErrorIs(t, err, ErrVerificationSuperseded)
+
+	result, err := service.VerifyEmail(ctx, second)
+	assert.NoError(t, err)
+	assert.Equal(t, models.OnboardingStatusPending, result.Status)
+
+	// Verifying again returns the request as stored
+	again, err := service.VerifyEmail(ctx, second)
+	assert.NoError(t, err)
+	assert.Equal(t, models.OnboardingStatusPending, again.Status)
+	if assert.NotNil(t, again.VerifiedAt) {
+		assert.WithinDuration(t, *result.VerifiedAt, *again.VerifiedAt, time.Millisecond)
+	}
+}
+
+func TestVerificationExpiry(t *testing.T) {
+	ctx := context.Background()
+	settings := config.OnboardingConfig{
+		VerificationSecret:         "verification-secret",
+		VerificationResendInterval: time.Minute,
+		VerificationMaxSends:       5,
+	}
+	expiredAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	requests := &memoryRequests{docs: []map[string]interface{}{{
+		"request_id":              "req-1",
+		"email":                   "owner@clinic.test",
+		"status":                  string(models.OnboardingStatusUnverified),
+		"verification_sends":      1,
+		"verification_sent_at":    primitive.NewDateTimeFromTime(expiredAt.Add(-24 * time.Hour)),
+		"verification_expires_at": primitive.NewDateTimeFromTime(expiredAt),
+	}}}
+	service := NewService(withoutTenants{requests}, registry.NewServiceRegistry(), zap.NewNop(), settings)
+
+	token := signVerificationToken([]byte(settings.VerificationSecret), verificationToken{RequestID: "req-1", Send: 1, ExpiresAt: expiredAt})
+	_, err := service.VerifyEmail(ctx, token)
+	assert.ErrorIs(t, err, ErrVerificationExpired)
+
+	err = service.ResendVerification(ctx, models.VerificationResend{RequestID: "req-1", Email: "owner@clinic.test"})
+	assert.ErrorIs(t, err, ErrNotUnverified, "an expired request cannot be emailed again")
+
+	assert.NoError(t, service.ExpireUnverified(ctx, "req-1"))
+	assert.Nil(t, requests.request("req-1"))
+}
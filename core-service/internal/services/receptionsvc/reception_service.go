@@ -5,22 +5,42 @@ import (
 	"errors"
 	"time"
 
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
 	"github.com/mrityunjay-vashisth/core-service/internal/config"
 	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/domainevents"
+	"github.com/mrityunjay-vashisth/core-service/internal/events"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
 	"github.com/mrityunjay-vashisth/core-service/internal/models"
 	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/auditsvc"
 	"github.com/mrityunjay-vashisth/go-idforge/pkg/idforge"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("github.com/mrityunjay-vashisth/core-service/internal/services/receptionsvc")
+
+// Errors returned by the reception service
+var (
+	ErrAppointmentNotFound = apperrors.NotFound("appointment_not_found", "appointment not found")
+	ErrDoctorUnavailable   = apperrors.Conflict("doctor_unavailable", "doctor is not available at the requested time")
+	ErrVersionMismatch     = apperrors.PreconditionFailed("appointment_modified", "appointment was modified by another request")
+	ErrDatabase            = apperrors.Unavailable("database_unavailable", "appointments are temporarily unavailable", nil)
+)
+
 type Service interface {
 	// Appointment management
 	CreateAppointment(ctx context.Context, req models.AppointmentCreateRequest, tenantID string, createdBy string) (*models.AppointmentResponse, error)
 	GetAppointmentByID(ctx context.Context, appointmentID string, tenantID string) (*models.AppointmentResponse, error)
 	UpdateAppointment(ctx context.Context, appointmentID string, req models.AppointmentUpdateRequest, tenantID string) (*models.AppointmentResponse, error)
-	CancelAppointment(ctx context.Context, appointmentID string, reason string, tenantID string) (*models.AppointmentResponse, error)
+	CancelAppointment(ctx context.Context, appointmentID string, req models.AppointmentCancelRequest, tenantID string) (*models.AppointmentResponse, error)
 	ListAppointments(ctx context.Context, filters map[string]interface{}, tenantID string) ([]models.AppointmentResponse, error)
+	StreamAppointments(ctx context.Context, filters map[string]interface{}, tenantID string, fn func(models.AppointmentResponse) error) error
 
 	// Availability checking
 	GetDoctorAvailability(ctx context.Context, doctorID string, date time.Time, tenantID string) ([]map[string]interface{}, error)
@@ -40,8 +60,35 @@ func NewService(db db.DBClientInterface, registry registry.ServiceRegistry, logg
 	}
 }
 
+// ValidateCreateRequest reports every missing or invalid field of a new appointment. Every
+// API validates requests with it before calling CreateAppointment.
+func ValidateCreateRequest(req models.AppointmentCreateRequest) error {
+	var fields []apperrors.FieldError
+	for _, required := range []struct{ name, value string }{
+		{"patient_id", req.PatientID},
+		{"patient_name", req.PatientName},
+		{"doctor_id", req.DoctorID},
+		{"doctor_name", req.DoctorName},
+	} {
+		if required.value == "" {
+			fields = append(fields, apperrors.FieldError{Field: required.name, Message: "is required"})
+		}
+	}
+	if req.Duration <= 0 {
+		fields = append(fields, apperrors.FieldError{Field: "duration", Message: "must be a positive number of minutes"})
+	}
+
+	if len(fields) > 0 {
+		return apperrors.Validation("missing_fields", "Missing required fields", fields...)
+	}
+	return nil
+}
+
 // CreateAppointment books a new appointment for a patient
 func (s *receptionService) CreateAppointment(ctx context.Context, req models.AppointmentCreateRequest, tenantID string, createdBy string) (*models.AppointmentResponse, error) {
+	ctx, span := tracer.Start(ctx, "receptionsvc.CreateAppointment")
+	defer span.End()
+
 	// First, check if the doctor is available at the requested time
 	isAvailable, err := s.checkDoctorAvailability(ctx, req.DoctorID, req.ScheduledTime, req.Duration, tenantID)
 	if err != nil {
@@ -49,7 +96,7 @@ func (s *receptionService) CreateAppointment(ctx context.Context, req models.App
 	}
 
 	if !isAvailable {
-		return nil, errors.New("doctor is not available at the requested time")
+		return nil, ErrDoctorUnavailable
 	}
 
 	// Generate a unique ID for the appointment
@@ -72,6 +119,7 @@ func (s *receptionService) CreateAppointment(ctx context.Context, req models.App
 		CreatedAt:     now,
 		UpdatedAt:     now,
 		TenantID:      tenantID,
+		Version:       1,
 	}
 
 	// Convert to map for database insertion
@@ -90,18 +138,7 @@ func (s *receptionService) CreateAppointment(ctx context.Context, req models.App
 		"created_at":       appointment.CreatedAt,
 		"updated_at":       appointment.UpdatedAt,
 		"tenant_id":        appointment.TenantID,
-	}
-
-	// Insert into database
-	_, err = s.db.Create(
-		ctx,
-		appointmentMap,
-		db.WithDatabaseName(config.DatabaseNames.CoreDB),
-		db.WithCollectionName(config.CollectionNames.Appointments),
-	)
-	if err != nil {
-		s.logger.Error("Failed to create appointment", zap.Error(err))
-		return nil, errors.New("failed to create appointment in database")
+		"version":          appointment.Version,
 	}
 
 	// Create response
@@ -117,13 +154,44 @@ func (s *receptionService) CreateAppointment(ctx context.Context, req models.App
 		Status:        appointment.Status,
 		Notes:         appointment.Notes,
 		CreatedAt:     appointment.CreatedAt,
-	}
-
+		UpdatedAt:     appointment.UpdatedAt,
+		Version:       appointment.Version,
+	}
+
+	// Insert into database, together with the event of the new appointment
+	err = s.db.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := s.db.Create(
+			ctx,
+			appointmentMap,
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.Appointments),
+		)
+		if err != nil {
+			return err
+		}
+		return s.publishDomainEvent(ctx, domainevents.AppointmentCreated{TenantID: tenantID, Appointment: *response})
+	})
+	if err != nil {
+		logging.WithContext(ctx, s.logger).Error("Failed to create appointment", zap.Error(err))
+		return nil, ErrDatabase.Wrap(err)
+	}
+	metrics.AppointmentsCreated.WithLabelValues(tenantID).Inc()
+
+	s.recordAudit(ctx, audit.Event{
+		Operation:    "appointment.create",
+		ResourceType: audit.ResourceAppointment,
+		ResourceID:   appointmentID,
+		TenantID:     tenantID,
+		After:        response,
+	})
 	return response, nil
 }
 
 // GetAppointmentByID retrieves an appointment by its ID
 func (s *receptionService) GetAppointmentByID(ctx context.Context, appointmentID string, tenantID string) (*models.AppointmentResponse, error) {
+	ctx, span := tracer.Start(ctx, "receptionsvc.GetAppointmentByID")
+	defer span.End()
+
 	// Prepare filter
 	filter := bson.M{
 		"_id":       appointmentID,
@@ -138,21 +206,19 @@ func (s *receptionService) GetAppointmentByID(ctx context.Context, appointmentID
 		db.WithCollectionName(config.CollectionNames.Appointments),
 	)
 	if err != nil {
-		s.logger.Error("Failed to retrieve appointment", zap.Error(err))
-		return nil, errors.New("failed to retrieve appointment from database")
+		logging.WithContext(ctx, s.logger).Error("Failed to retrieve appointment", zap.Error(err))
+		return nil, ErrDatabase.Wrap(err)
 	}
 
-	// Check if appointment exists
-	if result == nil {
-		return nil, errors.New("appointment not found")
-	}
-
-	// Convert result to map
+	// Convert result to map, a missing appointment comes back as an empty map
 	appointmentMap, ok := result.(map[string]interface{})
 	if !ok {
-		s.logger.Error("Failed to convert appointment to map", zap.Any("result", result))
+		logging.WithContext(ctx, s.logger).Error("Failed to convert appointment to map", zap.Any("result", result))
 		return nil, errors.New("invalid appointment data format")
 	}
+	if len(appointmentMap) == 0 {
+		return nil, ErrAppointmentNotFound
+	}
 
 	// Create response
 	response, err := s.mapToAppointmentResponse(appointmentMap)
@@ -165,6 +231,9 @@ func (s *receptionService) GetAppointmentByID(ctx context.Context, appointmentID
 
 // UpdateAppointment updates an existing appointment
 func (s *receptionService) UpdateAppointment(ctx context.Context, appointmentID string, req models.AppointmentUpdateRequest, tenantID string) (*models.AppointmentResponse, error) {
+	ctx, span := tracer.Start(ctx, "receptionsvc.UpdateAppointment")
+	defer span.End()
+
 	// First, retrieve the existing appointment
 	existingAppointment, err := s.GetAppointmentByID(ctx, appointmentID, tenantID)
 	if err != nil {
@@ -185,7 +254,7 @@ func (s *receptionService) UpdateAppointment(ctx context.Context, appointmentID
 		}
 
 		if !isAvailable {
-			return nil, errors.New("doctor is not available at the requested time")
+			return nil, ErrDoctorUnavailable
 		}
 		updateFields["scheduled_time"] = *req.ScheduledTime
 	}
@@ -211,60 +280,152 @@ func (s *receptionService) UpdateAppointment(ctx context.Context, appointmentID
 		"_id":       appointmentID,
 		"tenant_id": tenantID,
 	}
+	withExpectedVersion(filter, req.ExpectedVersion)
+
+	// Update in database and read the result back, storing the event of the change with it
+	var modified int64
+	var updatedAppointment *models.AppointmentResponse
+	var eventType string
+	err = s.db.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		modified, err = s.db.UpdateOne(
+			ctx,
+			filter,
+			bson.M{"$set": updateFields, "$inc": bson.M{"version": 1}},
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.Appointments),
+		)
+		if err != nil {
+			logging.WithContext(ctx, s.logger).Error("Failed to update appointment", zap.Error(err))
+			return ErrDatabase.Wrap(err)
+		}
+		if modified == 0 && req.ExpectedVersion != nil {
+			return ErrVersionMismatch
+		}
 
-	// Update in database
-	_, err = s.db.UpdateOne(
-		ctx,
-		filter,
-		bson.M{"$set": updateFields},
-		db.WithDatabaseName(config.DatabaseNames.CoreDB),
-		db.WithCollectionName(config.CollectionNames.Appointments),
-	)
+		// Retrieve updated appointment
+		updatedAppointment, err = s.GetAppointmentByID(ctx, appointmentID, tenantID)
+		if err != nil || modified == 0 {
+			return err
+		}
+		eventType = events.AppointmentUpdated
+		if updatedAppointment.Status == models.AppointmentStatusCancelled && existingAppointment.Status != models.AppointmentStatusCancelled {
+			eventType = events.AppointmentCancelled
+		}
+		return s.publishDomainEvent(ctx, appointmentEvent(eventType, tenantID, updatedAppointment))
+	})
 	if err != nil {
-		s.logger.Error("Failed to update appointment", zap.Error(err))
-		return nil, errors.New("failed to update appointment in database")
+		return nil, err
+	}
+	if modified > 0 && req.Status != nil && *req.Status == models.AppointmentStatusCancelled {
+		metrics.AppointmentsCancelled.WithLabelValues(tenantID).Inc()
 	}
 
-	// Retrieve updated appointment
-	return s.GetAppointmentByID(ctx, appointmentID, tenantID)
+	if modified > 0 {
+		s.recordAudit(ctx, audit.Event{
+			Operation:    "appointment.update",
+			ResourceType: audit.ResourceAppointment,
+			ResourceID:   appointmentID,
+			TenantID:     tenantID,
+			Before:       existingAppointment,
+			After:        updatedAppointment,
+		})
+	}
+	return updatedAppointment, nil
 }
 
 // CancelAppointment cancels an existing appointment
-func (s *receptionService) CancelAppointment(ctx context.Context, appointmentID string, reason string, tenantID string) (*models.AppointmentResponse, error) {
+func (s *receptionService) CancelAppointment(ctx context.Context, appointmentID string, req models.AppointmentCancelRequest, tenantID string) (*models.AppointmentResponse, error) {
+	ctx, span := tracer.Start(ctx, "receptionsvc.CancelAppointment")
+	defer span.End()
+
+	// Keep the appointment as it was for the audit trail
+	existingAppointment, err := s.GetAppointmentByID(ctx, appointmentID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Prepare filter
 	filter := bson.M{
 		"_id":       appointmentID,
 		"tenant_id": tenantID,
 	}
+	withExpectedVersion(filter, req.ExpectedVersion)
 
 	// Prepare update
 	update := bson.M{
 		"$set": bson.M{
 			"status":     models.AppointmentStatusCancelled,
-			"notes":      reason,
+			"notes":      req.Reason,
 			"updated_at": time.Now(),
 		},
-	}
+		"$inc": bson.M{"version": 1},
+	}
+
+	// Update in database and read the result back, storing the event of the change with it
+	var modified int64
+	var cancelledAppointment *models.AppointmentResponse
+	err = s.db.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		modified, err = s.db.UpdateOne(
+			ctx,
+			filter,
+			update,
+			db.WithDatabaseName(config.DatabaseNames.CoreDB),
+			db.WithCollectionName(config.CollectionNames.Appointments),
+		)
+		if err != nil {
+			logging.WithContext(ctx, s.logger).Error("Failed to cancel appointment", zap.Error(err))
+			return ErrDatabase.Wrap(err)
+		}
+		if modified == 0 && req.ExpectedVersion != nil {
+			return ErrVersionMismatch
+		}
 
-	// Update in database
-	_, err := s.db.UpdateOne(
-		ctx,
-		filter,
-		update,
-		db.WithDatabaseName(config.DatabaseNames.CoreDB),
-		db.WithCollectionName(config.CollectionNames.Appointments),
-	)
+		// Retrieve updated appointment
+		cancelledAppointment, err = s.GetAppointmentByID(ctx, appointmentID, tenantID)
+		if err != nil || modified == 0 {
+			return err
+		}
+		return s.publishDomainEvent(ctx, domainevents.AppointmentCancelled{TenantID: tenantID, Appointment: *cancelledAppointment})
+	})
 	if err != nil {
-		s.logger.Error("Failed to cancel appointment", zap.Error(err))
-		return nil, errors.New("failed to cancel appointment in database")
+		return nil, err
 	}
 
-	// Retrieve updated appointment
-	return s.GetAppointmentByID(ctx, appointmentID, tenantID)
+	if modified > 0 {
+		metrics.AppointmentsCancelled.WithLabelValues(tenantID).Inc()
+		s.recordAudit(ctx, audit.Event{
+			Operation:    "appointment.cancel",
+			ResourceType: audit.ResourceAppointment,
+			ResourceID:   appointmentID,
+			TenantID:     tenantID,
+			Before:       existingAppointment,
+			After:        cancelledAppointment,
+		})
+	}
+	return cancelledAppointment, nil
 }
 
 // ListAppointments returns appointments based on provided filters
 func (s *receptionService) ListAppointments(ctx context.Context, filters map[string]interface{}, tenantID string) ([]models.AppointmentResponse, error) {
+	response := []models.AppointmentResponse{}
+	err := s.StreamAppointments(ctx, filters, tenantID, func(appointment models.AppointmentResponse) error {
+		response = append(response, appointment)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// StreamAppointments calls fn with every appointment matching the filters as it is read
+// from the database. An error returned by fn stops the stream and is returned as is.
+func (s *receptionService) StreamAppointments(ctx context.Context, filters map[string]interface{}, tenantID string, fn func(models.AppointmentResponse) error) error {
+	ctx, span := tracer.Start(ctx, "receptionsvc.StreamAppointments")
+	defer span.End()
+
 	// Add tenant ID to filters
 	filters["tenant_id"] = tenantID
 
@@ -290,46 +451,41 @@ func (s *receptionService) ListAppointments(ctx context.Context, filters map[str
 		}
 	}
 
-	// Retrieve from database
-	results, err := s.db.ReadAll(
+	// Convert each document as it is read so the result set is never held in memory
+	var writeErr error
+	err := s.db.Stream(
 		ctx,
 		filters,
+		func(appointmentMap map[string]interface{}) error {
+			appointment, err := s.mapToAppointmentResponse(appointmentMap)
+			if err != nil {
+				logging.WithContext(ctx, s.logger).Warn("Failed to convert appointment", zap.Error(err))
+				return nil
+			}
+			if err := fn(*appointment); err != nil {
+				writeErr = err
+				return err
+			}
+			return nil
+		},
 		db.WithDatabaseName(config.DatabaseNames.CoreDB),
 		db.WithCollectionName(config.CollectionNames.Appointments),
 	)
-	if err != nil {
-		s.logger.Error("Failed to list appointments", zap.Error(err))
-		return nil, errors.New("failed to retrieve appointments from database")
-	}
-
-	// Check if we got results
-	appointmentsArray, ok := results.([]map[string]interface{})
-	if !ok {
-		// If no appointments found, return empty array
-		if results == nil {
-			return []models.AppointmentResponse{}, nil
-		}
-
-		s.logger.Error("Failed to convert appointments to array", zap.Any("results", results))
-		return nil, errors.New("invalid appointments data format")
+	if writeErr != nil {
+		return writeErr
 	}
-
-	// Convert to response objects
-	response := make([]models.AppointmentResponse, 0, len(appointmentsArray))
-	for _, appointmentMap := range appointmentsArray {
-		appointment, err := s.mapToAppointmentResponse(appointmentMap)
-		if err != nil {
-			s.logger.Warn("Failed to convert appointment", zap.Error(err))
-			continue
-		}
-		response = append(response, *appointment)
+	if err != nil {
+		logging.WithContext(ctx, s.logger).Error("Failed to list appointments", zap.Error(err))
+		return ErrDatabase.Wrap(err)
 	}
-
-	return response, nil
+	return nil
 }
 
 // GetDoctorAvailability returns available time slots for a doctor on a specific date
 func (s *receptionService) GetDoctorAvailability(ctx context.Context, doctorID string, date time.Time, tenantID string) ([]map[string]interface{}, error) {
+	ctx, span := tracer.Start(ctx, "receptionsvc.GetDoctorAvailability")
+	defer span.End()
+
 	// This is a simplified implementation
 	// A real implementation would:
 	// 1. Get the doctor's working hours for the given day
@@ -359,8 +515,8 @@ func (s *receptionService) GetDoctorAvailability(ctx context.Context, doctorID s
 		db.WithCollectionName(config.CollectionNames.Appointments),
 	)
 	if err != nil {
-		s.logger.Error("Failed to retrieve doctor appointments", zap.Error(err))
-		return nil, errors.New("failed to check doctor availability")
+		logging.WithContext(ctx, s.logger).Error("Failed to retrieve doctor appointments", zap.Error(err))
+		return nil, ErrDatabase.Wrap(err)
 	}
 
 	// Create a map of occupied time slots
@@ -370,13 +526,13 @@ func (s *receptionService) GetDoctorAvailability(ctx context.Context, doctorID s
 		appointmentsArray, ok := results.([]map[string]interface{})
 		if ok {
 			for _, appt := range appointmentsArray {
-				scheduledTime, ok := appt["scheduled_time"].(time.Time)
-				if !ok {
+				scheduledTime := timeField(appt, "scheduled_time")
+				if scheduledTime.IsZero() {
 					continue
 				}
 
-				duration, ok := appt["duration"].(int)
-				if !ok {
+				duration := int(intField(appt, "duration"))
+				if duration == 0 {
 					duration = 30 // Default duration if not specified
 				}
 
@@ -439,20 +595,15 @@ func (s *receptionService) mapToAppointmentResponse(appointmentMap map[string]in
 		return nil, errors.New("invalid doctor name format")
 	}
 
-	scheduledTime, ok := appointmentMap["scheduled_time"].(time.Time)
-	if !ok {
+	scheduledTime := timeField(appointmentMap, "scheduled_time")
+	if scheduledTime.IsZero() {
 		return nil, errors.New("invalid scheduled time format")
 	}
 
-	duration, ok := appointmentMap["duration"].(int)
-	if !ok {
-		durationFloat, okFloat := appointmentMap["duration"].(float64)
-		if okFloat {
-			duration = int(durationFloat)
-		} else {
-			return nil, errors.New("invalid duration format")
-		}
+	if _, ok := appointmentMap["duration"]; !ok {
+		return nil, errors.New("invalid duration format")
 	}
+	duration := int(intField(appointmentMap, "duration"))
 
 	appointmentTypeStr, ok := appointmentMap["appointment_type"].(string)
 	if !ok {
@@ -468,11 +619,19 @@ func (s *receptionService) mapToAppointmentResponse(appointmentMap map[string]in
 
 	notes, _ := appointmentMap["notes"].(string)
 
-	createdAt, ok := appointmentMap["created_at"].(time.Time)
-	if !ok {
+	createdAt := timeField(appointmentMap, "created_at")
+	if createdAt.IsZero() {
 		createdAt = time.Now() // Fallback to current time if not available
 	}
 
+	updatedAt := timeField(appointmentMap, "updated_at")
+	if updatedAt.IsZero() {
+		updatedAt = createdAt
+	}
+
+	// Appointments created before versioning have no version and count as version 0
+	version := intField(appointmentMap, "version")
+
 	// Create response object
 	response := &models.AppointmentResponse{
 		AppointmentID: appointmentID,
@@ -486,11 +645,83 @@ func (s *receptionService) mapToAppointmentResponse(appointmentMap map[string]in
 		Status:        status,
 		Notes:         notes,
 		CreatedAt:     createdAt,
+		UpdatedAt:     updatedAt,
+		Version:       version,
 	}
 
 	return response, nil
 }
 
+// timeField returns a time of a document, whether it was decoded from MongoDB as a
+// primitive.DateTime or set in memory as a time.Time, or the zero time
+func timeField(doc map[string]interface{}, key string) time.Time {
+	switch t := doc[key].(type) {
+	case time.Time:
+		return t
+	case primitive.DateTime:
+		return t.Time().UTC()
+	}
+	return time.Time{}
+}
+
+// intField returns a number of a document, whichever integer type it was decoded as,
+// or 0 when it is missing
+func intField(doc map[string]interface{}, key string) int64 {
+	switch n := doc[key].(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
+
+// recordAudit records a mutation in the audit trail
+func (s *receptionService) recordAudit(ctx context.Context, event audit.Event) {
+	auditService, ok := s.svcRegistry.Get(registry.AuditService).(auditsvc.Service)
+	if !ok {
+		logging.WithContext(ctx, s.logger).Error("Audit service not registered, mutation not audited",
+			zap.String("operation", event.Operation),
+			zap.String("resource_id", event.ResourceID))
+		return
+	}
+	auditService.Record(ctx, event)
+}
+
+// publishDomainEvent writes a domain event to the outbox. Call it inside the transaction
+// that makes the change. Without a bus, for example in tests, events are not recorded.
+func (s *receptionService) publishDomainEvent(ctx context.Context, event domainevents.Event) error {
+	bus, ok := s.svcRegistry.Get(registry.DomainEvents).(*domainevents.Bus)
+	if !ok {
+		return nil
+	}
+	return bus.Publish(ctx, event)
+}
+
+// appointmentEvent returns the domain event of an appointment change
+func appointmentEvent(eventType, tenantID string, appointment *models.AppointmentResponse) domainevents.Event {
+	if eventType == events.AppointmentCancelled {
+		return domainevents.AppointmentCancelled{TenantID: tenantID, Appointment: *appointment}
+	}
+	return domainevents.AppointmentUpdated{TenantID: tenantID, Appointment: *appointment}
+}
+
+// withExpectedVersion restricts an update filter to the expected appointment version
+func withExpectedVersion(filter bson.M, expectedVersion *int64) {
+	if expectedVersion == nil {
+		return
+	}
+	if *expectedVersion == 0 {
+		filter["version"] = bson.M{"$exists": false}
+		return
+	}
+	filter["version"] = *expectedVersion
+}
+
 // Helper method to check if a doctor is available at a specific time
 func (s *receptionService) checkDoctorAvailability(ctx context.Context, doctorID string, scheduledTime time.Time, duration int, tenantID string) (bool, error) {
 	// Check if the time falls within working hours
@@ -542,8 +773,8 @@ func (s *receptionService) checkDoctorAvailability(ctx context.Context, doctorID
 		db.WithCollectionName(config.CollectionNames.Appointments),
 	)
 	if err != nil {
-		s.logger.Error("Failed to check doctor availability", zap.Error(err))
-		return false, errors.New("failed to check doctor availability")
+		logging.WithContext(ctx, s.logger).Error("Failed to check doctor availability", zap.Error(err))
+		return false, ErrDatabase.Wrap(err)
 	}
 
 	// If any conflicting appointments found, the doctor is not available
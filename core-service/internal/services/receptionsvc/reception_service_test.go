@@ -0,0 +1,57 @@
+package receptionsvc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/handlers/utility"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+func TestAppointmentLastModified(t *testing.T) {
+	updatedAt := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	database := &mocks.MockDBClient{
+		ReadFn: func(context.Context, map[string]interface{}, ...db.DBOption) (interface{}, error) {
+			// Dates and numbers as MongoDB decodes them
+			return map[string]interface{}{
+				"_id":              "appt-1",
+				"patient_id":       "patient-1",
+				"patient_name":     "Ada",
+				"doctor_id":        "doctor-1",
+				"doctor_name":      "Dr. Who",
+				"scheduled_time":   primitive.NewDateTimeFromTime(updatedAt.Add(48 * time.Hour)),
+				"duration":         int32(30),
+				"appointment_type": "consultation",
+				"status":           "scheduled",
+				"created_at":       primitive.NewDateTimeFromTime(updatedAt.Add(-time.Hour)),
+				"updated_at":       primitive.NewDateTimeFromTime(updatedAt),
+				"version":          int64(3),
+			}, nil
+		},
+	}
+	service := NewService(database, registry.NewServiceRegistry(), zap.NewNop())
+
+	appointment, err := service.GetAppointmentByID(context.Background(), "appt-1", "t1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, updatedAt.Equal(appointment.UpdatedAt))
+	assert.Equal(t, 30, appointment.Duration)
+	assert.Equal(t, int64(3), appointment.Version)
+
+	// A client sending back the Last-Modified it was given may change the appointment
+	req := httptest.NewRequest(http.MethodPut, "/appointments/appt-1", nil)
+	req.Header.Set("If-Unmodified-Since", appointment.UpdatedAt.UTC().Format(http.TimeFormat))
+	assert.True(t, utility.PreconditionsMet(req, "", appointment.UpdatedAt))
+
+	req.Header.Set("If-Unmodified-Since", updatedAt.Add(-time.Minute).UTC().Format(http.TimeFormat))
+	assert.False(t, utility.PreconditionsMet(req, "", appointment.UpdatedAt))
+}
@@ -2,14 +2,25 @@ package services
 
 import (
 	"context"
-	"os"
+	"fmt"
 
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
 	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/domainevents"
+	"github.com/mrityunjay-vashisth/core-service/internal/events"
+	"github.com/mrityunjay-vashisth/core-service/internal/leader"
+	"github.com/mrityunjay-vashisth/core-service/internal/mailer"
 	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/saga"
 	"github.com/mrityunjay-vashisth/core-service/internal/services/adminsvc"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/auditsvc"
 	"github.com/mrityunjay-vashisth/core-service/internal/services/authsvc"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/healthsvc"
 	"github.com/mrityunjay-vashisth/core-service/internal/services/onboardingsvc"
 	"github.com/mrityunjay-vashisth/core-service/internal/services/receptionsvc"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/webhooksvc"
+	"github.com/mrityunjay-vashisth/core-service/internal/webhooks"
 	"go.uber.org/zap"
 )
 
@@ -18,32 +29,131 @@ type ServiceManager struct {
 	logger   *zap.Logger
 }
 
-func NewServiceManager(ctx context.Context, db db.DBClientInterface) *ServiceManager {
+func NewServiceManager(ctx context.Context, db db.DBClientInterface, cfg *config.Config) *ServiceManager {
 	// Create registry
 	serviceRegistry := registry.NewServiceRegistry()
 	logger, ok := ctx.Value("logger").(*zap.Logger)
 	if !ok {
 		logger = zap.L()
 	}
-	authServiceAddr := os.Getenv("AUTH_SERVICE_ADDR")
-	if authServiceAddr == "" {
-		authServiceAddr = "172.26.57.112:50051"
+	authServiceAddr := cfg.Auth.ServiceAddr
+
+	authCredentials, err := authsvc.TransportCredentials(cfg.Auth.TLS, cfg.TLS.ReloadInterval, logger)
+	if err != nil {
+		logger.Fatal("Failed to load auth service TLS certificates", zap.Error(err))
+	}
+
+	auditService := auditsvc.NewService(db, serviceRegistry, logger)
+	if err := audit.NewMongoStore(db).EnsureEventIndex(ctx); err != nil {
+		logger.Error("Failed to set up the audit event index", zap.Error(err))
+	}
+	authService := authsvc.NewService(db, authServiceAddr, authCredentials, logger)
+	onboardingService := onboardingsvc.NewService(db, serviceRegistry, logger, cfg.Onboarding)
+	outboxStore := domainevents.NewMongoStore(db)
+	if err := outboxStore.EnsureRetention(ctx, cfg.Outbox.Retention); err != nil {
+		logger.Error("Failed to set up domain event retention", zap.Error(err))
 	}
-	logger.Info("Initializedddddddddddddddddddd")
+	if checker, ok := db.(interface {
+		SupportsTransactions(ctx context.Context) (bool, error)
+	}); ok {
+		if transactional, err := checker.SupportsTransactions(ctx); err == nil && !transactional {
+			logger.Warn("MongoDB is a standalone server, changes and their domain events are not written atomically")
+		}
+	}
+	domainEvents := domainevents.NewBus(outboxStore)
 
-	authService := authsvc.NewService(db, authServiceAddr, logger)
-	onboardingService := onboardingsvc.NewService(db, serviceRegistry, logger)
-	recoverySystem := onboardingsvc.NewStuckRequestRecovery(db, authService, logger)
+	recoveryRuns := onboardingsvc.NewMongoRunStore(db)
+	if err := recoveryRuns.EnsureRetention(ctx, cfg.Recovery.RunRetention); err != nil {
+		logger.Error("Failed to set up recovery run retention", zap.Error(err))
+	}
+	recoveryElector := leader.NewElector(leader.NewMongoStore(db), onboardingsvc.RecoveryLease, leader.HolderID(), cfg.Recovery.LeaseTTL, logger)
+	recoverySystem := onboardingsvc.NewStuckRequestRecovery(db, authService, domainEvents, logger, cfg.Recovery, recoveryElector, recoveryRuns)
 	adminService := adminsvc.NewService(db, serviceRegistry, logger)
 	reception := receptionsvc.NewService(db, serviceRegistry, logger)
+	webhookService := webhooksvc.NewService(db, serviceRegistry, logger, cfg.Webhooks)
+
+	webhookStore := webhooks.NewMongoStore(db)
+	if err := webhookStore.EnsureRetention(ctx, cfg.Webhooks.Retention); err != nil {
+		logger.Error("Failed to set up webhook delivery retention", zap.Error(err))
+	}
+	dispatcher := webhooks.NewDispatcher(webhookStore, cfg.Webhooks, logger)
 
+	domainEvents.Subscribe("webhooks", webhookService.HandleEvent,
+		domainevents.TypeAppointmentCreated,
+		domainevents.TypeAppointmentUpdated,
+		domainevents.TypeAppointmentCancelled,
+		domainevents.TypeTenantApproved,
+		domainevents.TypeOnboardingRejected,
+	)
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		logger.Fatal("Failed to set up the mailer", zap.Error(err))
+	}
+	domainEvents.Subscribe("audit_trail", auditService.HandleEvent, domainevents.TypeAuditRecorded)
+	domainEvents.Subscribe("applicant_notifications", onboardingsvc.NewApplicantNotifier(mail, cfg.Onboarding, logger).HandleEvent,
+		domainevents.TypeOnboardingVerificationRequested,
+		domainevents.TypeOnboardingRejected,
+	)
+	outboxDispatcher := domainevents.NewDispatcher(domainEvents, cfg.Outbox, logger)
+
+	// Every replica follows the outbox, so its streams see changes made through the others
+	appointmentEvents := events.NewBroker()
+	appointmentFeed := domainevents.NewFeed(outboxStore, cfg.HTTP.Events.PollInterval, appointmentEvents.HandleEvent, logger,
+		domainevents.TypeAppointmentCreated,
+		domainevents.TypeAppointmentUpdated,
+		domainevents.TypeAppointmentCancelled,
+	)
+
+	sagaStore := saga.NewMongoStore(db)
+	if err := sagaStore.EnsureRetention(ctx, cfg.Sagas.Retention); err != nil {
+		logger.Error("Failed to set up saga retention", zap.Error(err))
+	}
+	sagas := saga.NewOrchestrator(sagaStore, cfg.Sagas, logger)
+	sagas.Register(onboardingsvc.NewApprovalSaga(onboardingService, authService))
+	approvalRetrier := onboardingsvc.NewApprovalRetrier(db, onboardingService, logger, cfg.Onboarding)
+	verificationExpirer := onboardingsvc.NewVerificationExpirer(db, onboardingService, logger, cfg.Onboarding)
+
+	serviceRegistry.Register(registry.AuditService, auditService)
 	serviceRegistry.Register(registry.AuthService, authService)
 	serviceRegistry.Register(registry.OnboardingService, onboardingService)
 	serviceRegistry.Register(registry.AdminService, adminService)
 	serviceRegistry.Register(registry.OnbardingRecoveryService, recoverySystem)
 	serviceRegistry.Register(registry.ReceptionService, reception)
+	serviceRegistry.Register(registry.AppointmentEvents, appointmentEvents)
+	serviceRegistry.Register(registry.WebhookService, webhookService)
+	serviceRegistry.Register(registry.DomainEvents, domainEvents)
+	serviceRegistry.Register(registry.Sagas, sagas)
+
+	healthService := healthsvc.NewService(logger)
+	healthService.RegisterReadinessCheck("mongodb", true, func(ctx context.Context) (map[string]interface{}, error) {
+		return nil, db.Ping(ctx)
+	})
+	healthService.RegisterReadinessCheck("auth_service", true, func(ctx context.Context) (map[string]interface{}, error) {
+		return map[string]interface{}{"address": authServiceAddr}, authService.CheckHealth(ctx)
+	})
+	healthService.RegisterReadinessCheck("audit_trail", false, func(ctx context.Context) (map[string]interface{}, error) {
+		dropped := auditService.DroppedEntries()
+		if dropped > 0 {
+			return map[string]interface{}{"dropped_entries": dropped}, fmt.Errorf("%d audit entries could not be written", dropped)
+		}
+		return map[string]interface{}{"dropped_entries": dropped}, nil
+	})
+	healthService.RegisterReadinessCheck("stuck_request_recovery", false, healthsvc.JobCheck(recoverySystem, 3))
+	healthService.RegisterReadinessCheck("webhook_dispatcher", false, healthsvc.JobCheck(dispatcher, 3))
+	healthService.RegisterReadinessCheck("domain_event_dispatcher", false, healthsvc.JobCheck(outboxDispatcher, 3))
+	healthService.RegisterReadinessCheck("appointment_event_feed", false, healthsvc.JobCheck(appointmentFeed, 3))
+	healthService.RegisterReadinessCheck("saga_orchestrator", false, healthsvc.JobCheck(sagas, 3))
+	healthService.RegisterReadinessCheck("approval_retrier", false, healthsvc.JobCheck(approvalRetrier, 3))
+	healthService.RegisterReadinessCheck("verification_expirer", false, healthsvc.JobCheck(verificationExpirer, 3))
+	serviceRegistry.Register(registry.HealthService, healthService)
 
 	recoverySystem.Start()
+	dispatcher.Start()
+	outboxDispatcher.Start()
+	appointmentFeed.Start()
+	sagas.Start()
+	approvalRetrier.Start()
+	verificationExpirer.Start()
 
 	return &ServiceManager{
 		registry: serviceRegistry,
@@ -94,3 +204,30 @@ func (sm *ServiceManager) GetReceptionService() receptionsvc.Service {
 	}
 	return svc
 }
+
+// GetWebhookService returns the webhook service
+func (sm *ServiceManager) GetWebhookService() webhooksvc.Service {
+	svc, ok := sm.registry.Get(registry.WebhookService).(webhooksvc.Service)
+	if !ok {
+		panic("Webhook service not found in registry or has wrong type")
+	}
+	return svc
+}
+
+// GetAuditService returns the audit service
+func (sm *ServiceManager) GetAuditService() auditsvc.Service {
+	svc, ok := sm.registry.Get(registry.AuditService).(auditsvc.Service)
+	if !ok {
+		panic("Audit service not found in registry or has wrong type")
+	}
+	return svc
+}
+
+// GetHealthService returns the health service
+func (sm *ServiceManager) GetHealthService() healthsvc.Service {
+	svc, ok := sm.registry.Get(registry.HealthService).(healthsvc.Service)
+	if !ok {
+		panic("Health service not found in registry or has wrong type")
+	}
+	return svc
+}
@@ -0,0 +1,451 @@
+package webhooksvc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/apperrors"
+	"github.com/mrityunjay-vashisth/core-service/internal/audit"
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"github.com/mrityunjay-vashisth/core-service/internal/domainevents"
+	"github.com/mrityunjay-vashisth/core-service/internal/logging"
+	"github.com/mrityunjay-vashisth/core-service/internal/models"
+	"github.com/mrityunjay-vashisth/core-service/internal/registry"
+	"github.com/mrityunjay-vashisth/core-service/internal/services/auditsvc"
+	"github.com/mrityunjay-vashisth/core-service/internal/webhooks"
+	"github.com/mrityunjay-vashisth/go-idforge/pkg/idforge"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var tracer = otel.Tracer("github.com/mrityunjay-vashisth/core-service/internal/services/webhooksvc")
+
+// Limits of webhook endpoints
+const (
+	maxEndpointsPerTenant = 10
+	maxURLLength          = 2048
+	maxDescriptionLength  = 256
+)
+
+// Errors returned by the webhook service
+var (
+	ErrEndpointNotFound = apperrors.NotFound("webhook_endpoint_not_found", "webhook endpoint not found")
+	ErrDeliveryNotFound = apperrors.NotFound("webhook_delivery_not_found", "webhook delivery not found")
+	ErrEndpointDisabled = apperrors.Conflict("webhook_endpoint_disabled", "webhook endpoint is disabled, enable it before redelivering")
+	ErrEndpointLimit    = apperrors.Conflict("webhook_endpoint_limit", "the tenant already has the maximum number of webhook endpoints")
+	ErrDatabase         = apperrors.Unavailable("database_unavailable", "webhook data is temporarily unavailable", nil)
+)
+
+// Service manages the webhook endpoints of tenants and queues events for them. The
+// deliveries themselves are sent by a webhooks.Dispatcher.
+type Service interface {
+	// CreateEndpoint registers an endpoint. The returned endpoint carries its signing
+	// secret, which is never shown again.
+	CreateEndpoint(ctx context.Context, tenantID string, req models.WebhookEndpointRequest) (*webhooks.Endpoint, error)
+	ListEndpoints(ctx context.Context, tenantID string) ([]webhooks.Endpoint, error)
+	GetEndpoint(ctx context.Context, tenantID, endpointID string) (*webhooks.Endpoint, error)
+	UpdateEndpoint(ctx context.Context, tenantID, endpointID string, req models.WebhookEndpointUpdateRequest) (*webhooks.Endpoint, error)
+	DeleteEndpoint(ctx context.Context, tenantID, endpointID string) error
+	// ListDeliveries returns the delivery log of an endpoint, newest first
+	ListDeliveries(ctx context.Context, filter webhooks.DeliveryFilter) ([]webhooks.Delivery, error)
+	// Redeliver queues a new delivery of the event of an earlier one
+	Redeliver(ctx context.Context, tenantID, endpointID, deliveryID string) (*webhooks.Delivery, error)
+	// HandleEvent queues a domain event for every enabled endpoint of its tenant subscribed
+	// to its type. Deliveries are keyed by the event, so an event handled twice is queued once.
+	HandleEvent(ctx context.Context, msg domainevents.Message) error
+}
+
+type webhookService struct {
+	store         webhooks.Store
+	logger        *zap.Logger
+	svcRegistry   registry.ServiceRegistry
+	allowInsecure bool
+	now           func() time.Time
+}
+
+func NewService(db db.DBClientInterface, registry registry.ServiceRegistry, logger *zap.Logger, settings config.WebhooksConfig) Service {
+	return &webhookService{
+		store:         webhooks.NewMongoStore(db),
+		svcRegistry:   registry,
+		logger:        logger,
+		allowInsecure: settings.AllowInsecure,
+		now:           time.Now,
+	}
+}
+
+func (s *webhookService) CreateEndpoint(ctx context.Context, tenantID string, req models.WebhookEndpointRequest) (*webhooks.Endpoint, error) {
+	ctx, span := tracer.Start(ctx, "webhooksvc.CreateEndpoint")
+	defer span.End()
+
+	fields := s.validateURL(req.URL)
+	fields = append(fields, validateEventTypes(req.EventTypes)...)
+	if len(req.Description) > maxDescriptionLength {
+		fields = append(fields, apperrors.FieldError{Field: "description", Message: "must be at most 256 characters"})
+	}
+	if len(fields) > 0 {
+		return nil, apperrors.Validation("invalid_webhook_endpoint", "Invalid webhook endpoint", fields...)
+	}
+
+	existing, err := s.store.ListEndpoints(ctx, tenantID)
+	if err != nil {
+		return nil, ErrDatabase.Wrap(err)
+	}
+	if len(existing) >= maxEndpointsPerTenant {
+		return nil, ErrEndpointLimit
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		return nil, apperrors.Internal("secret_generation_failed", "internal service error", err)
+	}
+	now := s.now().UTC()
+	endpoint := webhooks.Endpoint{
+		ID:          idforge.GenerateWithSize(20),
+		TenantID:    tenantID,
+		URL:         req.URL,
+		EventTypes:  uniqueEventTypes(req.EventTypes),
+		Description: req.Description,
+		Secret:      secret,
+		Enabled:     true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.store.CreateEndpoint(ctx, endpoint); err != nil {
+		return nil, ErrDatabase.Wrap(err)
+	}
+
+	s.recordAudit(ctx, audit.Event{
+		Operation:    "webhook_endpoint.create",
+		ResourceType: audit.ResourceWebhookEndpoint,
+		ResourceID:   endpoint.ID,
+		TenantID:     tenantID,
+		After:        withoutSecret(endpoint),
+	})
+	return &endpoint, nil
+}
+
+func (s *webhookService) ListEndpoints(ctx context.Context, tenantID string) ([]webhooks.Endpoint, error) {
+	ctx, span := tracer.Start(ctx, "webhooksvc.ListEndpoints")
+	defer span.End()
+
+	endpoints, err := s.store.ListEndpoints(ctx, tenantID)
+	if err != nil {
+		return nil, ErrDatabase.Wrap(err)
+	}
+	for i := range endpoints {
+		endpoints[i] = withoutSecret(endpoints[i])
+	}
+	return endpoints, nil
+}
+
+func (s *webhookService) GetEndpoint(ctx context.Context, tenantID, endpointID string) (*webhooks.Endpoint, error) {
+	ctx, span := tracer.Start(ctx, "webhooksvc.GetEndpoint")
+	defer span.End()
+
+	endpoint, err := s.getEndpoint(ctx, tenantID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	result := withoutSecret(*endpoint)
+	return &result, nil
+}
+
+func (s *webhookService) UpdateEndpoint(ctx context.Context, tenantID, endpointID string, req models.WebhookEndpointUpdateRequest) (*webhooks.Endpoint, error) {
+	ctx, span := tracer.Start(ctx, "webhooksvc.UpdateEndpoint")
+	defer span.End()
+
+	var fields []apperrors.FieldError
+	if req.URL != nil {
+		fields = append(fields, s.validateURL(*req.URL)...)
+	}
+	if req.EventTypes != nil {
+		fields = append(fields, validateEventTypes(req.EventTypes)...)
+	}
+	if req.Description != nil && len(*req.Description) > maxDescriptionLength {
+		fields = append(fields, apperrors.FieldError{Field: "description", Message: "must be at most 256 characters"})
+	}
+	if len(fields) > 0 {
+		return nil, apperrors.Validation("invalid_webhook_endpoint", "Invalid webhook endpoint", fields...)
+	}
+
+	existing, err := s.getEndpoint(ctx, tenantID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	updated := *existing
+	if req.URL != nil {
+		updated.URL = *req.URL
+	}
+	if req.EventTypes != nil {
+		updated.EventTypes = uniqueEventTypes(req.EventTypes)
+	}
+	if req.Description != nil {
+		updated.Description = *req.Description
+	}
+	if req.Enabled != nil {
+		updated.Enabled = *req.Enabled
+		if *req.Enabled {
+			updated.DisabledReason = ""
+			updated.ConsecutiveFailures = 0
+			updated.FailingSince = nil
+		} else if existing.Enabled {
+			updated.DisabledReason = "disabled by the tenant"
+		}
+	}
+	updated.UpdatedAt = s.now().UTC()
+
+	if err := s.store.UpdateEndpoint(ctx, updated); err != nil {
+		if errors.Is(err, webhooks.ErrNotFound) {
+			return nil, ErrEndpointNotFound
+		}
+		return nil, ErrDatabase.Wrap(err)
+	}
+
+	s.recordAudit(ctx, audit.Event{
+		Operation:    "webhook_endpoint.update",
+		ResourceType: audit.ResourceWebhookEndpoint,
+		ResourceID:   endpointID,
+		TenantID:     tenantID,
+		Before:       withoutSecret(*existing),
+		After:        withoutSecret(updated),
+	})
+	result := withoutSecret(updated)
+	return &result, nil
+}
+
+func (s *webhookService) DeleteEndpoint(ctx context.Context, tenantID, endpointID string) error {
+	ctx, span := tracer.Start(ctx, "webhooksvc.DeleteEndpoint")
+	defer span.End()
+
+	existing, err := s.getEndpoint(ctx, tenantID, endpointID)
+	if err != nil {
+		return err
+	}
+	if err := s.store.DeleteEndpoint(ctx, tenantID, endpointID); err != nil {
+		if errors.Is(err, webhooks.ErrNotFound) {
+			return ErrEndpointNotFound
+		}
+		return ErrDatabase.Wrap(err)
+	}
+
+	// Pending deliveries fail on their next attempt; the log stays until it expires
+	s.recordAudit(ctx, audit.Event{
+		Operation:    "webhook_endpoint.delete",
+		ResourceType: audit.ResourceWebhookEndpoint,
+		ResourceID:   endpointID,
+		TenantID:     tenantID,
+		Before:       withoutSecret(*existing),
+	})
+	return nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, filter webhooks.DeliveryFilter) ([]webhooks.Delivery, error) {
+	ctx, span := tracer.Start(ctx, "webhooksvc.ListDeliveries")
+	defer span.End()
+
+	if _, err := s.getEndpoint(ctx, filter.TenantID, filter.EndpointID); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.store.ListDeliveries(ctx, filter)
+	if err != nil {
+		return nil, ErrDatabase.Wrap(err)
+	}
+	return deliveries, nil
+}
+
+func (s *webhookService) Redeliver(ctx context.Context, tenantID, endpointID, deliveryID string) (*webhooks.Delivery, error) {
+	ctx, span := tracer.Start(ctx, "webhooksvc.Redeliver")
+	defer span.End()
+
+	endpoint, err := s.getEndpoint(ctx, tenantID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if !endpoint.Enabled {
+		return nil, ErrEndpointDisabled
+	}
+
+	original, err := s.store.GetDelivery(ctx, tenantID, deliveryID)
+	if errors.Is(err, webhooks.ErrNotFound) || (err == nil && original.EndpointID != endpointID) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, ErrDatabase.Wrap(err)
+	}
+
+	now := s.now().UTC()
+	delivery := webhooks.Delivery{
+		ID:            idforge.GenerateWithSize(20),
+		EndpointID:    endpointID,
+		TenantID:      tenantID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        webhooks.StatusPending,
+		Attempts:      []webhooks.Attempt{},
+		NextAttemptAt: &now,
+		RedeliveryOf:  original.ID,
+		CreatedAt:     now,
+	}
+	if err := s.store.CreateDelivery(ctx, delivery); err != nil {
+		return nil, ErrDatabase.Wrap(err)
+	}
+	return &delivery, nil
+}
+
+func (s *webhookService) HandleEvent(ctx context.Context, msg domainevents.Message) error {
+	ctx, span := tracer.Start(ctx, "webhooksvc.HandleEvent")
+	defer span.End()
+
+	endpoints, err := s.store.ListEndpoints(ctx, msg.TenantID)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	for _, endpoint := range endpoints {
+		if !endpoint.Enabled || !endpoint.Subscribes(msg.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = encodeEnvelope(msg); err != nil {
+				return err
+			}
+		}
+
+		now := s.now().UTC()
+		delivery := webhooks.Delivery{
+			ID:            msg.ID + "-" + endpoint.ID,
+			EndpointID:    endpoint.ID,
+			TenantID:      msg.TenantID,
+			EventID:       msg.ID,
+			EventType:     msg.Type,
+			Payload:       payload,
+			Status:        webhooks.StatusPending,
+			Attempts:      []webhooks.Attempt{},
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+		}
+		err := s.store.CreateDelivery(ctx, delivery)
+		if errors.Is(err, webhooks.ErrDuplicate) {
+			// Queued when the event was handled before
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getEndpoint reads an endpoint of the tenant, mapping store errors to service errors
+func (s *webhookService) getEndpoint(ctx context.Context, tenantID, endpointID string) (*webhooks.Endpoint, error) {
+	endpoint, err := s.store.GetEndpoint(ctx, tenantID, endpointID)
+	if errors.Is(err, webhooks.ErrNotFound) {
+		return nil, ErrEndpointNotFound
+	}
+	if err != nil {
+		return nil, ErrDatabase.Wrap(err)
+	}
+	return endpoint, nil
+}
+
+// validateURL accepts absolute https URLs without credentials, and http URLs when
+// insecure endpoints are allowed
+func (s *webhookService) validateURL(raw string) []apperrors.FieldError {
+	invalid := []apperrors.FieldError{{Field: "url", Message: "must be an absolute https URL"}}
+	if s.allowInsecure {
+		invalid[0].Message = "must be an absolute http or https URL"
+	}
+	if raw == "" {
+		return []apperrors.FieldError{{Field: "url", Message: "is required"}}
+	}
+	if len(raw) > maxURLLength {
+		return []apperrors.FieldError{{Field: "url", Message: "must be at most 2048 characters"}}
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || parsed.User != nil {
+		return invalid
+	}
+	if parsed.Scheme != "https" && !(s.allowInsecure && parsed.Scheme == "http") {
+		return invalid
+	}
+	return nil
+}
+
+// validateEventTypes requires at least one known event type
+func validateEventTypes(eventTypes []string) []apperrors.FieldError {
+	if len(eventTypes) == 0 {
+		return []apperrors.FieldError{{Field: "event_types", Message: "must list at least one event type"}}
+	}
+	for _, eventType := range eventTypes {
+		known := false
+		for _, candidate := range webhooks.EventTypes {
+			known = known || candidate == eventType
+		}
+		if !known {
+			return []apperrors.FieldError{{Field: "event_types", Message: "unknown event type " + eventType}}
+		}
+	}
+	return nil
+}
+
+// uniqueEventTypes drops repeated event types, keeping the order
+func uniqueEventTypes(eventTypes []string) []string {
+	seen := make(map[string]bool, len(eventTypes))
+	unique := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if !seen[eventType] {
+			seen[eventType] = true
+			unique = append(unique, eventType)
+		}
+	}
+	return unique
+}
+
+// withoutSecret returns the endpoint as it is shown after it was created
+func withoutSecret(endpoint webhooks.Endpoint) webhooks.Endpoint {
+	endpoint.Secret = ""
+	return endpoint
+}
+
+// encodeEnvelope builds the body delivered for a domain event. Appointment events carry
+// the appointment, like the live appointment updates; other events carry their fields.
+func encodeEnvelope(msg domainevents.Message) ([]byte, error) {
+	data := msg.Payload
+	switch msg.Type {
+	case domainevents.TypeAppointmentCreated, domainevents.TypeAppointmentUpdated, domainevents.TypeAppointmentCancelled:
+		var event domainevents.AppointmentUpdated
+		if err := msg.Decode(&event); err != nil {
+			return nil, err
+		}
+		encoded, err := json.Marshal(event.Appointment)
+		if err != nil {
+			return nil, err
+		}
+		data = encoded
+	}
+	return json.Marshal(webhooks.Envelope{
+		ID:        msg.ID,
+		Type:      msg.Type,
+		TenantID:  msg.TenantID,
+		CreatedAt: msg.OccurredAt,
+		Data:      data,
+	})
+}
+
+// recordAudit records a mutation in the audit trail
+func (s *webhookService) recordAudit(ctx context.Context, event audit.Event) {
+	auditService, ok := s.svcRegistry.Get(registry.AuditService).(auditsvc.Service)
+	if !ok {
+		logging.WithContext(ctx, s.logger).Error("Audit service not registered, mutation not audited",
+			zap.String("operation", event.Operation),
+			zap.String("resource_id", event.ResourceID))
+		return
+	}
+	auditService.Record(ctx, event)
+}
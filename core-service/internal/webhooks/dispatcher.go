@@ -0,0 +1,237 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/metrics"
+	"github.com/mrityunjay-vashisth/core-service/internal/worker"
+	"go.uber.org/zap"
+)
+
+// deliveryBatch is how many due deliveries a dispatcher claims per run
+const deliveryBatch = 50
+
+// deliveryConcurrency bounds the requests a dispatcher has in flight, so one slow
+// endpoint does not hold up the others
+const deliveryConcurrency = 8
+
+// maxBackoff caps the wait between two attempts of a delivery
+const maxBackoff = 6 * time.Hour
+
+// responseDrainLimit is how much of a response body is read so the connection can be reused
+const responseDrainLimit = 64 << 10
+
+// userAgent identifies delivery requests
+const userAgent = "Medusa-Webhooks/1.0"
+
+// Dispatcher periodically sends the deliveries that are due. Several dispatchers may share
+// a store; each delivery is leased to one of them per attempt.
+type Dispatcher struct {
+	// Runs DeliverDue. Deliveries in flight when it stops are leased and retried by the next run.
+	*worker.Loop
+
+	store        Store
+	client       *http.Client
+	logger       *zap.Logger
+	timeout      time.Duration // Per request
+	maxAttempts  int
+	retryDelay   time.Duration // Before the first retry, doubling with every further one
+	disableAfter time.Duration // How long an endpoint may fail before it is disabled
+	now          func() time.Time
+}
+
+// NewDispatcher creates a dispatcher sending the deliveries of store. Unless the settings
+// allow insecure endpoints, requests to loopback and private network addresses are refused.
+func NewDispatcher(store Store, settings config.WebhooksConfig, logger *zap.Logger) *Dispatcher {
+	d := &Dispatcher{
+		store:        store,
+		client:       newHTTPClient(settings.Timeout, settings.AllowInsecure),
+		logger:       logger,
+		timeout:      settings.Timeout,
+		maxAttempts:  settings.MaxAttempts,
+		retryDelay:   settings.RetryBaseDelay,
+		disableAfter: settings.DisableAfter,
+		now:          time.Now,
+	}
+	d.Loop = worker.NewLoop("Webhook dispatcher", settings.PollInterval, d.DeliverDue, logger)
+	return d
+}
+
+// DeliverDue sends every delivery whose next attempt is due, batch by batch
+func (d *Dispatcher) DeliverDue(ctx context.Context) error {
+	for {
+		// A claimed delivery is not due again until its requests have had time to finish
+		due, err := d.store.ClaimDue(ctx, d.now(), 2*d.timeout, deliveryBatch)
+		if err != nil {
+			return fmt.Errorf("failed to claim due webhook deliveries: %w", err)
+		}
+
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, deliveryConcurrency)
+		for _, delivery := range due {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(delivery Delivery) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				d.deliver(ctx, delivery)
+			}(delivery)
+		}
+		wg.Wait()
+
+		if len(due) < deliveryBatch {
+			break
+		}
+	}
+	return nil
+}
+
+// deliver makes one attempt of a delivery and schedules the next one if it failed
+func (d *Dispatcher) deliver(ctx context.Context, delivery Delivery) {
+	logger := d.logger.With(zap.String("delivery_id", delivery.ID), zap.String("endpoint_id", delivery.EndpointID))
+
+	endpoint, err := d.store.GetEndpoint(ctx, delivery.TenantID, delivery.EndpointID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		d.finish(ctx, logger, delivery, Attempt{At: d.now(), Error: "endpoint was deleted"}, StatusFailed)
+		return
+	case err != nil:
+		// The lease runs out and the next run tries again
+		logger.Error("Failed to read webhook endpoint", zap.Error(err))
+		return
+	case !endpoint.Enabled:
+		d.finish(ctx, logger, delivery, Attempt{At: d.now(), Error: "endpoint is disabled"}, StatusFailed)
+		return
+	}
+
+	attempt := d.send(ctx, endpoint, delivery)
+	succeeded := attempt.Error == ""
+	switch {
+	case succeeded:
+		d.finish(ctx, logger, delivery, attempt, StatusSucceeded)
+	case len(delivery.Attempts)+1 >= d.maxAttempts:
+		d.finish(ctx, logger, delivery, attempt, StatusFailed)
+	default:
+		next := attempt.At.Add(worker.Jitter(worker.Backoff(d.retryDelay, maxBackoff, len(delivery.Attempts)+1)))
+		delivery.Status = StatusPending
+		delivery.NextAttemptAt = &next
+		if err := d.store.CompleteAttempt(ctx, delivery, attempt); err != nil {
+			logger.Error("Failed to schedule webhook retry", zap.Error(err))
+		}
+		metrics.WebhookDeliveries.WithLabelValues("retrying").Inc()
+		logger.Info("Webhook delivery failed, retrying",
+			zap.String("error", attempt.Error),
+			zap.Time("next_attempt_at", next))
+	}
+
+	disabled, err := d.store.RecordOutcome(ctx, delivery.TenantID, delivery.EndpointID, succeeded, attempt.At, d.disableAfter)
+	if err != nil {
+		logger.Error("Failed to record webhook endpoint outcome", zap.Error(err))
+	}
+	if disabled {
+		metrics.WebhookEndpointsDisabled.Inc()
+		logger.Warn("Disabled failing webhook endpoint", zap.String("tenant_id", delivery.TenantID))
+	}
+}
+
+// finish records the last attempt of a delivery
+func (d *Dispatcher) finish(ctx context.Context, logger *zap.Logger, delivery Delivery, attempt Attempt, status string) {
+	completedAt := attempt.At
+	delivery.Status = status
+	delivery.NextAttemptAt = nil
+	delivery.CompletedAt = &completedAt
+	if err := d.store.CompleteAttempt(ctx, delivery, attempt); err != nil {
+		logger.Error("Failed to record webhook delivery", zap.Error(err), zap.String("status", status))
+	}
+	metrics.WebhookDeliveries.WithLabelValues(status).Inc()
+	if status == StatusFailed {
+		logger.Warn("Webhook delivery failed", zap.String("error", attempt.Error))
+	}
+}
+
+// send posts the signed payload to the endpoint. Any 2xx status is a success; redirects
+// are not followed.
+func (d *Dispatcher) send(ctx context.Context, endpoint *Endpoint, delivery Delivery) Attempt {
+	attempt := Attempt{At: d.now()}
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, attempt.At, delivery.Payload))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, responseDrainLimit))
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = "endpoint answered " + resp.Status
+	}
+	return attempt
+}
+
+// disabledReason explains why an endpoint was disabled
+func disabledReason(disableAfter time.Duration) string {
+	return "deliveries failed for " + disableAfter.String() + " without a success"
+}
+
+// newHTTPClient builds the client of delivery requests. Without allowPrivate, connections
+// to addresses that are not public are refused when dialing, after DNS resolution, so a
+// hostname cannot be pointed at internal services. No proxy is used for the same reason.
+func newHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = refusePrivateAddress
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+			ForceAttemptHTTP2:   true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// refusePrivateAddress is a dialer control that only lets connections to public addresses through
+func refusePrivateAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("address %s is not public", host)
+	}
+	return nil
+}
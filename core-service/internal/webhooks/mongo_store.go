@@ -0,0 +1,387 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/mrityunjay-vashisth/core-service/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoStore keeps endpoints and deliveries in coredb, keyed by their IDs
+type MongoStore struct {
+	db db.DBClientInterface
+}
+
+// NewMongoStore creates a store backed by the webhook_endpoints and webhook_deliveries collections
+func NewMongoStore(database db.DBClientInterface) *MongoStore {
+	return &MongoStore{db: database}
+}
+
+// EnsureRetention makes the database expire deliveries once they are older than retention
+func (s *MongoStore) EnsureRetention(ctx context.Context, retention time.Duration) error {
+	return s.db.EnsureTTLIndex(ctx, "created_at", retention, s.deliveries()...)
+}
+
+func (s *MongoStore) endpoints(extra ...db.DBOption) []db.DBOption {
+	return append([]db.DBOption{
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.WebhookEndpoints),
+	}, extra...)
+}
+
+func (s *MongoStore) deliveries(extra ...db.DBOption) []db.DBOption {
+	return append([]db.DBOption{
+		db.WithDatabaseName(config.DatabaseNames.CoreDB),
+		db.WithCollectionName(config.CollectionNames.WebhookDeliveries),
+	}, extra...)
+}
+
+func (s *MongoStore) CreateEndpoint(ctx context.Context, endpoint Endpoint) error {
+	_, err := s.db.Create(ctx, encodeEndpoint(endpoint), s.endpoints()...)
+	return err
+}
+
+func (s *MongoStore) GetEndpoint(ctx context.Context, tenantID, endpointID string) (*Endpoint, error) {
+	result, err := s.db.Read(ctx, bson.M{"_id": endpointID, "tenant_id": tenantID}, s.endpoints()...)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := result.(map[string]interface{})
+	if !ok || len(doc) == 0 {
+		return nil, ErrNotFound
+	}
+	endpoint := decodeEndpoint(doc)
+	return &endpoint, nil
+}
+
+func (s *MongoStore) ListEndpoints(ctx context.Context, tenantID string) ([]Endpoint, error) {
+	results, err := s.db.ReadAll(ctx, bson.M{"tenant_id": tenantID}, s.endpoints(db.WithSort("created_at", 1))...)
+	if err != nil {
+		return nil, err
+	}
+	docs, _ := results.([]map[string]interface{})
+	endpoints := make([]Endpoint, 0, len(docs))
+	for _, doc := range docs {
+		endpoints = append(endpoints, decodeEndpoint(doc))
+	}
+	return endpoints, nil
+}
+
+func (s *MongoStore) UpdateEndpoint(ctx context.Context, endpoint Endpoint) error {
+	set := bson.M{
+		"url":         endpoint.URL,
+		"event_types": endpoint.EventTypes,
+		"description": endpoint.Description,
+		"enabled":     endpoint.Enabled,
+		"updated_at":  endpoint.UpdatedAt,
+	}
+	if endpoint.Enabled {
+		set["disabled_reason"] = ""
+		set["consecutive_failures"] = 0
+		set["failing_since"] = nil
+	} else {
+		set["disabled_reason"] = endpoint.DisabledReason
+	}
+	filter := bson.M{"_id": endpoint.ID, "tenant_id": endpoint.TenantID}
+	if _, err := s.db.UpdateOne(ctx, filter, bson.M{"$set": set}, s.endpoints()...); err != nil {
+		return err
+	}
+	// An update that changes nothing modifies no document, so existence is checked apart
+	_, err := s.GetEndpoint(ctx, endpoint.TenantID, endpoint.ID)
+	return err
+}
+
+func (s *MongoStore) DeleteEndpoint(ctx context.Context, tenantID, endpointID string) error {
+	result, err := s.db.Delete(ctx, bson.M{"_id": endpointID, "tenant_id": tenantID}, s.endpoints()...)
+	if err != nil {
+		return err
+	}
+	if deleted, _ := result.(int64); deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *MongoStore) RecordOutcome(ctx context.Context, tenantID, endpointID string, succeeded bool, now time.Time, disableAfter time.Duration) (bool, error) {
+	filter := bson.M{"_id": endpointID, "tenant_id": tenantID}
+	if succeeded {
+		_, err := s.db.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"consecutive_failures": 0, "failing_since": nil}}, s.endpoints()...)
+		return false, err
+	}
+
+	// Start the failing period on the first failure, then count every failure
+	if _, err := s.db.UpdateOne(ctx, bson.M{"_id": endpointID, "tenant_id": tenantID, "failing_since": nil},
+		bson.M{"$set": bson.M{"failing_since": now}}, s.endpoints()...); err != nil {
+		return false, err
+	}
+	if _, err := s.db.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"consecutive_failures": 1}}, s.endpoints()...); err != nil {
+		return false, err
+	}
+	disabled, err := s.db.UpdateOne(ctx,
+		bson.M{"_id": endpointID, "tenant_id": tenantID, "enabled": true, "failing_since": bson.M{"$lte": now.Add(-disableAfter)}},
+		bson.M{"$set": bson.M{"enabled": false, "disabled_reason": disabledReason(disableAfter), "updated_at": now}},
+		s.endpoints()...)
+	return disabled > 0, err
+}
+
+func (s *MongoStore) CreateDelivery(ctx context.Context, delivery Delivery) error {
+	_, err := s.db.Create(ctx, encodeDelivery(delivery), s.deliveries()...)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (s *MongoStore) GetDelivery(ctx context.Context, tenantID, deliveryID string) (*Delivery, error) {
+	result, err := s.db.Read(ctx, bson.M{"_id": deliveryID, "tenant_id": tenantID}, s.deliveries()...)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := result.(map[string]interface{})
+	if !ok || len(doc) == 0 {
+		return nil, ErrNotFound
+	}
+	delivery := decodeDelivery(doc)
+	return &delivery, nil
+}
+
+func (s *MongoStore) ListDeliveries(ctx context.Context, filter DeliveryFilter) ([]Delivery, error) {
+	query := bson.M{"tenant_id": filter.TenantID}
+	if filter.EndpointID != "" {
+		query["endpoint_id"] = filter.EndpointID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.EventID != "" {
+		query["event_id"] = filter.EventID
+	}
+	if !filter.Before.IsZero() {
+		query["created_at"] = bson.M{"$lt": filter.Before}
+	}
+
+	results, err := s.db.ReadAll(ctx, query, s.deliveries(db.WithSort("created_at", -1), db.WithLimit(filter.Limit))...)
+	if err != nil {
+		return nil, err
+	}
+	docs, _ := results.([]map[string]interface{})
+	deliveries := make([]Delivery, 0, len(docs))
+	for _, doc := range docs {
+		deliveries = append(deliveries, decodeDelivery(doc))
+	}
+	return deliveries, nil
+}
+
+func (s *MongoStore) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int64) ([]Delivery, error) {
+	results, err := s.db.ReadAll(ctx,
+		bson.M{"status": StatusPending, "next_attempt_at": bson.M{"$lte": now}},
+		s.deliveries(db.WithSort("next_attempt_at", 1), db.WithLimit(limit))...)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, _ := results.([]map[string]interface{})
+	claimed := make([]Delivery, 0, len(docs))
+	for _, doc := range docs {
+		// Moving the next attempt past the lease claims the delivery; a dispatcher that
+		// read the same document first leaves nothing to modify
+		leased, err := s.db.UpdateOne(ctx,
+			bson.M{"_id": doc["_id"], "status": StatusPending, "next_attempt_at": doc["next_attempt_at"]},
+			bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}},
+			s.deliveries()...)
+		if err != nil {
+			return claimed, err
+		}
+		if leased > 0 {
+			claimed = append(claimed, decodeDelivery(doc))
+		}
+	}
+	return claimed, nil
+}
+
+func (s *MongoStore) CompleteAttempt(ctx context.Context, delivery Delivery, attempt Attempt) error {
+	set := bson.M{"status": delivery.Status, "next_attempt_at": nil, "completed_at": nil}
+	if delivery.NextAttemptAt != nil {
+		set["next_attempt_at"] = *delivery.NextAttemptAt
+	}
+	if delivery.CompletedAt != nil {
+		set["completed_at"] = *delivery.CompletedAt
+	}
+	_, err := s.db.UpdateOne(ctx, bson.M{"_id": delivery.ID},
+		bson.M{"$set": set, "$push": bson.M{"attempts": encodeAttempt(attempt)}},
+		s.deliveries()...)
+	return err
+}
+
+// encodeEndpoint converts an endpoint to the document saved in the database
+func encodeEndpoint(endpoint Endpoint) map[string]interface{} {
+	return map[string]interface{}{
+		"_id":                  endpoint.ID,
+		"tenant_id":            endpoint.TenantID,
+		"url":                  endpoint.URL,
+		"event_types":          endpoint.EventTypes,
+		"description":          endpoint.Description,
+		"secret":               endpoint.Secret,
+		"enabled":              endpoint.Enabled,
+		"disabled_reason":      endpoint.DisabledReason,
+		"consecutive_failures": endpoint.ConsecutiveFailures,
+		"failing_since":        endpoint.FailingSince,
+		"created_at":           endpoint.CreatedAt,
+		"updated_at":           endpoint.UpdatedAt,
+	}
+}
+
+// decodeEndpoint reads an endpoint document returned by the database
+func decodeEndpoint(doc map[string]interface{}) Endpoint {
+	endpoint := Endpoint{
+		CreatedAt:    timeField(doc["created_at"]),
+		UpdatedAt:    timeField(doc["updated_at"]),
+		FailingSince: optionalTime(doc["failing_since"]),
+		EventTypes:   stringList(doc["event_types"]),
+	}
+	endpoint.ID, _ = doc["_id"].(string)
+	endpoint.TenantID, _ = doc["tenant_id"].(string)
+	endpoint.URL, _ = doc["url"].(string)
+	endpoint.Description, _ = doc["description"].(string)
+	endpoint.Secret, _ = doc["secret"].(string)
+	endpoint.Enabled, _ = doc["enabled"].(bool)
+	endpoint.DisabledReason, _ = doc["disabled_reason"].(string)
+	endpoint.ConsecutiveFailures = intField(doc["consecutive_failures"])
+	return endpoint
+}
+
+// encodeDelivery converts a delivery to the document saved in the database. The payload
+// is kept as the exact bytes that are signed.
+func encodeDelivery(delivery Delivery) map[string]interface{} {
+	attempts := make([]map[string]interface{}, 0, len(delivery.Attempts))
+	for _, attempt := range delivery.Attempts {
+		attempts = append(attempts, encodeAttempt(attempt))
+	}
+	return map[string]interface{}{
+		"_id":             delivery.ID,
+		"endpoint_id":     delivery.EndpointID,
+		"tenant_id":       delivery.TenantID,
+		"event_id":        delivery.EventID,
+		"event_type":      delivery.EventType,
+		"payload":         string(delivery.Payload),
+		"status":          delivery.Status,
+		"attempts":        attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"redelivery_of":   delivery.RedeliveryOf,
+		"created_at":      delivery.CreatedAt,
+		"completed_at":    delivery.CompletedAt,
+	}
+}
+
+// decodeDelivery reads a delivery document returned by the database
+func decodeDelivery(doc map[string]interface{}) Delivery {
+	delivery := Delivery{
+		CreatedAt:     timeField(doc["created_at"]),
+		NextAttemptAt: optionalTime(doc["next_attempt_at"]),
+		CompletedAt:   optionalTime(doc["completed_at"]),
+		Attempts:      []Attempt{},
+	}
+	delivery.ID, _ = doc["_id"].(string)
+	delivery.EndpointID, _ = doc["endpoint_id"].(string)
+	delivery.TenantID, _ = doc["tenant_id"].(string)
+	delivery.EventID, _ = doc["event_id"].(string)
+	delivery.EventType, _ = doc["event_type"].(string)
+	delivery.Status, _ = doc["status"].(string)
+	delivery.RedeliveryOf, _ = doc["redelivery_of"].(string)
+	if payload, _ := doc["payload"].(string); payload != "" {
+		delivery.Payload = json.RawMessage(payload)
+	}
+	for _, item := range list(doc["attempts"]) {
+		fields := documentFields(item)
+		attempt := Attempt{
+			At:         timeField(fields["at"]),
+			StatusCode: intField(fields["status_code"]),
+			DurationMS: int64(intField(fields["duration_ms"])),
+		}
+		attempt.Error, _ = fields["error"].(string)
+		delivery.Attempts = append(delivery.Attempts, attempt)
+	}
+	return delivery
+}
+
+func encodeAttempt(attempt Attempt) map[string]interface{} {
+	return map[string]interface{}{
+		"at":          attempt.At,
+		"status_code": attempt.StatusCode,
+		"error":       attempt.Error,
+		"duration_ms": attempt.DurationMS,
+	}
+}
+
+func timeField(value interface{}) time.Time {
+	switch t := value.(type) {
+	case time.Time:
+		return t.UTC()
+	case primitive.DateTime:
+		return t.Time().UTC()
+	}
+	return time.Time{}
+}
+
+func optionalTime(value interface{}) *time.Time {
+	t := timeField(value)
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func intField(value interface{}) int {
+	switch n := value.(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
+
+func list(value interface{}) []interface{} {
+	switch items := value.(type) {
+	case primitive.A:
+		return items
+	case []interface{}:
+		return items
+	}
+	return nil
+}
+
+func stringList(value interface{}) []string {
+	var values []string
+	for _, item := range list(value) {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+// documentFields reads an embedded document, which the driver may decode as a map or as
+// an ordered document
+func documentFields(item interface{}) map[string]interface{} {
+	switch doc := item.(type) {
+	case map[string]interface{}:
+		return doc
+	case primitive.M:
+		return doc
+	case primitive.D:
+		fields := make(map[string]interface{}, len(doc))
+		for _, element := range doc {
+			fields[element.Key] = element.Value
+		}
+		return fields
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+// Package webhooks delivers tenant events to the HTTP endpoints tenants register. Every
+// delivery is signed with the endpoint secret, retried with exponential backoff and kept
+// in a log that tenants can query and redeliver from.
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/domainevents"
+)
+
+// Types of events endpoints can subscribe to, named after the domain events they carry
+const (
+	AppointmentCreated   = domainevents.TypeAppointmentCreated
+	AppointmentUpdated   = domainevents.TypeAppointmentUpdated
+	AppointmentCancelled = domainevents.TypeAppointmentCancelled
+	TenantApproved       = domainevents.TypeTenantApproved
+	OnboardingRejected   = domainevents.TypeOnboardingRejected
+)
+
+// EventTypes lists every event type endpoints can subscribe to
+var EventTypes = []string{AppointmentCreated, AppointmentUpdated, AppointmentCancelled, TenantApproved, OnboardingRejected}
+
+// Headers of a delivery request
+const (
+	SignatureHeader = "X-Medusa-Signature" // t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+	EventHeader     = "X-Medusa-Event"
+	DeliveryHeader  = "X-Medusa-Delivery"
+)
+
+// Statuses of a delivery
+const (
+	StatusPending   = "pending"   // Waiting for its next attempt
+	StatusSucceeded = "succeeded" // The endpoint answered with a 2xx status
+	StatusFailed    = "failed"    // Every attempt failed, or the endpoint went away
+)
+
+// Errors returned by a Store
+var (
+	ErrNotFound  = errors.New("webhook resource not found")
+	ErrDuplicate = errors.New("webhook delivery already exists")
+)
+
+// Endpoint is a URL of a tenant that receives the events it subscribes to
+type Endpoint struct {
+	ID                  string     `json:"id"`
+	TenantID            string     `json:"-"`
+	URL                 string     `json:"url"`
+	EventTypes          []string   `json:"event_types"`
+	Description         string     `json:"description,omitempty"`
+	Secret              string     `json:"secret,omitempty"` // Only shown when the endpoint is created
+	Enabled             bool       `json:"enabled"`
+	DisabledReason      string     `json:"disabled_reason,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	FailingSince        *time.Time `json:"failing_since,omitempty"` // First failure since the last success
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Subscribes reports whether the endpoint receives events of the given type
+func (e Endpoint) Subscribes(eventType string) bool {
+	for _, subscribed := range e.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Attempt records one request of a delivery
+type Attempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// Delivery is an event on its way to one endpoint. Redeliveries are new deliveries of the
+// same event, so receivers can deduplicate on the event ID.
+type Delivery struct {
+	ID            string          `json:"id"`
+	EndpointID    string          `json:"endpoint_id"`
+	TenantID      string          `json:"-"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      []Attempt       `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"` // Only while pending
+	RedeliveryOf  string          `json:"redelivery_of,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	CompletedAt   *time.Time      `json:"completed_at,omitempty"`
+}
+
+// DeliveryFilter selects deliveries of an endpoint, newest first. Zero values match everything.
+type DeliveryFilter struct {
+	TenantID   string
+	EndpointID string
+	Status     string
+	EventID    string
+	Before     time.Time // Only deliveries created earlier, for paging
+	Limit      int64
+}
+
+// Store keeps endpoints and their deliveries
+type Store interface {
+	CreateEndpoint(ctx context.Context, endpoint Endpoint) error
+	// GetEndpoint returns the endpoint with its secret, or ErrNotFound
+	GetEndpoint(ctx context.Context, tenantID, endpointID string) (*Endpoint, error)
+	ListEndpoints(ctx context.Context, tenantID string) ([]Endpoint, error)
+	// UpdateEndpoint saves the settings of an endpoint: URL, event types, description and
+	// whether it is enabled. Re-enabling an endpoint clears its failures.
+	UpdateEndpoint(ctx context.Context, endpoint Endpoint) error
+	DeleteEndpoint(ctx context.Context, tenantID, endpointID string) error
+	// RecordOutcome tracks consecutive failures of an endpoint and disables it once it has
+	// failed for disableAfter without a success, reporting whether it did so
+	RecordOutcome(ctx context.Context, tenantID, endpointID string, succeeded bool, now time.Time, disableAfter time.Duration) (bool, error)
+
+	// CreateDelivery returns ErrDuplicate if a delivery with the same ID exists
+	CreateDelivery(ctx context.Context, delivery Delivery) error
+	GetDelivery(ctx context.Context, tenantID, deliveryID string) (*Delivery, error)
+	ListDeliveries(ctx context.Context, filter DeliveryFilter) ([]Delivery, error)
+	// ClaimDue leases up to limit pending deliveries whose next attempt is due, so no other
+	// dispatcher sends them until the lease ends
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int64) ([]Delivery, error)
+	// CompleteAttempt appends the attempt to the delivery log and saves the new status and
+	// next attempt time of the delivery
+	CompleteAttempt(ctx context.Context, delivery Delivery, attempt Attempt) error
+}
+
+// NewSecret generates a signing secret for an endpoint
+func NewSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// Sign returns the signature header of a body sent at the given time
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac(secret, unix, body))
+}
+
+// VerifySignature checks a signature header against the body, rejecting signatures older
+// than tolerance so captured requests cannot be replayed. Receivers written in Go can
+// use it as is.
+func VerifySignature(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var unix string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return errors.New("malformed signature header")
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("signature timestamp is outside the tolerance of %s", tolerance)
+	}
+
+	expected := mac(secret, unix, body)
+	for _, signature := range signatures {
+		if decoded, err := hex.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return errors.New("signature does not match")
+}
+
+// mac computes the HMAC-SHA256 of "<timestamp>.<body>"
+func mac(secret, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Envelope is the body of every delivery
+type Envelope struct {
+	ID        string          `json:"id"` // Event ID, the same for every delivery of the event
+	Type      string          `json:"type"`
+	TenantID  string          `json:"tenant_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
@@ -0,0 +1,272 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrityunjay-vashisth/core-service/internal/config"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryStore keeps a single tenant's endpoints and deliveries for tests
+type memoryStore struct {
+	mu         sync.Mutex
+	endpoints  map[string]Endpoint
+	deliveries map[string]Delivery
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{endpoints: map[string]Endpoint{}, deliveries: map[string]Delivery{}}
+}
+
+func (s *memoryStore) CreateEndpoint(_ context.Context, endpoint Endpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endpoints[endpoint.ID] = endpoint
+	return nil
+}
+
+func (s *memoryStore) GetEndpoint(_ context.Context, _, endpointID string) (*Endpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoint, ok := s.endpoints[endpointID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &endpoint, nil
+}
+
+func (s *memoryStore) ListEndpoints(_ context.Context, _ string) ([]Endpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var endpoints []Endpoint
+	for _, endpoint := range s.endpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, nil
+}
+
+func (s *memoryStore) UpdateEndpoint(ctx context.Context, endpoint Endpoint) error {
+	return s.CreateEndpoint(ctx, endpoint)
+}
+
+func (s *memoryStore) DeleteEndpoint(_ context.Context, _, endpointID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.endpoints, endpointID)
+	return nil
+}
+
+func (s *memoryStore) RecordOutcome(_ context.Context, _, endpointID string, succeeded bool, now time.Time, disableAfter time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoint := s.endpoints[endpointID]
+	defer func() { s.endpoints[endpointID] = endpoint }()
+
+	if succeeded {
+		endpoint.ConsecutiveFailures = 0
+		endpoint.FailingSince = nil
+		return false, nil
+	}
+	if endpoint.FailingSince == nil {
+		endpoint.FailingSince = &now
+	}
+	endpoint.ConsecutiveFailures++
+	if endpoint.Enabled && !endpoint.FailingSince.After(now.Add(-disableAfter)) {
+		endpoint.Enabled = false
+		endpoint.DisabledReason = disabledReason(disableAfter)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *memoryStore) CreateDelivery(_ context.Context, delivery Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+func (s *memoryStore) GetDelivery(_ context.Context, _, deliveryID string) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delivery, ok := s.deliveries[deliveryID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &delivery, nil
+}
+
+func (s *memoryStore) ListDeliveries(_ context.Context, _ DeliveryFilter) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deliveries []Delivery
+	for _, delivery := range s.deliveries {
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (s *memoryStore) ClaimDue(_ context.Context, now time.Time, lease time.Duration, limit int64) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []Delivery
+	for id, delivery := range s.deliveries {
+		if int64(len(due)) == limit {
+			break
+		}
+		if delivery.Status == StatusPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+			leasedUntil := now.Add(lease)
+			delivery.NextAttemptAt = &leasedUntil
+			s.deliveries[id] = delivery
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) CompleteAttempt(_ context.Context, delivery Delivery, attempt Attempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	s.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+// newTestDispatcher returns a dispatcher of store whose clock is controlled by the test
+func newTestDispatcher(store Store, clock *time.Time) *Dispatcher {
+	settings := config.WebhooksConfig{
+		PollInterval:   time.Second,
+		Timeout:        2 * time.Second,
+		MaxAttempts:    3,
+		RetryBaseDelay: time.Minute,
+		DisableAfter:   time.Hour,
+		AllowInsecure:  true,
+	}
+	dispatcher := NewDispatcher(store, settings, zap.NewNop())
+	dispatcher.now = func() time.Time { return *clock }
+	return dispatcher
+}
+
+// addEndpoint registers an endpoint for url subscribed to created appointments
+func addEndpoint(store *memoryStore, url string) {
+	store.CreateEndpoint(context.Background(), Endpoint{
+		ID: "endpoint-1", TenantID: "tenant-1", URL: url, Secret: "whsec_test",
+		EventTypes: []string{AppointmentCreated}, Enabled: true,
+	})
+}
+
+// queue adds a delivery to the endpoint that is due at now
+func queue(store *memoryStore, deliveryID string, now time.Time) {
+	store.CreateDelivery(context.Background(), Delivery{
+		ID: deliveryID, EndpointID: "endpoint-1", TenantID: "tenant-1", EventID: "event-" + deliveryID,
+		EventType: AppointmentCreated, Payload: []byte(`{"type":"appointment.created"}`),
+		Status: StatusPending, NextAttemptAt: &now, CreatedAt: now,
+	})
+}
+
+func TestSignature(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"type":"appointment.created"}`)
+	header := Sign("whsec_test", now, body)
+
+	assert.NoError(t, VerifySignature("whsec_test", header, body, 5*time.Minute, now))
+	assert.Error(t, VerifySignature("whsec_other", header, body, 5*time.Minute, now), "Wrong secret")
+	assert.Error(t, VerifySignature("whsec_test", header, []byte(`{"type":"tenant.approved"}`), 5*time.Minute, now), "Tampered body")
+	assert.Error(t, VerifySignature("whsec_test", header, body, 5*time.Minute, now.Add(10*time.Minute)), "Replayed too late")
+	assert.Error(t, VerifySignature("whsec_test", "v1=abc", body, 5*time.Minute, now), "Missing timestamp")
+
+	// Receivers accept any matching signature, so secrets can be rotated
+	assert.NoError(t, VerifySignature("whsec_test", header+",v1=00", body, 5*time.Minute, now))
+}
+
+func TestDeliverSignedEvent(t *testing.T) {
+	var received http.Header
+	var verifyErr error
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = r.Header
+		verifyErr = VerifySignature("whsec_test", r.Header.Get(SignatureHeader), body, time.Minute, time.Now())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	clock := time.Now()
+	store := newMemoryStore()
+	addEndpoint(store, receiver.URL)
+	queue(store, "delivery-1", clock)
+
+	assert.NoError(t, newTestDispatcher(store, &clock).DeliverDue(context.Background()))
+	assert.NoError(t, verifyErr)
+	assert.Equal(t, AppointmentCreated, received.Get(EventHeader))
+	assert.Equal(t, "delivery-1", received.Get(DeliveryHeader))
+
+	delivery, _ := store.GetDelivery(context.Background(), "tenant-1", "delivery-1")
+	assert.Equal(t, StatusSucceeded, delivery.Status)
+	assert.Len(t, delivery.Attempts, 1)
+	assert.Equal(t, http.StatusNoContent, delivery.Attempts[0].StatusCode)
+}
+
+func TestRetryAndDisable(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	clock := time.Now()
+	store := newMemoryStore()
+	addEndpoint(store, receiver.URL)
+	queue(store, "delivery-1", clock)
+	dispatcher := newTestDispatcher(store, &clock)
+
+	// A failed attempt is retried after the backoff, not before
+	assert.NoError(t, dispatcher.DeliverDue(context.Background()))
+	delivery, _ := store.GetDelivery(context.Background(), "tenant-1", "delivery-1")
+	assert.Equal(t, StatusPending, delivery.Status)
+	assert.Equal(t, "endpoint answered 503 Service Unavailable", delivery.Attempts[0].Error)
+	assert.False(t, delivery.NextAttemptAt.Before(clock.Add(time.Minute)))
+
+	assert.NoError(t, dispatcher.DeliverDue(context.Background()))
+	delivery, _ = store.GetDelivery(context.Background(), "tenant-1", "delivery-1")
+	assert.Len(t, delivery.Attempts, 1, "The retry is not due yet")
+
+	// The last attempt fails the delivery
+	clock = clock.Add(10 * time.Minute)
+	assert.NoError(t, dispatcher.DeliverDue(context.Background()))
+	clock = clock.Add(10 * time.Minute)
+	assert.NoError(t, dispatcher.DeliverDue(context.Background()))
+	delivery, _ = store.GetDelivery(context.Background(), "tenant-1", "delivery-1")
+	assert.Equal(t, StatusFailed, delivery.Status)
+	assert.Len(t, delivery.Attempts, 3)
+
+	endpoint, _ := store.GetEndpoint(context.Background(), "tenant-1", "endpoint-1")
+	assert.True(t, endpoint.Enabled, "The endpoint has not failed for long enough")
+	assert.Equal(t, 3, endpoint.ConsecutiveFailures)
+
+	// An endpoint failing for longer than allowed is disabled
+	clock = clock.Add(time.Hour)
+	queue(store, "delivery-2", clock)
+	assert.NoError(t, dispatcher.DeliverDue(context.Background()))
+	endpoint, _ = store.GetEndpoint(context.Background(), "tenant-1", "endpoint-1")
+	assert.False(t, endpoint.Enabled)
+	assert.NotEmpty(t, endpoint.DisabledReason)
+}
+
+func TestPrivateAddressesRefused(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	_, err := newHTTPClient(time.Second, false).Get(receiver.URL)
+	assert.ErrorContains(t, err, "is not public")
+
+	resp, err := newHTTPClient(time.Second, true).Get(receiver.URL)
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+}
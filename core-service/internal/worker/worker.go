@@ -0,0 +1,116 @@
+// Package worker runs the periodic background jobs of the service and spaces out the
+// retries of the work they do.
+package worker
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Loop runs a job every interval, and whenever it is woken, until it is stopped. It
+// records when the job last succeeded so healthsvc.JobCheck can report a stalled job.
+// Jobs embed it to get Start, Stop, LastSuccessfulRun and Interval.
+type Loop struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+	logger   *zap.Logger
+	wake     chan struct{}
+	stopChan chan struct{}
+
+	statusMutex   sync.RWMutex
+	lastSuccessAt time.Time
+}
+
+// NewLoop creates a loop calling run every interval. The name appears in its log lines.
+func NewLoop(name string, interval time.Duration, run func(ctx context.Context) error, logger *zap.Logger) *Loop {
+	return &Loop{
+		name:     name,
+		interval: interval,
+		run:      run,
+		logger:   logger,
+		wake:     make(chan struct{}, 1),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start begins running the job every interval
+func (l *Loop) Start() {
+	ticker := time.NewTicker(l.interval)
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+			case <-l.wake:
+			case <-l.stopChan:
+				ticker.Stop()
+				return
+			}
+			l.RunOnce(context.Background())
+		}
+	}()
+
+	l.logger.Info(l.name + " started")
+}
+
+// Stop halts the loop. A run in progress finishes first.
+func (l *Loop) Stop() {
+	close(l.stopChan)
+	l.logger.Info(l.name + " stopped")
+}
+
+// Wake has the loop run before the next tick. Wakes arriving during a run are merged
+// into a single extra run.
+func (l *Loop) Wake() {
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// RunOnce runs the job and records its success
+func (l *Loop) RunOnce(ctx context.Context) {
+	if err := l.run(ctx); err != nil {
+		l.logger.Error(l.name+" run failed", zap.Error(err))
+		return
+	}
+	l.statusMutex.Lock()
+	l.lastSuccessAt = time.Now()
+	l.statusMutex.Unlock()
+}
+
+// LastSuccessfulRun returns when the job last completed a run without error
+func (l *Loop) LastSuccessfulRun() time.Time {
+	l.statusMutex.RLock()
+	defer l.statusMutex.RUnlock()
+	return l.lastSuccessAt
+}
+
+// Interval returns how often the job runs
+func (l *Loop) Interval() time.Duration {
+	return l.interval
+}
+
+// Backoff returns the wait after the given number of failed attempts: the base delay
+// doubled for every attempt after the first, up to max
+func Backoff(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// Jitter adds up to a fifth of a delay at random, so retries of many items that failed
+// together spread out
+func Jitter(delay time.Duration) time.Duration {
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, Backoff(time.Minute, 5*time.Minute, 1))
+	assert.Equal(t, 2*time.Minute, Backoff(time.Minute, 5*time.Minute, 2))
+	assert.Equal(t, 4*time.Minute, Backoff(time.Minute, 5*time.Minute, 3))
+	assert.Equal(t, 5*time.Minute, Backoff(time.Minute, 5*time.Minute, 4))
+	assert.Equal(t, 5*time.Minute, Backoff(time.Minute, 5*time.Minute, 40), "Backoff is capped")
+
+	assert.GreaterOrEqual(t, Jitter(time.Minute), time.Minute)
+	assert.LessOrEqual(t, Jitter(time.Minute), time.Minute+12*time.Second)
+}
+
+func TestLoopRecordsSuccessfulRuns(t *testing.T) {
+	fail := true
+	runs := make(chan struct{}, 1)
+	loop := NewLoop("Test job", time.Hour, func(ctx context.Context) error {
+		defer func() { runs <- struct{}{} }()
+		if fail {
+			return errors.New("unavailable")
+		}
+		return nil
+	}, zap.NewNop())
+	loop.Start()
+	defer loop.Stop()
+
+	loop.Wake()
+	<-runs
+	assert.True(t, loop.LastSuccessfulRun().IsZero(), "a failed run is not a success")
+
+	fail = false
+	loop.Wake()
+	<-runs
+	assert.Eventually(t, func() bool { return !loop.LastSuccessfulRun().IsZero() }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, time.Hour, loop.Interval())
+}
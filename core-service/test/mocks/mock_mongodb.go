@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/mrityunjay-vashisth/core-service/internal/db"
@@ -11,11 +12,17 @@ import (
 // MockDBClient implements DBClientInterface for testing
 type MockDBClient struct {
 	ConnectFn   func(ctx context.Context) error
+	PingFn      func(ctx context.Context) error
 	CreateFn    func(ctx context.Context, data map[string]interface{}, opts ...db.DBOption) (interface{}, error)
 	ReadFn      func(ctx context.Context, filter map[string]interface{}, opts ...db.DBOption) (interface{}, error)
 	ReadAllFn   func(ctx context.Context, filter map[string]interface{}, opts ...db.DBOption) (interface{}, error)
+	StreamFn    func(ctx context.Context, filter map[string]interface{}, fn func(map[string]interface{}) error, opts ...db.DBOption) error
 	DeleteFn    func(ctx context.Context, filter map[string]interface{}, opts ...db.DBOption) (interface{}, error)
 	UpdateOneFn func(ctx context.Context, filter map[string]interface{}, update map[string]interface{}, opts ...db.DBOption) (int64, error)
+
+	EnsureTTLIndexFn    func(ctx context.Context, field string, expireAfter time.Duration, opts ...db.DBOption) error
+	EnsureUniqueIndexFn func(ctx context.Context, field string, opts ...db.DBOption) error
+	WithTransactionFn   func(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 // MockClaims defines JWT claims for testing purposes
@@ -34,6 +41,14 @@ func (m *MockDBClient) Connect(ctx context.Context) error {
 	return nil
 }
 
+// Ping mock implementation (reports healthy unless overridden)
+func (m *MockDBClient) Ping(ctx context.Context) error {
+	if m.PingFn != nil {
+		return m.PingFn(ctx)
+	}
+	return nil
+}
+
 // Create mock implementation
 func (m *MockDBClient) Create(ctx context.Context, data map[string]interface{}, opts ...db.DBOption) (interface{}, error) {
 	if m.CreateFn != nil {
@@ -60,6 +75,27 @@ func (m *MockDBClient) ReadAll(ctx context.Context, filter map[string]interface{
 	return nil, errors.New("ReadAllFn not implemented")
 }
 
+// Stream mock implementation, replaying the documents of ReadAllFn unless overridden
+func (m *MockDBClient) Stream(ctx context.Context, filter map[string]interface{}, fn func(map[string]interface{}) error, opts ...db.DBOption) error {
+	if m.StreamFn != nil {
+		return m.StreamFn(ctx, filter, fn, opts...)
+	}
+	if m.ReadAllFn != nil {
+		data, err := m.ReadAllFn(ctx, filter, opts...)
+		if err != nil {
+			return err
+		}
+		documents, _ := data.([]map[string]interface{})
+		for _, document := range documents {
+			if err := fn(document); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errors.New("StreamFn not implemented")
+}
+
 // Delete mock implementation
 func (m *MockDBClient) Delete(ctx context.Context, filter map[string]interface{}, opts ...db.DBOption) (interface{}, error) {
 	if m.DeleteFn != nil {
@@ -75,3 +111,27 @@ func (m *MockDBClient) UpdateOne(ctx context.Context, filter map[string]interfac
 	}
 	return 0, errors.New("UpdateOneFn not implemented")
 }
+
+// EnsureTTLIndex mock implementation (succeeds unless overridden)
+func (m *MockDBClient) EnsureTTLIndex(ctx context.Context, field string, expireAfter time.Duration, opts ...db.DBOption) error {
+	if m.EnsureTTLIndexFn != nil {
+		return m.EnsureTTLIndexFn(ctx, field, expireAfter, opts...)
+	}
+	return nil
+}
+
+// EnsureUniqueIndex mock implementation (succeeds unless overridden)
+func (m *MockDBClient) EnsureUniqueIndex(ctx context.Context, field string, opts ...db.DBOption) error {
+	if m.EnsureUniqueIndexFn != nil {
+		return m.EnsureUniqueIndexFn(ctx, field, opts...)
+	}
+	return nil
+}
+
+// WithTransaction mock implementation (runs fn directly unless overridden)
+func (m *MockDBClient) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if m.WithTransactionFn != nil {
+		return m.WithTransactionFn(ctx, fn)
+	}
+	return fn(ctx)
+}
@@ -8,12 +8,12 @@ import (
 
 // MockOnboardingService implements OnboardingServices for testing
 type MockOnboardingService struct {
-	OnboardTenantFunc      func(ctx context.Context, req models.OnboardingRequest) (string, error)
+	OnboardTenantFunc      func(ctx context.Context, req models.OnboardingRequest) (*models.OnboardingReceipt, error)
 	GetPendingRequestsFunc func(ctx context.Context) (interface{}, error)
 	ApproveOnboardingFunc  func(ctx context.Context, requestID string) error
 }
 
-func (m *MockOnboardingService) OnboardTenant(ctx context.Context, req models.OnboardingRequest) (string, error) {
+func (m *MockOnboardingService) OnboardTenant(ctx context.Context, req models.OnboardingRequest) (*models.OnboardingReceipt, error) {
 	return m.OnboardTenantFunc(ctx, req)
 }
 
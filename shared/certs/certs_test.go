@@ -0,0 +1,99 @@
+package certs
+
+import (
+	"crypto/tls"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	serverLeaf   = DevLeaf{Name: "auth-service", DNSNames: []string{"localhost", "127.0.0.1"}, Server: true}
+	clientLeaf   = DevLeaf{Name: "core-service-client", DNSNames: []string{"core-service"}, Client: true}
+	intruderLeaf = DevLeaf{Name: "intruder", DNSNames: []string{"intruder"}, Client: true}
+)
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, EnsureDev(dir, serverLeaf, clientLeaf, intruderLeaf))
+	caFile := filepath.Join(dir, DevCAFile)
+
+	serverStore, err := NewStore(serverLeaf.CertFile(dir), serverLeaf.KeyFile(dir), caFile)
+	assert.NoError(t, err)
+	serverConfig := ServerConfig(serverStore, []string{"core-service"})
+
+	clientStore, err := NewStore(clientLeaf.CertFile(dir), clientLeaf.KeyFile(dir), caFile)
+	assert.NoError(t, err)
+	assert.NoError(t, handshake(t, serverConfig, ClientConfig(clientStore, "localhost")))
+
+	// The server name must match its certificate
+	assert.Error(t, handshake(t, serverConfig, ClientConfig(clientStore, "auth.example.com")))
+
+	// A certificate from the same CA is not enough without an allowed identity
+	intruderStore, err := NewStore(intruderLeaf.CertFile(dir), intruderLeaf.KeyFile(dir), caFile)
+	assert.NoError(t, err)
+	assert.Error(t, handshake(t, serverConfig, ClientConfig(intruderStore, "localhost")))
+
+	// Neither is no certificate at all
+	anonymousStore, err := NewStore("", "", caFile)
+	assert.NoError(t, err)
+	assert.Error(t, handshake(t, serverConfig, ClientConfig(anonymousStore, "localhost")))
+}
+
+func TestStoreReload(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, EnsureDev(dir, serverLeaf))
+
+	store, err := NewStore(serverLeaf.CertFile(dir), serverLeaf.KeyFile(dir), filepath.Join(dir, DevCAFile))
+	assert.NoError(t, err)
+	before := store.Certificate()
+
+	reloaded, err := store.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded, "unchanged files are not read again")
+
+	// A rotated certificate is picked up without restarting
+	assert.NoError(t, os.Remove(serverLeaf.CertFile(dir)))
+	assert.NoError(t, EnsureDev(dir, serverLeaf))
+	reloaded, err = store.Reload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.NotEqual(t, before.Certificate[0], store.Certificate().Certificate[0])
+
+	// A broken file keeps the last good certificate
+	assert.NoError(t, os.WriteFile(serverLeaf.CertFile(dir), []byte("garbage"), 0o644))
+	_, err = store.Reload()
+	assert.Error(t, err)
+	assert.NotNil(t, store.Certificate())
+}
+
+// handshake connects a client to a server and returns the error either side saw
+func handshake(t *testing.T, serverConfig, clientConfig *tls.Config) error {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.(*tls.Conn).Handshake()
+		conn.Close()
+	}()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// With TLS 1.3 a rejected client certificate is only reported on the first read
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ServerConfig returns a TLS configuration serving the certificate of the store. When
+// allowedClients is not empty, clients must present a certificate issued by the CA bundle
+// of the store whose common name or DNS names include one of the allowed identities.
+func ServerConfig(store *Store, allowedClients []string) *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return store.Certificate(), nil
+		},
+	}
+	if len(allowedClients) == 0 {
+		return config
+	}
+
+	// The CA bundle is read on every handshake so it can be rotated too
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		clientConfig := config.Clone()
+		clientConfig.GetConfigForClient = nil
+		clientConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		clientConfig.ClientCAs = store.Pool()
+		clientConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			return checkIdentity(state.PeerCertificates[0], allowedClients)
+		}
+		return clientConfig, nil
+	}
+	return config
+}
+
+// ClientConfig returns a TLS configuration that verifies the server against the CA bundle
+// of the store and, when the store has a key pair, presents it as the client certificate.
+// serverName overrides the host name expected in the server certificate.
+func ClientConfig(store *Store, serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert := store.Certificate(); cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+		// The standard verification pins the roots when the connection is configured, so
+		// it is replaced by one against the current bundle in VerifyConnection
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return verifyServer(state, store.Pool())
+		},
+	}
+}
+
+// verifyServer checks the server chain and host name like the standard verification does
+func verifyServer(state tls.ConnectionState, roots *x509.CertPool) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       state.ServerName,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	return err
+}
+
+// checkIdentity accepts a client certificate naming one of the allowed identities
+func checkIdentity(cert *x509.Certificate, allowed []string) error {
+	if slices.Contains(allowed, cert.Subject.CommonName) {
+		return nil
+	}
+	for _, name := range cert.DNSNames {
+		if slices.Contains(allowed, name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("client certificate %q is not an allowed identity", cert.Subject.CommonName)
+}
@@ -0,0 +1,236 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Files of the development CA. The bundle holds the CA certificate and key together so it
+// can be created atomically by whichever service starts first.
+const (
+	DevCAFile       = "ca.pem"
+	devCABundleFile = "ca-bundle.pem"
+)
+
+// Validity of development certificates
+const (
+	devCAValidity   = 10 * 365 * 24 * time.Hour
+	devLeafValidity = 365 * 24 * time.Hour
+)
+
+// DevLeaf describes a certificate issued by the development CA. It is written to
+// <Name>.pem with its key in <Name>-key.pem.
+type DevLeaf struct {
+	Name     string   // File name and common name
+	DNSNames []string // Host names, also used as client identities
+	Server   bool
+	Client   bool
+}
+
+// CertFile returns the path of the leaf certificate in dir
+func (l DevLeaf) CertFile(dir string) string {
+	return filepath.Join(dir, l.Name+".pem")
+}
+
+// KeyFile returns the path of the leaf key in dir
+func (l DevLeaf) KeyFile(dir string) string {
+	return filepath.Join(dir, l.Name+"-key.pem")
+}
+
+// EnsureDev creates a local CA in dir and issues the leaves from it, keeping any file
+// that already exists. It is meant for development only: the CA key is kept next to the
+// certificates it signs.
+func EnsureDev(dir string, leaves ...DevLeaf) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	ca, err := ensureDevCA(dir)
+	if err != nil {
+		return err
+	}
+	for _, leaf := range leaves {
+		if _, err := os.Stat(leaf.CertFile(dir)); err == nil {
+			continue
+		}
+		if err := issueDevLeaf(dir, ca, leaf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureDevCA loads the CA bundle, creating it first if needed
+func ensureDevCA(dir string) (tls.Certificate, error) {
+	bundleFile := filepath.Join(dir, devCABundleFile)
+	if _, err := os.Stat(bundleFile); errors.Is(err, os.ErrNotExist) {
+		bundle, err := newDevCA()
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		// Another service may have created the CA in the meantime, in which case it wins
+		if err := writeFileOnce(bundleFile, bundle); err != nil && !errors.Is(err, os.ErrExist) {
+			return tls.Certificate{}, err
+		}
+	}
+
+	bundle, err := os.ReadFile(bundleFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to read the development CA: %w", err)
+	}
+	ca, err := tls.X509KeyPair(bundle, bundle)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load the development CA: %w", err)
+	}
+	if ca.Leaf == nil {
+		if ca.Leaf, err = x509.ParseCertificate(ca.Certificate[0]); err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+
+	// The certificate alone is what clients and servers trust
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]})
+	caFile := filepath.Join(dir, DevCAFile)
+	if existing, err := os.ReadFile(caFile); err != nil || !bytes.Equal(existing, certPEM) {
+		if err := writeFileAtomic(caFile, certPEM, 0o644); err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+	return ca, nil
+}
+
+// newDevCA returns the PEM bundle of a new self-signed CA
+func newDevCA() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Medusa development"}, CommonName: "Medusa development CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(devCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the development CA: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return append(bundle, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})...), nil
+}
+
+// issueDevLeaf writes a certificate signed by the CA and its key
+func issueDevLeaf(dir string, ca tls.Certificate, leaf DevLeaf) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"Medusa development"}, CommonName: leaf.Name},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(devLeafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	for _, name := range leaf.DNSNames {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+	if leaf.Server {
+		template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
+	}
+	if leaf.Client {
+		template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageClientAuth)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Leaf, &key.PublicKey, ca.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to issue %s: %w", leaf.Name, err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	// The key goes first so a watcher never sees a certificate without its key
+	if err := writeFileAtomic(leaf.KeyFile(dir), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return err
+	}
+	return writeFileAtomic(leaf.CertFile(dir), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// writeFileAtomic replaces path with data in a single rename
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// writeFileOnce creates path with data, failing with os.ErrExist if it is already there.
+// The file appears complete or not at all.
+func writeFileOnce(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Link(tmp.Name(), path)
+}
@@ -0,0 +1,138 @@
+// Package certs loads TLS certificates from files, reloads them when the files change and
+// builds the server and client TLS configurations used by the services.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store holds a key pair and, optionally, a CA bundle read from PEM files. Handshakes always
+// see the latest successfully loaded files, so certificates can be rotated without a restart.
+type Store struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	cert atomic.Pointer[tls.Certificate]
+	pool atomic.Pointer[x509.CertPool]
+
+	mu      sync.Mutex
+	version string // Sizes and modification times of the files last loaded
+}
+
+// NewStore loads the key pair and the CA bundle. Either may be left empty when the
+// configuration using the store does not need it.
+func NewStore(certFile, keyFile, caFile string) (*Store, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("certificate and key files must be set together")
+	}
+	s := &Store{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads the files again if any of them changed since the last load and reports
+// whether it did. On error the previously loaded certificates stay in use.
+func (s *Store) Reload() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version, err := s.fileVersion()
+	if err != nil {
+		return false, err
+	}
+	if version == s.version {
+		return false, nil
+	}
+
+	if s.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+		if err != nil {
+			return false, fmt.Errorf("failed to load key pair %s: %w", s.certFile, err)
+		}
+		s.cert.Store(&cert)
+	}
+	if s.caFile != "" {
+		pool, err := loadPool(s.caFile)
+		if err != nil {
+			return false, err
+		}
+		s.pool.Store(pool)
+	}
+	s.version = version
+	return true, nil
+}
+
+// Watch reloads the files every interval until ctx is done. Every reload that read changed
+// files is reported to report, with a nil error, and so is every failed one, which is
+// retried on the next tick.
+func (s *Store) Watch(ctx context.Context, interval time.Duration, report func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := s.Reload()
+			if err != nil || reloaded {
+				report(err)
+			}
+		}
+	}
+}
+
+// CertFile returns the file the key pair is read from
+func (s *Store) CertFile() string {
+	return s.certFile
+}
+
+// Certificate returns the current key pair
+func (s *Store) Certificate() *tls.Certificate {
+	return s.cert.Load()
+}
+
+// Pool returns the current CA bundle
+func (s *Store) Pool() *x509.CertPool {
+	return s.pool.Load()
+}
+
+// fileVersion summarises the files so changes can be detected without reading them
+func (s *Store) fileVersion() (string, error) {
+	var version strings.Builder
+	for _, file := range []string{s.certFile, s.keyFile, s.caFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&version, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return version.String(), nil
+}
+
+// loadPool reads a PEM bundle of CA certificates
+func loadPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", file)
+	}
+	return pool, nil
+}
@@ -0,0 +1,5 @@
+// Package userpb holds the protobuf messages and gRPC service that manage auth-service
+// users. auth-service serves UserService and core-service calls it.
+package userpb
+
+//go:generate protoc -I .. --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative ../userpb/users.proto